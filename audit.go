@@ -0,0 +1,636 @@
+package zerokv
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// The audit log is a sequence of records, each a big-endian uint32 length
+// followed by that many bytes:
+//
+//	op        one byte, an Op
+//	time      uint64 big-endian, Unix nanoseconds when the record was written
+//	ttl       uvarint nanoseconds, 0 unless written by PutWithTTL
+//	key       uvarint(len(key)) key
+//	value     the rest: the value for OpPut, the end for OpDeleteRange, where
+//	          an empty end means no upper bound
+//
+// There is no header, so a log can be appended to across restarts.
+
+// ErrInvalidAuditLog is returned by ReplayAuditLog when a record is
+// malformed or the log ends in the middle of a record.
+var ErrInvalidAuditLog = errors.New("zerokv: invalid audit log")
+
+// maxAuditRecord bounds a record so a corrupt length cannot trigger a huge
+// allocation.
+const maxAuditRecord = 1<<31 - 1
+
+// auditCore logs every write to w before applying it to core.
+type auditCore struct {
+	core Core
+	mu   sync.Mutex // serializes logging and applying, so the log order is the apply order
+	w    io.Writer
+	buf  []byte
+}
+
+// WithAuditLog returns a Core that appends a record of every write to w
+// before applying it. Each logged write calls Flush on w if it has one, and
+// Sync if it has one, so an *os.File is synced before the write goes ahead.
+// Writes through the returned Core are serialized, which keeps the log in
+// the order the writes were applied. ReplayAuditLog rebuilds a store from
+// the log.
+//
+// Put, PutWithTTL, PutMany, Delete, DeletePrefix, DeleteRange, Truncate and
+// TruncatePrefix are logged as they are called; batches and transactions
+// log each of their writes when Commit is called. CompareAndSwap,
+// Increment, Merge and GetOrPut cannot know the value they store in
+// advance, so they log it once the write has succeeded. A record is not
+// withdrawn when the write it describes fails afterward. Restore is not
+// supported, because its writes cannot be logged. Close closes core but
+// not w.
+func WithAuditLog(core Core, w io.Writer) Core {
+	return &auditCore{core: core, w: w}
+}
+
+// log writes one record per event and flushes w.
+func (c *auditCore) log(ttl time.Duration, events ...Event) error {
+	now := uint64(time.Now().UnixNano())
+	for _, ev := range events {
+		buf := append(c.buf[:0], 0, 0, 0, 0, byte(ev.Op))
+		buf = binary.BigEndian.AppendUint64(buf, now)
+		buf = binary.AppendUvarint(buf, uint64(ttl))
+		buf = binary.AppendUvarint(buf, uint64(len(ev.Key)))
+		buf = append(buf, ev.Key...)
+		if ev.Op == OpDeleteRange {
+			buf = append(buf, ev.End...)
+		} else {
+			buf = append(buf, ev.Value...)
+		}
+		if len(buf)-4 > maxAuditRecord {
+			return fmt.Errorf("zerokv: audit record of %d bytes is too large", len(buf)-4)
+		}
+		binary.BigEndian.PutUint32(buf, uint32(len(buf)-4))
+		c.buf = buf
+		if _, err := c.w.Write(buf); err != nil {
+			return fmt.Errorf("zerokv: writing audit log: %w", err)
+		}
+	}
+	if f, ok := c.w.(interface{ Flush() error }); ok {
+		if err := f.Flush(); err != nil {
+			return fmt.Errorf("zerokv: flushing audit log: %w", err)
+		}
+	}
+	if s, ok := c.w.(interface{ Sync() error }); ok {
+		if err := s.Sync(); err != nil {
+			return fmt.Errorf("zerokv: syncing audit log: %w", err)
+		}
+	}
+	return nil
+}
+
+func (c *auditCore) Put(ctx context.Context, key []byte, data []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.log(0, Event{Op: OpPut, Key: key, Value: data}); err != nil {
+		return err
+	}
+	return c.core.Put(ctx, key, data)
+}
+
+func (c *auditCore) PutWithTTL(ctx context.Context, key, value []byte, ttl time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.log(ttl, Event{Op: OpPut, Key: key, Value: value}); err != nil {
+		return err
+	}
+	return c.core.PutWithTTL(ctx, key, value, ttl)
+}
+
+func (c *auditCore) Get(ctx context.Context, key []byte) ([]byte, error) {
+	return c.core.Get(ctx, key)
+}
+
+func (c *auditCore) GetOrDefault(ctx context.Context, key, def []byte) ([]byte, error) {
+	return c.core.GetOrDefault(ctx, key, def)
+}
+
+func (c *auditCore) GetInto(ctx context.Context, key, dst []byte) ([]byte, error) {
+	return c.core.GetInto(ctx, key, dst)
+}
+
+func (c *auditCore) View(ctx context.Context, key []byte, fn func(value []byte) error) error {
+	return c.core.View(ctx, key, fn)
+}
+
+func (c *auditCore) Has(ctx context.Context, key []byte) (bool, error) {
+	return c.core.Has(ctx, key)
+}
+
+func (c *auditCore) SizeOf(ctx context.Context, key []byte) (int, error) {
+	return c.core.SizeOf(ctx, key)
+}
+
+func (c *auditCore) GetMany(ctx context.Context, keys [][]byte) ([][]byte, error) {
+	return c.core.GetMany(ctx, keys)
+}
+
+func (c *auditCore) HasMany(ctx context.Context, keys [][]byte) ([]bool, error) {
+	return c.core.HasMany(ctx, keys)
+}
+
+func (c *auditCore) PutMany(ctx context.Context, keys, values [][]byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if len(keys) != len(values) {
+		return c.core.PutMany(ctx, keys, values)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.log(0, PutEvents(keys, values)...); err != nil {
+		return err
+	}
+	return c.core.PutMany(ctx, keys, values)
+}
+
+func (c *auditCore) Delete(ctx context.Context, key []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.log(0, Event{Op: OpDelete, Key: key}); err != nil {
+		return err
+	}
+	return c.core.Delete(ctx, key)
+}
+
+func (c *auditCore) DeletePrefix(ctx context.Context, prefix []byte) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.log(0, DeletePrefixEvent(prefix)); err != nil {
+		return 0, err
+	}
+	return c.core.DeletePrefix(ctx, prefix)
+}
+
+func (c *auditCore) DeleteRange(ctx context.Context, start, end []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// An empty range removes nothing; skipping it keeps an empty end free to
+	// mean "no upper bound" in the log.
+	if end == nil || bytes.Compare(start, end) < 0 {
+		if err := c.log(0, Event{Op: OpDeleteRange, Key: start, End: end}); err != nil {
+			return err
+		}
+	}
+	return c.core.DeleteRange(ctx, start, end)
+}
+
+// Truncate is logged as a DeleteRange with no bounds.
+func (c *auditCore) Truncate(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.log(0, Event{Op: OpDeleteRange}); err != nil {
+		return err
+	}
+	return c.core.Truncate(ctx)
+}
+
+// TruncatePrefix is logged as a DeletePrefix; compaction is not recorded.
+func (c *auditCore) TruncatePrefix(ctx context.Context, prefix []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.log(0, DeletePrefixEvent(prefix)); err != nil {
+		return err
+	}
+	return c.core.TruncatePrefix(ctx, prefix)
+}
+
+func (c *auditCore) Count(ctx context.Context, prefix []byte) (int64, error) {
+	return c.core.Count(ctx, prefix)
+}
+
+func (c *auditCore) EstimateSize(prefix []byte) (int64, error) {
+	return c.core.EstimateSize(prefix)
+}
+
+func (c *auditCore) NewTransaction(ctx context.Context) (Txn, error) {
+	txn, err := c.core.NewTransaction(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &auditTxn{txn: txn, c: c}, nil
+}
+
+func (c *auditCore) CompareAndSwap(ctx context.Context, key, old, new []byte) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	swapped, err := c.core.CompareAndSwap(ctx, key, old, new)
+	if err != nil || !swapped {
+		return swapped, err
+	}
+	return true, c.log(0, Event{Op: OpPut, Key: key, Value: new})
+}
+
+func (c *auditCore) Increment(ctx context.Context, key []byte, delta int64) (int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	n, err := c.core.Increment(ctx, key, delta)
+	if err != nil {
+		return n, err
+	}
+	return n, c.log(0, Event{Op: OpPut, Key: key, Value: EncodeCounter(n)})
+}
+
+func (c *auditCore) GetOrPut(ctx context.Context, key []byte, fill func() ([]byte, error)) ([]byte, error) {
+	if value, err := c.core.Get(ctx, key); err == nil {
+		return value, nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	value, err := c.core.GetOrPut(ctx, key, fill)
+	if err != nil {
+		return nil, err
+	}
+	return value, c.log(0, Event{Op: OpPut, Key: key, Value: value})
+}
+
+// Merge is applied under the log lock so the merged value can be read back
+// and logged before any other write through this Core changes it.
+func (c *auditCore) Merge(ctx context.Context, key, operand []byte, merge func(existing, operand []byte) []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var merged []byte
+	err := c.core.Merge(ctx, key, operand, func(existing, operand []byte) []byte {
+		merged = merge(existing, operand)
+		return merged
+	})
+	if err != nil {
+		return err
+	}
+	return c.log(0, Event{Op: OpPut, Key: key, Value: merged})
+}
+
+func (c *auditCore) Stats(ctx context.Context) (Stats, error) {
+	return c.core.Stats(ctx)
+}
+
+func (c *auditCore) Sync(ctx context.Context) error {
+	return c.core.Sync(ctx)
+}
+
+func (c *auditCore) Compact(ctx context.Context, start, end []byte) error {
+	return c.core.Compact(ctx, start, end)
+}
+
+func (c *auditCore) Ping(ctx context.Context) error {
+	return c.core.Ping(ctx)
+}
+
+func (c *auditCore) Backup(ctx context.Context, w io.Writer) error {
+	return c.core.Backup(ctx, w)
+}
+
+// Restore is not supported: the entries of a native backup cannot be logged.
+func (c *auditCore) Restore(ctx context.Context, r io.Reader) error {
+	return fmt.Errorf("zerokv: audit log: Restore: %w", ErrNotSupported)
+}
+
+// Checkpoint only reads the database, so nothing is logged.
+func (c *auditCore) Checkpoint(ctx context.Context, destDir string) error {
+	return c.core.Checkpoint(ctx, destDir)
+}
+
+func (c *auditCore) Snapshot() (Snapshot, error) {
+	return c.core.Snapshot()
+}
+
+func (c *auditCore) Batch() Batch {
+	return &auditBatch{c: c}
+}
+
+// BulkLoad stages writes in audited batches of zerokv.BulkLoadSegmentSize
+// bytes so each segment is logged as it is applied. It does not use the
+// wrapped Core's BulkLoad, whose writes could not be logged.
+func (c *auditCore) BulkLoad(ctx context.Context, fn func(b Batch) error) error {
+	return RunBulkLoad(ctx, c.BatchWithOptions(0, BulkLoadSegmentSize), fn)
+}
+
+func (c *auditCore) BatchWithOptions(maxOps, maxBytes int) Batch {
+	return NewAutoFlushBatch(c.Batch, maxOps, maxBytes)
+}
+
+func (c *auditCore) Scan(prefix []byte) Iterator {
+	return c.core.Scan(prefix)
+}
+
+func (c *auditCore) ReverseScan(prefix []byte) Iterator {
+	return c.core.ReverseScan(prefix)
+}
+
+func (c *auditCore) RangeScan(start, end []byte) Iterator {
+	return c.core.RangeScan(start, end)
+}
+
+func (c *auditCore) ScanFrom(prefix, start []byte) Iterator {
+	return c.core.ScanFrom(prefix, start)
+}
+
+func (c *auditCore) ScanKeys(prefix []byte) Iterator {
+	return c.core.ScanKeys(prefix)
+}
+
+func (c *auditCore) ScanContext(ctx context.Context, prefix []byte) Iterator {
+	return c.core.ScanContext(ctx, prefix)
+}
+
+func (c *auditCore) Watch(ctx context.Context, prefix []byte) (<-chan Event, error) {
+	return c.core.Watch(ctx, prefix)
+}
+
+func (c *auditCore) Close() error {
+	return c.core.Close()
+}
+
+// Unwrap returns the wrapped Core.
+func (c *auditCore) Unwrap() Core {
+	return c.core
+}
+
+// auditBatch stages writes in memory and, on Commit, logs them and applies
+// them in one batch of the wrapped Core.
+type auditBatch struct {
+	c        *auditCore
+	events   []Event
+	ifAbsent map[int]bool // indexes of events staged by PutIfAbsent
+}
+
+func (b *auditBatch) Put(key []byte, data []byte) error {
+	b.events = append(b.events, Event{Op: OpPut, Key: bytes.Clone(key), Value: bytes.Clone(data)})
+	return nil
+}
+
+func (b *auditBatch) Delete(key []byte) error {
+	b.events = append(b.events, Event{Op: OpDelete, Key: bytes.Clone(key)})
+	return nil
+}
+
+func (b *auditBatch) PutIfAbsent(key, data []byte) error {
+	if b.ifAbsent == nil {
+		b.ifAbsent = make(map[int]bool)
+	}
+	b.ifAbsent[len(b.events)] = true
+	return b.Put(key, data)
+}
+
+func (b *auditBatch) Len() int {
+	return len(b.events)
+}
+
+func (b *auditBatch) Size() int {
+	size := 0
+	for _, ev := range b.events {
+		size += len(ev.Key) + len(ev.Value)
+	}
+	return size
+}
+
+func (b *auditBatch) Reset() {
+	b.events = nil
+	b.ifAbsent = nil
+}
+
+// Validate stages the writes on a throwaway batch of the wrapped Core and
+// validates that; nothing is logged.
+func (b *auditBatch) Validate() error {
+	batch, err := b.stage(b.events, b.ifAbsent)
+	if err != nil {
+		return err
+	}
+	defer batch.Reset()
+	return batch.Validate()
+}
+
+// stage queues events on a new batch of the wrapped Core.
+func (b *auditBatch) stage(events []Event, ifAbsent map[int]bool) (Batch, error) {
+	batch := b.c.core.Batch()
+	for i, ev := range events {
+		var err error
+		if ifAbsent[i] {
+			err = batch.PutIfAbsent(ev.Key, ev.Value)
+		} else if ev.Op == OpPut {
+			err = batch.Put(ev.Key, ev.Value)
+		} else {
+			err = batch.Delete(ev.Key)
+		}
+		if err != nil {
+			batch.Reset()
+			return nil, err
+		}
+	}
+	return batch, nil
+}
+
+func (b *auditBatch) Commit(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	events, ifAbsent := b.events, b.ifAbsent
+	b.events, b.ifAbsent = nil, nil
+	if len(events) == 0 {
+		return nil
+	}
+	batch, err := b.stage(events, ifAbsent)
+	if err != nil {
+		return err
+	}
+	b.c.mu.Lock()
+	defer b.c.mu.Unlock()
+	logged, err := b.resolve(ctx, events, ifAbsent)
+	if err != nil {
+		batch.Reset()
+		return err
+	}
+	if err := b.c.log(0, logged...); err != nil {
+		return err
+	}
+	return batch.Commit(ctx)
+}
+
+// resolve returns the events that committing will apply, dropping each
+// PutIfAbsent whose key an earlier event put or, failing that, the store
+// holds. It runs under the log lock, so only writers bypassing the audit
+// log can change the answer before the commit.
+func (b *auditBatch) resolve(ctx context.Context, events []Event, ifAbsent map[int]bool) ([]Event, error) {
+	if len(ifAbsent) == 0 {
+		return events, nil
+	}
+	present := make(map[string]bool)
+	applied := make([]Event, 0, len(events))
+	for i, ev := range events {
+		if ifAbsent[i] {
+			has, ok := present[string(ev.Key)]
+			if !ok {
+				var err error
+				if has, err = b.c.core.Has(ctx, ev.Key); err != nil {
+					return nil, err
+				}
+			}
+			if has {
+				continue
+			}
+		}
+		present[string(ev.Key)] = ev.Op == OpPut
+		applied = append(applied, ev)
+	}
+	return applied, nil
+}
+
+// auditTxn records the transaction's writes and logs them when it commits.
+type auditTxn struct {
+	txn    Txn
+	c      *auditCore
+	events []Event
+}
+
+func (x *auditTxn) Get(ctx context.Context, key []byte) ([]byte, error) {
+	return x.txn.Get(ctx, key)
+}
+
+func (x *auditTxn) Put(ctx context.Context, key []byte, data []byte) error {
+	if err := x.txn.Put(ctx, key, data); err != nil {
+		return err
+	}
+	x.events = append(x.events, Event{Op: OpPut, Key: bytes.Clone(key), Value: bytes.Clone(data)})
+	return nil
+}
+
+func (x *auditTxn) Delete(ctx context.Context, key []byte) error {
+	if err := x.txn.Delete(ctx, key); err != nil {
+		return err
+	}
+	x.events = append(x.events, Event{Op: OpDelete, Key: bytes.Clone(key)})
+	return nil
+}
+
+func (x *auditTxn) Commit(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	x.c.mu.Lock()
+	defer x.c.mu.Unlock()
+	if err := x.c.log(0, x.events...); err != nil {
+		return err
+	}
+	return x.txn.Commit(ctx)
+}
+
+func (x *auditTxn) Discard() { x.txn.Discard() }
+
+// ReplayAuditLog applies every record of an audit log written by
+// WithAuditLog to core, in order. Keys written by PutWithTTL get the time
+// they had left, and are skipped if they have already expired. A log that
+// ends in the middle of a record, as after a crash during a write, returns
+// an error wrapping ErrInvalidAuditLog once every complete record before it
+// has been applied.
+func ReplayAuditLog(r io.Reader, core Core) error {
+	ctx := context.Background()
+	br := bufio.NewReader(r)
+	var header [4]byte
+	for n := 1; ; n++ {
+		if _, err := io.ReadFull(br, header[:]); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return auditCorrupt(n, err)
+		}
+		size := binary.BigEndian.Uint32(header[:])
+		if size > maxAuditRecord {
+			return fmt.Errorf("%w: record %d: %d bytes", ErrInvalidAuditLog, n, size)
+		}
+		body := make([]byte, size)
+		if _, err := io.ReadFull(br, body); err != nil {
+			return auditCorrupt(n, err)
+		}
+		if err := replayAuditRecord(ctx, core, body); err != nil {
+			return fmt.Errorf("record %d: %w", n, err)
+		}
+	}
+}
+
+// replayAuditRecord decodes one record body and applies it to core.
+func replayAuditRecord(ctx context.Context, core Core, body []byte) error {
+	if len(body) < 9 {
+		return fmt.Errorf("%w: short record", ErrInvalidAuditLog)
+	}
+	op := Op(body[0])
+	written := time.Unix(0, int64(binary.BigEndian.Uint64(body[1:9])))
+	rest := body[9:]
+	ttl, n := binary.Uvarint(rest)
+	if n <= 0 {
+		return fmt.Errorf("%w: bad ttl", ErrInvalidAuditLog)
+	}
+	rest = rest[n:]
+	keyLen, n := binary.Uvarint(rest)
+	if n <= 0 || keyLen > uint64(len(rest)-n) {
+		return fmt.Errorf("%w: bad key length", ErrInvalidAuditLog)
+	}
+	key := rest[n : n+int(keyLen)]
+	value := rest[n+int(keyLen):]
+
+	switch op {
+	case OpPut:
+		if ttl == 0 {
+			return core.Put(ctx, key, value)
+		}
+		left := time.Until(written.Add(time.Duration(ttl)))
+		if left <= 0 {
+			return nil
+		}
+		return core.PutWithTTL(ctx, key, value, left)
+	case OpDelete:
+		return core.Delete(ctx, key)
+	case OpDeleteRange:
+		if len(value) == 0 {
+			value = nil
+		}
+		return core.DeleteRange(ctx, key, value)
+	default:
+		return fmt.Errorf("%w: unknown op %d", ErrInvalidAuditLog, op)
+	}
+}
+
+// auditCorrupt reports a read failure in record n; running out of input
+// means the log was cut short.
+func auditCorrupt(n int, err error) error {
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return fmt.Errorf("%w: record %d is truncated", ErrInvalidAuditLog, n)
+	}
+	return err
+}
@@ -0,0 +1,174 @@
+package zerokv
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+)
+
+// Backends without a native backup format share a portable stream, so a
+// backup taken from one of them can be restored into any other. The stream
+// is the magic header followed by records, each introduced by a tag byte:
+//
+//	backupEntry    uvarint(len(key)) key uvarint(len(value)) value
+//	backupEntryTTL the same, followed by varint(expiresAt in Unix nanoseconds)
+//	backupEnd      marks a complete stream; nothing follows it
+//
+// The version byte at the end of the header changes whenever the record
+// layout does.
+var backupMagic = []byte("ZKVBAK\x00\x01")
+
+const (
+	backupEnd      byte = 0
+	backupEntry    byte = 1
+	backupEntryTTL byte = 2
+)
+
+// ErrInvalidBackup is returned when a backup stream has an unknown header,
+// an unsupported version or a malformed record.
+var ErrInvalidBackup = errors.New("zerokv: invalid backup stream")
+
+// maxBackupField bounds a single key or value so a corrupt length cannot
+// trigger a huge allocation.
+const maxBackupField = 1 << 30
+
+// BackupWriter writes the portable backup stream.
+type BackupWriter struct {
+	w   *bufio.Writer
+	buf []byte
+}
+
+// NewBackupWriter writes the stream header to w.
+func NewBackupWriter(w io.Writer) (*BackupWriter, error) {
+	bw := &BackupWriter{w: bufio.NewWriter(w)}
+	if _, err := bw.w.Write(backupMagic); err != nil {
+		return nil, err
+	}
+	return bw, nil
+}
+
+// Write appends one key-value pair; expiresAt is the expiry in Unix
+// nanoseconds, or 0 when the key does not expire.
+func (bw *BackupWriter) Write(key, value []byte, expiresAt int64) error {
+	tag := backupEntry
+	if expiresAt != 0 {
+		tag = backupEntryTTL
+	}
+	buf := append(bw.buf[:0], tag)
+	buf = binary.AppendUvarint(buf, uint64(len(key)))
+	buf = append(buf, key...)
+	buf = binary.AppendUvarint(buf, uint64(len(value)))
+	buf = append(buf, value...)
+	if tag == backupEntryTTL {
+		buf = binary.AppendVarint(buf, expiresAt)
+	}
+	bw.buf = buf
+	_, err := bw.w.Write(buf)
+	return err
+}
+
+// Close writes the end marker and flushes buffered data. It does not close
+// the underlying writer.
+func (bw *BackupWriter) Close() error {
+	if err := bw.w.WriteByte(backupEnd); err != nil {
+		return err
+	}
+	return bw.w.Flush()
+}
+
+// BackupReader reads the portable backup stream.
+type BackupReader struct {
+	r    *bufio.Reader
+	done bool
+}
+
+// NewBackupReader reads and checks the stream header from r.
+func NewBackupReader(r io.Reader) (*BackupReader, error) {
+	br := &BackupReader{r: bufio.NewReader(r)}
+	header := make([]byte, len(backupMagic))
+	if _, err := io.ReadFull(br.r, header); err != nil {
+		return nil, fmt.Errorf("%w: reading header: %v", ErrInvalidBackup, err)
+	}
+	if string(header[:len(header)-1]) != string(backupMagic[:len(backupMagic)-1]) {
+		return nil, fmt.Errorf("%w: bad magic", ErrInvalidBackup)
+	}
+	if v := header[len(header)-1]; v != backupMagic[len(backupMagic)-1] {
+		return nil, fmt.Errorf("%w: unsupported version %d", ErrInvalidBackup, v)
+	}
+	return br, nil
+}
+
+// Next returns the next record. It returns io.EOF after the end marker, and
+// an error wrapping ErrInvalidBackup if the stream is truncated or corrupt.
+func (br *BackupReader) Next() (key, value []byte, expiresAt int64, err error) {
+	if br.done {
+		return nil, nil, 0, io.EOF
+	}
+	tag, err := br.r.ReadByte()
+	if err != nil {
+		return nil, nil, 0, br.corrupt(err)
+	}
+	switch tag {
+	case backupEnd:
+		br.done = true
+		return nil, nil, 0, io.EOF
+	case backupEntry, backupEntryTTL:
+	default:
+		return nil, nil, 0, fmt.Errorf("%w: unknown record tag %d", ErrInvalidBackup, tag)
+	}
+	if key, err = br.readBytes(); err != nil {
+		return nil, nil, 0, err
+	}
+	if value, err = br.readBytes(); err != nil {
+		return nil, nil, 0, err
+	}
+	if tag == backupEntryTTL {
+		if expiresAt, err = binary.ReadVarint(br.r); err != nil {
+			return nil, nil, 0, br.corrupt(err)
+		}
+	}
+	return key, value, expiresAt, nil
+}
+
+// readBytes reads a uvarint length followed by that many bytes.
+func (br *BackupReader) readBytes() ([]byte, error) {
+	n, err := binary.ReadUvarint(br.r)
+	if err != nil {
+		return nil, br.corrupt(err)
+	}
+	if n > maxBackupField {
+		return nil, fmt.Errorf("%w: field of %d bytes", ErrInvalidBackup, n)
+	}
+	b := make([]byte, n)
+	if _, err := io.ReadFull(br.r, b); err != nil {
+		return nil, br.corrupt(err)
+	}
+	return b, nil
+}
+
+// corrupt reports a read failure inside the stream; running out of input
+// before the end marker means the backup was truncated.
+func (br *BackupReader) corrupt(err error) error {
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return fmt.Errorf("%w: truncated", ErrInvalidBackup)
+	}
+	return err
+}
+
+// CheckCheckpointDir returns an error wrapping ErrCheckpointExists if dir
+// already exists, since a checkpoint is only ever written to a fresh
+// directory. Backends call it before creating one.
+func CheckCheckpointDir(dir string) error {
+	_, err := os.Stat(dir)
+	if err == nil {
+		return fmt.Errorf("%w: %s", ErrCheckpointExists, dir)
+	}
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
@@ -1,139 +1,1460 @@
 package badgerdb
 
 import (
+	"bytes"
 	"context"
 	"errors"
+	"fmt"
+	"io"
+	"runtime"
+	"sync"
+	"time"
 
 	"github.com/rawbytedev/zerokv"
 
 	"github.com/dgraph-io/badger/v4"
 )
 
+// maxPendingWrites bounds the writes db.Load keeps in flight during Restore.
+const maxPendingWrites = 256
+
+// compactDiscardRatio is the share of a value log file that must be stale
+// before Compact rewrites it.
+const compactDiscardRatio = 0.5
+
 type BadgerDB struct {
-	db *badger.DB
+	db       *badger.DB
+	readOnly bool
+	fills    zerokv.KeyedMutex // serializes GetOrPut per key
+	watchers zerokv.Watchers
+	limits   zerokv.SizeLimits // Config.MaxKeySize and Config.MaxValueSize
+}
+type badgerBatch struct {
+	db       *badger.DB
+	batch    *badger.WriteBatch
+	readOnly bool
+	watchers *zerokv.Watchers
+	events   []zerokv.Event
+	ops      int
+	bytes    int
+	limits   zerokv.SizeLimits
+	// ifAbsent holds the PutIfAbsent calls in order, each with the write
+	// batch of the operations staged before it
+	ifAbsent []pendingPut
+	check    zerokv.BatchValidator
+}
+
+// pendingPut is a PutIfAbsent staged on a badgerBatch.
+type pendingPut struct {
+	before     *badger.WriteBatch
+	key, value []byte
+	event      int // index in badgerBatch.events, or -1
+}
+
+type badgerIterator struct {
+	txn      *badger.Txn
+	snap     *badgerSnapshot // set instead of txn when opened from a snapshot
+	Iterator *badger.Iterator
+	prefix   []byte
+	start    []byte // first key to seek to, nil to rewind
+	end      []byte // exclusive upper bound, nil for none
+	keysOnly bool
+	started  bool
+	valid    bool
+	err      []error
+}
+
+func init() {
+	zerokv.Register("badger", func(cfg zerokv.Config) (zerokv.Core, error) {
+		opts, err := paramOptions(cfg.Params)
+		if err != nil {
+			return nil, err
+		}
+		return New(cfg.Dir, opts...)
+	})
+}
+
+// NewBadgerDB initializes and returns a zerokv.Core instance at the specified path(BadgerDB).
+func NewBadgerDB(cfg Config) (zerokv.Core, error) {
+	var opts badger.Options
+	if cfg.BadgerConfigs != nil {
+		opts = *cfg.BadgerConfigs
+	} else {
+		opts = badger.DefaultOptions(cfg.Dir)
+	}
+	if cfg.ReadOnly {
+		opts = opts.WithReadOnly(true)
+	}
+	if cfg.EncryptionKey != nil {
+		opts = opts.WithEncryptionKey(cfg.EncryptionKey)
+	}
+	if cfg.IndexCacheSize > 0 {
+		opts = opts.WithIndexCacheSize(cfg.IndexCacheSize)
+	}
+	if len(opts.EncryptionKey) > 0 && opts.IndexCacheSize <= 0 {
+		return nil, ErrIndexCacheRequired
+	}
+	db, err := badger.Open(opts)
+	if err != nil {
+		return nil, err
+	}
+	limits := zerokv.SizeLimits{MaxKeySize: cfg.MaxKeySize, MaxValueSize: cfg.MaxValueSize}
+	return &BadgerDB{db: db, readOnly: opts.ReadOnly, limits: limits}, nil
+}
+
+// --- Basic CRUD operations ---
+
+// Put inserts or updates a key-value pair in the database.
+func (b *BadgerDB) Put(ctx context.Context, key, value []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if b.readOnly {
+		return zerokv.ErrReadOnly
+	}
+	if err := b.limits.Check(key, value); err != nil {
+		return err
+	}
+	err := b.db.Update(func(txn *badger.Txn) error {
+		return txn.Set(key, value)
+	})
+	if err == nil {
+		b.watchers.Publish(zerokv.Event{Op: zerokv.OpPut, Key: key, Value: value})
+	}
+	return err
+}
+
+// PutSync inserts or updates a key-value pair and, when sync is true, syncs
+// the value log and memtable before returning unless the database already
+// does so for every write (SyncWrites). Badger has no per-write setting, so
+// with SyncWrites on a write is synced even when sync is false.
+func (b *BadgerDB) PutSync(ctx context.Context, key, value []byte, sync bool) error {
+	if err := b.Put(ctx, key, value); err != nil {
+		return err
+	}
+	if sync && !b.db.Opts().SyncWrites {
+		return b.db.Sync()
+	}
+	return nil
+}
+
+// PutWithTTL inserts or updates a key-value pair using Badger's native
+// per-key expiry. Badger stores expiry with one-second granularity.
+func (b *BadgerDB) PutWithTTL(ctx context.Context, key, value []byte, ttl time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if b.readOnly {
+		return zerokv.ErrReadOnly
+	}
+	if err := b.limits.Check(key, value); err != nil {
+		return err
+	}
+	err := b.db.Update(func(txn *badger.Txn) error {
+		return txn.SetEntry(badger.NewEntry(key, value).WithTTL(ttl))
+	})
+	if err == nil {
+		b.watchers.Publish(zerokv.Event{Op: zerokv.OpPut, Key: key, Value: value})
+	}
+	return err
+}
+
+// PutMany writes all pairs atomically in a single transaction. A WriteBatch
+// would split large loads into several transactions, so it is not used here;
+// sets that exceed Badger's transaction limits fail with badger.ErrTxnTooBig.
+func (b *BadgerDB) PutMany(ctx context.Context, keys, values [][]byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if b.readOnly {
+		return zerokv.ErrReadOnly
+	}
+	if len(keys) != len(values) {
+		return fmt.Errorf("PutMany: %d keys but %d values", len(keys), len(values))
+	}
+	if err := b.limits.CheckAll(keys, values); err != nil {
+		return err
+	}
+	err := b.db.Update(func(txn *badger.Txn) error {
+		for i := range keys {
+			if err := txn.Set(keys[i], values[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err == nil && b.watchers.Active() {
+		b.watchers.Publish(zerokv.PutEvents(keys, values)...)
+	}
+	return err
+}
+
+// Get retrieves the value for a given key. Returns an error if not found.
+func (b *BadgerDB) Get(ctx context.Context, key []byte) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	var data []byte
+	err := b.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get(key)
+		if err != nil {
+			return err
+		}
+		return item.Value(func(val []byte) error {
+			data = make([]byte, len(val))
+			copy(data, val)
+			return nil
+		})
+	})
+	return data, notFound(err)
+}
+
+// GetOrDefault returns a copy of def if key is missing.
+func (b *BadgerDB) GetOrDefault(ctx context.Context, key, def []byte) ([]byte, error) {
+	value, err := b.Get(ctx, key)
+	if errors.Is(err, zerokv.ErrKeyNotFound) {
+		return bytes.Clone(def), nil
+	}
+	return value, err
+}
+
+// GetInto copies the value of key into dst from inside a View call, so
+// only Badger's own transaction is allocated.
+func (b *BadgerDB) GetInto(ctx context.Context, key, dst []byte) ([]byte, error) {
+	dst = dst[:0]
+	err := b.View(ctx, key, func(value []byte) error {
+		dst = append(dst, value...)
+		return nil
+	})
+	return dst, err
+}
+
+// View passes fn the value held by Badger's read transaction, without
+// copying it.
+func (b *BadgerDB) View(ctx context.Context, key []byte, fn func(value []byte) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return b.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get(key)
+		if err != nil {
+			return notFound(err)
+		}
+		return item.Value(fn)
+	})
+}
+
+// notFound lets the error badger reports for a missing key also match
+// zerokv.ErrKeyNotFound. Other errors are returned unchanged.
+func notFound(err error) error {
+	if errors.Is(err, badger.ErrKeyNotFound) {
+		return zerokv.KeyNotFound(err)
+	}
+	return err
+}
+
+// GetMany retrieves the values for keys inside a single read transaction.
+// Missing keys yield a nil entry at the same position.
+func (b *BadgerDB) GetMany(ctx context.Context, keys [][]byte) ([][]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	values := make([][]byte, len(keys))
+	err := b.db.View(func(txn *badger.Txn) error {
+		for i, key := range keys {
+			item, err := txn.Get(key)
+			if errors.Is(err, badger.ErrKeyNotFound) {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			if values[i], err = item.ValueCopy(nil); err != nil {
+				return err
+			}
+			if values[i] == nil {
+				values[i] = []byte{}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+// HasMany reports whether each key exists, all checked in one read-only
+// transaction.
+func (b *BadgerDB) HasMany(ctx context.Context, keys [][]byte) ([]bool, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	found := make([]bool, len(keys))
+	err := b.db.View(func(txn *badger.Txn) error {
+		for i, key := range keys {
+			_, err := txn.Get(key)
+			if errors.Is(err, badger.ErrKeyNotFound) {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			found[i] = true
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return found, nil
+}
+
+// Has reports whether a key exists. A missing key returns (false, nil).
+func (b *BadgerDB) Has(ctx context.Context, key []byte) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	err := b.db.View(func(txn *badger.Txn) error {
+		_, err := txn.Get(key)
+		return err
+	})
+	if errors.Is(err, badger.ErrKeyNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// SizeOf returns the length of a key's value from its index entry, without
+// reading the value. For values kept in the value log, those larger than
+// Options.ValueThreshold, Badger can only estimate the length to within a
+// few bytes.
+func (b *BadgerDB) SizeOf(ctx context.Context, key []byte) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	var size int64
+	err := b.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get(key)
+		if err != nil {
+			return notFound(err)
+		}
+		size = item.ValueSize()
+		return nil
+	})
+	return int(size), err
+}
+
+// Delete removes a key-value pair from the database.
+func (b *BadgerDB) Delete(ctx context.Context, key []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if b.readOnly {
+		return zerokv.ErrReadOnly
+	}
+	err := b.db.Update(func(txn *badger.Txn) error {
+		return txn.Delete(key)
+	})
+	if err == nil {
+		b.watchers.Publish(zerokv.Event{Op: zerokv.OpDelete, Key: key})
+	}
+	return err
+}
+
+// DeletePrefix removes every key with the given prefix using DropPrefix,
+// which is much faster than per-key deletes. Keys are counted with a
+// keys-only scan first; DropPrefix blocks writes while it runs.
+func (b *BadgerDB) DeletePrefix(ctx context.Context, prefix []byte) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	if b.readOnly {
+		return 0, zerokv.ErrReadOnly
+	}
+	count := 0
+	err := b.db.View(func(txn *badger.Txn) error {
+		it := txn.NewIterator(badger.IteratorOptions{Prefix: prefix})
+		defer it.Close()
+		for it.Rewind(); it.Valid(); it.Next() {
+			count++
+		}
+		return nil
+	})
+	if err != nil || count == 0 {
+		return 0, err
+	}
+	if len(prefix) == 0 {
+		err = b.db.DropAll()
+	} else {
+		err = b.db.DropPrefix(prefix)
+	}
+	if err != nil {
+		return 0, err
+	}
+	b.watchers.Publish(zerokv.Event{Op: zerokv.OpDeleteRange, Key: prefix, End: zerokv.PrefixUpperBound(prefix)})
+	return count, nil
+}
+
+// DeleteRange removes every key in [start, end). Badger has no range
+// tombstone, so keys are collected with a keys-only scan and deleted
+// through a WriteBatch, which splits the deletes into transactions as needed.
+func (b *BadgerDB) DeleteRange(ctx context.Context, start, end []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if b.readOnly {
+		return zerokv.ErrReadOnly
+	}
+	wb := b.db.NewWriteBatch()
+	defer wb.Cancel()
+	err := b.db.View(func(txn *badger.Txn) error {
+		it := txn.NewIterator(badger.IteratorOptions{PrefetchValues: false})
+		defer it.Close()
+		for it.Seek(start); it.Valid(); it.Next() {
+			key := it.Item().KeyCopy(nil)
+			if end != nil && bytes.Compare(key, end) >= 0 {
+				break
+			}
+			if err := wb.Delete(key); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if err := wb.Flush(); err != nil {
+		return err
+	}
+	b.watchers.Publish(zerokv.Event{Op: zerokv.OpDeleteRange, Key: start, End: end})
+	return nil
+}
+
+// Truncate removes every key with DropAll, which blocks writes while it
+// runs and deletes the LSM tables and value log files outright, so space is
+// freed at once.
+func (b *BadgerDB) Truncate(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if b.readOnly {
+		return zerokv.ErrReadOnly
+	}
+	if err := b.db.DropAll(); err != nil {
+		return err
+	}
+	b.watchers.Publish(zerokv.Event{Op: zerokv.OpDeleteRange})
+	return nil
+}
+
+// TruncatePrefix removes the prefix with DropPrefix, as DeletePrefix does,
+// then garbage collects the value log so the dropped values are freed
+// rather than left for the next GC round.
+func (b *BadgerDB) TruncatePrefix(ctx context.Context, prefix []byte) error {
+	if _, err := b.DeletePrefix(ctx, prefix); err != nil {
+		return err
+	}
+	return b.valueLogGC(ctx, compactDiscardRatio)
+}
+
+// Count returns the number of keys with the given prefix without fetching values.
+// It returns ctx.Err() if ctx is cancelled during the scan.
+func (b *BadgerDB) Count(ctx context.Context, prefix []byte) (int64, error) {
+	var count int64
+	err := b.db.View(func(txn *badger.Txn) error {
+		it := txn.NewIterator(badger.IteratorOptions{Prefix: prefix, PrefetchValues: false})
+		defer it.Close()
+		for it.Rewind(); it.Valid(); it.Next() {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			count++
+		}
+		return ctx.Err()
+	})
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// EstimateSize sums item.EstimatedSize over the keys with the given prefix
+// during a keys-only scan. The figure is approximate: it covers keys and the
+// values or value-log pointers Badger reports, but not value-log overhead
+// such as headers, checksums or space awaiting garbage collection.
+func (b *BadgerDB) EstimateSize(prefix []byte) (int64, error) {
+	var size int64
+	err := b.db.View(func(txn *badger.Txn) error {
+		it := txn.NewIterator(badger.IteratorOptions{Prefix: prefix, PrefetchValues: false})
+		defer it.Close()
+		for it.Rewind(); it.Valid(); it.Next() {
+			size += it.Item().EstimatedSize()
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return size, nil
+}
+
+// CompareAndSwap sets key to new if its current value equals old, or if it
+// is absent when old is nil. The check and the write run in one transaction
+// that is retried on conflict, so a concurrent writer can never slip in
+// between them.
+func (b *BadgerDB) CompareAndSwap(ctx context.Context, key, old, new []byte) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	if b.readOnly {
+		return false, zerokv.ErrReadOnly
+	}
+	var swapped bool
+	err := b.modify(ctx, key, func(current []byte, found bool) ([]byte, bool, error) {
+		swapped = found == (old != nil) && bytes.Equal(current, old)
+		return new, swapped, nil
+	})
+	return swapped && err == nil, err
+}
+
+// Increment adds delta to the counter at key in the same retried
+// transaction as CompareAndSwap, so concurrent increments never lose updates.
+func (b *BadgerDB) Increment(ctx context.Context, key []byte, delta int64) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	if b.readOnly {
+		return 0, zerokv.ErrReadOnly
+	}
+	var n int64
+	err := b.modify(ctx, key, func(current []byte, found bool) ([]byte, bool, error) {
+		n = 0
+		if found {
+			var err error
+			if n, err = zerokv.DecodeCounter(current); err != nil {
+				return nil, false, err
+			}
+		}
+		n += delta
+		return zerokv.EncodeCounter(n), true, nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// GetOrPut returns the value of key, or stores and returns the result of fill
+// when the key is missing. Callers for the same key wait for each other, so
+// fill runs once; the store happens in the same retried transaction as
+// CompareAndSwap and keeps a value that another writer stored first.
+func (b *BadgerDB) GetOrPut(ctx context.Context, key []byte, fill func() ([]byte, error)) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if b.readOnly {
+		return nil, zerokv.ErrReadOnly
+	}
+	unlock := b.fills.Lock(key)
+	defer unlock()
+	value, err := b.Get(ctx, key)
+	if !errors.Is(err, zerokv.ErrKeyNotFound) {
+		return value, err
+	}
+	filled, err := fill()
+	if err != nil {
+		return nil, err
+	}
+	err = b.modify(ctx, key, func(current []byte, found bool) ([]byte, bool, error) {
+		if found {
+			value = current
+			return nil, false, nil
+		}
+		value = filled
+		return filled, true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// Merge replaces the value of key with merge(existing, operand), where
+// existing is nil for a missing key. It runs in the same retried transaction
+// as CompareAndSwap, so merge may be called more than once on conflict and
+// must not have side effects.
+func (b *BadgerDB) Merge(ctx context.Context, key, operand []byte, merge func(existing, operand []byte) []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if b.readOnly {
+		return zerokv.ErrReadOnly
+	}
+	return b.modify(ctx, key, func(current []byte, found bool) ([]byte, bool, error) {
+		return merge(current, operand), true, nil
+	})
+}
+
+// modify runs a read-modify-write of key in a read-write transaction. fn gets
+// the current value and whether the key exists, and returns the value to
+// store and whether to store it. Conflicting commits re-run the whole
+// transaction until it succeeds or ctx is done.
+func (b *BadgerDB) modify(ctx context.Context, key []byte, fn func(current []byte, found bool) ([]byte, bool, error)) error {
+	for {
+		var written []byte
+		var wrote bool
+		err := b.db.Update(func(txn *badger.Txn) error {
+			var current []byte
+			item, err := txn.Get(key)
+			found := err == nil
+			if found {
+				if current, err = item.ValueCopy(nil); err != nil {
+					return err
+				}
+			} else if !errors.Is(err, badger.ErrKeyNotFound) {
+				return err
+			}
+			value, write, err := fn(current, found)
+			if err != nil || !write {
+				return err
+			}
+			if err := b.limits.Check(key, value); err != nil {
+				return err
+			}
+			written, wrote = value, true
+			return txn.Set(key, value)
+		})
+		if err == nil && wrote {
+			b.watchers.Publish(zerokv.Event{Op: zerokv.OpPut, Key: key, Value: written})
+		}
+		if !errors.Is(err, badger.ErrConflict) {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+	}
+}
+
+// Stats reports metrics from the LSM tree. KeyCount sums the key counts of
+// the on-disk tables, so it excludes the memtable and includes stale versions.
+// PendingCompactions counts levels whose compaction score is at least 1.
+// MemtableBytes is not exposed by Badger and is always zero.
+func (b *BadgerDB) Stats(ctx context.Context) (zerokv.Stats, error) {
+	if err := ctx.Err(); err != nil {
+		return zerokv.Stats{}, err
+	}
+	var stats zerokv.Stats
+	for _, table := range b.db.Tables() {
+		stats.KeyCount += int64(table.KeyCount)
+	}
+	lsm, vlog := b.db.Size()
+	stats.OnDiskBytes = lsm + vlog
+	for _, level := range b.db.Levels() {
+		if level.Score >= 1 {
+			stats.PendingCompactions++
+		}
+	}
+	return stats, nil
+}
+
+// Sync fsyncs the value log and memtable WAL with db.Sync. It is only
+// needed when SyncWrites is off, which is Badger's default, and does
+// nothing on a read-only database.
+func (b *BadgerDB) Sync(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if b.readOnly {
+		return nil
+	}
+	return b.db.Sync()
+}
+
+// Compact flattens the LSM tree into a single level and then rewrites value
+// log files until there is nothing left to reclaim. Badger cannot compact a
+// key range, so start and end are ignored and the whole database is
+// compacted. Flatten cannot be interrupted; ctx is checked between steps.
+func (b *BadgerDB) Compact(ctx context.Context, start, end []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if b.readOnly {
+		return zerokv.ErrReadOnly
+	}
+	if err := b.db.Flatten(1); err != nil {
+		return err
+	}
+	return b.valueLogGC(ctx, compactDiscardRatio)
+}
+
+// Optimize flattens the LSM tree into its last level with one compaction
+// worker per CPU. Unlike Compact it does not garbage collect the value log.
+func (b *BadgerDB) Optimize(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if b.readOnly {
+		return zerokv.ErrReadOnly
+	}
+	return b.db.Flatten(runtime.NumCPU())
+}
+
+// CompactAll flattens the LSM tree with one compaction worker. Unlike
+// Compact it leaves the value log to RunValueLogGC.
+func (b *BadgerDB) CompactAll(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if b.readOnly {
+		return zerokv.ErrReadOnly
+	}
+	return b.db.Flatten(1)
+}
+
+// RunValueLogGC rewrites value log files in which at least discardRatio of
+// the data is stale, one file per round, until Badger reports there is
+// nothing left to rewrite. Deleted and overwritten values stay on disk until
+// this runs, so schedule it after large deletes or overwrites, or
+// periodically (for example every few minutes) on write-heavy databases.
+// A discardRatio of 0.5 is a common choice; lower values reclaim more space
+// at the cost of more rewriting. It does nothing on an in-memory database.
+func (b *BadgerDB) RunValueLogGC(discardRatio float64) error {
+	if b.readOnly {
+		return zerokv.ErrReadOnly
+	}
+	return b.valueLogGC(context.Background(), discardRatio)
+}
+
+// valueLogGC calls db.RunValueLogGC until it returns badger.ErrNoRewrite,
+// checking ctx between rounds.
+func (b *BadgerDB) valueLogGC(ctx context.Context, discardRatio float64) error {
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		err := b.db.RunValueLogGC(discardRatio)
+		if errors.Is(err, badger.ErrNoRewrite) || errors.Is(err, badger.ErrGCInMemoryMode) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// Ping runs an empty read-only transaction, which fails once the
+// database is closed.
+func (b *BadgerDB) Ping(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return b.db.View(func(txn *badger.Txn) error { return nil })
+}
+
+// Backup writes a full backup using Badger's native db.Backup. The stream
+// is in Badger's own format and can only be restored into a BadgerDB.
+func (b *BadgerDB) Backup(ctx context.Context, w io.Writer) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	_, err := b.db.Backup(w, 0)
+	return err
+}
+
+// Restore loads a backup produced by Backup using Badger's native db.Load.
+// Existing keys not in the backup are kept.
+func (b *BadgerDB) Restore(ctx context.Context, r io.Reader) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if b.readOnly {
+		return zerokv.ErrReadOnly
+	}
+	return b.db.Load(r, maxPendingWrites)
+}
+
+// Checkpoint copies the database into a new Badger directory at destDir,
+// opened with the same options (including any encryption key), by streaming
+// db.Backup into db.Load. The copy is taken from a single read timestamp, so
+// it is consistent, but unlike Pebble's checkpoint every entry is rewritten.
+// An in-memory database can be checkpointed to disk this way.
+func (b *BadgerDB) Checkpoint(ctx context.Context, destDir string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := zerokv.CheckCheckpointDir(destDir); err != nil {
+		return err
+	}
+	opts := b.db.Opts()
+	opts.Dir, opts.ValueDir = destDir, destDir
+	opts.InMemory, opts.ReadOnly = false, false
+	dst, err := badger.Open(opts)
+	if err != nil {
+		return err
+	}
+	pr, pw := io.Pipe()
+	backupErr := make(chan error, 1)
+	go func() {
+		_, err := b.db.Backup(pw, 0)
+		pw.CloseWithError(err)
+		backupErr <- err
+	}()
+	err = dst.Load(pr, maxPendingWrites)
+	// unblock Backup if Load stopped reading early
+	pr.CloseWithError(err)
+	if berr := <-backupErr; err == nil {
+		err = berr
+	}
+	return errors.Join(err, dst.Close())
+}
+
+// Watch streams the changes made through this BadgerDB to keys with the
+// given prefix until ctx is done or the database is closed. Badger's own
+// Subscribe cannot tell a delete from a put of an empty value, so the write
+// methods publish events themselves. Restore and TTL expiry produce none.
+func (b *BadgerDB) Watch(ctx context.Context, prefix []byte) (<-chan zerokv.Event, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return b.watchers.Watch(ctx, prefix), nil
+}
+
+// Close closes the BadgerDB instance and releases all resources.
+func (b *BadgerDB) Close() error {
+	b.watchers.Close()
+	var errs []error
+	if b.db != nil {
+		if err := b.db.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return errors.Join(errs...)
+}
+
+// -- Batch operations
+
+// batchLimits are the constraints batches validate against: Config's key
+// and value sizes, and no empty keys, which Badger rejects.
+func (b *BadgerDB) batchLimits() zerokv.BatchLimits {
+	return zerokv.BatchLimits{MaxKeySize: b.limits.MaxKeySize, MaxValueSize: b.limits.MaxValueSize}
+}
+
+// Batch creates a new batch operation for the BadgerDB instance.
+func (b *BadgerDB) Batch() zerokv.Batch {
+	return &badgerBatch{db: b.db, batch: b.db.NewWriteBatch(), readOnly: b.readOnly, watchers: &b.watchers, limits: b.limits, check: zerokv.BatchValidator{Limits: b.batchLimits()}}
+}
+
+// BatchWithOptions creates a batch that commits itself once it holds maxOps
+// operations or maxBytes bytes, so bulk loads stay under Badger's
+// transaction size limit. Atomicity only holds per flushed segment.
+func (b *BadgerDB) BatchWithOptions(maxOps, maxBytes int) zerokv.Batch {
+	return zerokv.NewAutoFlushBatch(b.Batch, maxOps, maxBytes)
+}
+
+// bulkPendingTxns is how many transactions a bulk load's WriteBatch may
+// have committing at once, up from Badger's default of 16.
+const bulkPendingTxns = 64
+
+// BulkLoad gives fn a batch on a WriteBatch that keeps up to
+// bulkPendingTxns transactions in flight, then flushes it and syncs once.
+// Badger commits a WriteBatch as it fills, so a failed load leaves those
+// commits in place.
+func (b *BadgerDB) BulkLoad(ctx context.Context, fn func(zerokv.Batch) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if b.readOnly {
+		return zerokv.ErrReadOnly
+	}
+	wb := b.db.NewWriteBatch()
+	wb.SetMaxPendingTxns(bulkPendingTxns)
+	batch := &badgerBatch{db: b.db, batch: wb, watchers: &b.watchers, limits: b.limits, check: zerokv.BatchValidator{Limits: b.batchLimits()}}
+	if err := zerokv.RunBulkLoad(ctx, batch, fn); err != nil {
+		return err
+	}
+	return b.db.Sync()
 }
-type badgerBatch struct {
-	batch *badger.WriteBatch
+
+// Put inserts or updates a key-value pair in the batch.
+func (b *badgerBatch) Put(key, value []byte) error {
+	if b.readOnly {
+		return zerokv.ErrReadOnly
+	}
+	if err := b.limits.Check(key, value); err != nil {
+		return err
+	}
+	if err := b.apply(len(key)+len(value), func(s stager) error { return s.Set(key, value) }); err != nil {
+		return err
+	}
+	b.ops++
+	b.bytes += len(key) + len(value)
+	b.events = b.watchers.Record(b.events, zerokv.Event{Op: zerokv.OpPut, Key: key, Value: value})
+	b.check.Put(key, value)
+	return nil
 }
 
-type badgerIterator struct {
-	Iterator *badger.Iterator
-	started  bool
-	valid    bool
-	err      []error
+// PutIfAbsent stages a put that Commit applies only if key is missing once
+// the operations staged before it are written. A WriteBatch cannot read,
+// so those operations are set aside and the put is checked in its own
+// read-write transaction at Commit.
+func (b *badgerBatch) PutIfAbsent(key, value []byte) error {
+	if b.readOnly {
+		return zerokv.ErrReadOnly
+	}
+	if err := b.limits.Check(key, value); err != nil {
+		return err
+	}
+	event, n := -1, len(b.events)
+	b.events = b.watchers.Record(b.events, zerokv.Event{Op: zerokv.OpPut, Key: key, Value: value})
+	if len(b.events) > n {
+		event = n
+	}
+	b.ifAbsent = append(b.ifAbsent, pendingPut{before: b.batch, key: bytes.Clone(key), value: bytes.Clone(value), event: event})
+	b.batch = b.db.NewWriteBatch()
+	b.ops++
+	b.bytes += len(key) + len(value)
+	b.check.Put(key, value)
+	return nil
 }
 
-// NewBadgerDB initializes and returns a zerokv.Core instance at the specified path(BadgerDB).
-func NewBadgerDB(cfg Config) (zerokv.Core, error) {
-	var opts badger.Options
-	if cfg.BadgerConfigs != nil {
-		opts = *cfg.BadgerConfigs
-	} else {
-		opts = badger.DefaultOptions(cfg.Dir)
+// Delete removes a key-value pair from the batch.
+func (b *badgerBatch) Delete(key []byte) error {
+	if b.readOnly {
+		return zerokv.ErrReadOnly
 	}
-	db, err := badger.Open(opts)
+	if err := b.apply(len(key), func(s stager) error { return s.Delete(key) }); err != nil {
+		return err
+	}
+	b.ops++
+	b.bytes += len(key)
+	b.events = b.watchers.Record(b.events, zerokv.Event{Op: zerokv.OpDelete, Key: key})
+	b.check.Delete(key)
+	return nil
+}
+
+// Len returns the number of operations queued since the last Commit.
+// WriteBatch does not expose its pending count, so the batch keeps its own.
+func (b *badgerBatch) Len() int {
+	return b.ops
+}
+
+// Size returns the bytes of keys and values queued since the last Commit.
+func (b *badgerBatch) Size() int {
+	return b.bytes
+}
+
+// Reset drops the queued operations. A WriteBatch cannot be reused once
+// flushed or cancelled, so a fresh one replaces it.
+func (b *badgerBatch) Reset() {
+	for _, put := range b.ifAbsent {
+		put.before.Cancel()
+	}
+	b.ifAbsent = nil
+	// Cancel drops the pending writes; segments the WriteBatch committed by
+	// itself stay committed
+	b.batch.Cancel()
+	b.batch = b.db.NewWriteBatch()
+	b.events = nil
+	b.ops, b.bytes = 0, 0
+	b.check.Reset()
+}
+
+// Validate reports the first queued operation with an empty key, or a key
+// or value larger than Config allows. Put already rejects most of these, so
+// in practice it catches over-long keys passed to Delete.
+func (b *badgerBatch) Validate() error {
+	return b.check.Err()
+}
+
+// stager is the part of badger.WriteBatch and badger.Txn that apply uses.
+type stager interface {
+	Set(key, value []byte) error
+	Delete(key []byte) error
+}
+
+// apply runs op, which stages an entry of size bytes, against the current
+// WriteBatch. The WriteBatch commits by itself when its transaction fills
+// up, but an entry too large even for an empty transaction makes it fail
+// for good, and Badger then drops the errors of the commits it has in
+// flight. So an entry of at least half the transaction size limit is first
+// tried in a throwaway transaction and rejected if it does not fit there;
+// smaller entries always fit. Should the WriteBatch still fail that way, it
+// is flushed, which waits for its commits and returns their error, and a
+// fresh one replaces it; the batch as a whole is then no longer atomic.
+func (b *badgerBatch) apply(size int, op func(s stager) error) error {
+	if int64(size) >= b.db.MaxBatchSize()/2 {
+		txn := b.db.NewTransaction(true)
+		err := op(txn)
+		txn.Discard()
+		if errors.Is(err, badger.ErrTxnTooBig) {
+			return fmt.Errorf("badgerdb: entry does not fit in a single transaction: %w", err)
+		}
+	}
+	err := op(b.batch)
+	if !errors.Is(err, badger.ErrTxnTooBig) {
+		return err
+	}
+	if ferr := b.renew(); ferr != nil && !errors.Is(ferr, badger.ErrTxnTooBig) {
+		return ferr
+	}
+	return fmt.Errorf("badgerdb: entry does not fit in a single transaction: %w", err)
+}
+
+// renew flushes the current WriteBatch, waiting for the commits it has in
+// flight, replaces it with a fresh one and returns the flush's error. A
+// WriteBatch that already failed returns its error from Flush without
+// waiting, so it is cancelled as well to release it.
+func (b *badgerBatch) renew() error {
+	err := b.batch.Flush()
 	if err != nil {
-		return nil, err
+		b.batch.Cancel()
 	}
-	return &BadgerDB{db: db}, nil
+	b.batch = b.db.NewWriteBatch()
+	return err
 }
 
-// --- Basic CRUD operations ---
+// PutCtx inserts or updates a key-value pair in the batch unless ctx is done.
+func (b *badgerBatch) PutCtx(ctx context.Context, key, value []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return b.Put(key, value)
+}
 
-// Put inserts or updates a key-value pair in the database.
-func (b *BadgerDB) Put(ctx context.Context, key, value []byte) error {
+// DeleteCtx removes a key-value pair from the batch unless ctx is done.
+func (b *badgerBatch) DeleteCtx(ctx context.Context, key []byte) error {
 	if err := ctx.Err(); err != nil {
 		return err
 	}
-	return b.db.Update(func(txn *badger.Txn) error {
-		return txn.Set(key, value)
-	})
+	return b.Delete(key)
 }
 
-// Get retrieves the value for a given key. Returns an error if not found.
-func (b *BadgerDB) Get(ctx context.Context, key []byte) ([]byte, error) {
+// Commits commits the batch operations to the database.
+func (b *badgerBatch) Commit(ctx context.Context) error {
 	if err := ctx.Err(); err != nil {
-		return nil, err
+		return err
 	}
-	var data []byte
-	err := b.db.View(func(txn *badger.Txn) error {
-		item, err := txn.Get(key)
-		if err != nil {
+	if b.readOnly {
+		return zerokv.ErrReadOnly
+	}
+	if len(b.ifAbsent) > 0 {
+		return b.commitIfAbsent()
+	}
+	if err := b.batch.Flush(); err != nil {
+		return err
+	}
+	b.watchers.Publish(b.events...)
+	b.events = nil
+	b.ops, b.bytes = 0, 0
+	b.check.Reset()
+	return nil
+}
+
+// commitIfAbsent flushes the operations staged before each PutIfAbsent,
+// then applies the put in a read-write transaction if the key is missing,
+// and finally flushes the rest. The transaction makes each check-and-put
+// atomic, but the batch as a whole is not: an error leaves the segments
+// before it written.
+func (b *badgerBatch) commitIfAbsent() error {
+	skipped := make(map[int]bool)
+	for len(b.ifAbsent) > 0 {
+		put := b.ifAbsent[0]
+		b.ifAbsent = b.ifAbsent[1:]
+		if err := put.before.Flush(); err != nil {
+			b.Reset()
 			return err
 		}
-		return item.Value(func(val []byte) error {
-			data = make([]byte, len(val))
-			copy(data, val)
-			return nil
+		err := b.db.Update(func(txn *badger.Txn) error {
+			_, err := txn.Get(put.key)
+			if err == nil {
+				skipped[put.event] = true
+				return nil
+			}
+			if !errors.Is(err, badger.ErrKeyNotFound) {
+				return err
+			}
+			return txn.Set(put.key, put.value)
 		})
-	})
+		if err != nil {
+			b.Reset()
+			return err
+		}
+	}
+	if err := b.batch.Flush(); err != nil {
+		return err
+	}
+	events := b.events[:0]
+	for i, ev := range b.events {
+		if !skipped[i] {
+			events = append(events, ev)
+		}
+	}
+	b.watchers.Publish(events...)
+	b.events = nil
+	b.ops, b.bytes = 0, 0
+	b.check.Reset()
+	return nil
+}
+
+// -- Transaction operations
+
+type badgerTxn struct {
+	txn      *badger.Txn
+	done     bool
+	watchers *zerokv.Watchers
+	events   []zerokv.Event
+	limits   zerokv.SizeLimits
+}
+
+// NewTransaction starts a Badger read-write transaction. Badger tracks the
+// keys it reads and Commit fails with zerokv.ErrConflict when another
+// transaction committed a write to one of them first.
+func (b *BadgerDB) NewTransaction(ctx context.Context) (zerokv.Txn, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if b.readOnly {
+		return nil, zerokv.ErrReadOnly
+	}
+	return &badgerTxn{txn: b.db.NewTransaction(true), watchers: &b.watchers, limits: b.limits}, nil
+}
+
+// Get retrieves the value for a given key, including pending writes.
+func (t *badgerTxn) Get(ctx context.Context, key []byte) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if t.done {
+		return nil, zerokv.ErrTxnDone
+	}
+	item, err := t.txn.Get(key)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	data, err := item.ValueCopy(nil)
+	if err == nil && data == nil {
+		data = []byte{}
+	}
 	return data, err
 }
 
-// Delete removes a key-value pair from the database.
-func (b *BadgerDB) Delete(ctx context.Context, key []byte) error {
+// Put stages a key-value pair in the transaction.
+func (t *badgerTxn) Put(ctx context.Context, key []byte, data []byte) error {
 	if err := ctx.Err(); err != nil {
 		return err
 	}
-	return b.db.Update(func(txn *badger.Txn) error {
-		return txn.Delete(key)
-	})
+	if t.done {
+		return zerokv.ErrTxnDone
+	}
+	if err := t.limits.Check(key, data); err != nil {
+		return err
+	}
+	if err := t.txn.Set(key, data); err != nil {
+		return err
+	}
+	t.events = t.watchers.Record(t.events, zerokv.Event{Op: zerokv.OpPut, Key: key, Value: data})
+	return nil
 }
 
-// Close closes the BadgerDB instance and releases all resources.
-func (b *BadgerDB) Close() error {
-	var errs []error
-	if b.db != nil {
-		if err := b.db.Close(); err != nil {
-			errs = append(errs, err)
-		}
+// Delete stages a key removal in the transaction.
+func (t *badgerTxn) Delete(ctx context.Context, key []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
 	}
-	if len(errs) == 0 {
-		return nil
+	if t.done {
+		return zerokv.ErrTxnDone
 	}
-	return errors.Join(errs...)
+	if err := t.txn.Delete(key); err != nil {
+		return err
+	}
+	t.events = t.watchers.Record(t.events, zerokv.Event{Op: zerokv.OpDelete, Key: key})
+	return nil
 }
 
-// -- Batch operations
+// Commit applies the transaction, translating badger.ErrConflict to zerokv.ErrConflict.
+func (t *badgerTxn) Commit(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if t.done {
+		return zerokv.ErrTxnDone
+	}
+	t.done = true
+	err := t.txn.Commit()
+	if errors.Is(err, badger.ErrConflict) {
+		return zerokv.ErrConflict
+	}
+	if err == nil {
+		t.watchers.Publish(t.events...)
+	}
+	t.events = nil
+	return err
+}
 
-// Batch creates a new batch operation for the BadgerDB instance.
-func (b *BadgerDB) Batch() zerokv.Batch {
-	return &badgerBatch{batch: b.db.NewWriteBatch()}
+// Discard drops the transaction's staged writes.
+func (t *badgerTxn) Discard() {
+	t.done = true
+	t.txn.Discard()
 }
 
-// Put inserts or updates a key-value pair in the batch.
-func (b *badgerBatch) Put(key, value []byte) error {
-	return b.batch.Set(key, value)
+// -- Snapshot operations
+
+// badgerSnapshot is a read-only transaction. Badger panics when a
+// transaction is discarded with open iterators, so the transaction is only
+// discarded once the snapshot and every iterator opened from it are released.
+type badgerSnapshot struct {
+	mu       sync.RWMutex
+	txn      *badger.Txn
+	iters    int
+	released bool
 }
 
-// Delete removes a key-value pair from the batch.
-func (b *badgerBatch) Delete(key []byte) error {
-	return b.batch.Delete(key)
+// Snapshot returns a read-only view backed by a Badger read transaction.
+func (b *BadgerDB) Snapshot() (zerokv.Snapshot, error) {
+	return &badgerSnapshot{txn: b.db.NewTransaction(false)}, nil
 }
 
-// Commits commits the batch operations to the database.
-func (b *badgerBatch) Commit(ctx context.Context) error {
+// Get retrieves the value for a given key as of the snapshot.
+func (s *badgerSnapshot) Get(ctx context.Context, key []byte) ([]byte, error) {
 	if err := ctx.Err(); err != nil {
-		return err
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.released {
+		return nil, zerokv.ErrSnapshotReleased
+	}
+	item, err := s.txn.Get(key)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	data, err := item.ValueCopy(nil)
+	if err == nil && data == nil {
+		data = []byte{}
+	}
+	return data, err
+}
+
+// Has reports whether a key existed when the snapshot was taken.
+func (s *badgerSnapshot) Has(ctx context.Context, key []byte) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.released {
+		return false, zerokv.ErrSnapshotReleased
+	}
+	_, err := s.txn.Get(key)
+	if errors.Is(err, badger.ErrKeyNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Scan returns an iterator over keys with the given prefix as of the snapshot.
+func (s *badgerSnapshot) Scan(prefix []byte) zerokv.Iterator {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.released {
+		return zerokv.NewErrIterator(zerokv.ErrSnapshotReleased)
+	}
+	s.iters++
+	it := s.txn.NewIterator(badger.IteratorOptions{Prefix: prefix, PrefetchValues: true})
+	return &badgerIterator{snap: s, Iterator: it, prefix: prefix}
+}
+
+// Release discards the read transaction once no iterator still uses it.
+func (s *badgerSnapshot) Release() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.released {
+		return
+	}
+	s.released = true
+	if s.iters == 0 {
+		s.txn.Discard()
+	}
+}
+
+// unref is called when an iterator opened from the snapshot is released.
+func (s *badgerSnapshot) unref() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.iters--
+	if s.released && s.iters == 0 {
+		s.txn.Discard()
 	}
-	return b.batch.Flush()
 }
 
 // -- Iterator operations
 
 func (b *BadgerDB) Scan(prefix []byte) zerokv.Iterator {
+	return NewPrefixIterator(b, prefix)
+}
+
+// ReverseScan returns an iterator over keys with the given prefix in descending order.
+func (b *BadgerDB) ReverseScan(prefix []byte) zerokv.Iterator {
+	return NewReversePrefixIterator(b, prefix)
+}
+
+// RangeScan returns an iterator over keys in [start, end).
+func (b *BadgerDB) RangeScan(start, end []byte) zerokv.Iterator {
+	txn := b.db.NewTransaction(false)
+	it := txn.NewIterator(badger.IteratorOptions{PrefetchValues: true})
+	return &badgerIterator{txn: txn, Iterator: it, start: start, end: end}
+}
+
+// ScanFrom returns an iterator over keys with the given prefix, starting at
+// the first one >= start.
+func (b *BadgerDB) ScanFrom(prefix, start []byte) zerokv.Iterator {
+	// Seeking below the prefix lands outside it and ends the iteration, so
+	// never start before the prefix.
+	if bytes.Compare(start, prefix) < 0 {
+		start = prefix
+	}
 	txn := b.db.NewTransaction(false)
 	it := txn.NewIterator(badger.IteratorOptions{Prefix: prefix, PrefetchValues: true})
-	return &badgerIterator{Iterator: it}
+	return &badgerIterator{txn: txn, Iterator: it, prefix: prefix, start: start}
+}
+
+// ScanWithPrefetch returns an iterator over keys with the given prefix that
+// loads up to prefetch values ahead of the caller. Large prefetch sizes suit
+// small values; with values of many kilobytes a small one avoids reading
+// far more than is consumed. Zero or less uses Badger's default of 100.
+func (b *BadgerDB) ScanWithPrefetch(prefix []byte, prefetch int) zerokv.Iterator {
+	if prefetch <= 0 {
+		prefetch = badger.DefaultIteratorOptions.PrefetchSize
+	}
+	txn := b.db.NewTransaction(false)
+	it := txn.NewIterator(badger.IteratorOptions{Prefix: prefix, PrefetchValues: true, PrefetchSize: prefetch})
+	return &badgerIterator{txn: txn, Iterator: it, prefix: prefix}
+}
+
+// ScanKeys returns an iterator over keys with the given prefix without fetching values.
+func (b *BadgerDB) ScanKeys(prefix []byte) zerokv.Iterator {
+	txn := b.db.NewTransaction(false)
+	it := txn.NewIterator(badger.IteratorOptions{Prefix: prefix, PrefetchValues: false})
+	return &badgerIterator{txn: txn, Iterator: it, prefix: prefix, keysOnly: true}
+}
+
+// ScanContext returns a prefix iterator that stops once ctx is done.
+func (b *BadgerDB) ScanContext(ctx context.Context, prefix []byte) zerokv.Iterator {
+	return zerokv.NewContextIterator(ctx, b.Scan(prefix))
 }
+
 func (it *badgerIterator) Next() bool {
 	if !it.started {
-		it.Iterator.Rewind()
+		if it.start != nil {
+			it.Iterator.Seek(it.start)
+		} else {
+			it.Iterator.Rewind()
+		}
 		it.started = true
 	} else {
 		it.Iterator.Next()
 	}
-	it.valid = it.Iterator.Valid()
+	it.valid = it.Iterator.Valid() && it.inRange()
+	return it.valid
+}
+
+// Seek moves to the first key >= key, never before the iterator's lower bound.
+func (it *badgerIterator) Seek(key []byte) bool {
+	if bytes.Compare(key, it.start) < 0 {
+		key = it.start
+	}
+	if bytes.Compare(key, it.prefix) < 0 {
+		key = it.prefix
+	}
+	if len(key) == 0 {
+		it.Iterator.Rewind()
+	} else {
+		it.Iterator.Seek(key)
+	}
+	it.started = true
+	it.valid = it.Iterator.Valid() && it.inRange()
+	return it.valid
+}
+
+// First moves to the smallest key within the iterator's bounds.
+func (it *badgerIterator) First() bool {
+	return it.Seek(nil)
+}
+
+// Last moves to the greatest key within the iterator's bounds. A forward
+// badger.Iterator cannot step backwards, so the key is found with a
+// separate reverse iterator on the same transaction and then sought.
+func (it *badgerIterator) Last() bool {
+	txn := it.txn
+	if it.snap != nil {
+		txn = it.snap.txn
+	}
+	lower := it.start
+	if bytes.Compare(lower, it.prefix) < 0 {
+		lower = it.prefix
+	}
+	key := boundaryKey(txn, it.prefix, lower, it.end, true)
+	if key == nil {
+		// nothing is in range, so seeking the lower bound is invalid too
+		return it.Seek(nil)
+	}
+	return it.Seek(key)
+}
+
+// boundaryKey returns a copy of the smallest key, or the greatest one if
+// last is set, that has prefix and lies in [lower, upper), or nil if there
+// is none. A nil upper bound means unbounded.
+func boundaryKey(txn *badger.Txn, prefix, lower, upper []byte, last bool) []byte {
+	it := txn.NewIterator(badger.IteratorOptions{Reverse: last})
+	defer it.Close()
+	if !last {
+		if len(lower) == 0 {
+			it.Rewind()
+		} else {
+			it.Seek(lower)
+		}
+	} else {
+		if pu := zerokv.PrefixUpperBound(prefix); pu != nil && (upper == nil || bytes.Compare(pu, upper) < 0) {
+			upper = pu
+		}
+		if upper == nil {
+			it.Rewind()
+		} else {
+			it.Seek(upper)
+			for it.Valid() && bytes.Compare(it.Item().Key(), upper) >= 0 {
+				it.Next()
+			}
+		}
+	}
+	if !it.Valid() {
+		return nil
+	}
+	key := it.Item().Key()
+	if !bytes.HasPrefix(key, prefix) || bytes.Compare(key, lower) < 0 ||
+		(upper != nil && bytes.Compare(key, upper) >= 0) {
+		return nil
+	}
+	return it.Item().KeyCopy(nil)
+}
+
+// inRange reports whether the current key is below the exclusive end bound.
+func (it *badgerIterator) inRange() bool {
+	return it.end == nil || bytes.Compare(it.Iterator.Item().Key(), it.end) < 0
+}
+
+func (it *badgerIterator) Valid() bool {
 	return it.valid
 }
 
@@ -144,7 +1465,7 @@ func (it *badgerIterator) Key() []byte {
 	return it.Iterator.Item().KeyCopy(nil) // safer, doesn't make changes to key
 }
 func (it *badgerIterator) Value() []byte {
-	if !it.valid {
+	if !it.valid || it.keysOnly {
 		return nil
 	}
 	data, err := it.Iterator.Item().ValueCopy(nil)
@@ -155,16 +1476,20 @@ func (it *badgerIterator) Value() []byte {
 	return data
 }
 
-// Release Must be called to avoid memory leaks
+// Release Must be called to avoid memory leaks.
+// It closes the iterator and discards the read transaction backing it.
 func (it *badgerIterator) Release() {
+	it.valid = false
 	it.Iterator.Close()
+	if it.snap != nil {
+		it.snap.unref()
+		return
+	}
+	it.txn.Discard()
 }
 
 func (it *badgerIterator) Error() error {
-	if len(it.err) == 0 {
-		return nil
-	}
-	return it.err[len(it.err)-1]
+	return errors.Join(it.err...)
 }
 
 //  --- specials methods to use with an instance of badgerdb for some other operations
@@ -172,15 +1497,18 @@ func (it *badgerIterator) Error() error {
 func NewIterator(b *BadgerDB) zerokv.Iterator {
 	txn := b.db.NewTransaction(false)
 	it := txn.NewIterator(badger.IteratorOptions{PrefetchValues: true})
-	return &badgerIterator{Iterator: it}
+	return &badgerIterator{txn: txn, Iterator: it}
 }
 func NewPrefixIterator(b *BadgerDB, prefix []byte) zerokv.Iterator {
 	txn := b.db.NewTransaction(false)
 	it := txn.NewIterator(badger.IteratorOptions{Prefix: prefix, PrefetchValues: true})
-	return &badgerIterator{Iterator: it}
+	return &badgerIterator{txn: txn, Iterator: it, prefix: prefix}
 }
+
 type badgerReverseIterator struct {
+	txn      *badger.Txn
 	Iterator *badger.Iterator
+	prefix   []byte
 	started  bool
 	valid    bool
 	err      []error
@@ -188,12 +1516,65 @@ type badgerReverseIterator struct {
 
 func (it *badgerReverseIterator) Next() bool {
 	if !it.started {
-		it.Iterator.Seek([]byte{0xFF}) // Start from the end of the keyspace
+		it.seekLast()
 		it.started = true
 	} else {
 		it.Iterator.Next()
 	}
-	it.valid = it.Iterator.Valid()
+	it.valid = it.Iterator.ValidForPrefix(it.prefix)
+	return it.valid
+}
+
+// Seek moves to the last key <= key, never past the end of the prefix.
+func (it *badgerReverseIterator) Seek(key []byte) bool {
+	upbound := zerokv.PrefixUpperBound(it.prefix)
+	if upbound != nil && bytes.Compare(key, upbound) >= 0 {
+		it.seekLast()
+	} else {
+		it.Iterator.Seek(key)
+	}
+	it.started = true
+	it.valid = it.Iterator.ValidForPrefix(it.prefix)
+	return it.valid
+}
+
+// First moves to the smallest key under the prefix, found with a separate
+// forward iterator since Seek on a reverse one lands at or before its key.
+func (it *badgerReverseIterator) First() bool {
+	if key := boundaryKey(it.txn, it.prefix, it.prefix, nil, false); key != nil {
+		it.Iterator.Seek(key)
+	} else {
+		it.seekLast() // no key has the prefix, so this is invalid as well
+	}
+	it.started = true
+	it.valid = it.Iterator.ValidForPrefix(it.prefix)
+	return it.valid
+}
+
+// Last moves to the greatest key under the prefix.
+func (it *badgerReverseIterator) Last() bool {
+	it.seekLast()
+	it.started = true
+	it.valid = it.Iterator.ValidForPrefix(it.prefix)
+	return it.valid
+}
+
+// seekLast positions the iterator on the greatest key under the prefix.
+// Badger's reverse Rewind lands before the prefix range, so the iterator is
+// opened without a Prefix option and seeks from the prefix successor instead.
+func (it *badgerReverseIterator) seekLast() {
+	upbound := zerokv.PrefixUpperBound(it.prefix)
+	if upbound == nil {
+		it.Iterator.Rewind()
+		return
+	}
+	it.Iterator.Seek(upbound)
+	for it.Iterator.Valid() && bytes.Compare(it.Iterator.Item().Key(), upbound) >= 0 {
+		it.Iterator.Next()
+	}
+}
+
+func (it *badgerReverseIterator) Valid() bool {
 	return it.valid
 }
 
@@ -215,27 +1596,27 @@ func (it *badgerReverseIterator) Value() []byte {
 	return data
 }
 
-// Release Must be called to avoid memory leaks
+// Release Must be called to avoid memory leaks.
+// It closes the iterator and discards the read transaction backing it.
 func (it *badgerReverseIterator) Release() {
+	it.valid = false
 	it.Iterator.Close()
+	it.txn.Discard()
 }
 
 func (it *badgerReverseIterator) Error() error {
-	if len(it.err) == 0 {
-		return nil
-	}
-	return it.err[len(it.err)-1]
+	return errors.Join(it.err...)
 }
 
 func NewReverseIterator(b *BadgerDB) zerokv.Iterator {
 	txn := b.db.NewTransaction(false)
 	it := txn.NewIterator(badger.IteratorOptions{Reverse: true, PrefetchValues: true,
 		PrefetchSize: 100})
-	return &badgerReverseIterator{Iterator: it}
+	return &badgerReverseIterator{txn: txn, Iterator: it}
 }
 
 func NewReversePrefixIterator(b *BadgerDB, prefix []byte) zerokv.Iterator {
 	txn := b.db.NewTransaction(false)
-	it := txn.NewIterator(badger.IteratorOptions{Prefix: []byte(prefix), PrefetchValues: true, PrefetchSize: 100, Reverse: true})
-	return &badgerReverseIterator{Iterator: it}
+	it := txn.NewIterator(badger.IteratorOptions{PrefetchValues: true, PrefetchSize: 100, Reverse: true})
+	return &badgerReverseIterator{txn: txn, Iterator: it, prefix: prefix}
 }
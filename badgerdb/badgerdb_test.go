@@ -2,8 +2,12 @@ package badgerdb_test
 
 import (
 	"bytes"
+	"fmt"
+	"runtime"
 	"testing"
+	"time"
 
+	"github.com/dgraph-io/badger/v4"
 	"github.com/rawbytedev/zerokv"
 	"github.com/rawbytedev/zerokv/badgerdb"
 	"github.com/rawbytedev/zerokv/helpers"
@@ -38,6 +42,43 @@ func TestBadgerBatchOperations(t *testing.T) {
 	defer db.Close()
 }
 
+// TestBadgerIteratorReleaseDiscardsTxn opens and releases many iterators and
+// checks that no goroutines or read transactions are left behind.
+func TestBadgerIteratorReleaseDiscardsTxn(t *testing.T) {
+	db := helpers.SetupDB(t, "badgerdb")
+	defer db.Close()
+	bdb := db.(*badgerdb.BadgerDB)
+	_, _ = fillBadgerValues(t, db)
+
+	runtime.GC()
+	before := runtime.NumGoroutine()
+	for i := 0; i < 5000; i++ {
+		var it zerokv.Iterator
+		switch i % 3 {
+		case 0:
+			it = db.Scan([]byte("pre_"))
+		case 1:
+			it = badgerdb.NewIterator(bdb)
+		default:
+			it = badgerdb.NewReversePrefixIterator(bdb, []byte("pre_"))
+		}
+		require.True(t, it.Next())
+		it.Release()
+		// releasing twice must be safe
+		it.Release()
+	}
+	// give prefetch goroutines a moment to wind down
+	require.Eventually(t, func() bool {
+		return runtime.NumGoroutine() <= before+2
+	}, 5*time.Second, 50*time.Millisecond, "Iterators should not leak goroutines")
+
+	// writes must still be visible to fresh scans once old snapshots are gone
+	require.NoError(t, db.Put(t.Context(), []byte("pre_new"), []byte("value")))
+	it := db.Scan([]byte("pre_new"))
+	require.True(t, it.Next())
+	it.Release()
+}
+
 // Helper to fill database with test values
 func fillBadgerValues(t *testing.T, db zerokv.Core) ([][]byte, [][]byte) {
 	keys := make([][]byte, 10)
@@ -178,3 +219,260 @@ func TestBadgerReverseIteratorOrder(t *testing.T) {
 
 	defer bdb.Close()
 }
+
+// TestBadgerTxnConflict verifies a conflicting commit returns zerokv.ErrConflict.
+func TestBadgerTxnConflict(t *testing.T) {
+	db := helpers.SetupDB(t, "badgerdb")
+	defer db.Close()
+	require.NoError(t, db.Put(t.Context(), []byte("counter"), []byte("1")))
+
+	first, err := db.NewTransaction(t.Context())
+	require.NoError(t, err)
+	second, err := db.NewTransaction(t.Context())
+	require.NoError(t, err)
+	_, err = first.Get(t.Context(), []byte("counter"))
+	require.NoError(t, err)
+	_, err = second.Get(t.Context(), []byte("counter"))
+	require.NoError(t, err)
+	require.NoError(t, first.Put(t.Context(), []byte("counter"), []byte("2")))
+	require.NoError(t, second.Put(t.Context(), []byte("counter"), []byte("3")))
+
+	require.NoError(t, first.Commit(t.Context()))
+	require.ErrorIs(t, second.Commit(t.Context()), zerokv.ErrConflict)
+	value, err := db.Get(t.Context(), []byte("counter"))
+	require.NoError(t, err)
+	require.Equal(t, []byte("2"), value)
+}
+
+// TestBadgerNewOptions verifies New applies functional options, including a
+// read-only reopen.
+func TestBadgerNewOptions(t *testing.T) {
+	tmp := t.TempDir()
+	db, err := badgerdb.New(tmp, badgerdb.WithLogger(nil), badgerdb.WithValueThreshold(64), badgerdb.WithSyncWrites(true))
+	require.NoError(t, err)
+	value := bytes.Repeat([]byte("v"), 128)
+	require.NoError(t, db.Put(t.Context(), []byte("key"), value))
+	require.NoError(t, db.Close())
+
+	db, err = badgerdb.New(tmp, badgerdb.WithLogger(nil), badgerdb.WithReadOnly())
+	require.NoError(t, err)
+	defer db.Close()
+	got, err := db.Get(t.Context(), []byte("key"))
+	require.NoError(t, err)
+	require.Equal(t, value, got)
+	require.ErrorIs(t, db.Put(t.Context(), []byte("key"), []byte("value")), zerokv.ErrReadOnly)
+}
+
+// TestBadgerReadOnly verifies a read-only database serves reads and rejects
+// every write with zerokv.ErrReadOnly.
+func TestBadgerReadOnly(t *testing.T) {
+	tmp := t.TempDir()
+	db, err := badgerdb.NewBadgerDB(badgerdb.Config{Dir: tmp})
+	require.NoError(t, err)
+	require.NoError(t, db.Put(t.Context(), []byte("key"), []byte("value")))
+	require.NoError(t, db.Close())
+
+	db, err = badgerdb.NewBadgerDB(badgerdb.Config{Dir: tmp, ReadOnly: true})
+	require.NoError(t, err)
+	defer db.Close()
+	value, err := db.Get(t.Context(), []byte("key"))
+	require.NoError(t, err)
+	require.Equal(t, []byte("value"), value)
+
+	require.ErrorIs(t, db.Put(t.Context(), []byte("key"), []byte("new")), zerokv.ErrReadOnly)
+	require.ErrorIs(t, db.Delete(t.Context(), []byte("key")), zerokv.ErrReadOnly)
+	require.ErrorIs(t, db.PutMany(t.Context(), [][]byte{[]byte("a")}, [][]byte{[]byte("b")}), zerokv.ErrReadOnly)
+	_, err = db.DeletePrefix(t.Context(), nil)
+	require.ErrorIs(t, err, zerokv.ErrReadOnly)
+	_, err = db.CompareAndSwap(t.Context(), []byte("key"), []byte("value"), []byte("new"))
+	require.ErrorIs(t, err, zerokv.ErrReadOnly)
+	_, err = db.NewTransaction(t.Context())
+	require.ErrorIs(t, err, zerokv.ErrReadOnly)
+	batch := db.Batch()
+	_ = batch.Put([]byte("key"), []byte("new"))
+	require.ErrorIs(t, batch.Commit(t.Context()), zerokv.ErrReadOnly)
+	require.NoError(t, db.Sync(t.Context()))
+
+	value, err = db.Get(t.Context(), []byte("key"))
+	require.NoError(t, err)
+	require.Equal(t, []byte("value"), value)
+}
+
+// TestBadgerBatchTxnTooBig verifies a batch stays usable after an entry too
+// large for one transaction, and that large loads are split transparently.
+func TestBadgerBatchTxnTooBig(t *testing.T) {
+	tmp := t.TempDir()
+	opts := badger.DefaultOptions(tmp).WithMemTableSize(64 << 10).WithValueThreshold(1 << 10).WithLogger(nil)
+	db, err := badgerdb.New(tmp, badgerdb.WithBadgerOptions(opts))
+	require.NoError(t, err)
+	defer db.Close()
+
+	batch := db.Batch()
+	for i := range 2000 {
+		require.NoError(t, batch.Put([]byte(fmt.Sprintf("key_%04d", i)), []byte("value")))
+	}
+	err = batch.Put(bytes.Repeat([]byte("k"), 20<<10), []byte("value"))
+	require.ErrorIs(t, err, badger.ErrTxnTooBig)
+	require.NoError(t, batch.Put([]byte("key_last"), []byte("value")))
+	require.NoError(t, batch.Delete([]byte("key_0000")))
+	require.NoError(t, batch.Commit(t.Context()))
+
+	count, err := db.Count(t.Context(), []byte("key_"))
+	require.NoError(t, err)
+	require.Equal(t, int64(2000), count)
+}
+
+// TestBadgerRunValueLogGC verifies value-log GC runs to completion after a
+// large delete.
+func TestBadgerRunValueLogGC(t *testing.T) {
+	tmp := t.TempDir()
+	opts := badger.DefaultOptions(tmp).WithValueLogFileSize(1 << 20).WithValueThreshold(64).WithLogger(nil)
+	db, err := badgerdb.New(tmp, badgerdb.WithBadgerOptions(opts))
+	require.NoError(t, err)
+	defer db.Close()
+	for i := range 4000 {
+		require.NoError(t, db.Put(t.Context(), []byte(fmt.Sprintf("key_%04d", i)), helpers.RandomBytes(1024)))
+	}
+	_, err = db.DeletePrefix(t.Context(), []byte("key_"))
+	require.NoError(t, err)
+	require.NoError(t, db.Sync(t.Context()))
+
+	gc, ok := db.(zerokv.ValueLogGC)
+	require.True(t, ok, "BadgerDB should implement zerokv.ValueLogGC")
+	require.NoError(t, gc.RunValueLogGC(0.5))
+	require.NoError(t, gc.RunValueLogGC(0.5), "A second run should find nothing to rewrite")
+}
+
+// TestBadgerEncryption verifies an encrypted store can be reopened and read
+// with its key, and cannot be opened without it.
+func TestBadgerEncryption(t *testing.T) {
+	tmp := t.TempDir()
+	key := bytes.Repeat([]byte("k"), 32)
+	db, err := badgerdb.New(tmp, badgerdb.WithLogger(nil), badgerdb.WithEncryption(key, 1<<20))
+	require.NoError(t, err)
+	require.NoError(t, db.Put(t.Context(), []byte("secret"), []byte("value")))
+	require.NoError(t, db.Close())
+
+	_, err = badgerdb.New(tmp, badgerdb.WithLogger(nil))
+	require.Error(t, err, "Opening without the key should fail")
+	_, err = badgerdb.NewBadgerDB(badgerdb.Config{Dir: tmp, EncryptionKey: key})
+	require.ErrorIs(t, err, badgerdb.ErrIndexCacheRequired)
+
+	db, err = badgerdb.NewBadgerDB(badgerdb.Config{Dir: tmp, EncryptionKey: key, IndexCacheSize: 1 << 20})
+	require.NoError(t, err)
+	defer db.Close()
+	value, err := db.Get(t.Context(), []byte("secret"))
+	require.NoError(t, err)
+	require.Equal(t, []byte("value"), value)
+}
+
+// TestBadgerSizeLimits verifies writes over MaxKeySize or MaxValueSize fail
+// with the typed errors and store nothing.
+func TestBadgerSizeLimits(t *testing.T) {
+	db, err := badgerdb.New(t.TempDir(), badgerdb.WithLogger(nil), badgerdb.WithMaxKeySize(8), badgerdb.WithMaxValueSize(16))
+	require.NoError(t, err)
+	defer db.Close()
+	longKey, bigValue := []byte("key_too_long"), bytes.Repeat([]byte("v"), 17)
+
+	require.NoError(t, db.Put(t.Context(), []byte("key"), bytes.Repeat([]byte("v"), 16)))
+	require.ErrorIs(t, db.Put(t.Context(), longKey, []byte("value")), zerokv.ErrKeyTooLarge)
+	require.ErrorIs(t, db.Put(t.Context(), []byte("key"), bigValue), zerokv.ErrValueTooLarge)
+	require.ErrorIs(t, db.PutMany(t.Context(), [][]byte{[]byte("a"), []byte("b")}, [][]byte{[]byte("v"), bigValue}), zerokv.ErrValueTooLarge)
+	_, err = db.CompareAndSwap(t.Context(), []byte("key"), bytes.Repeat([]byte("v"), 16), bigValue)
+	require.ErrorIs(t, err, zerokv.ErrValueTooLarge)
+
+	batch := db.Batch()
+	require.ErrorIs(t, batch.Put(longKey, []byte("value")), zerokv.ErrKeyTooLarge)
+	require.ErrorIs(t, batch.Put([]byte("key"), bigValue), zerokv.ErrValueTooLarge)
+	require.Zero(t, batch.Len())
+	require.NoError(t, batch.Delete(longKey))
+	require.ErrorIs(t, batch.Validate(), zerokv.ErrKeyTooLarge, "Validate should report the key Delete let through")
+	require.NoError(t, batch.Commit(t.Context()))
+	require.NoError(t, batch.Validate())
+
+	txn, err := db.NewTransaction(t.Context())
+	require.NoError(t, err)
+	require.ErrorIs(t, txn.Put(t.Context(), []byte("key"), bigValue), zerokv.ErrValueTooLarge)
+	txn.Discard()
+
+	has, err := db.Has(t.Context(), longKey)
+	require.NoError(t, err)
+	require.False(t, has)
+	has, err = db.Has(t.Context(), []byte("a"))
+	require.NoError(t, err)
+	require.False(t, has, "PutMany should not write part of the pairs")
+	got, err := db.Get(t.Context(), []byte("key"))
+	require.NoError(t, err)
+	require.Len(t, got, 16)
+}
+
+// TestBadgerScanWithPrefetch verifies every prefetch size yields the same
+// keys and values as Scan.
+func TestBadgerScanWithPrefetch(t *testing.T) {
+	db, err := badgerdb.New(t.TempDir(), badgerdb.WithLogger(nil), badgerdb.WithValueThreshold(32))
+	require.NoError(t, err)
+	defer db.Close()
+	for i := range 50 {
+		require.NoError(t, db.Put(t.Context(), fmt.Appendf(nil, "pre_%02d", i), bytes.Repeat([]byte{byte(i)}, 64)))
+	}
+	require.NoError(t, db.Put(t.Context(), []byte("other"), []byte("value")))
+
+	want := collect(t, db.Scan([]byte("pre_")))
+	require.Len(t, want, 50)
+	scanner, ok := db.(zerokv.PrefetchScanner)
+	require.True(t, ok)
+	for _, prefetch := range []int{-1, 0, 1, 7, 1000} {
+		require.Equal(t, want, collect(t, scanner.ScanWithPrefetch([]byte("pre_"), prefetch)), "prefetch %d", prefetch)
+	}
+}
+
+// collect drains it into a map of key to value and releases it.
+func collect(t *testing.T, it zerokv.Iterator) map[string][]byte {
+	defer it.Release()
+	got := make(map[string][]byte)
+	for it.Next() {
+		got[string(it.Key())] = bytes.Clone(it.Value())
+	}
+	require.NoError(t, it.Error())
+	return got
+}
+
+// BenchmarkBadgerScanWithPrefetch scans values kept in the value log with
+// a range of prefetch sizes, for small and large values. Compare ns/op
+// within each value size; the best prefetch depends on the value size and
+// on how much of the value log is already in the page cache.
+func BenchmarkBadgerScanWithPrefetch(b *testing.B) {
+	for _, size := range []struct {
+		name  string
+		keys  int
+		value int
+	}{{"128B", 20_000, 128}, {"64KiB", 2_000, 64 << 10}} {
+		db, err := badgerdb.New(b.TempDir(), badgerdb.WithLogger(nil), badgerdb.WithValueThreshold(64))
+		if err != nil {
+			b.Fatalf("Failed to open: %v", err)
+		}
+		batch := db.Batch()
+		value := helpers.RandomBytes(size.value)
+		for i := range size.keys {
+			if err := batch.Put(fmt.Appendf(nil, "bench_%08d", i), value); err != nil {
+				b.Fatalf("Failed to queue key: %v", err)
+			}
+		}
+		if err := batch.Commit(b.Context()); err != nil {
+			b.Fatalf("Failed to commit batch: %v", err)
+		}
+		for _, prefetch := range []int{1, 10, 100, 1000} {
+			b.Run(fmt.Sprintf("%s/Prefetch%d", size.name, prefetch), func(b *testing.B) {
+				b.SetBytes(int64(size.keys * size.value))
+				for b.Loop() {
+					it := db.(zerokv.PrefetchScanner).ScanWithPrefetch([]byte("bench_"), prefetch)
+					for it.Next() {
+						_ = it.Value()
+					}
+					it.Release()
+				}
+			})
+		}
+		db.Close()
+	}
+}
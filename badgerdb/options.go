@@ -1,13 +1,155 @@
 package badgerdb
 
-import "github.com/dgraph-io/badger/v4"
+import (
+	"errors"
+	"net/url"
+
+	"github.com/dgraph-io/badger/v4"
+	"github.com/rawbytedev/zerokv"
+)
 
 // specific badgerdb options
 type Config struct {
 	Dir           string
 	BadgerConfigs *badger.Options
+	// ReadOnly opens the database without write access; writes return
+	// zerokv.ErrReadOnly. It is also set by BadgerConfigs.ReadOnly.
+	ReadOnly bool
+	// EncryptionKey turns on at-rest encryption with AES; it must be 16, 24
+	// or 32 bytes long. The same key is needed to reopen the store.
+	EncryptionKey []byte
+	// IndexCacheSize is the size in bytes of the cache for table indices
+	// and bloom filters. Badger requires it when EncryptionKey is set.
+	IndexCacheSize int64
+	// MaxKeySize and MaxValueSize make writes of a longer key or value fail
+	// with zerokv.ErrKeyTooLarge or zerokv.ErrValueTooLarge instead of an
+	// engine error. Zero means unlimited; Badger itself caps keys at 65000
+	// bytes.
+	MaxKeySize   int
+	MaxValueSize int
 }
 
+// ErrIndexCacheRequired is returned when opening a store with an encryption
+// key but no index cache size. Encrypted tables cannot be memory-mapped, so
+// Badger needs the cache to hold their decrypted indices.
+var ErrIndexCacheRequired = errors.New("badgerdb: encryption requires IndexCacheSize > 0")
+
 func DefaultOptions(Dir string) *Config {
-	return &Config{Dir, nil}
+	return &Config{Dir: Dir}
+}
+
+// Option adjusts the Config built by New.
+type Option func(*Config)
+
+// New opens a BadgerDB at dir, starting from Badger's defaults and applying
+// opts in order. It is shorthand for building a Config by hand.
+func New(dir string, opts ...Option) (zerokv.Core, error) {
+	cfg := DefaultOptions(dir)
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	return NewBadgerDB(*cfg)
+}
+
+// WithBadgerOptions replaces the engine options wholesale. Options applied
+// after it adjust the given value.
+func WithBadgerOptions(opts badger.Options) Option {
+	return func(c *Config) {
+		c.BadgerConfigs = &opts
+	}
+}
+
+// WithReadOnly opens the database without write access.
+func WithReadOnly() Option {
+	return func(c *Config) {
+		c.ReadOnly = true
+	}
+}
+
+// WithLogger sets the logger Badger reports to; nil silences it.
+func WithLogger(l badger.Logger) Option {
+	return func(c *Config) {
+		opts := c.badgerOptions()
+		*opts = opts.WithLogger(l)
+	}
+}
+
+// WithValueThreshold sets the size in bytes above which values are kept in
+// the value log instead of the LSM tree.
+func WithValueThreshold(n int64) Option {
+	return func(c *Config) {
+		opts := c.badgerOptions()
+		*opts = opts.WithValueThreshold(n)
+	}
+}
+
+// WithSyncWrites makes every write fsync before returning when sync is true.
+func WithSyncWrites(sync bool) Option {
+	return func(c *Config) {
+		opts := c.badgerOptions()
+		*opts = opts.WithSyncWrites(sync)
+	}
+}
+
+// WithEncryption encrypts the store at rest with key, keeping decrypted
+// table indices in an index cache of indexCacheSize bytes.
+func WithEncryption(key []byte, indexCacheSize int64) Option {
+	return func(c *Config) {
+		c.EncryptionKey = key
+		c.IndexCacheSize = indexCacheSize
+	}
+}
+
+// WithBlockCacheSize sets the size in bytes of the block cache.
+func WithBlockCacheSize(n int64) Option {
+	return func(c *Config) {
+		opts := c.badgerOptions()
+		*opts = opts.WithBlockCacheSize(n)
+	}
+}
+
+// WithMaxKeySize sets Config.MaxKeySize.
+func WithMaxKeySize(n int) Option {
+	return func(c *Config) {
+		c.MaxKeySize = n
+	}
+}
+
+// WithMaxValueSize sets Config.MaxValueSize.
+func WithMaxValueSize(n int) Option {
+	return func(c *Config) {
+		c.MaxValueSize = n
+	}
+}
+
+// paramOptions maps the DSN parameters accepted by the "badger" backend:
+// readOnly, syncWrites, cache (block cache size), maxKeySize, maxValueSize
+// and valueThreshold.
+func paramOptions(params url.Values) ([]Option, error) {
+	var opts []Option
+	err := zerokv.ApplyParams(params, map[string]func(string) error{
+		"readOnly": zerokv.BoolParam(func(b bool) {
+			if b {
+				opts = append(opts, WithReadOnly())
+			}
+		}),
+		"syncWrites":   zerokv.BoolParam(func(b bool) { opts = append(opts, WithSyncWrites(b)) }),
+		"cache":        zerokv.SizeParam(func(n int64) { opts = append(opts, WithBlockCacheSize(n)) }),
+		"maxKeySize":   zerokv.SizeParam(func(n int64) { opts = append(opts, WithMaxKeySize(int(n))) }),
+		"maxValueSize": zerokv.SizeParam(func(n int64) { opts = append(opts, WithMaxValueSize(int(n))) }),
+		"valueThreshold": zerokv.SizeParam(func(n int64) {
+			opts = append(opts, WithValueThreshold(n))
+		}),
+	})
+	return opts, err
+}
+
+// badgerOptions returns the engine options for an Option to adjust,
+// creating them from badger.DefaultOptions on first use.
+func (c *Config) badgerOptions() *badger.Options {
+	if c.BadgerConfigs == nil {
+		opts := badger.DefaultOptions(c.Dir)
+		c.BadgerConfigs = &opts
+	}
+	return c.BadgerConfigs
 }
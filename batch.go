@@ -0,0 +1,235 @@
+package zerokv
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// BulkLoadSegmentSize is the number of bytes of keys and values a bulk
+// load stages before committing them, on backends whose batches are held
+// in memory until Commit.
+const BulkLoadSegmentSize = 64 << 20
+
+// RunBulkLoad calls fn with batch and commits the batch once fn returns
+// nil. If fn fails, or ctx is done by then, the batch is reset and the
+// error returned; segments it flushed on its own stay committed. Backends
+// use it to implement Core.BulkLoad and then sync.
+func RunBulkLoad(ctx context.Context, batch Batch, fn func(b Batch) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := fn(batch); err != nil {
+		batch.Reset()
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		batch.Reset()
+		return err
+	}
+	return batch.Commit(ctx)
+}
+
+// autoFlushBatch commits its underlying Batch whenever it grows past a
+// threshold and carries on with a fresh one.
+type autoFlushBatch struct {
+	newBatch func() Batch
+	batch    Batch
+	maxOps   int
+	maxBytes int
+	ops      int
+	bytes    int
+}
+
+// NewAutoFlushBatch returns a Batch that stages writes in batches obtained
+// from newBatch and commits the current one as soon as it holds maxOps
+// operations or maxBytes bytes of keys and values, whichever comes first.
+// A threshold of zero or less is ignored. Commit flushes the remainder.
+//
+// Writes are applied in order, but only each flushed segment is atomic.
+// Flushes triggered by Put and Delete commit with context.Background();
+// use PutCtx and DeleteCtx to pass a context through. Backends use it to
+// implement Core.BatchWithOptions.
+func NewAutoFlushBatch(newBatch func() Batch, maxOps, maxBytes int) Batch {
+	return &autoFlushBatch{newBatch: newBatch, batch: newBatch(), maxOps: maxOps, maxBytes: maxBytes}
+}
+
+func (b *autoFlushBatch) Put(key []byte, data []byte) error {
+	return b.PutCtx(context.Background(), key, data)
+}
+
+func (b *autoFlushBatch) Delete(key []byte) error {
+	return b.DeleteCtx(context.Background(), key)
+}
+
+func (b *autoFlushBatch) PutCtx(ctx context.Context, key []byte, data []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := b.batch.Put(key, data); err != nil {
+		return err
+	}
+	return b.added(ctx, len(key)+len(data))
+}
+
+// PutIfAbsent stages a conditional put in the current segment. The check
+// only sees operations of that segment; earlier segments are already in
+// the store.
+func (b *autoFlushBatch) PutIfAbsent(key, data []byte) error {
+	if err := b.batch.PutIfAbsent(key, data); err != nil {
+		return err
+	}
+	return b.added(context.Background(), len(key)+len(data))
+}
+
+func (b *autoFlushBatch) DeleteCtx(ctx context.Context, key []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := b.batch.Delete(key); err != nil {
+		return err
+	}
+	return b.added(ctx, len(key))
+}
+
+// Len returns the number of operations staged since the last flush.
+func (b *autoFlushBatch) Len() int {
+	return b.batch.Len()
+}
+
+// Size returns the size of the operations staged since the last flush.
+func (b *autoFlushBatch) Size() int {
+	return b.batch.Size()
+}
+
+// Reset drops the operations staged since the last flush. Segments that
+// have already been flushed stay committed.
+func (b *autoFlushBatch) Reset() {
+	b.batch.Reset()
+	b.ops, b.bytes = 0, 0
+}
+
+// Validate checks the operations of the current segment; segments already
+// flushed were committed unchecked.
+func (b *autoFlushBatch) Validate() error {
+	return b.batch.Validate()
+}
+
+// Commit commits whatever has been staged since the last flush.
+func (b *autoFlushBatch) Commit(ctx context.Context) error {
+	return b.batch.Commit(ctx)
+}
+
+// added records one staged operation of size bytes and flushes if a
+// threshold has been reached. A failed flush leaves the committed batch in
+// place, so later writes report the backend's error instead of being lost.
+func (b *autoFlushBatch) added(ctx context.Context, size int) error {
+	b.ops++
+	b.bytes += size
+	if (b.maxOps <= 0 || b.ops < b.maxOps) && (b.maxBytes <= 0 || b.bytes < b.maxBytes) {
+		return nil
+	}
+	if err := b.batch.Commit(ctx); err != nil {
+		return fmt.Errorf("zerokv: auto-flush: %w", err)
+	}
+	b.batch = b.newBatch()
+	b.ops, b.bytes = 0, 0
+	return nil
+}
+
+// concurrentBatch serializes every call on an underlying Batch.
+type concurrentBatch struct {
+	mu    sync.Mutex
+	batch Batch
+}
+
+// NewConcurrentBatch returns a Batch that any number of goroutines may
+// write to at once, for parallel ingest into a single batch. Calls are
+// serialized by a mutex, and the staged writes are committed whenever
+// they reach maxBytes bytes of keys and values, as by
+// core.BatchWithOptions(0, maxBytes); a maxBytes of zero or less never
+// flushes before Commit. Call Commit once every writer is done to flush
+// the rest.
+//
+// Each call holds the lock for as long as it takes, including a flush, so
+// writers stall behind one another and behind every flush. When each
+// goroutine can own its data, giving every goroutine its own batch from
+// core.BatchWithOptions scales better; use a shared batch where writers
+// are cheap compared to producing their data, or when one batch is simpler
+// to manage than many.
+func NewConcurrentBatch(core Core, maxBytes int) Batch {
+	return &concurrentBatch{batch: core.BatchWithOptions(0, maxBytes)}
+}
+
+func (b *concurrentBatch) Put(key []byte, data []byte) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.batch.Put(key, data)
+}
+
+func (b *concurrentBatch) Delete(key []byte) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.batch.Delete(key)
+}
+
+func (b *concurrentBatch) PutIfAbsent(key, data []byte) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.batch.PutIfAbsent(key, data)
+}
+
+// PutCtx checks ctx once it holds the lock, not while waiting for it.
+func (b *concurrentBatch) PutCtx(ctx context.Context, key []byte, data []byte) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if cb, ok := b.batch.(ContextBatch); ok {
+		return cb.PutCtx(ctx, key, data)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return b.batch.Put(key, data)
+}
+
+func (b *concurrentBatch) DeleteCtx(ctx context.Context, key []byte) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if cb, ok := b.batch.(ContextBatch); ok {
+		return cb.DeleteCtx(ctx, key)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return b.batch.Delete(key)
+}
+
+func (b *concurrentBatch) Len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.batch.Len()
+}
+
+func (b *concurrentBatch) Size() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.batch.Size()
+}
+
+func (b *concurrentBatch) Reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.batch.Reset()
+}
+
+func (b *concurrentBatch) Validate() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.batch.Validate()
+}
+
+func (b *concurrentBatch) Commit(ctx context.Context) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.batch.Commit(ctx)
+}
@@ -0,0 +1,1352 @@
+package boltdb
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/rawbytedev/zerokv"
+	bolt "go.etcd.io/bbolt"
+)
+
+// fileName is the name of the bbolt data file created inside Config.Dir.
+const fileName = "zerokv.db"
+
+// bucketName is the single bucket holding every key.
+var bucketName = []byte("zerokv")
+
+// restoreBatchSize is the number of entries Restore commits at a time.
+const restoreBatchSize = 1000
+
+// defaultMmapSize is the initial memory map size used when no bolt.Options
+// are given. Only address space is reserved; the file grows as needed.
+const defaultMmapSize = 64 << 20
+
+var (
+	// ErrNotFound is returned by Get when the key does not exist.
+	ErrNotFound = errors.New("boltdb: key not found")
+	// ErrBatchCommitted is returned when a batch is used after Commit.
+	ErrBatchCommitted = errors.New("boltdb: batch already committed")
+)
+
+// errKeyNotFound is ErrNotFound wrapped so it also matches
+// zerokv.ErrKeyNotFound.
+var errKeyNotFound = zerokv.KeyNotFound(ErrNotFound)
+
+// BoltDB implements zerokv.Core on top of a single bbolt file.
+//
+// bbolt allows a single writer at a time: Put, Delete and Batch commits are
+// serialized, while reads run concurrently. Iterators and snapshots hold a
+// read transaction until Release; a write that has to grow the memory map
+// past its current size waits for open read transactions, so release them
+// before writing from the same goroutine. Unless Config.BoltConfigs is set,
+// the map starts at defaultMmapSize so small databases never hit this.
+type BoltDB struct {
+	db       *bolt.DB
+	fills    zerokv.KeyedMutex // serializes GetOrPut per key
+	watchers zerokv.Watchers
+}
+
+type boltBatch struct {
+	db        *bolt.DB
+	ops       []batchOp
+	committed bool
+	watchers  *zerokv.Watchers
+	check     zerokv.BatchValidator
+}
+
+// boltLimits are the keys and values bbolt refuses to store.
+var boltLimits = zerokv.BatchLimits{MaxKeySize: bolt.MaxKeySize, MaxValueSize: bolt.MaxValueSize}
+
+type batchOp struct {
+	key      []byte
+	value    []byte
+	delete   bool
+	ifAbsent bool // a put skipped if key exists when it is applied
+}
+
+type boltIterator struct {
+	tx       *bolt.Tx
+	snap     *boltSnapshot // set instead of tx when opened from a snapshot
+	cursor   *bolt.Cursor
+	prefix   []byte
+	start    []byte // first key to seek to, nil for the first key
+	end      []byte // exclusive upper bound, nil for none
+	keysOnly bool
+	key      []byte
+	value    []byte
+	started  bool
+	valid    bool
+	err      []error
+}
+
+type boltReverseIterator struct {
+	tx      *bolt.Tx
+	cursor  *bolt.Cursor
+	prefix  []byte
+	key     []byte
+	value   []byte
+	started bool
+	valid   bool
+	err     []error
+}
+
+func init() {
+	zerokv.Register("bolt", func(cfg zerokv.Config) (zerokv.Core, error) {
+		opts, err := paramOptions(cfg.Params)
+		if err != nil {
+			return nil, err
+		}
+		return New(cfg.Dir, opts...)
+	})
+}
+
+// NewBoltDB initializes and returns a zerokv.Core instance at the specified path(bbolt).
+func NewBoltDB(cfg Config) (zerokv.Core, error) {
+	if err := os.MkdirAll(cfg.Dir, 0o755); err != nil {
+		return nil, err
+	}
+	opts := cfg.BoltConfigs
+	if opts == nil {
+		opts = defaultBoltOptions()
+	}
+	db, err := bolt.Open(filepath.Join(cfg.Dir, fileName), 0o600, opts)
+	if err != nil {
+		return nil, err
+	}
+	if !db.IsReadOnly() {
+		err = db.Update(func(tx *bolt.Tx) error {
+			_, err := tx.CreateBucketIfNotExists(bucketName)
+			return err
+		})
+		if err != nil {
+			return nil, errors.Join(err, db.Close())
+		}
+	}
+	return &BoltDB{db: db}, nil
+}
+
+// defaultBoltOptions returns bolt.DefaultOptions with the package's larger
+// initial mmap size.
+func defaultBoltOptions() *bolt.Options {
+	opts := *bolt.DefaultOptions
+	opts.InitialMmapSize = defaultMmapSize
+	return &opts
+}
+
+// --- Basic CRUD operations ---
+
+// PutSync is Put. Bolt fsyncs every commit unless the database was opened
+// with NoSync, a process-wide setting, so sync has no effect per write.
+func (b *BoltDB) PutSync(ctx context.Context, key, value []byte, sync bool) error {
+	return b.Put(ctx, key, value)
+}
+
+// Put inserts or updates a key-value pair in the database.
+func (b *BoltDB) Put(ctx context.Context, key []byte, data []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketName).Put(key, data)
+	})
+	if err != nil {
+		return err
+	}
+	b.watchers.Publish(zerokv.Event{Op: zerokv.OpPut, Key: key, Value: data})
+	return nil
+}
+
+// PutWithTTL is not supported: bbolt has no key expiry.
+func (b *BoltDB) PutWithTTL(ctx context.Context, key, value []byte, ttl time.Duration) error {
+	return fmt.Errorf("boltdb: PutWithTTL: %w", zerokv.ErrNotSupported)
+}
+
+// PutMany writes all pairs atomically in a single write transaction.
+func (b *BoltDB) PutMany(ctx context.Context, keys, values [][]byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if len(keys) != len(values) {
+		return fmt.Errorf("PutMany: %d keys but %d values", len(keys), len(values))
+	}
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(bucketName)
+		for i := range keys {
+			if err := bucket.Put(keys[i], values[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if b.watchers.Active() {
+		b.watchers.Publish(zerokv.PutEvents(keys, values)...)
+	}
+	return nil
+}
+
+// Get retrieves the value for a given key. Returns ErrNotFound if missing.
+func (b *BoltDB) Get(ctx context.Context, key []byte) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	var data []byte
+	err := b.db.View(func(tx *bolt.Tx) error {
+		val := tx.Bucket(bucketName).Get(key)
+		if val == nil {
+			return errKeyNotFound
+		}
+		// val is only valid for the life of the transaction
+		data = bytes.Clone(val)
+		return nil
+	})
+	return data, err
+}
+
+// GetOrDefault returns a copy of def if key is missing.
+func (b *BoltDB) GetOrDefault(ctx context.Context, key, def []byte) ([]byte, error) {
+	value, err := b.Get(ctx, key)
+	if errors.Is(err, zerokv.ErrKeyNotFound) {
+		return bytes.Clone(def), nil
+	}
+	return value, err
+}
+
+// GetInto copies the value of key into dst while the read transaction
+// still holds it.
+func (b *BoltDB) GetInto(ctx context.Context, key, dst []byte) ([]byte, error) {
+	dst = dst[:0]
+	err := b.View(ctx, key, func(value []byte) error {
+		dst = append(dst, value...)
+		return nil
+	})
+	return dst, err
+}
+
+// View passes fn the value in bbolt's memory map, valid for the read
+// transaction that fn runs in.
+func (b *BoltDB) View(ctx context.Context, key []byte, fn func(value []byte) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return b.db.View(func(tx *bolt.Tx) error {
+		val := tx.Bucket(bucketName).Get(key)
+		if val == nil {
+			return errKeyNotFound
+		}
+		return fn(val)
+	})
+}
+
+// GetMany retrieves the values for keys inside a single read transaction.
+// Missing keys yield a nil entry at the same position.
+func (b *BoltDB) GetMany(ctx context.Context, keys [][]byte) ([][]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	values := make([][]byte, len(keys))
+	err := b.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(bucketName)
+		for i, key := range keys {
+			if val := bucket.Get(key); val != nil {
+				values[i] = append([]byte{}, val...)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+// HasMany reports whether each key exists, all checked in one read-only
+// transaction.
+func (b *BoltDB) HasMany(ctx context.Context, keys [][]byte) ([]bool, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	found := make([]bool, len(keys))
+	err := b.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(bucketName)
+		for i, key := range keys {
+			found[i] = bucket.Get(key) != nil
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return found, nil
+}
+
+// Has reports whether a key exists. A missing key returns (false, nil).
+func (b *BoltDB) Has(ctx context.Context, key []byte) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	var ok bool
+	err := b.db.View(func(tx *bolt.Tx) error {
+		ok = tx.Bucket(bucketName).Get(key) != nil
+		return nil
+	})
+	return ok, err
+}
+
+// SizeOf returns the length of a key's value, measured in the memory-mapped
+// page without copying it.
+func (b *BoltDB) SizeOf(ctx context.Context, key []byte) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	var size int
+	err := b.db.View(func(tx *bolt.Tx) error {
+		val := tx.Bucket(bucketName).Get(key)
+		if val == nil {
+			return errKeyNotFound
+		}
+		size = len(val)
+		return nil
+	})
+	return size, err
+}
+
+// Delete removes a key-value pair from the database.
+func (b *BoltDB) Delete(ctx context.Context, key []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketName).Delete(key)
+	})
+	if err != nil {
+		return err
+	}
+	b.watchers.Publish(zerokv.Event{Op: zerokv.OpDelete, Key: key})
+	return nil
+}
+
+// DeletePrefix removes every key with the given prefix in one write transaction.
+func (b *BoltDB) DeletePrefix(ctx context.Context, prefix []byte) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	count := 0
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		c := tx.Bucket(bucketName).Cursor()
+		k, _ := c.Seek(prefix)
+		for k != nil && bytes.HasPrefix(k, prefix) {
+			// re-seek after Cursor.Delete, which can leave the cursor
+			// past the next key when the page is rebalanced
+			if err := c.Delete(); err != nil {
+				return err
+			}
+			count++
+			k, _ = c.Seek(prefix)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	b.watchers.Publish(zerokv.DeletePrefixEvent(prefix))
+	return count, nil
+}
+
+// DeleteRange removes every key in [start, end) in one write transaction.
+func (b *BoltDB) DeleteRange(ctx context.Context, start, end []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		c := tx.Bucket(bucketName).Cursor()
+		k, _ := c.Seek(start)
+		for k != nil && (end == nil || bytes.Compare(k, end) < 0) {
+			if err := c.Delete(); err != nil {
+				return err
+			}
+			k, _ = c.Seek(start)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	b.watchers.Publish(zerokv.Event{Op: zerokv.OpDeleteRange, Key: start, End: end})
+	return nil
+}
+
+// Truncate deletes and recreates the bucket in one write transaction. The
+// freed pages are reused by later writes, but the file does not shrink.
+func (b *BoltDB) Truncate(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		if err := tx.DeleteBucket(bucketName); err != nil {
+			return err
+		}
+		_, err := tx.CreateBucket(bucketName)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	b.watchers.Publish(zerokv.Event{Op: zerokv.OpDeleteRange})
+	return nil
+}
+
+// TruncatePrefix is DeletePrefix: the freed pages are reused by later
+// writes, but the file does not shrink.
+func (b *BoltDB) TruncatePrefix(ctx context.Context, prefix []byte) error {
+	_, err := b.DeletePrefix(ctx, prefix)
+	return err
+}
+
+// Count returns the number of keys with the given prefix.
+// It returns ctx.Err() if ctx is cancelled during the scan.
+func (b *BoltDB) Count(ctx context.Context, prefix []byte) (int64, error) {
+	var count int64
+	err := b.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(bucketName).Cursor()
+		for k, _ := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Next() {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			count++
+		}
+		return ctx.Err()
+	})
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// EstimateSize returns the total length of the keys and values with the
+// given prefix. It does not include bbolt's page and freelist overhead.
+func (b *BoltDB) EstimateSize(prefix []byte) (int64, error) {
+	var size int64
+	err := b.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(bucketName).Cursor()
+		for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+			size += int64(len(k) + len(v))
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return size, nil
+}
+
+// CompareAndSwap sets key to new if its current value equals old, or if it
+// is absent when old is nil. bbolt allows one writer at a time, so the check
+// and the write in a single Update are atomic.
+func (b *BoltDB) CompareAndSwap(ctx context.Context, key, old, new []byte) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	var swapped bool
+	err := b.modify(key, func(current []byte, found bool) ([]byte, bool, error) {
+		swapped = found == (old != nil) && bytes.Equal(current, old)
+		return new, swapped, nil
+	})
+	return swapped && err == nil, err
+}
+
+// Increment adds delta to the counter at key in one Update.
+func (b *BoltDB) Increment(ctx context.Context, key []byte, delta int64) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	var n int64
+	err := b.modify(key, func(current []byte, found bool) ([]byte, bool, error) {
+		n = 0
+		if found {
+			var err error
+			if n, err = zerokv.DecodeCounter(current); err != nil {
+				return nil, false, err
+			}
+		}
+		n += delta
+		return zerokv.EncodeCounter(n), true, nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// GetOrPut returns the value of key, or stores and returns the result of fill
+// when the key is missing. fill runs once per key even with concurrent
+// callers, and outside the write transaction so it never blocks other
+// writers.
+func (b *BoltDB) GetOrPut(ctx context.Context, key []byte, fill func() ([]byte, error)) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	unlock := b.fills.Lock(key)
+	defer unlock()
+	value, err := b.Get(ctx, key)
+	if !errors.Is(err, zerokv.ErrKeyNotFound) {
+		return value, err
+	}
+	filled, err := fill()
+	if err != nil {
+		return nil, err
+	}
+	err = b.modify(key, func(current []byte, found bool) ([]byte, bool, error) {
+		if found {
+			value = bytes.Clone(current)
+			return nil, false, nil
+		}
+		value = filled
+		return filled, true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// Merge replaces the value of key with merge(existing, operand) in one
+// Update, where existing is nil for a missing key. existing is a copy, so
+// merge may keep or modify it.
+func (b *BoltDB) Merge(ctx context.Context, key, operand []byte, merge func(existing, operand []byte) []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return b.modify(key, func(current []byte, found bool) ([]byte, bool, error) {
+		return merge(bytes.Clone(current), operand), true, nil
+	})
+}
+
+// modify runs a read-modify-write of key in one Update. fn gets the current
+// value and whether the key exists, and returns the value to store and
+// whether to store it. current is only valid until fn returns.
+func (b *BoltDB) modify(key []byte, fn func(current []byte, found bool) ([]byte, bool, error)) error {
+	var written []byte
+	wrote := false
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(bucketName)
+		current := bucket.Get(key)
+		value, write, err := fn(current, current != nil)
+		if err != nil || !write {
+			return err
+		}
+		written, wrote = value, true
+		return bucket.Put(key, value)
+	})
+	if err != nil {
+		return err
+	}
+	if wrote {
+		b.watchers.Publish(zerokv.Event{Op: zerokv.OpPut, Key: key, Value: written})
+	}
+	return nil
+}
+
+// Stats reports the exact key count from bucket statistics and the size of
+// the data file. bbolt has no memtable or compactions, so those stay zero.
+func (b *BoltDB) Stats(ctx context.Context) (zerokv.Stats, error) {
+	if err := ctx.Err(); err != nil {
+		return zerokv.Stats{}, err
+	}
+	var stats zerokv.Stats
+	err := b.db.View(func(tx *bolt.Tx) error {
+		stats.KeyCount = int64(tx.Bucket(bucketName).Stats().KeyN)
+		stats.OnDiskBytes = tx.Size()
+		return nil
+	})
+	return stats, err
+}
+
+// Sync fsyncs the data file. bbolt syncs on every commit unless NoSync is
+// set in BoltConfigs, so this only matters in that mode.
+func (b *BoltDB) Sync(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return b.db.Sync()
+}
+
+// Compact is not supported: bbolt reuses freed pages but never shrinks its
+// file in place. Use bbolt's compact tool on a closed database instead.
+func (b *BoltDB) Compact(ctx context.Context, start, end []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return fmt.Errorf("boltdb: Compact: %w", zerokv.ErrNotSupported)
+}
+
+// RunValueLogGC does nothing: bbolt stores values in its B+tree pages.
+func (b *BoltDB) RunValueLogGC(discardRatio float64) error {
+	return nil
+}
+
+// Ping opens a read transaction and checks that the data bucket exists.
+func (b *BoltDB) Ping(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return b.db.View(func(tx *bolt.Tx) error {
+		if tx.Bucket(bucketName) == nil {
+			return fmt.Errorf("boltdb: bucket %q is missing", bucketName)
+		}
+		return nil
+	})
+}
+
+// Backup writes every entry from one read transaction to w in the portable
+// zerokv backup format.
+func (b *BoltDB) Backup(ctx context.Context, w io.Writer) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	bw, err := zerokv.NewBackupWriter(w)
+	if err != nil {
+		return err
+	}
+	err = b.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(bucketName).Cursor()
+		for k, v := c.First(); k != nil; k, v = c.Next() {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if err := bw.Write(k, v, 0); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return bw.Close()
+}
+
+// Restore writes every entry from a backup produced by Backup, committing
+// restoreBatchSize entries per write transaction. Existing keys not in the
+// backup are kept. Entries with an expiry are rejected with ErrNotSupported.
+func (b *BoltDB) Restore(ctx context.Context, r io.Reader) error {
+	br, err := zerokv.NewBackupReader(r)
+	if err != nil {
+		return err
+	}
+	ops := make([]batchOp, 0, restoreBatchSize)
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		key, value, expiresAt, err := br.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if expiresAt != 0 {
+			return fmt.Errorf("boltdb: backup has keys with a TTL: %w", zerokv.ErrNotSupported)
+		}
+		ops = append(ops, batchOp{key: key, value: value})
+		if len(ops) == restoreBatchSize {
+			if err := b.putOps(ops); err != nil {
+				return err
+			}
+			ops = ops[:0]
+		}
+	}
+	return b.putOps(ops)
+}
+
+// Checkpoint copies the database file into destDir from within a read-only
+// transaction, so the copy is consistent and writers are not blocked.
+// NewBoltDB can open destDir directly.
+func (b *BoltDB) Checkpoint(ctx context.Context, destDir string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := zerokv.CheckCheckpointDir(destDir); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(destDir, 0o755); err != nil {
+		return err
+	}
+	return b.db.View(func(tx *bolt.Tx) error {
+		return tx.CopyFile(filepath.Join(destDir, fileName), 0o600)
+	})
+}
+
+// putOps writes ops in a single write transaction.
+func (b *BoltDB) putOps(ops []batchOp) error {
+	if len(ops) == 0 {
+		return nil
+	}
+	return b.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(bucketName)
+		for _, op := range ops {
+			if err := bucket.Put(op.key, op.value); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Watch streams the changes made through this BoltDB to keys with the given
+// prefix until ctx is done or the database is closed. bbolt has no change
+// feed, so every write method publishes its changes once committed; Restore
+// produces no events.
+func (b *BoltDB) Watch(ctx context.Context, prefix []byte) (<-chan zerokv.Event, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return b.watchers.Watch(ctx, prefix), nil
+}
+
+// Close closes the database file and releases all resources.
+func (b *BoltDB) Close() error {
+	b.watchers.Close()
+	var errs []error
+	if err := b.db.Close(); err != nil {
+		errs = append(errs, err)
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return errors.Join(errs...)
+}
+
+// -- Batch operations
+
+// Batch creates a new batch that is applied in a single bolt.Update on Commit.
+func (b *BoltDB) Batch() zerokv.Batch {
+	return &boltBatch{db: b.db, watchers: &b.watchers, check: zerokv.BatchValidator{Limits: boltLimits}}
+}
+
+// BulkLoad applies what fn stages in one bolt.Update, and so one fsync, per
+// zerokv.BulkLoadSegmentSize bytes instead of one per write.
+func (b *BoltDB) BulkLoad(ctx context.Context, fn func(zerokv.Batch) error) error {
+	return zerokv.RunBulkLoad(ctx, b.BatchWithOptions(0, zerokv.BulkLoadSegmentSize), fn)
+}
+
+// BatchWithOptions creates a batch that applies its queued operations in a
+// separate bolt.Update each time maxOps operations or maxBytes bytes are
+// reached, and once more on Commit.
+func (b *BoltDB) BatchWithOptions(maxOps, maxBytes int) zerokv.Batch {
+	return zerokv.NewAutoFlushBatch(b.Batch, maxOps, maxBytes)
+}
+
+// Put queues a set operation in the batch.
+func (b *boltBatch) Put(key []byte, data []byte) error {
+	if b.committed {
+		return ErrBatchCommitted
+	}
+	b.ops = append(b.ops, batchOp{key: bytes.Clone(key), value: bytes.Clone(data)})
+	b.check.Put(key, data)
+	return nil
+}
+
+// PutIfAbsent queues a put that Commit skips if key exists once the
+// operations before it are applied, checked inside the commit's write
+// transaction.
+func (b *boltBatch) PutIfAbsent(key []byte, data []byte) error {
+	if b.committed {
+		return ErrBatchCommitted
+	}
+	b.ops = append(b.ops, batchOp{key: bytes.Clone(key), value: bytes.Clone(data), ifAbsent: true})
+	b.check.Put(key, data)
+	return nil
+}
+
+// Delete queues a delete operation in the batch.
+func (b *boltBatch) Delete(key []byte) error {
+	if b.committed {
+		return ErrBatchCommitted
+	}
+	b.ops = append(b.ops, batchOp{key: bytes.Clone(key), delete: true})
+	b.check.Delete(key)
+	return nil
+}
+
+// Len returns the number of queued operations.
+func (b *boltBatch) Len() int {
+	return len(b.ops)
+}
+
+// Size returns the bytes of keys and values queued in the batch.
+func (b *boltBatch) Size() int {
+	size := 0
+	for _, op := range b.ops {
+		size += len(op.key) + len(op.value)
+	}
+	return size
+}
+
+// Reset drops the queued operations and allows the batch to be used again
+// after Commit.
+func (b *boltBatch) Reset() {
+	b.ops = nil
+	b.committed = false
+	b.check.Reset()
+}
+
+// Validate reports the first queued operation with an empty key, or a key
+// or value larger than bbolt accepts, which would otherwise fail the whole
+// Commit.
+func (b *boltBatch) Validate() error {
+	return b.check.Err()
+}
+
+// PutCtx queues a set operation in the batch unless ctx is done.
+func (b *boltBatch) PutCtx(ctx context.Context, key []byte, data []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return b.Put(key, data)
+}
+
+// DeleteCtx queues a delete operation in the batch unless ctx is done.
+func (b *boltBatch) DeleteCtx(ctx context.Context, key []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return b.Delete(key)
+}
+
+// Commit applies all queued operations in one write transaction.
+func (b *boltBatch) Commit(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if b.committed {
+		return ErrBatchCommitted
+	}
+	publish := b.watchers.Active()
+	var applied []batchOp
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(bucketName)
+		for _, op := range b.ops {
+			if op.ifAbsent && bucket.Get(op.key) != nil {
+				continue
+			}
+			var err error
+			if op.delete {
+				err = bucket.Delete(op.key)
+			} else {
+				err = bucket.Put(op.key, op.value)
+			}
+			if err != nil {
+				return err
+			}
+			if publish {
+				applied = append(applied, op)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if publish {
+		b.watchers.Publish(opEvents(applied)...)
+	}
+	b.committed = true
+	b.ops = nil
+	b.check.Reset()
+	return nil
+}
+
+// opEvents returns the watch events for committed batch operations.
+func opEvents(ops []batchOp) []zerokv.Event {
+	events := make([]zerokv.Event, len(ops))
+	for i, op := range ops {
+		if op.delete {
+			events[i] = zerokv.Event{Op: zerokv.OpDelete, Key: op.key}
+		} else {
+			events[i] = zerokv.Event{Op: zerokv.OpPut, Key: op.key, Value: op.value}
+		}
+	}
+	return events
+}
+
+// -- Transaction operations
+
+// boltTxn stages writes in memory and applies them in one bbolt write
+// transaction on Commit. A native write transaction is not used because it
+// would hold the single writer lock for the life of the Txn.
+type boltTxn struct {
+	db       *bolt.DB
+	writes   map[string]batchOp
+	done     bool
+	watchers *zerokv.Watchers
+}
+
+// NewTransaction starts a buffered transaction. Reads fall through to the
+// database for keys the transaction has not written, and Commit applies
+// writes last-writer-wins without conflict detection.
+func (b *BoltDB) NewTransaction(ctx context.Context) (zerokv.Txn, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return &boltTxn{db: b.db, writes: make(map[string]batchOp), watchers: &b.watchers}, nil
+}
+
+// Get retrieves the value for a given key, including pending writes.
+func (t *boltTxn) Get(ctx context.Context, key []byte) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if t.done {
+		return nil, zerokv.ErrTxnDone
+	}
+	if op, ok := t.writes[string(key)]; ok {
+		if op.delete {
+			return nil, errKeyNotFound
+		}
+		return bytes.Clone(op.value), nil
+	}
+	var data []byte
+	err := t.db.View(func(tx *bolt.Tx) error {
+		val := tx.Bucket(bucketName).Get(key)
+		if val == nil {
+			return errKeyNotFound
+		}
+		data = bytes.Clone(val)
+		return nil
+	})
+	return data, err
+}
+
+// Put stages a key-value pair in the transaction.
+func (t *boltTxn) Put(ctx context.Context, key []byte, data []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if t.done {
+		return zerokv.ErrTxnDone
+	}
+	value := bytes.Clone(data)
+	if value == nil {
+		value = []byte{}
+	}
+	t.writes[string(key)] = batchOp{key: bytes.Clone(key), value: value}
+	return nil
+}
+
+// Delete stages a key removal in the transaction.
+func (t *boltTxn) Delete(ctx context.Context, key []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if t.done {
+		return zerokv.ErrTxnDone
+	}
+	t.writes[string(key)] = batchOp{key: bytes.Clone(key), delete: true}
+	return nil
+}
+
+// Commit applies every staged write in a single write transaction.
+func (t *boltTxn) Commit(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if t.done {
+		return zerokv.ErrTxnDone
+	}
+	err := t.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(bucketName)
+		for _, op := range t.writes {
+			var err error
+			if op.delete {
+				err = bucket.Delete(op.key)
+			} else {
+				err = bucket.Put(op.key, op.value)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if t.watchers.Active() {
+		ops := make([]batchOp, 0, len(t.writes))
+		for _, op := range t.writes {
+			ops = append(ops, op)
+		}
+		t.watchers.Publish(opEvents(ops)...)
+	}
+	t.done = true
+	t.writes = nil
+	return nil
+}
+
+// Discard drops the transaction's staged writes.
+func (t *boltTxn) Discard() {
+	t.done = true
+	t.writes = nil
+}
+
+// -- Snapshot operations
+
+// boltSnapshot is a read transaction kept open until the snapshot and every
+// iterator opened from it are released. Like iterators, an open snapshot
+// blocks writes that need to grow the file.
+type boltSnapshot struct {
+	mu       sync.RWMutex
+	tx       *bolt.Tx
+	bucket   *bolt.Bucket
+	iters    int
+	released bool
+}
+
+// Snapshot returns a read-only view backed by a bbolt read transaction.
+func (b *BoltDB) Snapshot() (zerokv.Snapshot, error) {
+	tx, err := b.db.Begin(false)
+	if err != nil {
+		return nil, err
+	}
+	return &boltSnapshot{tx: tx, bucket: tx.Bucket(bucketName)}, nil
+}
+
+// Get retrieves the value for a given key as of the snapshot.
+func (s *boltSnapshot) Get(ctx context.Context, key []byte) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.released {
+		return nil, zerokv.ErrSnapshotReleased
+	}
+	val := s.bucket.Get(key)
+	if val == nil {
+		return nil, errKeyNotFound
+	}
+	return bytes.Clone(val), nil
+}
+
+// Has reports whether a key existed when the snapshot was taken.
+func (s *boltSnapshot) Has(ctx context.Context, key []byte) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.released {
+		return false, zerokv.ErrSnapshotReleased
+	}
+	return s.bucket.Get(key) != nil, nil
+}
+
+// Scan returns an iterator over keys with the given prefix as of the snapshot.
+func (s *boltSnapshot) Scan(prefix []byte) zerokv.Iterator {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.released {
+		return zerokv.NewErrIterator(zerokv.ErrSnapshotReleased)
+	}
+	s.iters++
+	return &boltIterator{snap: s, cursor: s.bucket.Cursor(), prefix: prefix, start: prefix}
+}
+
+// Release rolls back the read transaction once no iterator still uses it.
+func (s *boltSnapshot) Release() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.released {
+		return
+	}
+	s.released = true
+	if s.iters == 0 {
+		_ = s.tx.Rollback()
+	}
+}
+
+// unref is called when an iterator opened from the snapshot is released.
+func (s *boltSnapshot) unref() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.iters--
+	if s.released && s.iters == 0 {
+		_ = s.tx.Rollback()
+	}
+}
+
+// -- Iterator operations
+
+// Scan returns an iterator over keys with the given prefix.
+func (b *BoltDB) Scan(prefix []byte) zerokv.Iterator {
+	return b.newIterator(prefix, nil, nil, false)
+}
+
+// RangeScan returns an iterator over keys in [start, end).
+func (b *BoltDB) RangeScan(start, end []byte) zerokv.Iterator {
+	return b.newIterator(nil, start, end, false)
+}
+
+// ScanFrom returns an iterator over keys with the given prefix, starting at
+// the first one >= start.
+func (b *BoltDB) ScanFrom(prefix, start []byte) zerokv.Iterator {
+	return b.newIterator(prefix, start, nil, false)
+}
+
+// ScanKeys returns an iterator over keys with the given prefix without copying values.
+func (b *BoltDB) ScanKeys(prefix []byte) zerokv.Iterator {
+	return b.newIterator(prefix, nil, nil, true)
+}
+
+// ScanContext returns a prefix iterator that stops once ctx is done.
+func (b *BoltDB) ScanContext(ctx context.Context, prefix []byte) zerokv.Iterator {
+	return zerokv.NewContextIterator(ctx, b.Scan(prefix))
+}
+
+// ReverseScan returns an iterator over keys with the given prefix in descending order.
+func (b *BoltDB) ReverseScan(prefix []byte) zerokv.Iterator {
+	tx, err := b.db.Begin(false)
+	if err != nil {
+		return &boltReverseIterator{err: []error{err}}
+	}
+	return &boltReverseIterator{tx: tx, cursor: tx.Bucket(bucketName).Cursor(), prefix: prefix}
+}
+
+func (b *BoltDB) newIterator(prefix, start, end []byte, keysOnly bool) zerokv.Iterator {
+	tx, err := b.db.Begin(false)
+	if err != nil {
+		return &boltIterator{err: []error{err}}
+	}
+	if bytes.Compare(start, prefix) < 0 {
+		start = prefix
+	}
+	return &boltIterator{
+		tx:       tx,
+		cursor:   tx.Bucket(bucketName).Cursor(),
+		prefix:   prefix,
+		start:    start,
+		end:      end,
+		keysOnly: keysOnly,
+	}
+}
+
+func (it *boltIterator) Next() bool {
+	if it.cursor == nil {
+		return false
+	}
+	if !it.started {
+		return it.Seek(it.start)
+	}
+	it.key, it.value = it.cursor.Next()
+	return it.check()
+}
+
+// Seek moves to the first key >= key, never before the iterator's lower bound.
+func (it *boltIterator) Seek(key []byte) bool {
+	if it.cursor == nil {
+		return false
+	}
+	if bytes.Compare(key, it.start) < 0 {
+		key = it.start
+	}
+	if len(key) == 0 {
+		it.key, it.value = it.cursor.First()
+	} else {
+		it.key, it.value = it.cursor.Seek(key)
+	}
+	it.started = true
+	return it.check()
+}
+
+// First moves to the smallest key within the iterator's bounds.
+func (it *boltIterator) First() bool {
+	return it.Seek(nil)
+}
+
+// Last moves to the greatest key within the iterator's bounds: the key
+// before the tighter of the end bound and the prefix's upper bound.
+func (it *boltIterator) Last() bool {
+	if it.cursor == nil {
+		return false
+	}
+	upper := it.end
+	if pu := zerokv.PrefixUpperBound(it.prefix); pu != nil && (upper == nil || bytes.Compare(pu, upper) < 0) {
+		upper = pu
+	}
+	if upper == nil {
+		it.key, it.value = it.cursor.Last()
+	} else if it.key, it.value = it.cursor.Seek(upper); it.key == nil {
+		it.key, it.value = it.cursor.Last()
+	} else {
+		it.key, it.value = it.cursor.Prev()
+	}
+	it.started = true
+	if it.key != nil && bytes.Compare(it.key, it.start) < 0 {
+		it.key, it.value = nil, nil
+	}
+	return it.check()
+}
+
+// check updates valid for the cursor position against the prefix and end bound.
+func (it *boltIterator) check() bool {
+	it.valid = it.key != nil && bytes.HasPrefix(it.key, it.prefix) &&
+		(it.end == nil || bytes.Compare(it.key, it.end) < 0)
+	return it.valid
+}
+
+func (it *boltIterator) Valid() bool {
+	return it.valid
+}
+
+func (it *boltIterator) Key() []byte {
+	if !it.valid {
+		return nil
+	}
+	return bytes.Clone(it.key) // cursor memory is only valid during the transaction
+}
+
+func (it *boltIterator) Value() []byte {
+	if !it.valid || it.keysOnly {
+		return nil
+	}
+	return bytes.Clone(it.value)
+}
+
+// Release Must be called to close the read transaction.
+func (it *boltIterator) Release() {
+	it.valid = false
+	if it.snap != nil {
+		it.snap.unref()
+		it.snap = nil
+		return
+	}
+	if it.tx != nil {
+		if err := it.tx.Rollback(); err != nil && !errors.Is(err, bolt.ErrTxClosed) {
+			it.err = append(it.err, err)
+		}
+	}
+}
+
+func (it *boltIterator) Error() error {
+	return errors.Join(it.err...)
+}
+
+func (it *boltReverseIterator) Next() bool {
+	if it.cursor == nil {
+		return false
+	}
+	if !it.started {
+		it.seekLast()
+		it.started = true
+	} else {
+		it.key, it.value = it.cursor.Prev()
+	}
+	return it.check()
+}
+
+// Seek moves to the last key <= key, never past the end of the prefix.
+func (it *boltReverseIterator) Seek(key []byte) bool {
+	if it.cursor == nil {
+		return false
+	}
+	upbound := zerokv.PrefixUpperBound(it.prefix)
+	if upbound != nil && bytes.Compare(key, upbound) >= 0 {
+		it.seekLast()
+	} else {
+		it.key, it.value = it.cursor.Seek(key)
+		if it.key == nil {
+			it.key, it.value = it.cursor.Last()
+		} else if bytes.Compare(it.key, key) > 0 {
+			it.key, it.value = it.cursor.Prev()
+		}
+	}
+	it.started = true
+	return it.check()
+}
+
+// First moves to the smallest key under the prefix.
+func (it *boltReverseIterator) First() bool {
+	if it.cursor == nil {
+		return false
+	}
+	if len(it.prefix) == 0 {
+		it.key, it.value = it.cursor.First()
+	} else {
+		it.key, it.value = it.cursor.Seek(it.prefix)
+	}
+	it.started = true
+	return it.check()
+}
+
+// Last moves to the greatest key under the prefix.
+func (it *boltReverseIterator) Last() bool {
+	if it.cursor == nil {
+		return false
+	}
+	it.seekLast()
+	it.started = true
+	return it.check()
+}
+
+// seekLast positions the cursor on the greatest key under the prefix.
+func (it *boltReverseIterator) seekLast() {
+	upbound := zerokv.PrefixUpperBound(it.prefix)
+	if upbound == nil {
+		it.key, it.value = it.cursor.Last()
+		return
+	}
+	it.key, it.value = it.cursor.Seek(upbound)
+	if it.key == nil {
+		it.key, it.value = it.cursor.Last()
+	} else {
+		it.key, it.value = it.cursor.Prev()
+	}
+}
+
+func (it *boltReverseIterator) check() bool {
+	it.valid = it.key != nil && bytes.HasPrefix(it.key, it.prefix)
+	return it.valid
+}
+
+func (it *boltReverseIterator) Valid() bool {
+	return it.valid
+}
+
+func (it *boltReverseIterator) Key() []byte {
+	if !it.valid {
+		return nil
+	}
+	return bytes.Clone(it.key)
+}
+
+func (it *boltReverseIterator) Value() []byte {
+	if !it.valid {
+		return nil
+	}
+	return bytes.Clone(it.value)
+}
+
+// Release Must be called to close the read transaction.
+func (it *boltReverseIterator) Release() {
+	it.valid = false
+	if it.tx != nil {
+		if err := it.tx.Rollback(); err != nil && !errors.Is(err, bolt.ErrTxClosed) {
+			it.err = append(it.err, err)
+		}
+	}
+}
+
+func (it *boltReverseIterator) Error() error {
+	return errors.Join(it.err...)
+}
@@ -0,0 +1,132 @@
+package boltdb_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rawbytedev/zerokv"
+	"github.com/rawbytedev/zerokv/boltdb"
+	"github.com/rawbytedev/zerokv/helpers"
+	"github.com/stretchr/testify/require"
+)
+
+// TestBoltBatchOperations tests batch Put and Get operations.
+func TestBoltBatchOperations(t *testing.T) {
+	db := helpers.SetupDB(t, "boltdb")
+	batch := db.Batch()
+	keys := make([][]byte, 5)
+	values := make([][]byte, 5)
+	for i := 0; i < 5; i++ {
+		keys[i] = helpers.RandomBytes(16)
+		values[i] = helpers.RandomBytes(32)
+		err := batch.Put(keys[i], values[i])
+		require.NoError(t, err, "Error adding Put operation to batch")
+	}
+	err := batch.Commit(t.Context())
+	require.NoError(t, err, "Error committing batch operations")
+	for i := 0; i < 5; i++ {
+		retrievedValue, err := db.Get(t.Context(), keys[i])
+		require.NoError(t, err, "Error getting value after batch commit")
+		require.Equal(t, values[i], retrievedValue, "Retrieved value does not match expected after batch commit")
+	}
+	// This should fail because the batch has already been committed
+	err = batch.Put(keys[0], values[1])
+	require.ErrorIs(t, err, boltdb.ErrBatchCommitted)
+	err = batch.Commit(t.Context())
+	require.ErrorIs(t, err, boltdb.ErrBatchCommitted)
+	defer db.Close()
+}
+
+// TestBoltBatchValidate tests that a bolt batch reports an empty key, which
+// bbolt would refuse at Commit.
+func TestBoltBatchValidate(t *testing.T) {
+	db := helpers.SetupDB(t, "boltdb")
+	defer db.Close()
+	batch := db.Batch()
+	require.NoError(t, batch.Put([]byte("key"), []byte("value")))
+	require.NoError(t, batch.Validate())
+	require.NoError(t, batch.Put(nil, []byte("value")))
+	require.ErrorIs(t, batch.Validate(), zerokv.ErrInvalidBatch)
+	require.Error(t, batch.Commit(t.Context()))
+	batch.Reset()
+	require.NoError(t, batch.Validate())
+}
+
+// TestBoltReopen verifies data persists in the bbolt file across reopen.
+func TestBoltReopen(t *testing.T) {
+	tmp := t.TempDir()
+	db, err := boltdb.NewBoltDB(boltdb.Config{Dir: tmp})
+	require.NoError(t, err)
+	require.NoError(t, db.Put(t.Context(), []byte("key"), []byte("value")))
+	require.NoError(t, db.Close())
+
+	db, err = boltdb.NewBoltDB(boltdb.Config{Dir: tmp})
+	require.NoError(t, err)
+	defer db.Close()
+	value, err := db.Get(t.Context(), []byte("key"))
+	require.NoError(t, err)
+	require.Equal(t, []byte("value"), value)
+	_, err = db.Get(t.Context(), []byte("missing"))
+	require.ErrorIs(t, err, boltdb.ErrNotFound)
+}
+
+// TestBoltNewOptions verifies New applies functional options, including a
+// read-only reopen.
+func TestBoltNewOptions(t *testing.T) {
+	tmp := t.TempDir()
+	db, err := boltdb.New(tmp, boltdb.WithSyncWrites(false), boltdb.WithTimeout(time.Second))
+	require.NoError(t, err)
+	require.NoError(t, db.Put(t.Context(), []byte("key"), []byte("value")))
+	require.NoError(t, db.Sync(t.Context()))
+	require.NoError(t, db.Close())
+
+	db, err = boltdb.New(tmp, boltdb.WithReadOnly())
+	require.NoError(t, err)
+	defer db.Close()
+	value, err := db.Get(t.Context(), []byte("key"))
+	require.NoError(t, err)
+	require.Equal(t, []byte("value"), value)
+	require.Error(t, db.Put(t.Context(), []byte("key"), []byte("value")))
+}
+
+// TestBoltReverseIteratorOrder verifies reverse order against forward order
+func TestBoltReverseIteratorOrder(t *testing.T) {
+	db := helpers.SetupDB(t, "boltdb")
+	defer db.Close()
+	for _, key := range []string{"key_01", "key_02", "key_03", "key_04", "key_05", "kez"} {
+		require.NoError(t, db.Put(t.Context(), []byte(key), []byte("value")))
+	}
+
+	forwardKeys := make([][]byte, 0)
+	it := db.Scan([]byte("key_"))
+	for it.Next() {
+		forwardKeys = append(forwardKeys, it.Key())
+	}
+	it.Release()
+
+	reverseKeys := make([][]byte, 0)
+	rit := db.ReverseScan([]byte("key_"))
+	for rit.Next() {
+		reverseKeys = append(reverseKeys, rit.Key())
+	}
+	rit.Release()
+
+	require.Len(t, forwardKeys, 5)
+	require.Equal(t, len(forwardKeys), len(reverseKeys), "Should have same count")
+	for i := 0; i < len(forwardKeys); i++ {
+		require.Equal(t, forwardKeys[i], reverseKeys[len(reverseKeys)-1-i], "Keys should be in reverse order")
+	}
+}
+
+// TestBoltStats verifies Stats reports the exact key count and file size.
+func TestBoltStats(t *testing.T) {
+	db := helpers.SetupDB(t, "boltdb")
+	defer db.Close()
+	for _, key := range []string{"a", "b", "c"} {
+		require.NoError(t, db.Put(t.Context(), []byte(key), []byte("value")))
+	}
+	stats, err := db.Stats(t.Context())
+	require.NoError(t, err)
+	require.Equal(t, int64(3), stats.KeyCount)
+	require.Positive(t, stats.OnDiskBytes)
+}
@@ -0,0 +1,94 @@
+package boltdb
+
+import (
+	"net/url"
+	"time"
+
+	"github.com/rawbytedev/zerokv"
+	bolt "go.etcd.io/bbolt"
+)
+
+// specific boltdb options
+type Config struct {
+	Dir         string
+	BoltConfigs *bolt.Options
+}
+
+func DefaultOptions(Dir string) *Config {
+	return &Config{Dir, nil}
+}
+
+// Option adjusts the Config built by New.
+type Option func(*Config)
+
+// New opens a BoltDB in dir, starting from the package defaults and applying
+// opts in order. It is shorthand for building a Config by hand.
+func New(dir string, opts ...Option) (zerokv.Core, error) {
+	cfg := DefaultOptions(dir)
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	return NewBoltDB(*cfg)
+}
+
+// WithBoltOptions replaces the engine options wholesale. Options applied
+// after it modify the given value.
+func WithBoltOptions(opts *bolt.Options) Option {
+	return func(c *Config) {
+		c.BoltConfigs = opts
+	}
+}
+
+// WithReadOnly opens the data file with a shared lock and no write access.
+func WithReadOnly() Option {
+	return func(c *Config) {
+		c.boltOptions().ReadOnly = true
+	}
+}
+
+// WithTimeout sets how long to wait for the file lock; zero waits forever.
+func WithTimeout(d time.Duration) Option {
+	return func(c *Config) {
+		c.boltOptions().Timeout = d
+	}
+}
+
+// WithSyncWrites controls whether each commit fsyncs the data file.
+func WithSyncWrites(sync bool) Option {
+	return func(c *Config) {
+		c.boltOptions().NoSync = !sync
+	}
+}
+
+// WithInitialMmapSize sets the initial size in bytes of the memory map.
+func WithInitialMmapSize(n int) Option {
+	return func(c *Config) {
+		c.boltOptions().InitialMmapSize = n
+	}
+}
+
+// paramOptions maps the DSN parameters accepted by the "bolt" backend:
+// readOnly, syncWrites, timeout (file lock wait) and mmapSize.
+func paramOptions(params url.Values) ([]Option, error) {
+	var opts []Option
+	err := zerokv.ApplyParams(params, map[string]func(string) error{
+		"readOnly": zerokv.BoolParam(func(b bool) {
+			if b {
+				opts = append(opts, WithReadOnly())
+			}
+		}),
+		"syncWrites": zerokv.BoolParam(func(b bool) { opts = append(opts, WithSyncWrites(b)) }),
+		"timeout":    zerokv.DurationParam(func(d time.Duration) { opts = append(opts, WithTimeout(d)) }),
+		"mmapSize":   zerokv.SizeParam(func(n int64) { opts = append(opts, WithInitialMmapSize(int(n))) }),
+	})
+	return opts, err
+}
+
+// boltOptions returns the engine options for an Option to modify, creating
+// the package defaults on first use.
+func (c *Config) boltOptions() *bolt.Options {
+	if c.BoltConfigs == nil {
+		c.BoltConfigs = defaultBoltOptions()
+	}
+	return c.BoltConfigs
+}
@@ -0,0 +1,43 @@
+package zerokv
+
+import "context"
+
+// RunWithContext runs fn in its own goroutine and returns its result, or
+// ctx.Err() as soon as ctx is done, whichever comes first. An early return
+// does not stop fn: it runs to completion and its result is dropped, so any
+// write it makes may still be applied.
+func RunWithContext[T any](ctx context.Context, fn func() (T, error)) (T, error) {
+	if err := ctx.Err(); err != nil {
+		var zero T
+		return zero, err
+	}
+	type result struct {
+		v   T
+		err error
+	}
+	done := make(chan result, 1)
+	go func() {
+		v, err := fn()
+		done <- result{v, err}
+	}()
+	select {
+	case <-ctx.Done():
+		var zero T
+		return zero, ctx.Err()
+	case res := <-done:
+		return res.v, res.err
+	}
+}
+
+// RunWithCancel is RunWithContext for work that can stop early. fn receives
+// a context derived from ctx that is cancelled as soon as RunWithCancel
+// returns, whether because fn finished or because ctx was done first. This
+// only frees resources if fn honors its context: a fn that ignores it keeps
+// running after the early return, exactly as with RunWithContext.
+func RunWithCancel[T any](ctx context.Context, fn func(context.Context) (T, error)) (T, error) {
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	return RunWithContext(runCtx, func() (T, error) {
+		return fn(runCtx)
+	})
+}
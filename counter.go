@@ -0,0 +1,24 @@
+package zerokv
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// counterSize is the length of a counter value written by Core.Increment.
+const counterSize = 8
+
+// EncodeCounter returns n in the 8-byte big-endian form used by
+// Core.Increment, for seeding a counter with Put.
+func EncodeCounter(n int64) []byte {
+	return binary.BigEndian.AppendUint64(make([]byte, 0, counterSize), uint64(n))
+}
+
+// DecodeCounter parses a counter value read with Get. It returns an error
+// wrapping ErrInvalidCounter if value is not 8 bytes long.
+func DecodeCounter(value []byte) (int64, error) {
+	if len(value) != counterSize {
+		return 0, fmt.Errorf("%w: value is %d bytes, want %d", ErrInvalidCounter, len(value), counterSize)
+	}
+	return int64(binary.BigEndian.Uint64(value)), nil
+}
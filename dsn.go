@@ -0,0 +1,147 @@
+package zerokv
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidDSN is returned by OpenDSN for a connection string that cannot
+// be parsed, names an unknown backend, or carries a parameter the backend
+// does not recognize or cannot parse.
+var ErrInvalidDSN = errors.New("zerokv: invalid DSN")
+
+// OpenDSN opens a backend described by a URL-style connection string:
+//
+//	badger:///var/data/app?syncWrites=true
+//	pebble:///var/data/app?cache=256MB&ttl=true
+//	pebble:relative/dir
+//	memory:
+//
+// The scheme is a backend name as passed to Open, the path is Config.Dir
+// and the query parameters are passed to the backend in Config.Params. Each
+// backend documents the parameters it accepts; an unknown parameter is an
+// error rather than being ignored. Sizes accept the suffixes understood by
+// ParseSize.
+func OpenDSN(dsn string) (Core, error) {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidDSN, err)
+	}
+	if u.Scheme == "" {
+		return nil, fmt.Errorf("%w: %q has no backend scheme", ErrInvalidDSN, dsn)
+	}
+	backendsMu.RLock()
+	fn, ok := backends[u.Scheme]
+	backendsMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown backend %q", ErrInvalidDSN, u.Scheme)
+	}
+	dir := u.Opaque
+	if dir == "" {
+		dir = u.Host + u.Path
+	}
+	params, err := url.ParseQuery(u.RawQuery)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidDSN, err)
+	}
+	return fn(Config{Dir: dir, Params: params})
+}
+
+// ApplyParams calls the handler registered for each parameter in params, in
+// sorted order, with the parameter's last value. Backends use it to map
+// Config.Params onto their options. A parameter without a handler, or one
+// whose handler fails, yields an error wrapping ErrInvalidDSN that names it.
+func ApplyParams(params url.Values, handlers map[string]func(value string) error) error {
+	names := make([]string, 0, len(params))
+	for name := range params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		handle, ok := handlers[name]
+		if !ok {
+			return fmt.Errorf("%w: unknown parameter %q", ErrInvalidDSN, name)
+		}
+		values := params[name]
+		if err := handle(values[len(values)-1]); err != nil {
+			return fmt.Errorf("%w: parameter %q: %v", ErrInvalidDSN, name, err)
+		}
+	}
+	return nil
+}
+
+// BoolParam returns an ApplyParams handler that parses a boolean, as
+// strconv.ParseBool does, and passes it to set.
+func BoolParam(set func(bool)) func(string) error {
+	return func(s string) error {
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", s)
+		}
+		set(b)
+		return nil
+	}
+}
+
+// SizeParam returns an ApplyParams handler that parses a size with
+// ParseSize and passes it to set.
+func SizeParam(set func(int64)) func(string) error {
+	return func(s string) error {
+		n, err := ParseSize(s)
+		if err != nil {
+			return err
+		}
+		set(n)
+		return nil
+	}
+}
+
+// DurationParam returns an ApplyParams handler that parses a duration, as
+// time.ParseDuration does, and passes it to set.
+func DurationParam(set func(time.Duration)) func(string) error {
+	return func(s string) error {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q", s)
+		}
+		set(d)
+		return nil
+	}
+}
+
+var sizeUnits = []struct {
+	suffix string
+	shift  uint
+}{
+	{"KIB", 10}, {"MIB", 20}, {"GIB", 30}, {"TIB", 40},
+	{"KB", 10}, {"MB", 20}, {"GB", 30}, {"TB", 40},
+	{"K", 10}, {"M", 20}, {"G", 30}, {"T", 40},
+	{"B", 0},
+}
+
+// ParseSize parses a byte size such as "4096", "64KB" or "1.5GiB". Suffixes
+// are case-insensitive and, with or without the "i", are powers of 1024.
+func ParseSize(s string) (int64, error) {
+	num := strings.ToUpper(strings.TrimSpace(s))
+	var shift uint
+	for _, u := range sizeUnits {
+		if strings.HasSuffix(num, u.suffix) {
+			num, shift = strings.TrimSpace(strings.TrimSuffix(num, u.suffix)), u.shift
+			break
+		}
+	}
+	f, err := strconv.ParseFloat(num, 64)
+	if err != nil || !(f >= 0) {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	n := f * float64(uint64(1)<<shift)
+	if n >= 1<<63 {
+		return 0, fmt.Errorf("size %q is too large", s)
+	}
+	return int64(n), nil
+}
@@ -0,0 +1,73 @@
+package zerokv
+
+import "errors"
+
+var (
+	// ErrSnapshotReleased is returned by reads on a Snapshot after Release.
+	ErrSnapshotReleased = errors.New("zerokv: snapshot released")
+	// ErrConflict is returned by Txn.Commit when a key read by the
+	// transaction was changed by another commit; retry the transaction.
+	ErrConflict = errors.New("zerokv: transaction conflict")
+	// ErrNotSupported is returned when a backend cannot provide an operation,
+	// or cannot provide it with its current configuration.
+	ErrNotSupported = errors.New("zerokv: operation not supported")
+	// ErrReadOnly is returned by writes to a database opened read-only.
+	ErrReadOnly = errors.New("zerokv: database is read-only")
+	// ErrInvalidCounter is returned by Increment when the key holds a value
+	// that is not an 8-byte big-endian integer.
+	ErrInvalidCounter = errors.New("zerokv: value is not a counter")
+	// ErrTxnDone is returned by operations on a committed or discarded Txn.
+	ErrTxnDone = errors.New("zerokv: transaction already committed or discarded")
+	// ErrKeyNotFound is matched by the error Get returns for a missing key,
+	// whichever backend produced it.
+	ErrKeyNotFound = errors.New("zerokv: key not found")
+	// ErrKeyTooLarge is returned by writes of a key longer than the
+	// backend's configured MaxKeySize.
+	ErrKeyTooLarge = errors.New("zerokv: key too large")
+	// ErrValueTooLarge is returned by writes of a value longer than the
+	// backend's configured MaxValueSize.
+	ErrValueTooLarge = errors.New("zerokv: value too large")
+	// ErrInvalidBatch is returned by Batch.Validate for a queued operation
+	// that breaks the batch's constraints.
+	ErrInvalidBatch = errors.New("zerokv: invalid batch operation")
+	// ErrCheckpointExists is returned by Checkpoint when the destination
+	// directory already exists.
+	ErrCheckpointExists = errors.New("zerokv: checkpoint destination already exists")
+)
+
+// keyNotFoundError carries a backend's own not-found error while also
+// matching ErrKeyNotFound.
+type keyNotFoundError struct {
+	err error
+}
+
+func (e *keyNotFoundError) Error() string        { return e.err.Error() }
+func (e *keyNotFoundError) Unwrap() error        { return e.err }
+func (e *keyNotFoundError) Is(target error) bool { return target == ErrKeyNotFound }
+
+// KeyNotFound wraps a backend's not-found error so that errors.Is matches
+// ErrKeyNotFound while errors.Unwrap still returns err.
+func KeyNotFound(err error) error {
+	return &keyNotFoundError{err: err}
+}
+
+// errIterator is an empty Iterator that reports a fixed error.
+type errIterator struct {
+	err error
+}
+
+// NewErrIterator returns an Iterator that yields nothing and whose Error
+// method returns err. Backends use it when an iterator cannot be opened.
+func NewErrIterator(err error) Iterator {
+	return &errIterator{err: err}
+}
+
+func (it *errIterator) Next() bool           { return false }
+func (it *errIterator) Seek(key []byte) bool { return false }
+func (it *errIterator) First() bool          { return false }
+func (it *errIterator) Last() bool           { return false }
+func (it *errIterator) Valid() bool          { return false }
+func (it *errIterator) Key() []byte          { return nil }
+func (it *errIterator) Value() []byte        { return nil }
+func (it *errIterator) Release()             {}
+func (it *errIterator) Error() error         { return it.err }
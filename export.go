@@ -0,0 +1,155 @@
+package zerokv
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ErrInvalidImport is returned, wrapped with the offending line number, by
+// the import functions when their input is malformed.
+var ErrInvalidImport = errors.New("zerokv: invalid import")
+
+// Imports commit through an auto-flushing batch of at most importBatchOps
+// entries or importBatchBytes bytes, so memory stays bounded.
+const (
+	importBatchOps   = 1000
+	importBatchBytes = 4 << 20
+)
+
+// jsonlRecord is one line of the JSON Lines format. encoding/json writes
+// []byte fields as standard base64.
+type jsonlRecord struct {
+	Key   []byte `json:"key"`
+	Value []byte `json:"value"`
+}
+
+// ExportJSONL writes every key with prefix to w as JSON Lines, one
+// {"key":...,"value":...} object per line with both fields base64-encoded.
+// Entries are streamed from a single iterator, so memory use does not grow
+// with the size of the database. It stops with ctx.Err() once ctx is done,
+// leaving the lines written so far in w.
+func ExportJSONL(ctx context.Context, core Core, prefix []byte, w io.Writer) error {
+	it := core.ScanContext(ctx, prefix)
+	defer it.Release()
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	for it.Next() {
+		if err := enc.Encode(jsonlRecord{Key: it.Key(), Value: it.Value()}); err != nil {
+			return err
+		}
+	}
+	if err := it.Error(); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// putCtx adds a put to batch unless ctx is done, passing ctx on to batches
+// that flush themselves.
+func putCtx(ctx context.Context, batch Batch, key, value []byte) error {
+	if cb, ok := batch.(ContextBatch); ok {
+		return cb.PutCtx(ctx, key, value)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return batch.Put(key, value)
+}
+
+// ImportJSONL reads JSON Lines in the format written by ExportJSONL and
+// writes each pair to core through an auto-flushing batch. Blank lines are
+// skipped. It returns the number of pairs imported. A malformed line stops
+// the import with an error wrapping ErrInvalidImport that names the line;
+// pairs before it may already be committed.
+func ImportJSONL(ctx context.Context, core Core, r io.Reader) (int, error) {
+	batch := core.BatchWithOptions(importBatchOps, importBatchBytes)
+	br := bufio.NewReader(r)
+	count := 0
+	for line := 1; ; line++ {
+		data, err := br.ReadBytes('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return count, err
+		}
+		if data = bytes.TrimSpace(data); len(data) > 0 {
+			var rec jsonlRecord
+			if jerr := json.Unmarshal(data, &rec); jerr != nil {
+				return count, fmt.Errorf("%w: line %d: %v", ErrInvalidImport, line, jerr)
+			}
+			if rec.Key == nil {
+				return count, fmt.Errorf("%w: line %d: missing key", ErrInvalidImport, line)
+			}
+			if perr := putCtx(ctx, batch, rec.Key, rec.Value); perr != nil {
+				return count, perr
+			}
+			count++
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		}
+	}
+	if err := batch.Commit(ctx); err != nil {
+		return count, err
+	}
+	return count, nil
+}
+
+// ImportCSV reads key,value rows from r, decodes both fields with encoding,
+// which is "hex" or "base64" (standard alphabet, padded), and writes each
+// pair to core through an auto-flushing batch. It returns the number of rows
+// imported. A row that does not have exactly two fields or does not decode
+// stops the import with an error wrapping ErrInvalidImport that names the
+// line; rows before it may already be committed.
+func ImportCSV(ctx context.Context, core Core, r io.Reader, encoding string) (int, error) {
+	var decode func(string) ([]byte, error)
+	switch encoding {
+	case "hex":
+		decode = hex.DecodeString
+	case "base64":
+		decode = base64.StdEncoding.DecodeString
+	default:
+		return 0, fmt.Errorf("zerokv: ImportCSV: unknown encoding %q, want \"hex\" or \"base64\"", encoding)
+	}
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = 2
+	cr.ReuseRecord = true
+	batch := core.BatchWithOptions(importBatchOps, importBatchBytes)
+	count := 0
+	for {
+		row, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		var perr *csv.ParseError
+		if errors.As(err, &perr) {
+			return count, fmt.Errorf("%w: line %d: %v", ErrInvalidImport, perr.Line, perr.Err)
+		}
+		if err != nil {
+			return count, err
+		}
+		line, _ := cr.FieldPos(0)
+		key, err := decode(row[0])
+		if err != nil {
+			return count, fmt.Errorf("%w: line %d: key: %v", ErrInvalidImport, line, err)
+		}
+		value, err := decode(row[1])
+		if err != nil {
+			return count, fmt.Errorf("%w: line %d: value: %v", ErrInvalidImport, line, err)
+		}
+		if err := putCtx(ctx, batch, key, value); err != nil {
+			return count, err
+		}
+		count++
+	}
+	if err := batch.Commit(ctx); err != nil {
+		return count, err
+	}
+	return count, nil
+}
@@ -0,0 +1,9 @@
+package zerokv
+
+// TieredExpiries returns how many deadlines a Tiered Core remembers.
+func TieredExpiries(core Core) int {
+	t := core.(*tiered)
+	t.ttlMu.Lock()
+	defer t.ttlMu.Unlock()
+	return len(t.expiries)
+}
@@ -1,6 +1,10 @@
 package helpers
 
-import "context"
+import (
+	"context"
+
+	"github.com/rawbytedev/zerokv"
+)
 
 // IgnoreContext runs the provided function while respecting the context's cancellation.
 func IgnoreContext(ctx context.Context, fn func() error) error {
@@ -10,22 +14,15 @@ func IgnoreContext(ctx context.Context, fn func() error) error {
 	return fn()
 }
 
-type result struct {
-	data []byte
-	err  error
-}
-
 // RunWithContext runs the provided function while respecting the context's cancellation.
+// It is zerokv.RunWithContext for functions that return a value.
 func RunWithContext(ctx context.Context, fn func() ([]byte, error)) ([]byte, error) {
-	done := make(chan result, 1)
-	go func() {
-		data, err := fn()
-		done <- result{data, err}
-	}()
-	select {
-	case <-ctx.Done():
-		return nil, ctx.Err()
-	case res := <-done:
-		return res.data, res.err
-	}
+	return zerokv.RunWithContext(ctx, fn)
+}
+
+// RunWithCancel runs fn like RunWithContext, but passes it a context that is
+// cancelled once RunWithCancel returns so that fn can abort its work.
+// It is zerokv.RunWithCancel for functions that return a value.
+func RunWithCancel(ctx context.Context, fn func(context.Context) ([]byte, error)) ([]byte, error) {
+	return zerokv.RunWithCancel(ctx, fn)
 }
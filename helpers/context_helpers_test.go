@@ -0,0 +1,43 @@
+package helpers_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/rawbytedev/zerokv/helpers"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRunWithCancelStopsFn tests that RunWithCancel returns ctx.Err() when
+// ctx is done and cancels the context fn runs under, so that a fn blocked
+// on it exits instead of leaking.
+func TestRunWithCancelStopsFn(t *testing.T) {
+	ctx, cancel := context.WithTimeout(t.Context(), 20*time.Millisecond)
+	defer cancel()
+	exited := make(chan struct{})
+	_, err := helpers.RunWithCancel(ctx, func(ctx context.Context) ([]byte, error) {
+		defer close(exited)
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	select {
+	case <-exited:
+	case <-time.After(time.Second):
+		t.Fatal("fn still running after RunWithCancel returned")
+	}
+}
+
+// TestRunWithCancelResult tests that RunWithCancel returns fn's result and
+// cancels fn's context once fn is done.
+func TestRunWithCancelResult(t *testing.T) {
+	var runCtx context.Context
+	v, err := helpers.RunWithCancel(t.Context(), func(ctx context.Context) ([]byte, error) {
+		runCtx = ctx
+		return []byte("value"), nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, []byte("value"), v)
+	require.ErrorIs(t, runCtx.Err(), context.Canceled)
+}
@@ -0,0 +1,109 @@
+package helpers
+
+import (
+	"bytes"
+	"errors"
+)
+
+// ErrInvalidCompositeKey is returned by SplitKey for keys that were not
+// built by KeyBuilder.
+var ErrInvalidCompositeKey = errors.New("helpers: invalid composite key")
+
+// Each part is written with 0x00 escaped as 0x00 0xFF and ends with the
+// terminator 0x00 0x01. The terminator sorts below every escaped byte, so a
+// part sorts before any longer part it is a prefix of, and no part can run
+// into the next.
+const (
+	keyEscape     = 0x00
+	keyEscaped    = 0xFF
+	keyTerminator = 0x01
+)
+
+// KeyBuilder builds composite keys such as tenant|type|id that are
+// unambiguous whatever bytes the parts contain and that sort part by part:
+// first by the first part, then by the second, and so on. Uint64 parts sort
+// numerically. The zero value is an empty builder.
+//
+// Every part, including the last, ends with a terminator, so the key built
+// from the first N parts is a prefix of exactly the keys whose first N parts
+// are equal. Scanning with it never matches a longer part that merely starts
+// with the same bytes:
+//
+//	prefix := new(helpers.KeyBuilder).AddString("acme").AddString("user").Build()
+//	it := db.Scan(prefix) // every acme/user key, but not acme/username
+type KeyBuilder struct {
+	buf []byte
+}
+
+// AddString appends s as the next part.
+func (b *KeyBuilder) AddString(s string) *KeyBuilder {
+	return b.AddBytes([]byte(s))
+}
+
+// AddBytes appends p as the next part.
+func (b *KeyBuilder) AddBytes(p []byte) *KeyBuilder {
+	for {
+		i := bytes.IndexByte(p, keyEscape)
+		if i < 0 {
+			break
+		}
+		b.buf = append(b.buf, p[:i+1]...)
+		b.buf = append(b.buf, keyEscaped)
+		p = p[i+1:]
+	}
+	b.buf = append(b.buf, p...)
+	b.buf = append(b.buf, keyEscape, keyTerminator)
+	return b
+}
+
+// AddUint64 appends v as the next part in the EncodeUint64 form. Read it
+// back with DecodeUint64 on the part returned by SplitKey.
+func (b *KeyBuilder) AddUint64(v uint64) *KeyBuilder {
+	var n [intKeySize]byte
+	return b.AddBytes(AppendUint64(n[:0], v))
+}
+
+// Build returns the key built so far. The builder can keep being used;
+// later parts do not change the returned slice.
+func (b *KeyBuilder) Build() []byte {
+	return bytes.Clone(b.buf)
+}
+
+// Reset empties the builder so it can build another key.
+func (b *KeyBuilder) Reset() {
+	b.buf = b.buf[:0]
+}
+
+// SplitKey parses a key built by KeyBuilder back into its parts, with
+// escaping removed. It returns an error wrapping ErrInvalidCompositeKey if
+// key is not a valid composite key.
+func SplitKey(key []byte) ([][]byte, error) {
+	var parts [][]byte
+	var part []byte
+	for i := 0; i < len(key); i++ {
+		if key[i] != keyEscape {
+			part = append(part, key[i])
+			continue
+		}
+		if i+1 == len(key) {
+			return nil, ErrInvalidCompositeKey
+		}
+		i++
+		switch key[i] {
+		case keyEscaped:
+			part = append(part, keyEscape)
+		case keyTerminator:
+			if part == nil {
+				part = []byte{}
+			}
+			parts = append(parts, part)
+			part = nil
+		default:
+			return nil, ErrInvalidCompositeKey
+		}
+	}
+	if part != nil {
+		return nil, ErrInvalidCompositeKey
+	}
+	return parts, nil
+}
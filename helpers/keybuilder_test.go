@@ -0,0 +1,76 @@
+package helpers_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/rawbytedev/zerokv/helpers"
+	"github.com/stretchr/testify/require"
+)
+
+// TestKeyBuilderRoundTrip tests that SplitKey returns the parts added to
+// the builder, including ones that contain the escape and terminator bytes.
+func TestKeyBuilderRoundTrip(t *testing.T) {
+	var b helpers.KeyBuilder
+	key := b.AddString("tenant|1").AddBytes([]byte{0x00, 0x01, 0xFF}).AddBytes(nil).AddUint64(42).Build()
+	parts, err := helpers.SplitKey(key)
+	require.NoError(t, err)
+	require.Len(t, parts, 4)
+	require.Equal(t, []byte("tenant|1"), parts[0])
+	require.Equal(t, []byte{0x00, 0x01, 0xFF}, parts[1])
+	require.Empty(t, parts[2])
+	id, err := helpers.DecodeUint64(parts[3])
+	require.NoError(t, err)
+	require.Equal(t, uint64(42), id)
+
+	b.Reset()
+	require.Empty(t, b.Build())
+}
+
+// TestKeyBuilderUnambiguous tests that moving bytes between parts always
+// changes the key, which is where a plain separator breaks.
+func TestKeyBuilderUnambiguous(t *testing.T) {
+	a := new(helpers.KeyBuilder).AddString("a|b").AddString("c").Build()
+	b := new(helpers.KeyBuilder).AddString("a").AddString("b|c").Build()
+	c := new(helpers.KeyBuilder).AddBytes([]byte("a\x00")).AddString("c").Build()
+	d := new(helpers.KeyBuilder).AddString("a").AddBytes([]byte("\x00c")).Build()
+	require.NotEqual(t, a, b)
+	require.NotEqual(t, c, d)
+}
+
+// TestKeyBuilderOrder tests that keys sort by their first part, then their
+// second, with shorter parts before longer ones they prefix.
+func TestKeyBuilderOrder(t *testing.T) {
+	build := func(first string, id uint64) []byte {
+		return new(helpers.KeyBuilder).AddString(first).AddUint64(id).Build()
+	}
+	ordered := [][]byte{
+		build("a", 1),
+		build("a", 2),
+		build("a", 300),
+		build("a\x00", 0),
+		build("ab", 0),
+		build("b", 0),
+	}
+	for i := 1; i < len(ordered); i++ {
+		require.Negative(t, bytes.Compare(ordered[i-1], ordered[i]), "key %d should sort before key %d", i-1, i)
+	}
+}
+
+// TestKeyBuilderPrefix tests that the key of the first N parts prefixes
+// keys with those parts but not keys whose part only starts the same.
+func TestKeyBuilderPrefix(t *testing.T) {
+	prefix := new(helpers.KeyBuilder).AddString("acme").AddString("user").Build()
+	match := new(helpers.KeyBuilder).AddString("acme").AddString("user").AddUint64(7).Build()
+	other := new(helpers.KeyBuilder).AddString("acme").AddString("username").AddUint64(7).Build()
+	require.True(t, bytes.HasPrefix(match, prefix))
+	require.False(t, bytes.HasPrefix(other, prefix))
+}
+
+// TestSplitKeyInvalid tests that keys not built by KeyBuilder are rejected.
+func TestSplitKeyInvalid(t *testing.T) {
+	for _, key := range [][]byte{[]byte("plain"), {'a', 0x00}, {'a', 0x00, 0x02}} {
+		_, err := helpers.SplitKey(key)
+		require.ErrorIs(t, err, helpers.ErrInvalidCompositeKey)
+	}
+}
@@ -0,0 +1,61 @@
+package helpers
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// ErrInvalidIntKey is returned when decoding an integer key that is not
+// exactly 8 bytes long.
+var ErrInvalidIntKey = errors.New("helpers: invalid integer key")
+
+// intKeySize is the length of an encoded integer key.
+const intKeySize = 8
+
+// signBit is flipped by the signed encoders so that negative numbers sort
+// before positive ones.
+const signBit = 1 << 63
+
+// EncodeUint64 returns v as an 8-byte big-endian key. Byte order of the
+// encoded keys matches numeric order, so they iterate in ascending order.
+func EncodeUint64(v uint64) []byte {
+	return AppendUint64(make([]byte, 0, intKeySize), v)
+}
+
+// AppendUint64 appends the EncodeUint64 form of v to dst, for building
+// composite keys such as a prefix followed by an ID.
+func AppendUint64(dst []byte, v uint64) []byte {
+	return binary.BigEndian.AppendUint64(dst, v)
+}
+
+// DecodeUint64 parses a key written by EncodeUint64. It returns an error
+// wrapping ErrInvalidIntKey if b is not 8 bytes long.
+func DecodeUint64(b []byte) (uint64, error) {
+	if len(b) != intKeySize {
+		return 0, fmt.Errorf("%w: key is %d bytes, want %d", ErrInvalidIntKey, len(b), intKeySize)
+	}
+	return binary.BigEndian.Uint64(b), nil
+}
+
+// EncodeInt64 returns v as an 8-byte key that sorts in numeric order,
+// negative numbers included. It is big-endian with the sign bit flipped, so
+// it is not interchangeable with EncodeUint64 or zerokv.EncodeCounter.
+func EncodeInt64(v int64) []byte {
+	return AppendInt64(make([]byte, 0, intKeySize), v)
+}
+
+// AppendInt64 appends the EncodeInt64 form of v to dst.
+func AppendInt64(dst []byte, v int64) []byte {
+	return binary.BigEndian.AppendUint64(dst, uint64(v)^signBit)
+}
+
+// DecodeInt64 parses a key written by EncodeInt64. It returns an error
+// wrapping ErrInvalidIntKey if b is not 8 bytes long.
+func DecodeInt64(b []byte) (int64, error) {
+	u, err := DecodeUint64(b)
+	if err != nil {
+		return 0, err
+	}
+	return int64(u ^ signBit), nil
+}
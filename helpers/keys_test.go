@@ -0,0 +1,76 @@
+package helpers_test
+
+import (
+	"bytes"
+	"math"
+	"math/rand"
+	"slices"
+	"testing"
+
+	"github.com/rawbytedev/zerokv/helpers"
+	"github.com/stretchr/testify/require"
+)
+
+// TestUint64KeyOrder tests that encoded unsigned keys round-trip and sort
+// in numeric order, including the edges of the range.
+func TestUint64KeyOrder(t *testing.T) {
+	values := []uint64{0, 1, 255, 256, 1 << 32, math.MaxInt64, math.MaxInt64 + 1, math.MaxUint64 - 1, math.MaxUint64}
+	for i := 0; i < 1000; i++ {
+		values = append(values, rand.Uint64())
+	}
+	keys := make([][]byte, len(values))
+	for i, v := range values {
+		keys[i] = helpers.EncodeUint64(v)
+		got, err := helpers.DecodeUint64(keys[i])
+		require.NoError(t, err)
+		require.Equal(t, v, got)
+	}
+	slices.Sort(values)
+	slices.SortFunc(keys, bytes.Compare)
+	for i, k := range keys {
+		got, err := helpers.DecodeUint64(k)
+		require.NoError(t, err)
+		require.Equal(t, values[i], got, "Byte order should match numeric order")
+	}
+}
+
+// TestInt64KeyOrder tests that encoded signed keys round-trip and that
+// negative numbers sort before positive ones.
+func TestInt64KeyOrder(t *testing.T) {
+	values := []int64{math.MinInt64, math.MinInt64 + 1, -256, -1, 0, 1, 256, math.MaxInt64 - 1, math.MaxInt64}
+	for i := 0; i < 1000; i++ {
+		values = append(values, int64(rand.Uint64()))
+	}
+	keys := make([][]byte, len(values))
+	for i, v := range values {
+		keys[i] = helpers.EncodeInt64(v)
+		got, err := helpers.DecodeInt64(keys[i])
+		require.NoError(t, err)
+		require.Equal(t, v, got)
+	}
+	slices.Sort(values)
+	slices.SortFunc(keys, bytes.Compare)
+	for i, k := range keys {
+		got, err := helpers.DecodeInt64(k)
+		require.NoError(t, err)
+		require.Equal(t, values[i], got, "Byte order should match numeric order")
+	}
+}
+
+// TestAppendUint64 tests that appended integers keep composite keys ordered
+// by prefix first and number second.
+func TestAppendUint64(t *testing.T) {
+	a := helpers.AppendUint64([]byte("user:"), 2)
+	b := helpers.AppendUint64([]byte("user:"), 10)
+	require.True(t, bytes.HasPrefix(a, []byte("user:")))
+	require.Negative(t, bytes.Compare(a, b))
+	require.Equal(t, helpers.AppendInt64(nil, -5), helpers.EncodeInt64(-5))
+}
+
+// TestDecodeIntKeyInvalid tests that keys of the wrong length are rejected.
+func TestDecodeIntKeyInvalid(t *testing.T) {
+	_, err := helpers.DecodeUint64([]byte{1, 2, 3})
+	require.ErrorIs(t, err, helpers.ErrInvalidIntKey)
+	_, err = helpers.DecodeInt64(nil)
+	require.ErrorIs(t, err, helpers.ErrInvalidIntKey)
+}
@@ -0,0 +1,57 @@
+package helpers
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/rawbytedev/zerokv"
+)
+
+// Backoff bounds for Retry. The delay before the second attempt is
+// retryBaseDelay and doubles after every failed attempt up to retryMaxDelay.
+const (
+	retryBaseDelay = 10 * time.Millisecond
+	retryMaxDelay  = time.Second
+)
+
+// IsConflict is the default retry predicate: it reports whether err is a
+// transaction conflict, the one failure that retrying can fix.
+func IsConflict(err error) bool {
+	return errors.Is(err, zerokv.ErrConflict)
+}
+
+// Retry calls fn up to attempts times, waiting with exponential backoff
+// between calls, for as long as fn fails with zerokv.ErrConflict. It is
+// RetryIf with IsConflict; fn should run the whole transaction, reads
+// included.
+func Retry(ctx context.Context, attempts int, fn func() error) error {
+	return RetryIf(ctx, attempts, IsConflict, fn)
+}
+
+// RetryIf calls fn up to attempts times, waiting with exponential backoff
+// between calls, while the error it returns satisfies retryable. It returns
+// nil once fn succeeds, fn's error as soon as it is not retryable, and the
+// last error once attempts are used up. If ctx is done while waiting it
+// returns ctx.Err() joined with fn's last error. An attempts of zero or
+// less still calls fn once.
+func RetryIf(ctx context.Context, attempts int, retryable func(error) bool, fn func() error) error {
+	delay := retryBaseDelay
+	for attempt := 1; ; attempt++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		err := fn()
+		if err == nil || !retryable(err) || attempt >= attempts {
+			return err
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return errors.Join(ctx.Err(), err)
+		case <-timer.C:
+		}
+		delay = min(delay*2, retryMaxDelay)
+	}
+}
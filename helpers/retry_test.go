@@ -0,0 +1,77 @@
+package helpers_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/rawbytedev/zerokv"
+	"github.com/rawbytedev/zerokv/helpers"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRetryStopsAfterAttempts tests that Retry calls fn exactly attempts
+// times on repeated conflicts and returns the last error.
+func TestRetryStopsAfterAttempts(t *testing.T) {
+	calls := 0
+	err := helpers.Retry(t.Context(), 3, func() error {
+		calls++
+		return fmt.Errorf("attempt %d: %w", calls, zerokv.ErrConflict)
+	})
+	require.Equal(t, 3, calls)
+	require.ErrorIs(t, err, zerokv.ErrConflict)
+	require.EqualError(t, err, "attempt 3: zerokv: transaction conflict")
+}
+
+// TestRetrySucceeds tests that Retry returns nil once fn stops conflicting.
+func TestRetrySucceeds(t *testing.T) {
+	calls := 0
+	err := helpers.Retry(t.Context(), 5, func() error {
+		calls++
+		if calls < 3 {
+			return zerokv.ErrConflict
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, 3, calls)
+}
+
+// TestRetryNotRetryable tests that errors the predicate rejects are returned
+// without another attempt.
+func TestRetryNotRetryable(t *testing.T) {
+	boom := errors.New("boom")
+	calls := 0
+	err := helpers.Retry(t.Context(), 5, func() error {
+		calls++
+		return boom
+	})
+	require.ErrorIs(t, err, boom)
+	require.Equal(t, 1, calls)
+
+	calls = 0
+	err = helpers.RetryIf(t.Context(), 2, func(err error) bool { return errors.Is(err, boom) }, func() error {
+		calls++
+		return boom
+	})
+	require.ErrorIs(t, err, boom)
+	require.Equal(t, 2, calls)
+}
+
+// TestRetryContextCancelled tests that Retry stops once ctx is done and
+// reports both the context error and the last conflict.
+func TestRetryContextCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(t.Context())
+	calls := 0
+	err := helpers.Retry(ctx, 100, func() error {
+		calls++
+		cancel()
+		return zerokv.ErrConflict
+	})
+	require.Equal(t, 1, calls)
+	require.ErrorIs(t, err, context.Canceled)
+	require.ErrorIs(t, err, zerokv.ErrConflict)
+
+	require.ErrorIs(t, helpers.Retry(ctx, 3, func() error { return nil }), context.Canceled)
+}
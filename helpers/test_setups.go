@@ -6,21 +6,39 @@ import (
 
 	"github.com/rawbytedev/zerokv"
 	"github.com/rawbytedev/zerokv/badgerdb"
+	"github.com/rawbytedev/zerokv/boltdb"
+	"github.com/rawbytedev/zerokv/leveldb"
+	"github.com/rawbytedev/zerokv/memdb"
 	"github.com/rawbytedev/zerokv/pebbledb"
 )
 
 // setupBadgerDB creates a temporary BadgerDB instance for testing.
-func SetupDB(t *testing.T, name string) zerokv.Core {
-	tmp := t.TempDir()
+func SetupDB(t testing.TB, name string) zerokv.Core {
 	var db zerokv.Core
 	var err error
-	if name == "badgerdb" {
+	switch name {
+	case "badgerdb":
 		db, err = badgerdb.NewBadgerDB(badgerdb.Config{
-			Dir: tmp,
+			Dir: t.TempDir(),
 		})
-	} else {
+	case "boltdb":
+		db, err = boltdb.NewBoltDB(boltdb.Config{
+			Dir: t.TempDir(),
+		})
+	case "leveldb":
+		db, err = leveldb.NewLevelDB(leveldb.Config{
+			Dir: t.TempDir(),
+		})
+	case "pebbledb-ttl":
+		db, err = pebbledb.NewPebbleDB(pebbledb.Config{
+			Dir:       t.TempDir(),
+			EnableTTL: true,
+		})
+	case "memdb":
+		db = memdb.New()
+	default:
 		db, err = pebbledb.NewPebbleDB(pebbledb.Config{
-			Dir: tmp,
+			Dir: t.TempDir(),
 		})
 	}
 	if err != nil || db == nil {
@@ -1,33 +1,190 @@
 package zerokv
 
-import "context"
+import (
+	"context"
+	"io"
+	"time"
+)
 
 // Core defines the main interface for a key-value database
 type Core interface {
 	// Put inserts or updates a key-value pair in the database
 	Put(ctx context.Context, key []byte, data []byte) error
+	// PutWithTTL inserts or updates a key-value pair that expires after ttl
+	PutWithTTL(ctx context.Context, key, value []byte, ttl time.Duration) error
 	// Get retrieves the value for a given key
 	Get(ctx context.Context, key []byte) ([]byte, error)
+	// GetOrDefault is Get, except that a missing key returns a copy of def
+	// instead of ErrKeyNotFound; other errors are returned as is
+	GetOrDefault(ctx context.Context, key, def []byte) ([]byte, error)
+	// GetInto copies the value of key into dst, reusing its capacity and
+	// growing it by append if needed, and returns the result, which may
+	// alias dst. On error it returns dst[:0]
+	GetInto(ctx context.Context, key, dst []byte) ([]byte, error)
+	// View calls fn with the value of key without copying it where the
+	// backend allows. The slice is only valid until fn returns: it must not
+	// be retained, returned or modified, and fn must not write to the
+	// database. A missing key returns ErrKeyNotFound without calling fn; an
+	// error from fn is returned as is
+	View(ctx context.Context, key []byte, fn func(value []byte) error) error
+	// Has reports whether a key exists without copying its value
+	Has(ctx context.Context, key []byte) (bool, error)
+	// SizeOf returns the length of a key's value without copying it out;
+	// ErrKeyNotFound if the key does not exist
+	SizeOf(ctx context.Context, key []byte) (int, error)
+	// GetMany retrieves the values for several keys in one read; missing keys yield nil
+	GetMany(ctx context.Context, keys [][]byte) ([][]byte, error)
+	// HasMany reports, in input order, whether each of several keys exists,
+	// checked in one consistent read without copying values
+	HasMany(ctx context.Context, keys [][]byte) ([]bool, error)
+	// PutMany atomically writes keys[i] = values[i] for every pair
+	PutMany(ctx context.Context, keys, values [][]byte) error
 	// Delete removes a key-value pair from the database
 	Delete(ctx context.Context, key []byte) error
+	// DeletePrefix removes every key with the specified prefix and returns how many were removed
+	DeletePrefix(ctx context.Context, prefix []byte) (int, error)
+	// DeleteRange removes every key in [start, end); a nil end means no upper bound
+	DeleteRange(ctx context.Context, start, end []byte) error
+	// Truncate removes every key, leaving an empty store in place
+	Truncate(ctx context.Context) error
+	// TruncatePrefix removes every key with the specified prefix, then
+	// reclaims the disk space they held where the backend can
+	TruncatePrefix(ctx context.Context, prefix []byte) error
+	// Count returns the number of keys with the specified prefix
+	Count(ctx context.Context, prefix []byte) (int64, error)
+	// EstimateSize returns a rough byte size of the keys with the specified
+	// prefix; how it is measured, and how exact it is, depends on the backend
+	EstimateSize(prefix []byte) (int64, error)
+	// NewTransaction starts a read-write transaction that sees its own pending writes
+	NewTransaction(ctx context.Context) (Txn, error)
+	// CompareAndSwap atomically sets key to new if its value equals old, or if
+	// the key is absent when old is nil, and reports whether it did
+	CompareAndSwap(ctx context.Context, key, old, new []byte) (bool, error)
+	// Increment atomically adds delta to the big-endian int64 at key, treating
+	// a missing key as zero, and returns the new value
+	Increment(ctx context.Context, key []byte, delta int64) (int64, error)
+	// GetOrPut returns the value of key, or calls fill, stores its result and
+	// returns it when the key is missing; concurrent callers for the same key
+	// share a single fill call
+	GetOrPut(ctx context.Context, key []byte, fill func() ([]byte, error)) ([]byte, error)
+	// Merge atomically replaces the value of key with merge(existing, operand),
+	// where existing is nil for a missing key; see AppendMerge and SumInt64Merge
+	Merge(ctx context.Context, key, operand []byte, merge func(existing, operand []byte) []byte) error
+	// Stats returns engine-level metrics; unsupported fields are zero
+	Stats(ctx context.Context) (Stats, error)
+	// Sync flushes buffered writes so everything committed so far is durable
+	Sync(ctx context.Context) error
+	// Compact asks the engine to compact keys in [start, end) now, so space
+	// from deleted or overwritten keys is reclaimed without waiting for
+	// background compaction; a nil start or end leaves that side unbounded
+	Compact(ctx context.Context, start, end []byte) error
+	// Ping performs a trivial read and returns an error if the store is
+	// closed or unusable; it never writes
+	Ping(ctx context.Context) error
+	// Backup writes a full backup of the database to w
+	Backup(ctx context.Context, w io.Writer) error
+	// Restore writes every entry from a backup produced by Backup into the database
+	Restore(ctx context.Context, r io.Reader) error
+	// Checkpoint writes a consistent copy of the database to destDir, which
+	// must not exist yet, in a form the same backend can open directly
+	Checkpoint(ctx context.Context, destDir string) error
+	// Snapshot returns a read-only view of the database frozen at the time of the call
+	Snapshot() (Snapshot, error)
 	// Batch creates a new write batch that needs to be committed separately
 	Batch() Batch
+	// BatchWithOptions creates a write batch that commits itself and starts
+	// afresh whenever it reaches maxOps operations or maxBytes bytes of keys
+	// and values; zero disables a threshold. Commit flushes the remainder.
+	// Only each flushed segment is atomic.
+	BatchWithOptions(maxOps, maxBytes int) Batch
+	// BulkLoad tunes the engine for ingest while fn fills the batch it is
+	// given, then commits it and makes everything durable with one sync; fn
+	// must not call Commit. The load is not atomic: if fn fails, writes
+	// already flushed remain
+	BulkLoad(ctx context.Context, fn func(b Batch) error) error
 	// Scan returns an iterator to traverse key-value pairs with the specified prefix
 	Scan(prefix []byte) Iterator
+	// ReverseScan returns an iterator over keys with the specified prefix in descending order
+	ReverseScan(prefix []byte) Iterator
+	// RangeScan returns an iterator over keys in the half-open range [start, end).
+	// A nil start begins at the first key and a nil end runs to the last key.
+	RangeScan(start, end []byte) Iterator
+	// ScanFrom returns an iterator over keys with the specified prefix that
+	// begins at the first such key >= start, so a paginated scan can resume
+	// just after the last key it returned
+	ScanFrom(prefix, start []byte) Iterator
+	// ScanKeys returns a keys-only iterator over the specified prefix; Value always returns nil
+	ScanKeys(prefix []byte) Iterator
+	// ScanContext is Scan with an iterator that stops once ctx is done and
+	// then reports ctx.Err() from Error
+	ScanContext(ctx context.Context, prefix []byte) Iterator
+	// Watch streams the changes committed through this Core to keys with the
+	// specified prefix. The channel is closed once ctx is done or the
+	// database is closed
+	Watch(ctx context.Context, prefix []byte) (<-chan Event, error)
 	// Close closes the database connection
 	Close() error
 }
 
-// Iterator defines methods for iterating over key-value pairs in the database
+// Iterator defines methods for iterating over key-value pairs in the database.
+// Key and Value return copies that belong to the caller: they stay intact
+// after the iterator moves or is released, on every backend.
 type Iterator interface {
 	Next() bool    // advances the iterator to the next key-value pair
 	Key() []byte   // returns the current key
 	Value() []byte // returns the current value
 	Release()      // releases the iterator resources
 	Error() error  // returns any error encountered during iteration
+	// Seek positions the iterator on the first key >= key (<= key for reverse
+	// iterators) within its bounds and reports whether it is valid afterward.
+	// Next continues from the sought position.
+	Seek(key []byte) bool
+	// First and Last position the iterator on the smallest and greatest key
+	// within its bounds, whatever its direction, and report whether it is
+	// valid afterward. Next continues in the iteration direction.
+	First() bool
+	Last() bool
+	// Valid reports whether the iterator is positioned on an entry, without
+	// moving it. It is false before the first Next or Seek and after Release.
+	Valid() bool
 }
 
-// Batch defines methods for batching multiple write operations together
+// Snapshot is a read-only, point-in-time view of the database. Writes made
+// after it was taken are not visible through it. Release must be called to
+// free the resources it pins; reads after Release return ErrSnapshotReleased.
+// Iterators opened from a snapshot stay usable until they are released.
+type Snapshot interface {
+	// Get retrieves the value for a given key as of the snapshot
+	Get(ctx context.Context, key []byte) ([]byte, error)
+	// Has reports whether a key existed when the snapshot was taken
+	Has(ctx context.Context, key []byte) (bool, error)
+	// Scan returns an iterator over keys with the specified prefix as of the snapshot
+	Scan(prefix []byte) Iterator
+	// Release frees the snapshot; it is safe to call more than once
+	Release()
+}
+
+// Txn is a read-write transaction. Reads see the transaction's own pending
+// writes, and nothing is visible to other readers until Commit. A Txn is not
+// safe for concurrent use. Backends with conflict detection (BadgerDB) return
+// ErrConflict from Commit when a key read by the transaction was changed by
+// another commit; the caller should retry the whole transaction. Other
+// backends apply writes last-writer-wins.
+type Txn interface {
+	// Get retrieves the value for a given key, including pending writes
+	Get(ctx context.Context, key []byte) ([]byte, error)
+	// Put stages a key-value pair to be written on Commit
+	Put(ctx context.Context, key []byte, data []byte) error
+	// Delete stages a key removal to be applied on Commit
+	Delete(ctx context.Context, key []byte) error
+	// Commit atomically applies every staged write
+	Commit(ctx context.Context) error
+	// Discard drops staged writes; it is a no-op after Commit
+	Discard()
+}
+
+// Batch defines methods for batching multiple write operations together.
+// A Batch is not safe for concurrent use; see NewConcurrentBatch.
 type Batch interface {
 	// Flush commits all batched operations to the database
 	Commit(ctx context.Context) error
@@ -35,4 +192,81 @@ type Batch interface {
 	Put(key []byte, data []byte) error
 	// Delete deletes a key-value pair from the database
 	Delete(key []byte) error
+	// PutIfAbsent queues a put that Commit applies only if key does not
+	// exist once the operations queued before it are applied. The check
+	// reads the store at Commit and is not isolated from concurrent writers
+	PutIfAbsent(key, data []byte) error
+	// Len returns the number of operations queued since the last Commit
+	Len() int
+	// Size returns the approximate size in bytes of the queued operations
+	Size() int
+	// Reset discards any queued operations so the batch can be reused,
+	// including after Commit
+	Reset()
+	// Validate reports the first operation queued since the last Commit
+	// that the batch's constraints reject, without writing anything; see
+	// NewBatch for setting constraints of your own
+	Validate() error
+}
+
+// SyncWriter is implemented by backends that let a caller choose per write
+// whether to wait for it to reach stable storage, trading durability for
+// latency. Use a type assertion on a Core to access it. Every bundled
+// backend implements it, but only Pebble and LevelDB can skip the sync for
+// a single write; see each backend's PutSync for what sync false means.
+type SyncWriter interface {
+	// PutSync inserts or updates a key-value pair and, when sync is true,
+	// returns only once the write is durable
+	PutSync(ctx context.Context, key, value []byte, sync bool) error
+}
+
+// ValueLogGC is implemented by backends whose storage can garbage collect a
+// value log on demand. Use a type assertion on a Core to access it;
+// StartMaintenance also finds it behind wrappers with an Unwrap() Core
+// method, such as WithMetrics. Only BadgerDB keeps one; the other backends
+// implement RunValueLogGC as a no-op so maintenance code can call it
+// unconditionally.
+type ValueLogGC interface {
+	// RunValueLogGC reclaims value log space until no file has at least
+	// discardRatio of stale data
+	RunValueLogGC(discardRatio float64) error
+}
+
+// Optimizer is implemented by backends that can rewrite their storage into
+// a fully compacted steady state, which shortens reads. Call it through
+// Optimize, which looks through wrappers and ignores backends without it.
+// Only BadgerDB and PebbleDB implement it.
+type Optimizer interface {
+	// Optimize compacts the whole database, blocking until it is done
+	Optimize(ctx context.Context) error
+}
+
+// Compactor is implemented by backends whose storage benefits from a
+// periodic compaction of the whole keyspace. StartMaintenance calls it each
+// round after RunValueLogGC; unlike Compact it never garbage collects a
+// value log. BadgerDB, PebbleDB and LevelDB implement it.
+type Compactor interface {
+	// CompactAll compacts the whole keyspace once, blocking until it is done
+	CompactAll(ctx context.Context) error
+}
+
+// PrefetchScanner is implemented by backends whose prefix scans can be tuned
+// for value size. Use a type assertion on a Core to access it. BadgerDB
+// honors prefetch; PebbleDB accepts and ignores it so callers need not
+// special-case it.
+type PrefetchScanner interface {
+	// ScanWithPrefetch is Scan with up to prefetch values read ahead of the
+	// iterator; zero or less uses the backend's default
+	ScanWithPrefetch(prefix []byte, prefetch int) Iterator
+}
+
+// ContextBatch is implemented by batches whose write operations can observe
+// cancellation before Commit. Use a type assertion on the value returned by
+// Core.Batch to access it.
+type ContextBatch interface {
+	Batch
+	// PutCtx adds a put operation unless the context is already done
+	PutCtx(ctx context.Context, key []byte, data []byte) error
+	// DeleteCtx adds a delete operation unless the context is already done
+	DeleteCtx(ctx context.Context, key []byte) error
 }
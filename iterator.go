@@ -0,0 +1,294 @@
+package zerokv
+
+import (
+	"bytes"
+	"context"
+	"errors"
+)
+
+// limitIterator stops an Iterator after a fixed number of entries.
+type limitIterator struct {
+	it        Iterator
+	remaining int
+	valid     bool
+}
+
+// Limit wraps it so that it yields at most n entries; Next returns false
+// once n entries have been read. A successful Seek counts as one entry,
+// since it positions the iterator on one. Key, Value, Error and Release are
+// forwarded, and Release still releases it.
+func Limit(it Iterator, n int) Iterator {
+	return &limitIterator{it: it, remaining: n}
+}
+
+func (l *limitIterator) Next() bool {
+	l.valid = l.remaining > 0 && l.it.Next()
+	if l.valid {
+		l.remaining--
+	}
+	return l.valid
+}
+
+func (l *limitIterator) Seek(key []byte) bool {
+	l.valid = l.remaining > 0 && l.it.Seek(key)
+	if l.valid {
+		l.remaining--
+	}
+	return l.valid
+}
+
+func (l *limitIterator) First() bool { return l.position(l.it.First) }
+func (l *limitIterator) Last() bool  { return l.position(l.it.Last) }
+
+// position applies a First or Last move, which counts as one entry like a
+// successful Seek.
+func (l *limitIterator) position(move func() bool) bool {
+	l.valid = l.remaining > 0 && move()
+	if l.valid {
+		l.remaining--
+	}
+	return l.valid
+}
+
+// Valid is false once the limit has stopped the iterator, even though the
+// wrapped iterator is still positioned on an entry.
+func (l *limitIterator) Valid() bool { return l.valid && l.it.Valid() }
+
+func (l *limitIterator) Key() []byte   { return l.it.Key() }
+func (l *limitIterator) Value() []byte { return l.it.Value() }
+func (l *limitIterator) Release()      { l.it.Release() }
+func (l *limitIterator) Error() error  { return l.it.Error() }
+
+// filterIterator hides the entries of an Iterator that fail a predicate.
+type filterIterator struct {
+	it    Iterator
+	pred  func(key, value []byte) bool
+	valid bool
+}
+
+// Filter wraps it so that it yields only the entries for which pred returns
+// true; Next, Seek, First and Last step past the others in the iteration
+// direction. pred sees each candidate's key and value, which are only valid
+// during the call. If Last on a forward iterator (or First on a reverse
+// one) lands on a rejected entry, Next cannot step back from it, so the
+// closest accepted entry is found by walking the whole range from the other
+// end and seeking to it, which costs a full scan. Key, Value, Error and
+// Release are forwarded, and Release still releases it.
+func Filter(it Iterator, pred func(key, value []byte) bool) Iterator {
+	return &filterIterator{it: it, pred: pred}
+}
+
+func (f *filterIterator) Next() bool           { return f.skip(f.it.Next()) }
+func (f *filterIterator) Seek(key []byte) bool { return f.skip(f.it.Seek(key)) }
+func (f *filterIterator) First() bool          { return f.edge(f.it.First, f.it.Last) }
+func (f *filterIterator) Last() bool           { return f.edge(f.it.Last, f.it.First) }
+
+// edge moves to one end of the range with move, or to the closest entry
+// pred accepts. If the end is rejected and Next leads past it (a forward
+// iterator at Last, or a reverse one at First), the range is walked from
+// the other end with opposite, and the last accepted key seen is sought.
+func (f *filterIterator) edge(move, opposite func() bool) bool {
+	if !move() {
+		f.valid = false
+		return false
+	}
+	if f.pred(f.it.Key(), f.it.Value()) {
+		f.valid = true
+		return true
+	}
+	if f.it.Next() {
+		return f.skip(true)
+	}
+	var last []byte
+	found := false
+	for ok := opposite(); ok; ok = f.it.Next() {
+		if f.pred(f.it.Key(), f.it.Value()) {
+			last, found = f.it.Key(), true
+		}
+	}
+	if !found || f.it.Error() != nil {
+		f.valid = false
+		return false
+	}
+	return f.skip(f.it.Seek(last))
+}
+
+// skip advances from the entry a move landed on to the first one pred
+// accepts; ok is the result of the move.
+func (f *filterIterator) skip(ok bool) bool {
+	for ok && !f.pred(f.it.Key(), f.it.Value()) {
+		ok = f.it.Next()
+	}
+	f.valid = ok
+	return ok
+}
+
+func (f *filterIterator) Valid() bool   { return f.valid && f.it.Valid() }
+func (f *filterIterator) Key() []byte   { return f.it.Key() }
+func (f *filterIterator) Value() []byte { return f.it.Value() }
+func (f *filterIterator) Release()      { f.it.Release() }
+func (f *filterIterator) Error() error  { return f.it.Error() }
+
+// contextIterator stops an Iterator once its context is done.
+type contextIterator struct {
+	ctx context.Context
+	it  Iterator
+	err error
+}
+
+// NewContextIterator wraps it so that Next and Seek return false once ctx is
+// done, and Error then reports ctx.Err() along with any error from it.
+// Backends use it to implement Core.ScanContext.
+func NewContextIterator(ctx context.Context, it Iterator) Iterator {
+	return &contextIterator{ctx: ctx, it: it}
+}
+
+func (c *contextIterator) Next() bool {
+	if c.done() {
+		return false
+	}
+	return c.it.Next()
+}
+
+func (c *contextIterator) Seek(key []byte) bool {
+	if c.done() {
+		return false
+	}
+	return c.it.Seek(key)
+}
+
+func (c *contextIterator) First() bool {
+	if c.done() {
+		return false
+	}
+	return c.it.First()
+}
+
+func (c *contextIterator) Last() bool {
+	if c.done() {
+		return false
+	}
+	return c.it.Last()
+}
+
+// done records ctx.Err() the first time the context is found to be done.
+func (c *contextIterator) done() bool {
+	if c.err == nil {
+		c.err = c.ctx.Err()
+	}
+	return c.err != nil
+}
+
+// Valid is false once the context has stopped the iterator.
+func (c *contextIterator) Valid() bool { return c.err == nil && c.it.Valid() }
+
+func (c *contextIterator) Key() []byte   { return c.it.Key() }
+func (c *contextIterator) Value() []byte { return c.it.Value() }
+func (c *contextIterator) Release()      { c.it.Release() }
+
+func (c *contextIterator) Error() error {
+	return errors.Join(c.it.Error(), c.err)
+}
+
+// KeyValue is a key and its value, as collected by CollectAll.
+type KeyValue struct {
+	Key   []byte
+	Value []byte
+}
+
+// CollectAll drains it into a slice, copying each key and value, then
+// releases it and returns it.Error(). On error the entries read before it
+// are discarded. Every entry is held in memory at once, so use it only for
+// scans known to be small, such as a short prefix or one bounded by Limit.
+func CollectAll(it Iterator) ([]KeyValue, error) {
+	defer it.Release()
+	var kvs []KeyValue
+	for it.Next() {
+		kvs = append(kvs, KeyValue{
+			Key:   append([]byte(nil), it.Key()...),
+			Value: append([]byte(nil), it.Value()...),
+		})
+	}
+	if err := it.Error(); err != nil {
+		return nil, err
+	}
+	return kvs, nil
+}
+
+// Fold scans the keys with the given prefix in order, passing each entry
+// to fn together with the accumulator, which starts at init and is replaced
+// by each call's result; it returns the final accumulator. key and value
+// are only valid until fn returns. The scan stops at the first error from
+// fn or the iterator, or once ctx is done, and Fold then returns the zero
+// T and that error, or ctx.Err(). The iterator is always released.
+func Fold[T any](ctx context.Context, core Core, prefix []byte, init T, fn func(acc T, key, value []byte) (T, error)) (T, error) {
+	var zero T
+	it := core.ScanContext(ctx, prefix)
+	defer it.Release()
+	acc := init
+	for it.Next() {
+		var err error
+		if acc, err = fn(acc, it.Key(), it.Value()); err != nil {
+			return zero, err
+		}
+	}
+	if err := it.Error(); err != nil {
+		return zero, err
+	}
+	return acc, nil
+}
+
+// ListPrefixes returns the distinct segments before the first separator
+// byte of the keys in core, in key order, such as "users" and "orders" for
+// keys like "users/1" and "orders/7". A key without separator is listed
+// whole. Once a segment is found the scan seeks past every key under it, so
+// the cost grows with the number of segments rather than keys.
+func ListPrefixes(core Core, separator byte) ([][]byte, error) {
+	it := core.ScanKeys(nil)
+	defer it.Release()
+	var prefixes [][]byte
+	seen := make(map[string]bool)
+	ok := it.Next()
+	for ok {
+		key := it.Key()
+		i := bytes.IndexByte(key, separator)
+		if i < 0 {
+			// a bare key; keys extending it may still hold a separator
+			if !seen[string(key)] {
+				seen[string(key)] = true
+				prefixes = append(prefixes, bytes.Clone(key))
+			}
+			ok = it.Next()
+			continue
+		}
+		prefix := bytes.Clone(key[:i])
+		if !seen[string(prefix)] {
+			seen[string(prefix)] = true
+			prefixes = append(prefixes, prefix)
+		}
+		end := PrefixUpperBound(key[:i+1])
+		if end == nil {
+			break
+		}
+		ok = it.Seek(end)
+	}
+	if err := it.Error(); err != nil {
+		return nil, err
+	}
+	return prefixes, nil
+}
+
+// PrefixUpperBound returns the smallest key greater than every key starting
+// with prefix, or nil when no such key exists (empty or all-0xFF prefix),
+// meaning the range is unbounded. Backends use it to bound prefix scans.
+func PrefixUpperBound(prefix []byte) []byte {
+	for i := len(prefix) - 1; i >= 0; i-- {
+		if prefix[i] != 0xFF {
+			upbound := make([]byte, i+1)
+			copy(upbound, prefix[:i+1])
+			upbound[i]++
+			return upbound
+		}
+	}
+	return nil
+}
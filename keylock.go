@@ -0,0 +1,42 @@
+package zerokv
+
+import "sync"
+
+// KeyedMutex serializes callers that lock the same key while letting
+// different keys proceed in parallel. The zero value is ready to use.
+// Backends use it to make GetOrPut run its fill function once per key.
+type KeyedMutex struct {
+	mu    sync.Mutex
+	locks map[string]*keyedLock
+}
+
+type keyedLock struct {
+	mu   sync.Mutex
+	refs int
+}
+
+// Lock blocks until key is free and returns the function that unlocks it.
+func (m *KeyedMutex) Lock(key []byte) (unlock func()) {
+	k := string(key)
+	m.mu.Lock()
+	if m.locks == nil {
+		m.locks = make(map[string]*keyedLock)
+	}
+	l, ok := m.locks[k]
+	if !ok {
+		l = &keyedLock{}
+		m.locks[k] = l
+	}
+	l.refs++
+	m.mu.Unlock()
+
+	l.mu.Lock()
+	return func() {
+		l.mu.Unlock()
+		m.mu.Lock()
+		if l.refs--; l.refs == 0 {
+			delete(m.locks, k)
+		}
+		m.mu.Unlock()
+	}
+}
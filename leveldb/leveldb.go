@@ -0,0 +1,1194 @@
+package leveldb
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/rawbytedev/zerokv"
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/iterator"
+	"github.com/syndtr/goleveldb/leveldb/opt"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+// ErrBatchCommitted is returned when a batch is used after Commit.
+var ErrBatchCommitted = errors.New("leveldb: batch already committed")
+
+// restoreBatchSize is the number of entries Restore commits at a time.
+const restoreBatchSize = 1000
+
+type LevelDB struct {
+	db *leveldb.DB
+	// modifyMu serializes read-modify-write operations such as CompareAndSwap
+	modifyMu sync.Mutex
+	fills    zerokv.KeyedMutex // serializes GetOrPut per key
+	watchers zerokv.Watchers
+}
+type levelBatch struct {
+	db        *leveldb.DB
+	batch     *leveldb.Batch
+	committed bool
+	watchers  *zerokv.Watchers
+	ifAbsent  []pendingPut // PutIfAbsent calls, resolved by Commit
+	check     zerokv.BatchValidator
+}
+
+// levelLimits are the keys and values goleveldb refuses to store: none,
+// since it holds any key, including an empty one, and any value.
+var levelLimits = zerokv.BatchLimits{AllowEmptyKey: true}
+
+// pendingPut is a PutIfAbsent staged after the first at operations of a
+// batch.
+type pendingPut struct {
+	key, value []byte
+	at         int
+}
+
+type levelIterator struct {
+	Iterator iterator.Iterator
+	keysOnly bool
+	started  bool
+	valid    bool
+}
+
+type levelReverseIterator struct {
+	Iterator iterator.Iterator
+	started  bool
+	valid    bool
+}
+
+func init() {
+	zerokv.Register("leveldb", func(cfg zerokv.Config) (zerokv.Core, error) {
+		opts, err := paramOptions(cfg.Params)
+		if err != nil {
+			return nil, err
+		}
+		return New(cfg.Dir, opts...)
+	})
+}
+
+// NewLevelDB initializes and returns a zerokv.Core instance at the specified path(LevelDB).
+func NewLevelDB(cfg Config) (zerokv.Core, error) {
+	db, err := leveldb.OpenFile(cfg.Dir, cfg.LevelDBConfigs)
+	if err != nil {
+		return nil, err
+	}
+	return &LevelDB{db: db}, nil
+}
+
+// --- Basic CRUD operations ---
+
+// Put inserts or updates a key-value pair in the database.
+func (l *LevelDB) Put(ctx context.Context, key []byte, data []byte) error {
+	return l.put(ctx, key, data, nil)
+}
+
+// PutSync inserts or updates a key-value pair, syncing the journal before
+// returning when sync is true. Put never syncs, so sync false behaves like
+// Put: the write survives a process crash but not a machine failure.
+func (l *LevelDB) PutSync(ctx context.Context, key, value []byte, sync bool) error {
+	return l.put(ctx, key, value, &opt.WriteOptions{Sync: sync})
+}
+
+func (l *LevelDB) put(ctx context.Context, key, data []byte, wo *opt.WriteOptions) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := l.db.Put(key, data, wo); err != nil {
+		return err
+	}
+	l.watchers.Publish(zerokv.Event{Op: zerokv.OpPut, Key: key, Value: data})
+	return nil
+}
+
+// PutWithTTL is not supported: goleveldb has no key expiry.
+func (l *LevelDB) PutWithTTL(ctx context.Context, key, value []byte, ttl time.Duration) error {
+	return fmt.Errorf("leveldb: PutWithTTL: %w", zerokv.ErrNotSupported)
+}
+
+// PutMany writes all pairs atomically in one synced leveldb.Batch.
+func (l *LevelDB) PutMany(ctx context.Context, keys, values [][]byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if len(keys) != len(values) {
+		return fmt.Errorf("PutMany: %d keys but %d values", len(keys), len(values))
+	}
+	batch := new(leveldb.Batch)
+	for i := range keys {
+		batch.Put(keys[i], values[i])
+	}
+	return writeBatch(l.db, &l.watchers, batch, &opt.WriteOptions{Sync: true})
+}
+
+// Get retrieves the value for a given key. Returns an error if not found.
+func (l *LevelDB) Get(ctx context.Context, key []byte) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	val, err := l.db.Get(key, nil) // goleveldb already returns an owned copy
+	return val, notFound(err)
+}
+
+// GetOrDefault returns a copy of def if key is missing.
+func (l *LevelDB) GetOrDefault(ctx context.Context, key, def []byte) ([]byte, error) {
+	value, err := l.Get(ctx, key)
+	if errors.Is(err, zerokv.ErrKeyNotFound) {
+		return bytes.Clone(def), nil
+	}
+	return value, err
+}
+
+// GetInto copies the value of key into dst. goleveldb allocates a copy of
+// every value it reads, so this saves no allocation over Get.
+func (l *LevelDB) GetInto(ctx context.Context, key, dst []byte) ([]byte, error) {
+	dst = dst[:0]
+	err := l.View(ctx, key, func(value []byte) error {
+		dst = append(dst, value...)
+		return nil
+	})
+	return dst, err
+}
+
+// View calls fn with the value of key. goleveldb has no way to expose its
+// buffers, so the value is a copy, as with Get.
+func (l *LevelDB) View(ctx context.Context, key []byte, fn func(value []byte) error) error {
+	val, err := l.Get(ctx, key)
+	if err != nil {
+		return err
+	}
+	return fn(val)
+}
+
+// notFound lets the error goleveldb reports for a missing key also match
+// zerokv.ErrKeyNotFound. Other errors are returned unchanged.
+func notFound(err error) error {
+	if errors.Is(err, leveldb.ErrNotFound) {
+		return zerokv.KeyNotFound(err)
+	}
+	return err
+}
+
+// GetMany retrieves the values for keys under a single snapshot.
+// Missing keys yield a nil entry at the same position.
+func (l *LevelDB) GetMany(ctx context.Context, keys [][]byte) ([][]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	snap, err := l.db.GetSnapshot()
+	if err != nil {
+		return nil, err
+	}
+	defer snap.Release()
+	values := make([][]byte, len(keys))
+	for i, key := range keys {
+		val, err := snap.Get(key, nil)
+		if errors.Is(err, leveldb.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if val == nil {
+			val = []byte{}
+		}
+		values[i] = val
+	}
+	return values, nil
+}
+
+// HasMany reports whether each key exists, all checked against one
+// snapshot.
+func (l *LevelDB) HasMany(ctx context.Context, keys [][]byte) ([]bool, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	snap, err := l.db.GetSnapshot()
+	if err != nil {
+		return nil, err
+	}
+	defer snap.Release()
+	found := make([]bool, len(keys))
+	for i, key := range keys {
+		if found[i], err = snap.Has(key, nil); err != nil {
+			return nil, err
+		}
+	}
+	return found, nil
+}
+
+// Has reports whether a key exists. A missing key returns (false, nil).
+func (l *LevelDB) Has(ctx context.Context, key []byte) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	return l.db.Has(key, nil)
+}
+
+// SizeOf returns the length of a key's value. goleveldb cannot read a value
+// without copying it, so this costs as much as Get.
+func (l *LevelDB) SizeOf(ctx context.Context, key []byte) (int, error) {
+	val, err := l.Get(ctx, key)
+	if err != nil {
+		return 0, err
+	}
+	return len(val), nil
+}
+
+// Delete removes a key-value pair from the database.
+func (l *LevelDB) Delete(ctx context.Context, key []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := l.db.Delete(key, nil); err != nil {
+		return err
+	}
+	l.watchers.Publish(zerokv.Event{Op: zerokv.OpDelete, Key: key})
+	return nil
+}
+
+// DeletePrefix removes every key with the given prefix in one synced batch.
+func (l *LevelDB) DeletePrefix(ctx context.Context, prefix []byte) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	it := l.db.NewIterator(util.BytesPrefix(prefix), nil)
+	batch := new(leveldb.Batch)
+	for it.Next() {
+		batch.Delete(it.Key()) // Delete copies the key into the batch
+	}
+	it.Release()
+	if err := it.Error(); err != nil {
+		return 0, err
+	}
+	if batch.Len() == 0 {
+		return 0, nil
+	}
+	if err := l.db.Write(batch, &opt.WriteOptions{Sync: true}); err != nil {
+		return 0, err
+	}
+	l.watchers.Publish(zerokv.DeletePrefixEvent(prefix))
+	return batch.Len(), nil
+}
+
+// DeleteRange removes every key in [start, end) in one synced batch.
+func (l *LevelDB) DeleteRange(ctx context.Context, start, end []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	it := l.db.NewIterator(&util.Range{Start: start, Limit: end}, nil)
+	batch := new(leveldb.Batch)
+	for it.Next() {
+		batch.Delete(it.Key())
+	}
+	it.Release()
+	if err := it.Error(); err != nil {
+		return err
+	}
+	if batch.Len() == 0 {
+		return nil
+	}
+	if err := l.db.Write(batch, &opt.WriteOptions{Sync: true}); err != nil {
+		return err
+	}
+	l.watchers.Publish(zerokv.Event{Op: zerokv.OpDeleteRange, Key: start, End: end})
+	return nil
+}
+
+// Truncate deletes every key in one synced batch, then compacts the whole
+// keyspace to reclaim the space. Both steps grow with the store's size.
+func (l *LevelDB) Truncate(ctx context.Context) error {
+	if err := l.DeleteRange(ctx, nil, nil); err != nil {
+		return err
+	}
+	return l.db.CompactRange(util.Range{})
+}
+
+// TruncatePrefix deletes the prefix, then compacts its key range to drop
+// the deleted entries from the table files.
+func (l *LevelDB) TruncatePrefix(ctx context.Context, prefix []byte) error {
+	if _, err := l.DeletePrefix(ctx, prefix); err != nil {
+		return err
+	}
+	return l.db.CompactRange(*util.BytesPrefix(prefix))
+}
+
+// Count returns the number of keys with the given prefix.
+// It returns ctx.Err() if ctx is cancelled during the scan.
+func (l *LevelDB) Count(ctx context.Context, prefix []byte) (int64, error) {
+	it := l.db.NewIterator(util.BytesPrefix(prefix), nil)
+	defer it.Release()
+	var count int64
+	for it.Next() {
+		if err := ctx.Err(); err != nil {
+			return 0, err
+		}
+		count++
+	}
+	if err := it.Error(); err != nil {
+		return 0, err
+	}
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// EstimateSize returns goleveldb's approximation of the table file bytes
+// holding keys with the given prefix. Writes still in the memtable are not
+// counted until they are flushed.
+func (l *LevelDB) EstimateSize(prefix []byte) (int64, error) {
+	r := util.BytesPrefix(prefix)
+	if r.Limit == nil {
+		// the prefix has no upper bound, so stop just past the last key
+		it := l.db.NewIterator(r, nil)
+		if it.Last() {
+			r.Limit = append(bytes.Clone(it.Key()), 0)
+		}
+		it.Release()
+		if err := it.Error(); err != nil {
+			return 0, err
+		}
+		if r.Limit == nil {
+			return 0, nil
+		}
+	}
+	sizes, err := l.db.SizeOf([]util.Range{*r})
+	if err != nil {
+		return 0, err
+	}
+	return sizes.Sum(), nil
+}
+
+// CompareAndSwap sets key to new if its current value equals old, or if it
+// is absent when old is nil. The read and the write run under modifyMu: the
+// swap is atomic with respect to other CompareAndSwap calls, but not to a
+// plain Put of the same key.
+func (l *LevelDB) CompareAndSwap(ctx context.Context, key, old, new []byte) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	var swapped bool
+	err := l.modify(key, func(current []byte, found bool) ([]byte, bool, error) {
+		swapped = found == (old != nil) && bytes.Equal(current, old)
+		return new, swapped, nil
+	})
+	return swapped && err == nil, err
+}
+
+// Increment adds delta to the counter at key under modifyMu. Concurrent
+// increments never lose updates, but a plain Put of the key can.
+func (l *LevelDB) Increment(ctx context.Context, key []byte, delta int64) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	var n int64
+	err := l.modify(key, func(current []byte, found bool) ([]byte, bool, error) {
+		n = 0
+		if found {
+			var err error
+			if n, err = zerokv.DecodeCounter(current); err != nil {
+				return nil, false, err
+			}
+		}
+		n += delta
+		return zerokv.EncodeCounter(n), true, nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// GetOrPut returns the value of key, or stores and returns the result of fill
+// when the key is missing. Callers for the same key wait on a per-key lock,
+// so fill runs once; the value is then stored under modifyMu only if the key
+// is still absent.
+func (l *LevelDB) GetOrPut(ctx context.Context, key []byte, fill func() ([]byte, error)) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	unlock := l.fills.Lock(key)
+	defer unlock()
+	value, err := l.Get(ctx, key)
+	if !errors.Is(err, zerokv.ErrKeyNotFound) {
+		return value, err
+	}
+	filled, err := fill()
+	if err != nil {
+		return nil, err
+	}
+	err = l.modify(key, func(current []byte, found bool) ([]byte, bool, error) {
+		if found {
+			value = current
+			return nil, false, nil
+		}
+		value = filled
+		return filled, true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// Merge replaces the value of key with merge(existing, operand) under
+// modifyMu, where existing is nil for a missing key. Concurrent merges never
+// lose updates, but a plain Put of the key can.
+func (l *LevelDB) Merge(ctx context.Context, key, operand []byte, merge func(existing, operand []byte) []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return l.modify(key, func(current []byte, found bool) ([]byte, bool, error) {
+		return merge(current, operand), true, nil
+	})
+}
+
+// modify runs a read-modify-write of key under modifyMu. fn gets the current
+// value and whether the key exists, and returns the value to store and
+// whether to store it. goleveldb transactions write straight to table files,
+// which is far too heavy for single-key updates, so a mutex is used instead.
+func (l *LevelDB) modify(key []byte, fn func(current []byte, found bool) ([]byte, bool, error)) error {
+	l.modifyMu.Lock()
+	defer l.modifyMu.Unlock()
+	current, err := l.db.Get(key, nil)
+	found := err == nil
+	if err != nil && !errors.Is(err, leveldb.ErrNotFound) {
+		return err
+	}
+	value, write, err := fn(current, found)
+	if err != nil || !write {
+		return err
+	}
+	if err := l.db.Put(key, value, &opt.WriteOptions{Sync: true}); err != nil {
+		return err
+	}
+	l.watchers.Publish(zerokv.Event{Op: zerokv.OpPut, Key: key, Value: value})
+	return nil
+}
+
+// Stats reports the total size of the on-disk levels. goleveldb exposes no
+// key count, memtable size or compaction backlog, so those stay zero.
+func (l *LevelDB) Stats(ctx context.Context) (zerokv.Stats, error) {
+	if err := ctx.Err(); err != nil {
+		return zerokv.Stats{}, err
+	}
+	var dbStats leveldb.DBStats
+	if err := l.db.Stats(&dbStats); err != nil {
+		return zerokv.Stats{}, err
+	}
+	var stats zerokv.Stats
+	for _, size := range dbStats.LevelSizes {
+		stats.OnDiskBytes += size
+	}
+	return stats, nil
+}
+
+// Sync only checks the context: goleveldb cannot fsync its journal on
+// demand. Batch, PutMany and transaction commits are already written with
+// Sync set, and a single Put or Delete becomes durable with the next of them.
+func (l *LevelDB) Sync(ctx context.Context) error {
+	return ctx.Err()
+}
+
+// Compact runs CompactRange over [start, end). goleveldb treats a nil start
+// or end as unbounded, so a nil range compacts everything.
+func (l *LevelDB) Compact(ctx context.Context, start, end []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return l.db.CompactRange(util.Range{Start: start, Limit: end})
+}
+
+// CompactAll is Compact over the whole keyspace.
+func (l *LevelDB) CompactAll(ctx context.Context) error {
+	return l.Compact(ctx, nil, nil)
+}
+
+// RunValueLogGC does nothing: goleveldb keeps values in its table files,
+// which Compact reclaims.
+func (l *LevelDB) RunValueLogGC(discardRatio float64) error {
+	return nil
+}
+
+// Ping reads a cheap database property, which fails once the database is
+// closed.
+func (l *LevelDB) Ping(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	_, err := l.db.GetProperty("leveldb.alivesnaps")
+	return err
+}
+
+// Backup writes every entry from a consistent snapshot to w in the portable
+// zerokv backup format.
+func (l *LevelDB) Backup(ctx context.Context, w io.Writer) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	bw, err := zerokv.NewBackupWriter(w)
+	if err != nil {
+		return err
+	}
+	snap, err := l.db.GetSnapshot()
+	if err != nil {
+		return err
+	}
+	defer snap.Release()
+	it := snap.NewIterator(nil, nil)
+	defer it.Release()
+	for it.Next() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := bw.Write(it.Key(), it.Value(), 0); err != nil {
+			return err
+		}
+	}
+	if err := it.Error(); err != nil {
+		return err
+	}
+	return bw.Close()
+}
+
+// Restore writes every entry from a backup produced by Backup, committing
+// restoreBatchSize entries per synced batch. Existing keys not in the backup
+// are kept. Entries with an expiry are rejected with ErrNotSupported.
+func (l *LevelDB) Restore(ctx context.Context, r io.Reader) error {
+	br, err := zerokv.NewBackupReader(r)
+	if err != nil {
+		return err
+	}
+	batch := new(leveldb.Batch)
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		key, value, expiresAt, err := br.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if expiresAt != 0 {
+			return fmt.Errorf("leveldb: backup has keys with a TTL: %w", zerokv.ErrNotSupported)
+		}
+		batch.Put(key, value)
+		if batch.Len() == restoreBatchSize {
+			if err := l.db.Write(batch, &opt.WriteOptions{Sync: true}); err != nil {
+				return err
+			}
+			batch.Reset()
+		}
+	}
+	if batch.Len() == 0 {
+		return nil
+	}
+	return l.db.Write(batch, &opt.WriteOptions{Sync: true})
+}
+
+// Checkpoint creates a new LevelDB at destDir and copies every entry into it
+// from a snapshot, committing restoreBatchSize entries per batch. goleveldb
+// has no native checkpoint, so every entry is rewritten.
+func (l *LevelDB) Checkpoint(ctx context.Context, destDir string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := zerokv.CheckCheckpointDir(destDir); err != nil {
+		return err
+	}
+	snap, err := l.db.GetSnapshot()
+	if err != nil {
+		return err
+	}
+	defer snap.Release()
+	dst, err := leveldb.OpenFile(destDir, nil)
+	if err != nil {
+		return err
+	}
+	return errors.Join(copySnapshot(ctx, snap, dst), dst.Close())
+}
+
+// copySnapshot writes every entry in snap to dst, syncing the last batch.
+func copySnapshot(ctx context.Context, snap *leveldb.Snapshot, dst *leveldb.DB) error {
+	it := snap.NewIterator(nil, nil)
+	defer it.Release()
+	batch := new(leveldb.Batch)
+	for it.Next() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		batch.Put(it.Key(), it.Value())
+		if batch.Len() >= restoreBatchSize {
+			if err := dst.Write(batch, nil); err != nil {
+				return err
+			}
+			batch.Reset()
+		}
+	}
+	if err := it.Error(); err != nil {
+		return err
+	}
+	return dst.Write(batch, &opt.WriteOptions{Sync: true})
+}
+
+// Watch streams the changes made through this LevelDB to keys with the
+// given prefix until ctx is done or the database is closed. goleveldb has no
+// change feed, so every write method publishes its changes once written;
+// Restore produces no events.
+func (l *LevelDB) Watch(ctx context.Context, prefix []byte) (<-chan zerokv.Event, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return l.watchers.Watch(ctx, prefix), nil
+}
+
+// Close closes the database and releases all resources.
+func (l *LevelDB) Close() error {
+	l.watchers.Close()
+	var errs []error
+	if err := l.db.Close(); err != nil {
+		errs = append(errs, err)
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return errors.Join(errs...)
+}
+
+// -- Batch operations
+
+// Batch creates a new leveldb.Batch that is written atomically on Commit.
+func (l *LevelDB) Batch() zerokv.Batch {
+	return &levelBatch{db: l.db, batch: new(leveldb.Batch), watchers: &l.watchers, check: zerokv.BatchValidator{Limits: levelLimits}}
+}
+
+// BulkLoad writes what fn stages in one leveldb.Batch per
+// zerokv.BulkLoadSegmentSize bytes.
+func (l *LevelDB) BulkLoad(ctx context.Context, fn func(zerokv.Batch) error) error {
+	return zerokv.RunBulkLoad(ctx, l.BatchWithOptions(0, zerokv.BulkLoadSegmentSize), fn)
+}
+
+// BatchWithOptions creates a batch that is written and replaced by a fresh
+// leveldb.Batch each time it reaches maxOps operations or maxBytes bytes.
+func (l *LevelDB) BatchWithOptions(maxOps, maxBytes int) zerokv.Batch {
+	return zerokv.NewAutoFlushBatch(l.Batch, maxOps, maxBytes)
+}
+
+// Put adds a set operation to the batch.
+func (b *levelBatch) Put(key []byte, data []byte) error {
+	if b.committed {
+		return ErrBatchCommitted
+	}
+	b.batch.Put(key, data)
+	b.check.Put(key, data)
+	return nil
+}
+
+// Delete adds a delete operation to the batch.
+func (b *levelBatch) Delete(key []byte) error {
+	if b.committed {
+		return ErrBatchCommitted
+	}
+	b.batch.Delete(key)
+	b.check.Delete(key)
+	return nil
+}
+
+// PutIfAbsent stages a put that Commit applies only if key is absent once
+// the operations staged before it are applied.
+func (b *levelBatch) PutIfAbsent(key []byte, data []byte) error {
+	if b.committed {
+		return ErrBatchCommitted
+	}
+	b.ifAbsent = append(b.ifAbsent, pendingPut{key: bytes.Clone(key), value: bytes.Clone(data), at: b.Len()})
+	b.check.Put(key, data)
+	return nil
+}
+
+// Len returns the number of operations in the batch, or zero once it has
+// been committed.
+func (b *levelBatch) Len() int {
+	if b.committed {
+		return 0
+	}
+	return b.batch.Len() + len(b.ifAbsent)
+}
+
+// Size returns the length of the batch's internal encoding, or zero once
+// it has been committed.
+func (b *levelBatch) Size() int {
+	if b.committed {
+		return 0
+	}
+	size := len(b.batch.Dump())
+	for _, put := range b.ifAbsent {
+		size += len(put.key) + len(put.value)
+	}
+	return size
+}
+
+// Reset empties the leveldb.Batch and allows it to be used again after
+// Commit.
+func (b *levelBatch) Reset() {
+	b.batch.Reset()
+	b.committed = false
+	b.ifAbsent = nil
+	b.check.Reset()
+}
+
+// Validate checks the queued operations against levelLimits, which
+// goleveldb never breaks, so it always returns nil.
+func (b *levelBatch) Validate() error {
+	return b.check.Err()
+}
+
+// PutCtx adds a set operation to the batch unless ctx is done.
+func (b *levelBatch) PutCtx(ctx context.Context, key []byte, data []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return b.Put(key, data)
+}
+
+// DeleteCtx adds a delete operation to the batch unless ctx is done.
+func (b *levelBatch) DeleteCtx(ctx context.Context, key []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return b.Delete(key)
+}
+
+// Commit writes the batch to the database.
+func (b *levelBatch) Commit(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if b.committed {
+		return ErrBatchCommitted
+	}
+	batch := b.batch
+	if len(b.ifAbsent) > 0 {
+		r := &absentResolver{db: b.db, out: new(leveldb.Batch), pending: b.ifAbsent, present: make(map[string]bool)}
+		if err := r.run(b.batch); err != nil {
+			return err
+		}
+		batch = r.out
+	}
+	if err := writeBatch(b.db, b.watchers, batch, nil); err != nil {
+		return err
+	}
+	b.committed = true
+	b.ifAbsent = nil
+	b.check.Reset()
+	return nil
+}
+
+// absentResolver replays a batch into out, inserting each pending
+// PutIfAbsent at its position if its key is absent there: not written by
+// the operations before it and, failing that, not in the database. The
+// database reads are not isolated from concurrent writers.
+type absentResolver struct {
+	db      *leveldb.DB
+	out     *leveldb.Batch
+	pending []pendingPut
+	present map[string]bool // keys the replay has put (true) or deleted (false)
+	n       int             // operations replayed so far
+	err     error
+}
+
+// run replays batch through r and resolves the puts staged after it.
+func (r *absentResolver) run(batch *leveldb.Batch) error {
+	if err := batch.Replay(r); err != nil {
+		return err
+	}
+	r.resolve()
+	return r.err
+}
+
+// resolve applies the pending puts staged after the first n operations.
+func (r *absentResolver) resolve() {
+	for ; len(r.pending) > 0 && r.pending[0].at == r.n; r.pending = r.pending[1:] {
+		put := r.pending[0]
+		present, ok := r.present[string(put.key)]
+		if !ok && r.err == nil {
+			present, r.err = r.db.Has(put.key, nil)
+		}
+		if !present {
+			r.out.Put(put.key, put.value)
+			r.present[string(put.key)] = true
+		}
+		r.n++
+	}
+}
+
+func (r *absentResolver) Put(key, value []byte) {
+	r.resolve()
+	r.out.Put(key, value)
+	r.present[string(key)] = true
+	r.n++
+}
+
+func (r *absentResolver) Delete(key []byte) {
+	r.resolve()
+	r.out.Delete(key)
+	r.present[string(key)] = false
+	r.n++
+}
+
+// writeBatch writes batch and publishes its operations to watchers.
+func writeBatch(db *leveldb.DB, watchers *zerokv.Watchers, batch *leveldb.Batch, wo *opt.WriteOptions) error {
+	if err := db.Write(batch, wo); err != nil {
+		return err
+	}
+	if watchers.Active() {
+		var events batchEvents
+		if err := batch.Replay(&events); err != nil {
+			return err
+		}
+		watchers.Publish(events...)
+	}
+	return nil
+}
+
+// batchEvents collects watch events from leveldb.Batch.Replay.
+type batchEvents []zerokv.Event
+
+func (e *batchEvents) Put(key, value []byte) {
+	*e = append(*e, zerokv.Event{Op: zerokv.OpPut, Key: key, Value: value})
+}
+
+func (e *batchEvents) Delete(key []byte) {
+	*e = append(*e, zerokv.Event{Op: zerokv.OpDelete, Key: key})
+}
+
+// -- Transaction operations
+
+// levelTxn stages writes in memory and writes them as one leveldb.Batch on
+// Commit. goleveldb's own transactions block every other writer until they
+// finish, so they are not used here.
+type levelTxn struct {
+	db       *leveldb.DB
+	writes   map[string][]byte // nil value marks a delete
+	done     bool
+	watchers *zerokv.Watchers
+}
+
+// NewTransaction starts a buffered transaction. Reads fall through to the
+// database for keys the transaction has not written, and Commit applies
+// writes last-writer-wins without conflict detection.
+func (l *LevelDB) NewTransaction(ctx context.Context) (zerokv.Txn, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return &levelTxn{db: l.db, writes: make(map[string][]byte), watchers: &l.watchers}, nil
+}
+
+// Get retrieves the value for a given key, including pending writes.
+func (t *levelTxn) Get(ctx context.Context, key []byte) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if t.done {
+		return nil, zerokv.ErrTxnDone
+	}
+	if val, ok := t.writes[string(key)]; ok {
+		if val == nil {
+			return nil, zerokv.KeyNotFound(leveldb.ErrNotFound)
+		}
+		return bytes.Clone(val), nil
+	}
+	val, err := t.db.Get(key, nil)
+	return val, notFound(err)
+}
+
+// Put stages a key-value pair in the transaction.
+func (t *levelTxn) Put(ctx context.Context, key []byte, data []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if t.done {
+		return zerokv.ErrTxnDone
+	}
+	value := bytes.Clone(data)
+	if value == nil {
+		value = []byte{}
+	}
+	t.writes[string(key)] = value
+	return nil
+}
+
+// Delete stages a key removal in the transaction.
+func (t *levelTxn) Delete(ctx context.Context, key []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if t.done {
+		return zerokv.ErrTxnDone
+	}
+	t.writes[string(key)] = nil
+	return nil
+}
+
+// Commit writes every staged write in one synced batch.
+func (t *levelTxn) Commit(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if t.done {
+		return zerokv.ErrTxnDone
+	}
+	batch := new(leveldb.Batch)
+	for key, val := range t.writes {
+		if val == nil {
+			batch.Delete([]byte(key))
+		} else {
+			batch.Put([]byte(key), val)
+		}
+	}
+	if err := writeBatch(t.db, t.watchers, batch, &opt.WriteOptions{Sync: true}); err != nil {
+		return err
+	}
+	t.done = true
+	t.writes = nil
+	return nil
+}
+
+// Discard drops the transaction's staged writes.
+func (t *levelTxn) Discard() {
+	t.done = true
+	t.writes = nil
+}
+
+// -- Snapshot operations
+
+type levelSnapshot struct {
+	mu       sync.RWMutex
+	snap     *leveldb.Snapshot
+	released bool
+}
+
+// Snapshot returns a read-only view backed by a leveldb.Snapshot.
+func (l *LevelDB) Snapshot() (zerokv.Snapshot, error) {
+	snap, err := l.db.GetSnapshot()
+	if err != nil {
+		return nil, err
+	}
+	return &levelSnapshot{snap: snap}, nil
+}
+
+// Get retrieves the value for a given key as of the snapshot.
+func (s *levelSnapshot) Get(ctx context.Context, key []byte) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.released {
+		return nil, zerokv.ErrSnapshotReleased
+	}
+	val, err := s.snap.Get(key, nil)
+	return val, notFound(err)
+}
+
+// Has reports whether a key existed when the snapshot was taken.
+func (s *levelSnapshot) Has(ctx context.Context, key []byte) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.released {
+		return false, zerokv.ErrSnapshotReleased
+	}
+	return s.snap.Has(key, nil)
+}
+
+// Scan returns an iterator over keys with the given prefix as of the snapshot.
+func (s *levelSnapshot) Scan(prefix []byte) zerokv.Iterator {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.released {
+		return zerokv.NewErrIterator(zerokv.ErrSnapshotReleased)
+	}
+	return &levelIterator{Iterator: s.snap.NewIterator(util.BytesPrefix(prefix), nil)}
+}
+
+// Release releases the underlying leveldb.Snapshot.
+func (s *levelSnapshot) Release() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.released {
+		return
+	}
+	s.released = true
+	s.snap.Release()
+}
+
+// -- Iterator operations
+
+// Scan returns an iterator over keys with the given prefix.
+func (l *LevelDB) Scan(prefix []byte) zerokv.Iterator {
+	return &levelIterator{Iterator: l.db.NewIterator(util.BytesPrefix(prefix), nil)}
+}
+
+// ReverseScan returns an iterator over keys with the given prefix in descending order.
+func (l *LevelDB) ReverseScan(prefix []byte) zerokv.Iterator {
+	return &levelReverseIterator{Iterator: l.db.NewIterator(util.BytesPrefix(prefix), nil)}
+}
+
+// RangeScan returns an iterator over keys in [start, end).
+func (l *LevelDB) RangeScan(start, end []byte) zerokv.Iterator {
+	return &levelIterator{Iterator: l.db.NewIterator(&util.Range{Start: start, Limit: end}, nil)}
+}
+
+// ScanFrom returns an iterator over keys with the given prefix, starting at
+// the first one >= start.
+func (l *LevelDB) ScanFrom(prefix, start []byte) zerokv.Iterator {
+	r := util.BytesPrefix(prefix)
+	if bytes.Compare(start, r.Start) > 0 {
+		r.Start = start
+	}
+	return &levelIterator{Iterator: l.db.NewIterator(r, nil)}
+}
+
+// ScanKeys returns an iterator over keys with the given prefix without copying values.
+func (l *LevelDB) ScanKeys(prefix []byte) zerokv.Iterator {
+	return &levelIterator{Iterator: l.db.NewIterator(util.BytesPrefix(prefix), nil), keysOnly: true}
+}
+
+// ScanContext returns a prefix iterator that stops once ctx is done.
+func (l *LevelDB) ScanContext(ctx context.Context, prefix []byte) zerokv.Iterator {
+	return zerokv.NewContextIterator(ctx, l.Scan(prefix))
+}
+
+func (it *levelIterator) Next() bool {
+	if !it.started {
+		it.valid = it.Iterator.First()
+		it.started = true
+	} else {
+		it.valid = it.Iterator.Next()
+	}
+	return it.valid
+}
+
+// Seek moves to the first key >= key; goleveldb clamps the key to the iterator range.
+func (it *levelIterator) Seek(key []byte) bool {
+	it.valid = it.Iterator.Seek(key)
+	it.started = true
+	return it.valid
+}
+
+// First moves to the smallest key in the iterator range.
+func (it *levelIterator) First() bool {
+	it.valid = it.Iterator.First()
+	it.started = true
+	return it.valid
+}
+
+// Last moves to the greatest key in the iterator range.
+func (it *levelIterator) Last() bool {
+	it.valid = it.Iterator.Last()
+	it.started = true
+	return it.valid
+}
+
+func (it *levelIterator) Valid() bool {
+	return it.valid
+}
+
+func (it *levelIterator) Key() []byte {
+	if !it.valid {
+		return nil
+	}
+	return bytes.Clone(it.Iterator.Key()) // the slice is reused on the next move
+}
+
+func (it *levelIterator) Value() []byte {
+	if !it.valid || it.keysOnly {
+		return nil
+	}
+	return bytes.Clone(it.Iterator.Value())
+}
+
+// Release Must be called to release the underlying snapshot.
+func (it *levelIterator) Release() {
+	it.valid = false
+	it.Iterator.Release()
+}
+
+func (it *levelIterator) Error() error {
+	return it.Iterator.Error()
+}
+
+func (it *levelReverseIterator) Next() bool {
+	if !it.started {
+		it.valid = it.Iterator.Last()
+		it.started = true
+	} else {
+		it.valid = it.Iterator.Prev()
+	}
+	return it.valid
+}
+
+// Seek moves to the last key <= key.
+func (it *levelReverseIterator) Seek(key []byte) bool {
+	if !it.Iterator.Seek(key) {
+		it.valid = it.Iterator.Last()
+	} else if bytes.Compare(it.Iterator.Key(), key) > 0 {
+		it.valid = it.Iterator.Prev()
+	} else {
+		it.valid = true
+	}
+	it.started = true
+	return it.valid
+}
+
+// First moves to the smallest key in the iterator range.
+func (it *levelReverseIterator) First() bool {
+	it.valid = it.Iterator.First()
+	it.started = true
+	return it.valid
+}
+
+// Last moves to the greatest key in the iterator range.
+func (it *levelReverseIterator) Last() bool {
+	it.valid = it.Iterator.Last()
+	it.started = true
+	return it.valid
+}
+
+func (it *levelReverseIterator) Valid() bool {
+	return it.valid
+}
+
+func (it *levelReverseIterator) Key() []byte {
+	if !it.valid {
+		return nil
+	}
+	return bytes.Clone(it.Iterator.Key())
+}
+
+func (it *levelReverseIterator) Value() []byte {
+	if !it.valid {
+		return nil
+	}
+	return bytes.Clone(it.Iterator.Value())
+}
+
+// Release Must be called to release the underlying snapshot.
+func (it *levelReverseIterator) Release() {
+	it.valid = false
+	it.Iterator.Release()
+}
+
+func (it *levelReverseIterator) Error() error {
+	return it.Iterator.Error()
+}
@@ -0,0 +1,99 @@
+package leveldb_test
+
+import (
+	"testing"
+
+	"github.com/rawbytedev/zerokv/helpers"
+	"github.com/rawbytedev/zerokv/leveldb"
+	"github.com/stretchr/testify/require"
+)
+
+// TestLevelBatchOperations tests batch Put and Get operations.
+func TestLevelBatchOperations(t *testing.T) {
+	db := helpers.SetupDB(t, "leveldb")
+	batch := db.Batch()
+	keys := make([][]byte, 5)
+	values := make([][]byte, 5)
+	for i := 0; i < 5; i++ {
+		keys[i] = helpers.RandomBytes(16)
+		values[i] = helpers.RandomBytes(32)
+		err := batch.Put(keys[i], values[i])
+		require.NoError(t, err, "Error adding Put operation to batch")
+	}
+	err := batch.Commit(t.Context())
+	require.NoError(t, err, "Error committing batch operations")
+	for i := 0; i < 5; i++ {
+		retrievedValue, err := db.Get(t.Context(), keys[i])
+		require.NoError(t, err, "Error getting value after batch commit")
+		require.Equal(t, values[i], retrievedValue, "Retrieved value does not match expected after batch commit")
+	}
+	// This should fail because the batch has already been committed
+	err = batch.Put(keys[0], values[1])
+	require.ErrorIs(t, err, leveldb.ErrBatchCommitted)
+	err = batch.Commit(t.Context())
+	require.ErrorIs(t, err, leveldb.ErrBatchCommitted)
+	defer db.Close()
+}
+
+// TestLevelReopen verifies data persists across reopening the directory.
+func TestLevelReopen(t *testing.T) {
+	tmp := t.TempDir()
+	db, err := leveldb.NewLevelDB(leveldb.Config{Dir: tmp})
+	require.NoError(t, err)
+	require.NoError(t, db.Put(t.Context(), []byte("key"), []byte("value")))
+	require.NoError(t, db.Close())
+
+	db, err = leveldb.NewLevelDB(leveldb.Config{Dir: tmp})
+	require.NoError(t, err)
+	defer db.Close()
+	value, err := db.Get(t.Context(), []byte("key"))
+	require.NoError(t, err)
+	require.Equal(t, []byte("value"), value)
+}
+
+// TestLevelReverseIteratorOrder verifies reverse order against forward order
+func TestLevelReverseIteratorOrder(t *testing.T) {
+	db := helpers.SetupDB(t, "leveldb")
+	defer db.Close()
+	for _, key := range []string{"key_01", "key_02", "key_03", "key_04", "key_05", "kez"} {
+		require.NoError(t, db.Put(t.Context(), []byte(key), []byte("value")))
+	}
+
+	forwardKeys := make([][]byte, 0)
+	it := db.Scan([]byte("key_"))
+	for it.Next() {
+		forwardKeys = append(forwardKeys, it.Key())
+	}
+	it.Release()
+
+	reverseKeys := make([][]byte, 0)
+	rit := db.ReverseScan([]byte("key_"))
+	for rit.Next() {
+		reverseKeys = append(reverseKeys, rit.Key())
+	}
+	rit.Release()
+
+	require.Len(t, forwardKeys, 5)
+	require.Equal(t, len(forwardKeys), len(reverseKeys), "Should have same count")
+	for i := 0; i < len(forwardKeys); i++ {
+		require.Equal(t, forwardKeys[i], reverseKeys[len(reverseKeys)-1-i], "Keys should be in reverse order")
+	}
+}
+
+// TestLevelNewOptions verifies New applies functional options, including a
+// read-only reopen.
+func TestLevelNewOptions(t *testing.T) {
+	tmp := t.TempDir()
+	db, err := leveldb.New(tmp, leveldb.WithWriteBuffer(1<<20), leveldb.WithBlockCacheCapacity(1<<20))
+	require.NoError(t, err)
+	require.NoError(t, db.Put(t.Context(), []byte("key"), []byte("value")))
+	require.NoError(t, db.Close())
+
+	db, err = leveldb.New(tmp, leveldb.WithReadOnly())
+	require.NoError(t, err)
+	defer db.Close()
+	value, err := db.Get(t.Context(), []byte("key"))
+	require.NoError(t, err)
+	require.Equal(t, []byte("value"), value)
+	require.Error(t, db.Put(t.Context(), []byte("key"), []byte("value")))
+}
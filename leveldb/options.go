@@ -0,0 +1,85 @@
+package leveldb
+
+import (
+	"net/url"
+
+	"github.com/rawbytedev/zerokv"
+	"github.com/syndtr/goleveldb/leveldb/opt"
+)
+
+// specific leveldb options
+type Config struct {
+	Dir            string
+	LevelDBConfigs *opt.Options
+}
+
+func DefaultOptions(Dir string) *Config {
+	return &Config{Dir, nil}
+}
+
+// Option adjusts the Config built by New.
+type Option func(*Config)
+
+// New opens a LevelDB at dir, starting from goleveldb's defaults and applying
+// opts in order. It is shorthand for building a Config by hand.
+func New(dir string, opts ...Option) (zerokv.Core, error) {
+	cfg := DefaultOptions(dir)
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	return NewLevelDB(*cfg)
+}
+
+// WithLevelDBOptions replaces the engine options wholesale. Options applied
+// after it modify the given value.
+func WithLevelDBOptions(opts *opt.Options) Option {
+	return func(c *Config) {
+		c.LevelDBConfigs = opts
+	}
+}
+
+// WithReadOnly opens the database without write access.
+func WithReadOnly() Option {
+	return func(c *Config) {
+		c.levelDBOptions().ReadOnly = true
+	}
+}
+
+// WithBlockCacheCapacity sets the size in bytes of the block cache.
+func WithBlockCacheCapacity(n int) Option {
+	return func(c *Config) {
+		c.levelDBOptions().BlockCacheCapacity = n
+	}
+}
+
+// WithWriteBuffer sets the size in bytes of the memtable.
+func WithWriteBuffer(n int) Option {
+	return func(c *Config) {
+		c.levelDBOptions().WriteBuffer = n
+	}
+}
+
+// paramOptions maps the DSN parameters accepted by the "leveldb" backend:
+// readOnly, cache (block cache capacity) and writeBuffer.
+func paramOptions(params url.Values) ([]Option, error) {
+	var opts []Option
+	err := zerokv.ApplyParams(params, map[string]func(string) error{
+		"readOnly": zerokv.BoolParam(func(b bool) {
+			if b {
+				opts = append(opts, WithReadOnly())
+			}
+		}),
+		"cache":       zerokv.SizeParam(func(n int64) { opts = append(opts, WithBlockCacheCapacity(int(n))) }),
+		"writeBuffer": zerokv.SizeParam(func(n int64) { opts = append(opts, WithWriteBuffer(int(n))) }),
+	})
+	return opts, err
+}
+
+// levelDBOptions returns the engine options for an Option to modify,
+// creating empty ones on first use.
+func (c *Config) levelDBOptions() *opt.Options {
+	if c.LevelDBConfigs == nil {
+		c.LevelDBConfigs = &opt.Options{}
+	}
+	return c.LevelDBConfigs
+}
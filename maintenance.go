@@ -0,0 +1,91 @@
+package zerokv
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MaintenanceDiscardRatio is the discard ratio StartMaintenance passes to
+// RunValueLogGC.
+const MaintenanceDiscardRatio = 0.5
+
+// StartMaintenance runs core's background housekeeping every interval until
+// the returned stop function is called. Each round garbage collects the
+// value log if core implements ValueLogGC, then compacts the whole keyspace
+// if it implements Compactor. Core.Compact is not used, since on BadgerDB it
+// would garbage collect the value log a second time. For backends without
+// maintenance operations, such as the in-memory one, the rounds do nothing,
+// and an interval of zero or less starts no goroutine at all.
+//
+// Errors from a round are dropped and the next round tries again; call the
+// methods directly if they need to be observed. Wrappers such as
+// WithMetrics are looked through with their Unwrap method, so ValueLogGC and
+// Compactor are found on the backend underneath.
+//
+// stop cancels a round in progress where the backend can be interrupted and
+// returns once the goroutine has exited; calling it more than once is safe.
+func StartMaintenance(core Core, interval time.Duration) (stop func()) {
+	if interval <= 0 {
+		return func() {}
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			if gc, ok := unwrapAs[ValueLogGC](core); ok {
+				_ = gc.RunValueLogGC(MaintenanceDiscardRatio)
+			}
+			if c, ok := unwrapAs[Compactor](core); ok && ctx.Err() == nil {
+				_ = c.CompactAll(ctx)
+			}
+		}
+	}()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			cancel()
+			<-done
+		})
+	}
+}
+
+// Optimize compacts core to a steady state if it implements Optimizer, and
+// returns nil without doing anything otherwise. It rewrites every table of
+// the database, so it takes time and disk I/O proportional to the total data
+// size, can need as much free space again while it runs, and slows
+// concurrent writes; on a large dataset expect minutes rather than seconds.
+// Run it at quiet times, not on a short interval like StartMaintenance.
+// Like StartMaintenance, it looks through wrappers such as WithMetrics.
+func Optimize(ctx context.Context, core Core) error {
+	if o, ok := unwrapAs[Optimizer](core); ok {
+		return o.Optimize(ctx)
+	}
+	return nil
+}
+
+// unwrapAs returns the first Core implementing T, starting at core and
+// following the Unwrap() Core method that wrappers such as WithMetrics and
+// Namespace provide.
+func unwrapAs[T any](core Core) (T, bool) {
+	for core != nil {
+		if t, ok := core.(T); ok {
+			return t, true
+		}
+		u, ok := core.(interface{ Unwrap() Core })
+		if !ok {
+			break
+		}
+		core = u.Unwrap()
+	}
+	var zero T
+	return zero, false
+}
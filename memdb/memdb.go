@@ -0,0 +1,1172 @@
+package memdb
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/rawbytedev/zerokv"
+)
+
+var (
+	// ErrNotFound is returned by Get when the key does not exist.
+	ErrNotFound = errors.New("memdb: key not found")
+	// ErrClosed is returned by operations on a closed database.
+	ErrClosed = errors.New("memdb: database closed")
+	// ErrBatchCommitted is returned when a batch is used after Commit.
+	ErrBatchCommitted = errors.New("memdb: batch already committed")
+)
+
+// errKeyNotFound is ErrNotFound wrapped so it also matches
+// zerokv.ErrKeyNotFound.
+var errKeyNotFound = zerokv.KeyNotFound(ErrNotFound)
+
+// MemDB is an in-memory zerokv.Core backed by a slice kept sorted by key.
+// Stored keys and values are private copies, so callers may reuse their buffers.
+type MemDB struct {
+	mu      sync.RWMutex
+	entries []entry
+	closed  bool
+	fills   zerokv.KeyedMutex // serializes GetOrPut per key
+	// watchers is published to while mu is held, so events arrive in
+	// commit order.
+	watchers zerokv.Watchers
+}
+
+type entry struct {
+	key       []byte
+	value     []byte
+	expiresAt int64 // Unix nanoseconds, 0 for no expiry
+}
+
+// live reports whether the entry has not expired at now (Unix nanoseconds).
+func (e entry) live(now int64) bool {
+	return e.expiresAt == 0 || now < e.expiresAt
+}
+
+type memBatch struct {
+	db        *MemDB
+	ops       []batchOp
+	committed bool
+	check     zerokv.BatchValidator
+}
+
+// memLimits are the keys and values MemDB refuses to store: none, since it
+// holds any key, including an empty one, and any value.
+var memLimits = zerokv.BatchLimits{AllowEmptyKey: true}
+
+type batchOp struct {
+	key      []byte
+	value    []byte
+	delete   bool
+	ifAbsent bool // a put skipped if key exists when it is applied
+}
+
+type memIterator struct {
+	entries  []entry // snapshot of the entries in range, in iteration order
+	reverse  bool
+	keysOnly bool
+	pos      int
+	started  bool
+	valid    bool
+	err      []error
+}
+
+func init() {
+	zerokv.Register("memory", func(cfg zerokv.Config) (zerokv.Core, error) {
+		if err := zerokv.ApplyParams(cfg.Params, nil); err != nil {
+			return nil, err
+		}
+		return New(), nil
+	})
+}
+
+// New returns an empty in-memory zerokv.Core instance.
+func New() zerokv.Core {
+	return &MemDB{}
+}
+
+// --- Basic CRUD operations ---
+
+// PutSync is Put: nothing is ever written to disk, so sync has no effect.
+func (m *MemDB) PutSync(ctx context.Context, key, value []byte, sync bool) error {
+	return m.Put(ctx, key, value)
+}
+
+// Put inserts or updates a key-value pair in the database.
+func (m *MemDB) Put(ctx context.Context, key []byte, data []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return ErrClosed
+	}
+	m.set(key, data, 0)
+	m.watchers.Publish(zerokv.Event{Op: zerokv.OpPut, Key: key, Value: data})
+	return nil
+}
+
+// PutWithTTL inserts or updates a key-value pair that expires after ttl.
+// Expired entries are hidden from reads and dropped when overwritten or deleted.
+func (m *MemDB) PutWithTTL(ctx context.Context, key, value []byte, ttl time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return ErrClosed
+	}
+	m.set(key, value, time.Now().Add(ttl).UnixNano())
+	m.watchers.Publish(zerokv.Event{Op: zerokv.OpPut, Key: key, Value: value})
+	return nil
+}
+
+// PutMany writes all pairs atomically under one write lock.
+func (m *MemDB) PutMany(ctx context.Context, keys, values [][]byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if len(keys) != len(values) {
+		return fmt.Errorf("PutMany: %d keys but %d values", len(keys), len(values))
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return ErrClosed
+	}
+	for i := range keys {
+		m.set(keys[i], values[i], 0)
+	}
+	if m.watchers.Active() {
+		m.watchers.Publish(zerokv.PutEvents(keys, values)...)
+	}
+	return nil
+}
+
+// Get retrieves the value for a given key. Returns ErrNotFound if missing.
+func (m *MemDB) Get(ctx context.Context, key []byte) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.closed {
+		return nil, ErrClosed
+	}
+	e, ok := lookup(m.entries, key)
+	if !ok {
+		return nil, errKeyNotFound
+	}
+	return bytes.Clone(e.value), nil
+}
+
+// GetOrDefault returns a copy of def if key is missing.
+func (m *MemDB) GetOrDefault(ctx context.Context, key, def []byte) ([]byte, error) {
+	value, err := m.Get(ctx, key)
+	if errors.Is(err, zerokv.ErrKeyNotFound) {
+		return bytes.Clone(def), nil
+	}
+	return value, err
+}
+
+// GetInto copies the value of key into dst through View, without
+// allocating when dst is large enough.
+func (m *MemDB) GetInto(ctx context.Context, key, dst []byte) ([]byte, error) {
+	dst = dst[:0]
+	err := m.View(ctx, key, func(value []byte) error {
+		dst = append(dst, value...)
+		return nil
+	})
+	return dst, err
+}
+
+// View calls fn with the stored value without copying it. Values are
+// replaced rather than overwritten, so fn runs without holding the lock.
+func (m *MemDB) View(ctx context.Context, key []byte, fn func(value []byte) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.mu.RLock()
+	if m.closed {
+		m.mu.RUnlock()
+		return ErrClosed
+	}
+	e, ok := lookup(m.entries, key)
+	m.mu.RUnlock()
+	if !ok {
+		return errKeyNotFound
+	}
+	return fn(e.value)
+}
+
+// GetMany retrieves the values for keys under one read lock.
+// Missing keys yield a nil entry at the same position.
+func (m *MemDB) GetMany(ctx context.Context, keys [][]byte) ([][]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.closed {
+		return nil, ErrClosed
+	}
+	values := make([][]byte, len(keys))
+	for i, key := range keys {
+		if e, ok := lookup(m.entries, key); ok {
+			values[i] = bytes.Clone(e.value)
+		}
+	}
+	return values, nil
+}
+
+// HasMany reports whether each key exists, all checked under one lock.
+func (m *MemDB) HasMany(ctx context.Context, keys [][]byte) ([]bool, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.closed {
+		return nil, ErrClosed
+	}
+	found := make([]bool, len(keys))
+	for i, key := range keys {
+		_, found[i] = lookup(m.entries, key)
+	}
+	return found, nil
+}
+
+// Has reports whether a key exists. A missing key returns (false, nil).
+func (m *MemDB) Has(ctx context.Context, key []byte) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.closed {
+		return false, ErrClosed
+	}
+	_, ok := lookup(m.entries, key)
+	return ok, nil
+}
+
+// SizeOf returns the length of a key's value.
+func (m *MemDB) SizeOf(ctx context.Context, key []byte) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.closed {
+		return 0, ErrClosed
+	}
+	e, ok := lookup(m.entries, key)
+	if !ok {
+		return 0, errKeyNotFound
+	}
+	return len(e.value), nil
+}
+
+// Delete removes a key-value pair from the database.
+func (m *MemDB) Delete(ctx context.Context, key []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return ErrClosed
+	}
+	m.remove(key)
+	m.watchers.Publish(zerokv.Event{Op: zerokv.OpDelete, Key: key})
+	return nil
+}
+
+// DeletePrefix removes every key with the given prefix.
+func (m *MemDB) DeletePrefix(ctx context.Context, prefix []byte) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return 0, ErrClosed
+	}
+	lo, _ := m.find(prefix)
+	hi, count := lo, 0
+	now := time.Now().UnixNano()
+	for hi < len(m.entries) && bytes.HasPrefix(m.entries[hi].key, prefix) {
+		if m.entries[hi].live(now) {
+			count++
+		}
+		hi++
+	}
+	m.entries = append(m.entries[:lo], m.entries[hi:]...)
+	m.watchers.Publish(zerokv.DeletePrefixEvent(prefix))
+	return count, nil
+}
+
+// DeleteRange removes every key in [start, end).
+func (m *MemDB) DeleteRange(ctx context.Context, start, end []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return ErrClosed
+	}
+	lo, _ := m.find(start)
+	hi := len(m.entries)
+	if end != nil {
+		hi, _ = m.find(end)
+	}
+	if lo < hi {
+		m.entries = append(m.entries[:lo], m.entries[hi:]...)
+	}
+	m.watchers.Publish(zerokv.Event{Op: zerokv.OpDeleteRange, Key: start, End: end})
+	return nil
+}
+
+// Truncate drops every entry.
+func (m *MemDB) Truncate(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return ErrClosed
+	}
+	m.entries = nil
+	m.watchers.Publish(zerokv.Event{Op: zerokv.OpDeleteRange})
+	return nil
+}
+
+// TruncatePrefix is DeletePrefix, which already frees the entries.
+func (m *MemDB) TruncatePrefix(ctx context.Context, prefix []byte) error {
+	_, err := m.DeletePrefix(ctx, prefix)
+	return err
+}
+
+// Count returns the number of keys with the given prefix.
+func (m *MemDB) Count(ctx context.Context, prefix []byte) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.closed {
+		return 0, ErrClosed
+	}
+	var count int64
+	now := time.Now().UnixNano()
+	lo, _ := m.find(prefix)
+	for i := lo; i < len(m.entries) && bytes.HasPrefix(m.entries[i].key, prefix); i++ {
+		if m.entries[i].live(now) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// EstimateSize returns the exact number of key and value bytes held for the
+// live keys with the given prefix.
+func (m *MemDB) EstimateSize(prefix []byte) (int64, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.closed {
+		return 0, ErrClosed
+	}
+	var size int64
+	now := time.Now().UnixNano()
+	lo, _ := m.find(prefix)
+	for i := lo; i < len(m.entries) && bytes.HasPrefix(m.entries[i].key, prefix); i++ {
+		if m.entries[i].live(now) {
+			size += int64(len(m.entries[i].key) + len(m.entries[i].value))
+		}
+	}
+	return size, nil
+}
+
+// CompareAndSwap sets key to new if its current value equals old, or if it
+// is absent when old is nil. The check and the write happen under one lock.
+func (m *MemDB) CompareAndSwap(ctx context.Context, key, old, new []byte) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	var swapped bool
+	err := m.modify(key, func(current []byte, found bool) ([]byte, bool, error) {
+		swapped = found == (old != nil) && bytes.Equal(current, old)
+		return new, swapped, nil
+	})
+	return swapped && err == nil, err
+}
+
+// Increment adds delta to the counter at key under the write lock.
+func (m *MemDB) Increment(ctx context.Context, key []byte, delta int64) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	var n int64
+	err := m.modify(key, func(current []byte, found bool) ([]byte, bool, error) {
+		n = 0
+		if found {
+			var err error
+			if n, err = zerokv.DecodeCounter(current); err != nil {
+				return nil, false, err
+			}
+		}
+		n += delta
+		return zerokv.EncodeCounter(n), true, nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// GetOrPut returns the value of key, or stores and returns the result of fill
+// when the key is missing. fill runs once per key even with concurrent
+// callers, and without holding the write lock.
+func (m *MemDB) GetOrPut(ctx context.Context, key []byte, fill func() ([]byte, error)) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	unlock := m.fills.Lock(key)
+	defer unlock()
+	value, err := m.Get(ctx, key)
+	if !errors.Is(err, zerokv.ErrKeyNotFound) {
+		return value, err
+	}
+	filled, err := fill()
+	if err != nil {
+		return nil, err
+	}
+	err = m.modify(key, func(current []byte, found bool) ([]byte, bool, error) {
+		if found {
+			value = bytes.Clone(current)
+			return nil, false, nil
+		}
+		value = filled
+		return filled, true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// Merge replaces the value of key with merge(existing, operand) under the
+// write lock, where existing is nil for a missing key.
+func (m *MemDB) Merge(ctx context.Context, key, operand []byte, merge func(existing, operand []byte) []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return m.modify(key, func(current []byte, found bool) ([]byte, bool, error) {
+		return merge(bytes.Clone(current), operand), true, nil
+	})
+}
+
+// modify runs a read-modify-write of key under the write lock. fn gets the
+// current value and whether the key is live, and returns the value to store
+// and whether to store it. The value is written without an expiry.
+func (m *MemDB) modify(key []byte, fn func(current []byte, found bool) ([]byte, bool, error)) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return ErrClosed
+	}
+	e, found := lookup(m.entries, key)
+	value, write, err := fn(e.value, found)
+	if err != nil || !write {
+		return err
+	}
+	m.set(key, value, 0)
+	m.watchers.Publish(zerokv.Event{Op: zerokv.OpPut, Key: key, Value: value})
+	return nil
+}
+
+// Stats reports the number of live keys and the bytes held by all entries
+// as MemtableBytes. OnDiskBytes and PendingCompactions are always zero.
+func (m *MemDB) Stats(ctx context.Context) (zerokv.Stats, error) {
+	if err := ctx.Err(); err != nil {
+		return zerokv.Stats{}, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.closed {
+		return zerokv.Stats{}, ErrClosed
+	}
+	var stats zerokv.Stats
+	now := time.Now().UnixNano()
+	for _, e := range m.entries {
+		if e.live(now) {
+			stats.KeyCount++
+		}
+		stats.MemtableBytes += int64(len(e.key) + len(e.value))
+	}
+	return stats, nil
+}
+
+// Sync has nothing to flush; it only reports ErrClosed after Close.
+func (m *MemDB) Sync(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.closed {
+		return ErrClosed
+	}
+	return nil
+}
+
+// Compact has nothing to reclaim, since deleted entries are dropped
+// immediately; it only reports ErrClosed after Close.
+func (m *MemDB) Compact(ctx context.Context, start, end []byte) error {
+	return m.Sync(ctx)
+}
+
+// RunValueLogGC does nothing: MemDB has no value log.
+func (m *MemDB) RunValueLogGC(discardRatio float64) error {
+	return nil
+}
+
+// Ping reports ErrClosed after Close.
+func (m *MemDB) Ping(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.closed {
+		return ErrClosed
+	}
+	return nil
+}
+
+// Backup writes every live entry to w in the portable zerokv backup format,
+// keeping each key's expiry. Entries are copied under the read lock first,
+// so a slow writer does not block the database.
+func (m *MemDB) Backup(ctx context.Context, w io.Writer) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.mu.RLock()
+	if m.closed {
+		m.mu.RUnlock()
+		return ErrClosed
+	}
+	entries := slices.Clone(m.entries)
+	m.mu.RUnlock()
+	bw, err := zerokv.NewBackupWriter(w)
+	if err != nil {
+		return err
+	}
+	now := time.Now().UnixNano()
+	for _, e := range entries {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if !e.live(now) {
+			continue
+		}
+		if err := bw.Write(e.key, e.value, e.expiresAt); err != nil {
+			return err
+		}
+	}
+	return bw.Close()
+}
+
+// Restore writes every entry from a backup produced by Backup. The whole
+// stream is read before anything is applied, so a corrupt backup changes
+// nothing. Existing keys not in the backup are kept.
+func (m *MemDB) Restore(ctx context.Context, r io.Reader) error {
+	br, err := zerokv.NewBackupReader(r)
+	if err != nil {
+		return err
+	}
+	var entries []entry
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		key, value, expiresAt, err := br.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		entries = append(entries, entry{key: key, value: value, expiresAt: expiresAt})
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return ErrClosed
+	}
+	for _, e := range entries {
+		m.set(e.key, e.value, e.expiresAt)
+	}
+	return nil
+}
+
+// Checkpoint is not supported: MemDB has no on-disk form to open a
+// checkpoint from. Use Backup to save its contents.
+func (m *MemDB) Checkpoint(ctx context.Context, destDir string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return fmt.Errorf("memdb: Checkpoint: %w", zerokv.ErrNotSupported)
+}
+
+// Watch streams the changes made to keys with the given prefix until ctx
+// is done or the database is closed. Restore and TTL expiry produce no
+// events.
+func (m *MemDB) Watch(ctx context.Context, prefix []byte) (<-chan zerokv.Event, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return m.watchers.Watch(ctx, prefix), nil
+}
+
+// Close drops all data held by the database.
+func (m *MemDB) Close() error {
+	m.watchers.Close()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.closed = true
+	m.entries = nil
+	return nil
+}
+
+// find returns the index of key, or the index it would be inserted at.
+// Callers must hold m.mu.
+func (m *MemDB) find(key []byte) (int, bool) {
+	return search(m.entries, key)
+}
+
+// search returns the index of key in sorted entries, or where it would be inserted.
+func search(entries []entry, key []byte) (int, bool) {
+	i := sort.Search(len(entries), func(i int) bool {
+		return bytes.Compare(entries[i].key, key) >= 0
+	})
+	return i, i < len(entries) && bytes.Equal(entries[i].key, key)
+}
+
+// lookup returns the live entry for key in sorted entries.
+func lookup(entries []entry, key []byte) (entry, bool) {
+	i, ok := search(entries, key)
+	if !ok || !entries[i].live(time.Now().UnixNano()) {
+		return entry{}, false
+	}
+	return entries[i], true
+}
+
+// set stores copies of key and value with the given expiry (0 for none).
+// Callers must hold m.mu for writing.
+func (m *MemDB) set(key, value []byte, expiresAt int64) {
+	// values are replaced rather than overwritten in place so that
+	// snapshots taken by iterators keep seeing the old bytes
+	value = bytes.Clone(value)
+	if value == nil {
+		value = []byte{}
+	}
+	i, ok := m.find(key)
+	if ok {
+		m.entries[i].value = value
+		m.entries[i].expiresAt = expiresAt
+		return
+	}
+	m.entries = append(m.entries, entry{})
+	copy(m.entries[i+1:], m.entries[i:])
+	m.entries[i] = entry{key: bytes.Clone(key), value: value, expiresAt: expiresAt}
+}
+
+// remove deletes key if present. Callers must hold m.mu for writing.
+func (m *MemDB) remove(key []byte) {
+	i, ok := m.find(key)
+	if !ok {
+		return
+	}
+	m.entries = append(m.entries[:i], m.entries[i+1:]...)
+}
+
+// -- Batch operations
+
+// Batch creates a new batch that is applied atomically on Commit.
+func (m *MemDB) Batch() zerokv.Batch {
+	return &memBatch{db: m, check: zerokv.BatchValidator{Limits: memLimits}}
+}
+
+// BulkLoad applies everything fn stages in one batch; there is nothing to
+// tune or sync in memory.
+func (m *MemDB) BulkLoad(ctx context.Context, fn func(zerokv.Batch) error) error {
+	return zerokv.RunBulkLoad(ctx, m.Batch(), fn)
+}
+
+// BatchWithOptions creates a batch that is applied each time it reaches
+// maxOps operations or maxBytes bytes, and once more on Commit.
+func (m *MemDB) BatchWithOptions(maxOps, maxBytes int) zerokv.Batch {
+	return zerokv.NewAutoFlushBatch(m.Batch, maxOps, maxBytes)
+}
+
+// Put queues a set operation in the batch.
+func (b *memBatch) Put(key []byte, data []byte) error {
+	if b.committed {
+		return ErrBatchCommitted
+	}
+	b.ops = append(b.ops, batchOp{key: bytes.Clone(key), value: bytes.Clone(data)})
+	b.check.Put(key, data)
+	return nil
+}
+
+// PutIfAbsent queues a set operation that Commit skips if key exists once
+// the operations before it are applied.
+func (b *memBatch) PutIfAbsent(key []byte, data []byte) error {
+	if b.committed {
+		return ErrBatchCommitted
+	}
+	b.ops = append(b.ops, batchOp{key: bytes.Clone(key), value: bytes.Clone(data), ifAbsent: true})
+	b.check.Put(key, data)
+	return nil
+}
+
+// Delete queues a delete operation in the batch.
+func (b *memBatch) Delete(key []byte) error {
+	if b.committed {
+		return ErrBatchCommitted
+	}
+	b.ops = append(b.ops, batchOp{key: bytes.Clone(key), delete: true})
+	b.check.Delete(key)
+	return nil
+}
+
+// Len returns the number of queued operations.
+func (b *memBatch) Len() int {
+	return len(b.ops)
+}
+
+// Size returns the bytes of keys and values queued in the batch.
+func (b *memBatch) Size() int {
+	size := 0
+	for _, op := range b.ops {
+		size += len(op.key) + len(op.value)
+	}
+	return size
+}
+
+// Reset drops the queued operations and allows the batch to be used again
+// after Commit.
+func (b *memBatch) Reset() {
+	b.ops = nil
+	b.committed = false
+	b.check.Reset()
+}
+
+// Validate checks the queued operations against memLimits, which MemDB
+// never breaks, so it always returns nil.
+func (b *memBatch) Validate() error {
+	return b.check.Err()
+}
+
+// PutCtx queues a set operation in the batch unless ctx is done.
+func (b *memBatch) PutCtx(ctx context.Context, key []byte, data []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return b.Put(key, data)
+}
+
+// DeleteCtx queues a delete operation in the batch unless ctx is done.
+func (b *memBatch) DeleteCtx(ctx context.Context, key []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return b.Delete(key)
+}
+
+// Commit applies all queued operations atomically.
+func (b *memBatch) Commit(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if b.committed {
+		return ErrBatchCommitted
+	}
+	b.db.mu.Lock()
+	defer b.db.mu.Unlock()
+	if b.db.closed {
+		return ErrClosed
+	}
+	publish := b.db.watchers.Active()
+	var applied []batchOp
+	for _, op := range b.ops {
+		if op.ifAbsent {
+			if _, ok := lookup(b.db.entries, op.key); ok {
+				continue
+			}
+		}
+		if op.delete {
+			b.db.remove(op.key)
+		} else {
+			b.db.set(op.key, op.value, 0)
+		}
+		if publish {
+			applied = append(applied, op)
+		}
+	}
+	if publish {
+		b.db.watchers.Publish(opEvents(applied)...)
+	}
+	b.committed = true
+	b.ops = nil
+	b.check.Reset()
+	return nil
+}
+
+// opEvents returns the watch events for committed batch operations.
+func opEvents(ops []batchOp) []zerokv.Event {
+	events := make([]zerokv.Event, len(ops))
+	for i, op := range ops {
+		if op.delete {
+			events[i] = zerokv.Event{Op: zerokv.OpDelete, Key: op.key}
+		} else {
+			events[i] = zerokv.Event{Op: zerokv.OpPut, Key: op.key, Value: op.value}
+		}
+	}
+	return events
+}
+
+// -- Transaction operations
+
+// memTxn stages writes in a map keyed by the string form of the key and
+// applies them under the write lock on Commit.
+type memTxn struct {
+	db     *MemDB
+	writes map[string]batchOp
+	done   bool
+}
+
+// NewTransaction starts a buffered transaction. Reads fall through to the
+// live database for keys the transaction has not written, and Commit applies
+// writes last-writer-wins without conflict detection.
+func (m *MemDB) NewTransaction(ctx context.Context) (zerokv.Txn, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return &memTxn{db: m, writes: make(map[string]batchOp)}, nil
+}
+
+// Get retrieves the value for a given key, including pending writes.
+func (t *memTxn) Get(ctx context.Context, key []byte) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if t.done {
+		return nil, zerokv.ErrTxnDone
+	}
+	if op, ok := t.writes[string(key)]; ok {
+		if op.delete {
+			return nil, errKeyNotFound
+		}
+		return bytes.Clone(op.value), nil
+	}
+	return t.db.Get(ctx, key)
+}
+
+// Put stages a key-value pair in the transaction.
+func (t *memTxn) Put(ctx context.Context, key []byte, data []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if t.done {
+		return zerokv.ErrTxnDone
+	}
+	value := bytes.Clone(data)
+	if value == nil {
+		value = []byte{}
+	}
+	t.writes[string(key)] = batchOp{key: bytes.Clone(key), value: value}
+	return nil
+}
+
+// Delete stages a key removal in the transaction.
+func (t *memTxn) Delete(ctx context.Context, key []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if t.done {
+		return zerokv.ErrTxnDone
+	}
+	t.writes[string(key)] = batchOp{key: bytes.Clone(key), delete: true}
+	return nil
+}
+
+// Commit applies every staged write atomically.
+func (t *memTxn) Commit(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if t.done {
+		return zerokv.ErrTxnDone
+	}
+	t.db.mu.Lock()
+	defer t.db.mu.Unlock()
+	if t.db.closed {
+		return ErrClosed
+	}
+	var ops []batchOp
+	watched := t.db.watchers.Active()
+	for _, op := range t.writes {
+		if op.delete {
+			t.db.remove(op.key)
+		} else {
+			t.db.set(op.key, op.value, 0)
+		}
+		if watched {
+			ops = append(ops, op)
+		}
+	}
+	if watched {
+		t.db.watchers.Publish(opEvents(ops)...)
+	}
+	t.done = true
+	t.writes = nil
+	return nil
+}
+
+// Discard drops the transaction's staged writes.
+func (t *memTxn) Discard() {
+	t.done = true
+	t.writes = nil
+}
+
+// -- Snapshot operations
+
+type memSnapshot struct {
+	mu       sync.RWMutex
+	entries  []entry
+	released bool
+}
+
+// Snapshot copies the entry index under the read lock. Values are never
+// modified in place, so only the slice of entries needs copying.
+func (m *MemDB) Snapshot() (zerokv.Snapshot, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.closed {
+		return nil, ErrClosed
+	}
+	return &memSnapshot{entries: slices.Clone(m.entries)}, nil
+}
+
+// Get retrieves the value for a given key as of the snapshot.
+func (s *memSnapshot) Get(ctx context.Context, key []byte) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.released {
+		return nil, zerokv.ErrSnapshotReleased
+	}
+	e, ok := lookup(s.entries, key)
+	if !ok {
+		return nil, errKeyNotFound
+	}
+	return bytes.Clone(e.value), nil
+}
+
+// Has reports whether a key existed when the snapshot was taken.
+func (s *memSnapshot) Has(ctx context.Context, key []byte) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.released {
+		return false, zerokv.ErrSnapshotReleased
+	}
+	_, ok := lookup(s.entries, key)
+	return ok, nil
+}
+
+// Scan returns an iterator over keys with the given prefix as of the snapshot.
+func (s *memSnapshot) Scan(prefix []byte) zerokv.Iterator {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.released {
+		return zerokv.NewErrIterator(zerokv.ErrSnapshotReleased)
+	}
+	return newMemIterator(s.entries, prefix, nil, nil, false, false)
+}
+
+// Release drops the snapshot's copy of the entries.
+func (s *memSnapshot) Release() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.released = true
+	s.entries = nil
+}
+
+// -- Iterator operations
+
+// Scan returns an iterator over keys with the given prefix.
+func (m *MemDB) Scan(prefix []byte) zerokv.Iterator {
+	return m.newIterator(prefix, nil, nil, false, false)
+}
+
+// ReverseScan returns an iterator over keys with the given prefix in descending order.
+func (m *MemDB) ReverseScan(prefix []byte) zerokv.Iterator {
+	return m.newIterator(prefix, nil, nil, true, false)
+}
+
+// RangeScan returns an iterator over keys in [start, end).
+func (m *MemDB) RangeScan(start, end []byte) zerokv.Iterator {
+	return m.newIterator(nil, start, end, false, false)
+}
+
+// ScanFrom returns an iterator over keys with the given prefix, starting at
+// the first one >= start.
+func (m *MemDB) ScanFrom(prefix, start []byte) zerokv.Iterator {
+	return m.newIterator(prefix, start, nil, false, false)
+}
+
+// ScanKeys returns an iterator over keys with the given prefix without values.
+func (m *MemDB) ScanKeys(prefix []byte) zerokv.Iterator {
+	return m.newIterator(prefix, nil, nil, false, true)
+}
+
+// ScanContext returns a prefix iterator that stops once ctx is done.
+func (m *MemDB) ScanContext(ctx context.Context, prefix []byte) zerokv.Iterator {
+	return zerokv.NewContextIterator(ctx, m.Scan(prefix))
+}
+
+// newIterator snapshots the entries matching prefix and [start, end).
+func (m *MemDB) newIterator(prefix, start, end []byte, reverse, keysOnly bool) zerokv.Iterator {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.closed {
+		return &memIterator{err: []error{ErrClosed}}
+	}
+	return newMemIterator(m.entries, prefix, start, end, reverse, keysOnly)
+}
+
+// newMemIterator copies the entries matching prefix and [start, end) out of
+// a sorted entry slice. Callers must keep entries from changing meanwhile.
+func newMemIterator(entries []entry, prefix, start, end []byte, reverse, keysOnly bool) *memIterator {
+	it := &memIterator{reverse: reverse, keysOnly: keysOnly}
+	if bytes.Compare(start, prefix) < 0 {
+		start = prefix
+	}
+	lo, _ := search(entries, start)
+	hi := len(entries)
+	if end != nil {
+		hi, _ = search(entries, end)
+	}
+	now := time.Now().UnixNano()
+	for i := lo; i < hi && bytes.HasPrefix(entries[i].key, prefix); i++ {
+		if entries[i].live(now) {
+			it.entries = append(it.entries, entries[i])
+		}
+	}
+	if reverse {
+		for i, j := 0, len(it.entries)-1; i < j; i, j = i+1, j-1 {
+			it.entries[i], it.entries[j] = it.entries[j], it.entries[i]
+		}
+	}
+	return it
+}
+
+func (it *memIterator) Next() bool {
+	if !it.started {
+		it.pos = 0
+		it.started = true
+	} else if it.pos < len(it.entries) {
+		it.pos++
+	}
+	it.valid = it.pos < len(it.entries)
+	return it.valid
+}
+
+// Seek moves to the first key >= key, or the last key <= key when reversed.
+func (it *memIterator) Seek(key []byte) bool {
+	it.pos = sort.Search(len(it.entries), func(i int) bool {
+		cmp := bytes.Compare(it.entries[i].key, key)
+		if it.reverse {
+			return cmp <= 0
+		}
+		return cmp >= 0
+	})
+	it.started = true
+	it.valid = it.pos < len(it.entries)
+	return it.valid
+}
+
+// First moves to the smallest key, which is the last entry when reversed.
+func (it *memIterator) First() bool {
+	if it.reverse {
+		return it.moveTo(len(it.entries) - 1)
+	}
+	return it.moveTo(0)
+}
+
+// Last moves to the greatest key, which is the first entry when reversed.
+func (it *memIterator) Last() bool {
+	if it.reverse {
+		return it.moveTo(0)
+	}
+	return it.moveTo(len(it.entries) - 1)
+}
+
+// moveTo positions the iterator on entry pos; a position outside the
+// entries, as for an empty iterator, leaves it invalid.
+func (it *memIterator) moveTo(pos int) bool {
+	it.started = true
+	it.valid = pos >= 0 && pos < len(it.entries)
+	if it.valid {
+		it.pos = pos
+	} else {
+		it.pos = len(it.entries)
+	}
+	return it.valid
+}
+
+func (it *memIterator) Valid() bool {
+	return it.valid
+}
+
+func (it *memIterator) Key() []byte {
+	if !it.valid {
+		return nil
+	}
+	return bytes.Clone(it.entries[it.pos].key)
+}
+
+func (it *memIterator) Value() []byte {
+	if !it.valid || it.keysOnly {
+		return nil
+	}
+	return bytes.Clone(it.entries[it.pos].value)
+}
+
+// Release drops the iterator's snapshot.
+func (it *memIterator) Release() {
+	it.valid = false
+	it.entries = nil
+}
+
+func (it *memIterator) Error() error {
+	return errors.Join(it.err...)
+}
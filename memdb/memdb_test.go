@@ -0,0 +1,118 @@
+package memdb_test
+
+import (
+	"testing"
+
+	"github.com/rawbytedev/zerokv/helpers"
+	"github.com/rawbytedev/zerokv/memdb"
+	"github.com/stretchr/testify/require"
+)
+
+// TestMemBatchOperations tests batch Put and Get operations.
+func TestMemBatchOperations(t *testing.T) {
+	db := memdb.New()
+	batch := db.Batch()
+	keys := make([][]byte, 5)
+	values := make([][]byte, 5)
+	for i := 0; i < 5; i++ {
+		keys[i] = helpers.RandomBytes(16)
+		values[i] = helpers.RandomBytes(32)
+		err := batch.Put(keys[i], values[i])
+		require.NoError(t, err, "Error adding Put operation to batch")
+	}
+	// nothing is visible before commit
+	_, err := db.Get(t.Context(), keys[0])
+	require.ErrorIs(t, err, memdb.ErrNotFound)
+	err = batch.Commit(t.Context())
+	require.NoError(t, err, "Error committing batch operations")
+	for i := 0; i < 5; i++ {
+		retrievedValue, err := db.Get(t.Context(), keys[i])
+		require.NoError(t, err, "Error getting value after batch commit")
+		require.Equal(t, values[i], retrievedValue, "Retrieved value does not match expected after batch commit")
+	}
+	// This should fail because the batch has already been committed
+	err = batch.Put(keys[0], values[1])
+	require.ErrorIs(t, err, memdb.ErrBatchCommitted)
+	err = batch.Commit(t.Context())
+	require.ErrorIs(t, err, memdb.ErrBatchCommitted)
+	defer db.Close()
+}
+
+// TestMemStoresCopies verifies that callers reusing buffers don't corrupt stored data.
+func TestMemStoresCopies(t *testing.T) {
+	db := memdb.New()
+	defer db.Close()
+	key := []byte("key")
+	value := []byte("value")
+	require.NoError(t, db.Put(t.Context(), key, value))
+	key[0], value[0] = 'X', 'X'
+	got, err := db.Get(t.Context(), []byte("key"))
+	require.NoError(t, err)
+	require.Equal(t, []byte("value"), got)
+	got[0] = 'Y'
+	got, err = db.Get(t.Context(), []byte("key"))
+	require.NoError(t, err)
+	require.Equal(t, []byte("value"), got, "Modifying a returned value must not affect the store")
+}
+
+// TestMemIteratorSnapshot verifies iterators don't observe writes made after creation.
+func TestMemIteratorSnapshot(t *testing.T) {
+	db := memdb.New()
+	defer db.Close()
+	require.NoError(t, db.Put(t.Context(), []byte("pre_1"), []byte("old")))
+	require.NoError(t, db.Put(t.Context(), []byte("pre_3"), []byte("old")))
+	it := db.Scan([]byte("pre_"))
+	require.NoError(t, db.Put(t.Context(), []byte("pre_1"), []byte("new")))
+	require.NoError(t, db.Put(t.Context(), []byte("pre_2"), []byte("new")))
+	require.NoError(t, db.Delete(t.Context(), []byte("pre_3")))
+
+	require.True(t, it.Next())
+	require.Equal(t, []byte("pre_1"), it.Key())
+	require.Equal(t, []byte("old"), it.Value())
+	require.True(t, it.Next())
+	require.Equal(t, []byte("pre_3"), it.Key())
+	require.False(t, it.Next())
+	it.Release()
+}
+
+// TestMemClosed verifies operations fail cleanly after Close.
+func TestMemClosed(t *testing.T) {
+	db := memdb.New()
+	require.NoError(t, db.Put(t.Context(), []byte("key"), []byte("value")))
+	require.NoError(t, db.Close())
+	_, err := db.Get(t.Context(), []byte("key"))
+	require.ErrorIs(t, err, memdb.ErrClosed)
+	require.ErrorIs(t, db.Put(t.Context(), []byte("key"), nil), memdb.ErrClosed)
+	it := db.Scan(nil)
+	require.False(t, it.Next())
+	require.ErrorIs(t, it.Error(), memdb.ErrClosed)
+	it.Release()
+}
+
+// TestMemStats verifies Stats reports exact key and byte counts.
+func TestMemStats(t *testing.T) {
+	db := memdb.New()
+	defer db.Close()
+	require.NoError(t, db.Put(t.Context(), []byte("ab"), []byte("cde")))
+	require.NoError(t, db.Put(t.Context(), []byte("f"), []byte("gh")))
+	stats, err := db.Stats(t.Context())
+	require.NoError(t, err)
+	require.Equal(t, int64(2), stats.KeyCount)
+	require.Equal(t, int64(8), stats.MemtableBytes)
+	require.Zero(t, stats.OnDiskBytes)
+}
+
+// TestMemEstimateSize verifies EstimateSize counts key and value bytes exactly.
+func TestMemEstimateSize(t *testing.T) {
+	db := memdb.New()
+	defer db.Close()
+	require.NoError(t, db.Put(t.Context(), []byte("a1"), []byte("xyz")))
+	require.NoError(t, db.Put(t.Context(), []byte("a2"), []byte("x")))
+	require.NoError(t, db.Put(t.Context(), []byte("b"), []byte("xyz")))
+	size, err := db.EstimateSize([]byte("a"))
+	require.NoError(t, err)
+	require.Equal(t, int64(8), size)
+	size, err = db.EstimateSize(nil)
+	require.NoError(t, err)
+	require.Equal(t, int64(12), size)
+}
@@ -0,0 +1,19 @@
+package zerokv
+
+// AppendMerge is a merge function for Core.Merge that appends operand to the
+// existing value, for event logs and other append-only values. It always
+// returns a new slice and never writes into existing.
+func AppendMerge(existing, operand []byte) []byte {
+	out := make([]byte, 0, len(existing)+len(operand))
+	out = append(out, existing...)
+	return append(out, operand...)
+}
+
+// SumInt64Merge is a merge function for Core.Merge that adds two counters
+// in the format of EncodeCounter. A missing existing value counts as zero,
+// and so does any operand or existing value that is not a valid counter.
+func SumInt64Merge(existing, operand []byte) []byte {
+	a, _ := DecodeCounter(existing)
+	b, _ := DecodeCounter(operand)
+	return EncodeCounter(a + b)
+}
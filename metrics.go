@@ -0,0 +1,469 @@
+package zerokv
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Metric names registered by WithMetrics. Both carry an "op" label naming
+// the operation: the Core method in snake_case ("get", "put_many",
+// "delete_prefix", ...), "scan" for opening any iterator, "iterator_next"
+// for each Next or Seek, "batch_commit" and "txn_commit".
+const (
+	MetricOperationDuration = "zerokv_operation_duration_seconds"
+	MetricOperationErrors   = "zerokv_operation_errors_total"
+)
+
+type metrics struct {
+	duration *prometheus.HistogramVec
+	errors   *prometheus.CounterVec
+}
+
+// metricsCore times every call to the wrapped Core.
+type metricsCore struct {
+	core Core
+	m    *metrics
+}
+
+// WithMetrics returns a Core that records the latency and failures of every
+// call to core in reg, or in prometheus.DefaultRegisterer when reg is nil.
+// Several Cores can share one registry: the collectors are registered once
+// and reused. Missing keys are not counted as errors.
+//
+// Iterators, batches and transactions are wrapped too, so iterator Next
+// calls and commits are timed. Close, Snapshot and Watch are forwarded
+// without timing.
+func WithMetrics(core Core, reg prometheus.Registerer) Core {
+	if reg == nil {
+		reg = prometheus.DefaultRegisterer
+	}
+	m := &metrics{
+		duration: register(reg, prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    MetricOperationDuration,
+			Help:    "Duration of zerokv operations in seconds.",
+			Buckets: prometheus.ExponentialBuckets(0.00001, 4, 10), // 10µs to ~2.6s
+		}, []string{"op"})),
+		errors: register(reg, prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: MetricOperationErrors,
+			Help: "Number of zerokv operations that returned an error.",
+		}, []string{"op"})),
+	}
+	return &metricsCore{core: core, m: m}
+}
+
+// register registers c, or returns the collector already registered under
+// the same description.
+func register[C prometheus.Collector](reg prometheus.Registerer, c C) C {
+	if err := reg.Register(c); err != nil {
+		var are prometheus.AlreadyRegisteredError
+		if errors.As(err, &are) {
+			if existing, ok := are.ExistingCollector.(C); ok {
+				return existing
+			}
+		}
+		panic(err)
+	}
+	return c
+}
+
+// observe records one call to op that started at start and returned err.
+func (m *metrics) observe(op string, start time.Time, err error) {
+	m.duration.WithLabelValues(op).Observe(time.Since(start).Seconds())
+	if err != nil && !errors.Is(err, ErrKeyNotFound) {
+		m.errors.WithLabelValues(op).Inc()
+	}
+}
+
+func (c *metricsCore) Put(ctx context.Context, key []byte, data []byte) error {
+	start := time.Now()
+	err := c.core.Put(ctx, key, data)
+	c.m.observe("put", start, err)
+	return err
+}
+
+func (c *metricsCore) PutWithTTL(ctx context.Context, key, value []byte, ttl time.Duration) error {
+	start := time.Now()
+	err := c.core.PutWithTTL(ctx, key, value, ttl)
+	c.m.observe("put_with_ttl", start, err)
+	return err
+}
+
+func (c *metricsCore) Get(ctx context.Context, key []byte) ([]byte, error) {
+	start := time.Now()
+	value, err := c.core.Get(ctx, key)
+	c.m.observe("get", start, err)
+	return value, err
+}
+
+func (c *metricsCore) GetOrDefault(ctx context.Context, key, def []byte) ([]byte, error) {
+	start := time.Now()
+	value, err := c.core.GetOrDefault(ctx, key, def)
+	c.m.observe("get_or_default", start, err)
+	return value, err
+}
+
+func (c *metricsCore) GetInto(ctx context.Context, key, dst []byte) ([]byte, error) {
+	start := time.Now()
+	value, err := c.core.GetInto(ctx, key, dst)
+	c.m.observe("get_into", start, err)
+	return value, err
+}
+
+func (c *metricsCore) View(ctx context.Context, key []byte, fn func(value []byte) error) error {
+	start := time.Now()
+	err := c.core.View(ctx, key, fn)
+	c.m.observe("view", start, err)
+	return err
+}
+
+func (c *metricsCore) Has(ctx context.Context, key []byte) (bool, error) {
+	start := time.Now()
+	ok, err := c.core.Has(ctx, key)
+	c.m.observe("has", start, err)
+	return ok, err
+}
+
+func (c *metricsCore) SizeOf(ctx context.Context, key []byte) (int, error) {
+	start := time.Now()
+	n, err := c.core.SizeOf(ctx, key)
+	c.m.observe("size_of", start, err)
+	return n, err
+}
+
+func (c *metricsCore) GetMany(ctx context.Context, keys [][]byte) ([][]byte, error) {
+	start := time.Now()
+	values, err := c.core.GetMany(ctx, keys)
+	c.m.observe("get_many", start, err)
+	return values, err
+}
+
+func (c *metricsCore) HasMany(ctx context.Context, keys [][]byte) ([]bool, error) {
+	start := time.Now()
+	found, err := c.core.HasMany(ctx, keys)
+	c.m.observe("has_many", start, err)
+	return found, err
+}
+
+func (c *metricsCore) PutMany(ctx context.Context, keys, values [][]byte) error {
+	start := time.Now()
+	err := c.core.PutMany(ctx, keys, values)
+	c.m.observe("put_many", start, err)
+	return err
+}
+
+func (c *metricsCore) Delete(ctx context.Context, key []byte) error {
+	start := time.Now()
+	err := c.core.Delete(ctx, key)
+	c.m.observe("delete", start, err)
+	return err
+}
+
+func (c *metricsCore) DeletePrefix(ctx context.Context, prefix []byte) (int, error) {
+	start := time.Now()
+	n, err := c.core.DeletePrefix(ctx, prefix)
+	c.m.observe("delete_prefix", start, err)
+	return n, err
+}
+
+func (c *metricsCore) DeleteRange(ctx context.Context, start, end []byte) error {
+	begin := time.Now()
+	err := c.core.DeleteRange(ctx, start, end)
+	c.m.observe("delete_range", begin, err)
+	return err
+}
+
+func (c *metricsCore) Truncate(ctx context.Context) error {
+	begin := time.Now()
+	err := c.core.Truncate(ctx)
+	c.m.observe("truncate", begin, err)
+	return err
+}
+
+func (c *metricsCore) TruncatePrefix(ctx context.Context, prefix []byte) error {
+	start := time.Now()
+	err := c.core.TruncatePrefix(ctx, prefix)
+	c.m.observe("truncate_prefix", start, err)
+	return err
+}
+
+func (c *metricsCore) Count(ctx context.Context, prefix []byte) (int64, error) {
+	start := time.Now()
+	n, err := c.core.Count(ctx, prefix)
+	c.m.observe("count", start, err)
+	return n, err
+}
+
+func (c *metricsCore) EstimateSize(prefix []byte) (int64, error) {
+	start := time.Now()
+	n, err := c.core.EstimateSize(prefix)
+	c.m.observe("estimate_size", start, err)
+	return n, err
+}
+
+func (c *metricsCore) NewTransaction(ctx context.Context) (Txn, error) {
+	start := time.Now()
+	txn, err := c.core.NewTransaction(ctx)
+	c.m.observe("new_transaction", start, err)
+	if err != nil {
+		return nil, err
+	}
+	return &metricsTxn{txn: txn, m: c.m}, nil
+}
+
+func (c *metricsCore) CompareAndSwap(ctx context.Context, key, old, new []byte) (bool, error) {
+	start := time.Now()
+	swapped, err := c.core.CompareAndSwap(ctx, key, old, new)
+	c.m.observe("compare_and_swap", start, err)
+	return swapped, err
+}
+
+func (c *metricsCore) Increment(ctx context.Context, key []byte, delta int64) (int64, error) {
+	start := time.Now()
+	n, err := c.core.Increment(ctx, key, delta)
+	c.m.observe("increment", start, err)
+	return n, err
+}
+
+func (c *metricsCore) GetOrPut(ctx context.Context, key []byte, fill func() ([]byte, error)) ([]byte, error) {
+	start := time.Now()
+	value, err := c.core.GetOrPut(ctx, key, fill)
+	c.m.observe("get_or_put", start, err)
+	return value, err
+}
+
+func (c *metricsCore) Merge(ctx context.Context, key, operand []byte, merge func(existing, operand []byte) []byte) error {
+	start := time.Now()
+	err := c.core.Merge(ctx, key, operand, merge)
+	c.m.observe("merge", start, err)
+	return err
+}
+
+func (c *metricsCore) Stats(ctx context.Context) (Stats, error) {
+	start := time.Now()
+	stats, err := c.core.Stats(ctx)
+	c.m.observe("stats", start, err)
+	return stats, err
+}
+
+func (c *metricsCore) Sync(ctx context.Context) error {
+	start := time.Now()
+	err := c.core.Sync(ctx)
+	c.m.observe("sync", start, err)
+	return err
+}
+
+func (c *metricsCore) Compact(ctx context.Context, start, end []byte) error {
+	begin := time.Now()
+	err := c.core.Compact(ctx, start, end)
+	c.m.observe("compact", begin, err)
+	return err
+}
+
+func (c *metricsCore) Ping(ctx context.Context) error {
+	start := time.Now()
+	err := c.core.Ping(ctx)
+	c.m.observe("ping", start, err)
+	return err
+}
+
+func (c *metricsCore) Backup(ctx context.Context, w io.Writer) error {
+	start := time.Now()
+	err := c.core.Backup(ctx, w)
+	c.m.observe("backup", start, err)
+	return err
+}
+
+func (c *metricsCore) Restore(ctx context.Context, r io.Reader) error {
+	start := time.Now()
+	err := c.core.Restore(ctx, r)
+	c.m.observe("restore", start, err)
+	return err
+}
+
+func (c *metricsCore) Checkpoint(ctx context.Context, destDir string) error {
+	start := time.Now()
+	err := c.core.Checkpoint(ctx, destDir)
+	c.m.observe("checkpoint", start, err)
+	return err
+}
+
+func (c *metricsCore) Snapshot() (Snapshot, error) {
+	return c.core.Snapshot()
+}
+
+func (c *metricsCore) Batch() Batch {
+	return &metricsBatch{batch: c.core.Batch(), m: c.m}
+}
+
+func (c *metricsCore) BulkLoad(ctx context.Context, fn func(b Batch) error) error {
+	start := time.Now()
+	err := c.core.BulkLoad(ctx, fn)
+	c.m.observe("bulk_load", start, err)
+	return err
+}
+
+func (c *metricsCore) BatchWithOptions(maxOps, maxBytes int) Batch {
+	return &metricsBatch{batch: c.core.BatchWithOptions(maxOps, maxBytes), m: c.m}
+}
+
+func (c *metricsCore) Scan(prefix []byte) Iterator {
+	start := time.Now()
+	return c.iterator(start, c.core.Scan(prefix))
+}
+
+func (c *metricsCore) ReverseScan(prefix []byte) Iterator {
+	start := time.Now()
+	return c.iterator(start, c.core.ReverseScan(prefix))
+}
+
+func (c *metricsCore) RangeScan(start, end []byte) Iterator {
+	begin := time.Now()
+	return c.iterator(begin, c.core.RangeScan(start, end))
+}
+
+func (c *metricsCore) ScanFrom(prefix, start []byte) Iterator {
+	begin := time.Now()
+	return c.iterator(begin, c.core.ScanFrom(prefix, start))
+}
+
+func (c *metricsCore) ScanKeys(prefix []byte) Iterator {
+	start := time.Now()
+	return c.iterator(start, c.core.ScanKeys(prefix))
+}
+
+func (c *metricsCore) ScanContext(ctx context.Context, prefix []byte) Iterator {
+	start := time.Now()
+	return c.iterator(start, c.core.ScanContext(ctx, prefix))
+}
+
+func (c *metricsCore) Watch(ctx context.Context, prefix []byte) (<-chan Event, error) {
+	return c.core.Watch(ctx, prefix)
+}
+
+func (c *metricsCore) Close() error {
+	return c.core.Close()
+}
+
+// Unwrap returns the wrapped Core.
+func (c *metricsCore) Unwrap() Core {
+	return c.core
+}
+
+// iterator records the time taken to open it, then wraps it so each step
+// is timed.
+func (c *metricsCore) iterator(start time.Time, it Iterator) Iterator {
+	c.m.observe("scan", start, nil)
+	return &metricsIterator{it: it, m: c.m}
+}
+
+// metricsIterator times Next and Seek, and counts the iterator's error
+// once when it is released.
+type metricsIterator struct {
+	it Iterator
+	m  *metrics
+}
+
+func (it *metricsIterator) Next() bool {
+	start := time.Now()
+	ok := it.it.Next()
+	it.m.observe("iterator_next", start, nil)
+	return ok
+}
+
+func (it *metricsIterator) Seek(key []byte) bool {
+	start := time.Now()
+	ok := it.it.Seek(key)
+	it.m.observe("iterator_next", start, nil)
+	return ok
+}
+
+func (it *metricsIterator) First() bool {
+	start := time.Now()
+	ok := it.it.First()
+	it.m.observe("iterator_next", start, nil)
+	return ok
+}
+
+func (it *metricsIterator) Last() bool {
+	start := time.Now()
+	ok := it.it.Last()
+	it.m.observe("iterator_next", start, nil)
+	return ok
+}
+
+func (it *metricsIterator) Valid() bool   { return it.it.Valid() }
+func (it *metricsIterator) Key() []byte   { return it.it.Key() }
+func (it *metricsIterator) Value() []byte { return it.it.Value() }
+func (it *metricsIterator) Error() error  { return it.it.Error() }
+
+func (it *metricsIterator) Release() {
+	if it.it.Error() != nil {
+		it.m.errors.WithLabelValues("scan").Inc()
+	}
+	it.it.Release()
+}
+
+// metricsBatch times Commit; staging writes is not timed.
+type metricsBatch struct {
+	batch Batch
+	m     *metrics
+}
+
+func (b *metricsBatch) Put(key []byte, data []byte) error  { return b.batch.Put(key, data) }
+func (b *metricsBatch) Delete(key []byte) error            { return b.batch.Delete(key) }
+func (b *metricsBatch) PutIfAbsent(key, data []byte) error { return b.batch.PutIfAbsent(key, data) }
+func (b *metricsBatch) Len() int                           { return b.batch.Len() }
+func (b *metricsBatch) Size() int                          { return b.batch.Size() }
+func (b *metricsBatch) Reset()                             { b.batch.Reset() }
+func (b *metricsBatch) Validate() error                    { return b.batch.Validate() }
+
+func (b *metricsBatch) PutCtx(ctx context.Context, key []byte, data []byte) error {
+	if cb, ok := b.batch.(ContextBatch); ok {
+		return cb.PutCtx(ctx, key, data)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return b.batch.Put(key, data)
+}
+
+func (b *metricsBatch) DeleteCtx(ctx context.Context, key []byte) error {
+	if cb, ok := b.batch.(ContextBatch); ok {
+		return cb.DeleteCtx(ctx, key)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return b.batch.Delete(key)
+}
+
+func (b *metricsBatch) Commit(ctx context.Context) error {
+	start := time.Now()
+	err := b.batch.Commit(ctx)
+	b.m.observe("batch_commit", start, err)
+	return err
+}
+
+// metricsTxn times Commit; reads and staged writes are not timed.
+type metricsTxn struct {
+	txn Txn
+	m   *metrics
+}
+
+func (t *metricsTxn) Get(ctx context.Context, key []byte) ([]byte, error) { return t.txn.Get(ctx, key) }
+func (t *metricsTxn) Put(ctx context.Context, key []byte, data []byte) error {
+	return t.txn.Put(ctx, key, data)
+}
+func (t *metricsTxn) Delete(ctx context.Context, key []byte) error { return t.txn.Delete(ctx, key) }
+func (t *metricsTxn) Discard()                                     { t.txn.Discard() }
+
+func (t *metricsTxn) Commit(ctx context.Context) error {
+	start := time.Now()
+	err := t.txn.Commit(ctx)
+	t.m.observe("txn_commit", start, err)
+	return err
+}
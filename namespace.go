@@ -0,0 +1,451 @@
+package zerokv
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"time"
+)
+
+// namespace is a Core that stores every key under a fixed prefix.
+type namespace struct {
+	core   Core
+	prefix []byte
+}
+
+// Namespace returns a Core that prepends prefix to every key it writes and
+// strips it from every key it returns, so callers see clean keys and cannot
+// reach keys outside the namespace. Namespaces nest: Namespace(Namespace(db,
+// a), b) stores keys under a+b.
+//
+// Stats and Sync act on the whole underlying database. Close does nothing,
+// because the underlying Core is shared and must be closed by its owner.
+func Namespace(core Core, prefix []byte) Core {
+	if ns, ok := core.(*namespace); ok {
+		return &namespace{core: ns.core, prefix: ns.key(prefix)}
+	}
+	return &namespace{core: core, prefix: append([]byte(nil), prefix...)}
+}
+
+// key returns k with the namespace prefix prepended, in a new slice.
+func (ns *namespace) key(k []byte) []byte {
+	full := make([]byte, 0, len(ns.prefix)+len(k))
+	full = append(full, ns.prefix...)
+	return append(full, k...)
+}
+
+func (ns *namespace) keys(keys [][]byte) [][]byte {
+	full := make([][]byte, len(keys))
+	for i := range keys {
+		full[i] = ns.key(keys[i])
+	}
+	return full
+}
+
+// bounds maps a range inside the namespace to the underlying keyspace; nil
+// bounds become the edges of the namespace.
+func (ns *namespace) bounds(start, end []byte) ([]byte, []byte) {
+	start = ns.key(start)
+	if end == nil {
+		end = PrefixUpperBound(ns.prefix)
+	} else {
+		end = ns.key(end)
+	}
+	return start, end
+}
+
+func (ns *namespace) Put(ctx context.Context, key []byte, data []byte) error {
+	return ns.core.Put(ctx, ns.key(key), data)
+}
+
+func (ns *namespace) PutWithTTL(ctx context.Context, key, value []byte, ttl time.Duration) error {
+	return ns.core.PutWithTTL(ctx, ns.key(key), value, ttl)
+}
+
+func (ns *namespace) Get(ctx context.Context, key []byte) ([]byte, error) {
+	return ns.core.Get(ctx, ns.key(key))
+}
+
+func (ns *namespace) GetOrDefault(ctx context.Context, key, def []byte) ([]byte, error) {
+	return ns.core.GetOrDefault(ctx, ns.key(key), def)
+}
+
+func (ns *namespace) GetInto(ctx context.Context, key, dst []byte) ([]byte, error) {
+	return ns.core.GetInto(ctx, ns.key(key), dst)
+}
+
+func (ns *namespace) View(ctx context.Context, key []byte, fn func(value []byte) error) error {
+	return ns.core.View(ctx, ns.key(key), fn)
+}
+
+func (ns *namespace) Has(ctx context.Context, key []byte) (bool, error) {
+	return ns.core.Has(ctx, ns.key(key))
+}
+
+func (ns *namespace) SizeOf(ctx context.Context, key []byte) (int, error) {
+	return ns.core.SizeOf(ctx, ns.key(key))
+}
+
+func (ns *namespace) GetMany(ctx context.Context, keys [][]byte) ([][]byte, error) {
+	return ns.core.GetMany(ctx, ns.keys(keys))
+}
+
+func (ns *namespace) HasMany(ctx context.Context, keys [][]byte) ([]bool, error) {
+	return ns.core.HasMany(ctx, ns.keys(keys))
+}
+
+func (ns *namespace) PutMany(ctx context.Context, keys, values [][]byte) error {
+	return ns.core.PutMany(ctx, ns.keys(keys), values)
+}
+
+func (ns *namespace) Delete(ctx context.Context, key []byte) error {
+	return ns.core.Delete(ctx, ns.key(key))
+}
+
+func (ns *namespace) DeletePrefix(ctx context.Context, prefix []byte) (int, error) {
+	return ns.core.DeletePrefix(ctx, ns.key(prefix))
+}
+
+func (ns *namespace) DeleteRange(ctx context.Context, start, end []byte) error {
+	start, end = ns.bounds(start, end)
+	return ns.core.DeleteRange(ctx, start, end)
+}
+
+// Truncate removes every key in the namespace, leaving other keys alone.
+func (ns *namespace) Truncate(ctx context.Context) error {
+	_, err := ns.core.DeletePrefix(ctx, ns.prefix)
+	return err
+}
+
+func (ns *namespace) TruncatePrefix(ctx context.Context, prefix []byte) error {
+	return ns.core.TruncatePrefix(ctx, ns.key(prefix))
+}
+
+func (ns *namespace) Count(ctx context.Context, prefix []byte) (int64, error) {
+	return ns.core.Count(ctx, ns.key(prefix))
+}
+
+func (ns *namespace) EstimateSize(prefix []byte) (int64, error) {
+	return ns.core.EstimateSize(ns.key(prefix))
+}
+
+func (ns *namespace) NewTransaction(ctx context.Context) (Txn, error) {
+	txn, err := ns.core.NewTransaction(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &namespaceTxn{txn: txn, ns: ns}, nil
+}
+
+func (ns *namespace) CompareAndSwap(ctx context.Context, key, old, new []byte) (bool, error) {
+	return ns.core.CompareAndSwap(ctx, ns.key(key), old, new)
+}
+
+func (ns *namespace) Increment(ctx context.Context, key []byte, delta int64) (int64, error) {
+	return ns.core.Increment(ctx, ns.key(key), delta)
+}
+
+func (ns *namespace) GetOrPut(ctx context.Context, key []byte, fill func() ([]byte, error)) ([]byte, error) {
+	return ns.core.GetOrPut(ctx, ns.key(key), fill)
+}
+
+func (ns *namespace) Merge(ctx context.Context, key, operand []byte, merge func(existing, operand []byte) []byte) error {
+	return ns.core.Merge(ctx, ns.key(key), operand, merge)
+}
+
+// Stats reports metrics for the whole underlying database.
+func (ns *namespace) Stats(ctx context.Context) (Stats, error) {
+	return ns.core.Stats(ctx)
+}
+
+// Sync syncs the whole underlying database.
+func (ns *namespace) Sync(ctx context.Context) error {
+	return ns.core.Sync(ctx)
+}
+
+func (ns *namespace) Compact(ctx context.Context, start, end []byte) error {
+	start, end = ns.bounds(start, end)
+	return ns.core.Compact(ctx, start, end)
+}
+
+// Ping pings the underlying database.
+func (ns *namespace) Ping(ctx context.Context) error {
+	return ns.core.Ping(ctx)
+}
+
+// Backup writes the keys of the namespace, without the prefix, in the
+// portable backup format. Iterators do not expose expiries, so every entry
+// is written without one.
+func (ns *namespace) Backup(ctx context.Context, w io.Writer) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	bw, err := NewBackupWriter(w)
+	if err != nil {
+		return err
+	}
+	it := ns.Scan(nil)
+	defer it.Release()
+	for it.Next() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := bw.Write(it.Key(), it.Value(), 0); err != nil {
+			return err
+		}
+	}
+	if err := it.Error(); err != nil {
+		return err
+	}
+	return bw.Close()
+}
+
+// Restore writes every entry of a portable backup into the namespace.
+// Entries with an expiry are written with PutWithTTL and the time they have
+// left; entries that already expired are skipped.
+func (ns *namespace) Restore(ctx context.Context, r io.Reader) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	br, err := NewBackupReader(r)
+	if err != nil {
+		return err
+	}
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		key, value, expiresAt, err := br.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if expiresAt == 0 {
+			err = ns.Put(ctx, key, value)
+		} else if ttl := time.Until(time.Unix(0, expiresAt)); ttl > 0 {
+			err = ns.PutWithTTL(ctx, key, value, ttl)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// Checkpoint is not supported: a checkpoint copies the whole underlying
+// database, including every other namespace. Use Backup to save just this
+// one.
+func (ns *namespace) Checkpoint(ctx context.Context, destDir string) error {
+	return fmt.Errorf("zerokv: namespace: Checkpoint: %w", ErrNotSupported)
+}
+
+func (ns *namespace) Snapshot() (Snapshot, error) {
+	snap, err := ns.core.Snapshot()
+	if err != nil {
+		return nil, err
+	}
+	return &namespaceSnapshot{snap: snap, ns: ns}, nil
+}
+
+func (ns *namespace) Batch() Batch {
+	return &namespaceBatch{batch: ns.core.Batch(), ns: ns}
+}
+
+func (ns *namespace) BulkLoad(ctx context.Context, fn func(b Batch) error) error {
+	return ns.core.BulkLoad(ctx, func(b Batch) error {
+		return fn(&namespaceBatch{batch: b, ns: ns})
+	})
+}
+
+func (ns *namespace) BatchWithOptions(maxOps, maxBytes int) Batch {
+	return &namespaceBatch{batch: ns.core.BatchWithOptions(maxOps, maxBytes), ns: ns}
+}
+
+func (ns *namespace) Scan(prefix []byte) Iterator {
+	return &namespaceIterator{it: ns.core.Scan(ns.key(prefix)), ns: ns}
+}
+
+func (ns *namespace) ReverseScan(prefix []byte) Iterator {
+	return &namespaceIterator{it: ns.core.ReverseScan(ns.key(prefix)), ns: ns}
+}
+
+func (ns *namespace) RangeScan(start, end []byte) Iterator {
+	start, end = ns.bounds(start, end)
+	return &namespaceIterator{it: ns.core.RangeScan(start, end), ns: ns}
+}
+
+func (ns *namespace) ScanFrom(prefix, start []byte) Iterator {
+	return &namespaceIterator{it: ns.core.ScanFrom(ns.key(prefix), ns.key(start)), ns: ns}
+}
+
+func (ns *namespace) ScanKeys(prefix []byte) Iterator {
+	return &namespaceIterator{it: ns.core.ScanKeys(ns.key(prefix)), ns: ns}
+}
+
+func (ns *namespace) ScanContext(ctx context.Context, prefix []byte) Iterator {
+	return NewContextIterator(ctx, ns.Scan(prefix))
+}
+
+// Watch watches the prefixed keys of the underlying Core and strips the
+// namespace prefix from the events it forwards. Range deletes are clipped to
+// the namespace.
+func (ns *namespace) Watch(ctx context.Context, prefix []byte) (<-chan Event, error) {
+	in, err := ns.core.Watch(ctx, ns.key(prefix))
+	if err != nil {
+		return nil, err
+	}
+	out := make(chan Event)
+	go func() {
+		defer close(out)
+		for ev := range in {
+			select {
+			case out <- ns.event(ev):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out, nil
+}
+
+// event maps an event from the underlying keyspace into the namespace.
+func (ns *namespace) event(ev Event) Event {
+	ev.Key = ns.strip(ev.Key)
+	if ev.Op == OpDeleteRange && ev.End != nil {
+		if end := PrefixUpperBound(ns.prefix); end != nil && bytes.Compare(ev.End, end) >= 0 {
+			ev.End = nil
+		} else {
+			ev.End = ns.strip(ev.End)
+		}
+	}
+	return ev
+}
+
+// strip removes the namespace prefix from k. Keys that sort before the
+// namespace, such as the start of a wider range delete, become empty.
+func (ns *namespace) strip(k []byte) []byte {
+	if !bytes.HasPrefix(k, ns.prefix) {
+		return []byte{}
+	}
+	return k[len(ns.prefix):]
+}
+
+// Close does nothing; the underlying Core is closed by its owner.
+func (ns *namespace) Close() error {
+	return nil
+}
+
+// Unwrap returns the Core holding every namespace.
+func (ns *namespace) Unwrap() Core {
+	return ns.core
+}
+
+// namespaceIterator strips the namespace prefix from the keys it returns.
+type namespaceIterator struct {
+	it Iterator
+	ns *namespace
+}
+
+func (it *namespaceIterator) Next() bool           { return it.it.Next() }
+func (it *namespaceIterator) Seek(key []byte) bool { return it.it.Seek(it.ns.key(key)) }
+func (it *namespaceIterator) First() bool          { return it.it.First() }
+func (it *namespaceIterator) Last() bool           { return it.it.Last() }
+func (it *namespaceIterator) Valid() bool          { return it.it.Valid() }
+func (it *namespaceIterator) Value() []byte        { return it.it.Value() }
+func (it *namespaceIterator) Release()             { it.it.Release() }
+func (it *namespaceIterator) Error() error         { return it.it.Error() }
+
+func (it *namespaceIterator) Key() []byte {
+	key := it.it.Key()
+	if len(key) < len(it.ns.prefix) {
+		return key
+	}
+	return key[len(it.ns.prefix):]
+}
+
+// namespaceBatch prefixes the keys of every staged write.
+type namespaceBatch struct {
+	batch Batch
+	ns    *namespace
+}
+
+func (b *namespaceBatch) Put(key []byte, data []byte) error {
+	return b.batch.Put(b.ns.key(key), data)
+}
+
+func (b *namespaceBatch) Delete(key []byte) error {
+	return b.batch.Delete(b.ns.key(key))
+}
+
+func (b *namespaceBatch) PutIfAbsent(key, data []byte) error {
+	return b.batch.PutIfAbsent(b.ns.key(key), data)
+}
+
+func (b *namespaceBatch) PutCtx(ctx context.Context, key []byte, data []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return b.Put(key, data)
+}
+
+func (b *namespaceBatch) DeleteCtx(ctx context.Context, key []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return b.Delete(key)
+}
+
+func (b *namespaceBatch) Len() int  { return b.batch.Len() }
+func (b *namespaceBatch) Size() int { return b.batch.Size() }
+func (b *namespaceBatch) Reset()    { b.batch.Reset() }
+
+// Validate checks the prefixed keys, so a key limit includes the prefix.
+func (b *namespaceBatch) Validate() error { return b.batch.Validate() }
+
+func (b *namespaceBatch) Commit(ctx context.Context) error {
+	return b.batch.Commit(ctx)
+}
+
+// namespaceTxn prefixes the keys of every transaction read and write.
+type namespaceTxn struct {
+	txn Txn
+	ns  *namespace
+}
+
+func (t *namespaceTxn) Get(ctx context.Context, key []byte) ([]byte, error) {
+	return t.txn.Get(ctx, t.ns.key(key))
+}
+
+func (t *namespaceTxn) Put(ctx context.Context, key []byte, data []byte) error {
+	return t.txn.Put(ctx, t.ns.key(key), data)
+}
+
+func (t *namespaceTxn) Delete(ctx context.Context, key []byte) error {
+	return t.txn.Delete(ctx, t.ns.key(key))
+}
+
+func (t *namespaceTxn) Commit(ctx context.Context) error { return t.txn.Commit(ctx) }
+func (t *namespaceTxn) Discard()                         { t.txn.Discard() }
+
+// namespaceSnapshot reads a snapshot of the underlying database through
+// the namespace.
+type namespaceSnapshot struct {
+	snap Snapshot
+	ns   *namespace
+}
+
+func (s *namespaceSnapshot) Get(ctx context.Context, key []byte) ([]byte, error) {
+	return s.snap.Get(ctx, s.ns.key(key))
+}
+
+func (s *namespaceSnapshot) Has(ctx context.Context, key []byte) (bool, error) {
+	return s.snap.Has(ctx, s.ns.key(key))
+}
+
+func (s *namespaceSnapshot) Scan(prefix []byte) Iterator {
+	return &namespaceIterator{it: s.snap.Scan(s.ns.key(prefix)), ns: s.ns}
+}
+
+func (s *namespaceSnapshot) Release() { s.snap.Release() }
@@ -0,0 +1,64 @@
+package zerokv
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"sync"
+)
+
+// Config holds the backend-independent settings passed to Open.
+type Config struct {
+	Dir string // data directory, ignored by in-memory backends
+	// Params holds backend tuning, such as the query parameters of a DSN
+	// passed to OpenDSN. Backends reject parameters they do not know.
+	Params url.Values
+}
+
+// OpenFunc constructs a Core for a registered backend.
+type OpenFunc func(cfg Config) (Core, error)
+
+var (
+	backendsMu sync.RWMutex
+	backends   = make(map[string]OpenFunc)
+)
+
+// Register makes a backend available to Open under the given name.
+// Backend packages call it from init, so importing a backend (even with a
+// blank import) is enough to select it at runtime. It panics if fn is nil or
+// the name is already registered.
+func Register(name string, fn OpenFunc) {
+	backendsMu.Lock()
+	defer backendsMu.Unlock()
+	if fn == nil {
+		panic("zerokv: Register open func is nil")
+	}
+	if _, dup := backends[name]; dup {
+		panic("zerokv: Register called twice for backend " + name)
+	}
+	backends[name] = fn
+}
+
+// Backends returns the sorted names of the registered backends.
+func Backends() []string {
+	backendsMu.RLock()
+	defer backendsMu.RUnlock()
+	names := make([]string, 0, len(backends))
+	for name := range backends {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Open returns the Core implementation registered under backend, such as
+// "badger", "pebble" or "memory".
+func Open(backend string, cfg Config) (Core, error) {
+	backendsMu.RLock()
+	fn, ok := backends[backend]
+	backendsMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown backend %q", backend)
+	}
+	return fn(cfg)
+}
@@ -0,0 +1,103 @@
+package zerokv
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ParallelScan calls fn for every key with the given prefix, scanning
+// shards sub-ranges of the prefix concurrently. The keyspace is split on
+// the byte that follows the prefix into shards contiguous ranges of equal
+// width, so shards is capped at 256; a value below 1 means 1. Keys that are
+// not spread evenly over that byte leave some shards with most of the work.
+//
+// fn is called from several goroutines at once and must be safe for
+// concurrent use. Entries arrive in key order within a shard, but there is
+// no ordering across shards. key and value are only valid until fn
+// returns.
+//
+// The first error returned by fn or by an iterator stops every shard;
+// errors from shards that failed at the same time are joined with it. If
+// ctx is done first, ParallelScan returns ctx.Err().
+func ParallelScan(ctx context.Context, core Core, prefix []byte, shards int, fn func(key, value []byte) error) error {
+	shards = min(max(shards, 1), 256)
+	scanCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs []error
+	)
+	for i := range shards {
+		start, end := shardBounds(prefix, i, shards)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := scanShard(scanCtx, core, start, end, fn)
+			// Shards stopped by another shard's failure, or by ctx, report
+			// the cancellation, which is not worth returning.
+			if err == nil || (scanCtx.Err() != nil && errors.Is(err, scanCtx.Err())) {
+				return
+			}
+			mu.Lock()
+			errs = append(errs, err)
+			mu.Unlock()
+			cancel()
+		}()
+	}
+	wg.Wait()
+	if len(errs) == 0 {
+		return ctx.Err()
+	}
+	return errors.Join(errs...)
+}
+
+// shardBounds returns the range of shard i of n under prefix. The first
+// shard starts at the prefix itself, so it also covers a key equal to the
+// prefix, and the last one ends at the prefix's upper bound.
+func shardBounds(prefix []byte, i, n int) (start, end []byte) {
+	if i == 0 {
+		start = append([]byte{}, prefix...)
+	} else {
+		start = append(append([]byte{}, prefix...), byte(i*256/n))
+	}
+	if i == n-1 {
+		end = PrefixUpperBound(prefix)
+	} else {
+		end = append(append([]byte{}, prefix...), byte((i+1)*256/n))
+	}
+	return start, end
+}
+
+// PrefixHistogram counts the keys under prefix grouped by the byte that
+// immediately follows it, which shows how evenly ParallelScan would spread
+// work over its shards. A key equal to the prefix has no next byte and is
+// not counted. Bytes with no keys are absent from the map.
+func PrefixHistogram(core Core, prefix []byte) (map[byte]int64, error) {
+	it := core.ScanKeys(prefix)
+	defer it.Release()
+	hist := make(map[byte]int64)
+	for it.Next() {
+		key := it.Key()
+		if len(key) > len(prefix) {
+			hist[key[len(prefix)]]++
+		}
+	}
+	if err := it.Error(); err != nil {
+		return nil, err
+	}
+	return hist, nil
+}
+
+func scanShard(ctx context.Context, core Core, start, end []byte, fn func(key, value []byte) error) error {
+	it := NewContextIterator(ctx, core.RangeScan(start, end))
+	defer it.Release()
+	for it.Next() {
+		if err := fn(it.Key(), it.Value()); err != nil {
+			return err
+		}
+	}
+	return it.Error()
+}
@@ -0,0 +1,55 @@
+package pebbledb
+
+// LevelMetricsReporter is implemented by backends that expose per-level
+// metrics for their LSM tree. Use a type assertion on a zerokv.Core to
+// access it.
+type LevelMetricsReporter interface {
+	// LevelMetrics returns one entry per level, from L0 down
+	LevelMetrics() []LevelMetric
+}
+
+// LevelMetric describes one level of Pebble's LSM tree. A growing L0
+// ReadAmp, or a Score that stays above 1, means compactions are falling
+// behind the write rate.
+type LevelMetric struct {
+	Level    int     // 0 for L0 through 6 for the bottom level
+	NumFiles int64   // sstables in the level
+	Size     int64   // bytes in the level's sstables
+	Score    float64 // compaction score; at or above 1 the level is due for compaction
+	// ReadAmp is the number of sublevels a read has to consult in this
+	// level: any number in L0, and at most 1 elsewhere
+	ReadAmp int
+	// WriteAmp is the bytes written to the level by flushes and compactions
+	// per byte that entered it
+	WriteAmp       float64
+	BytesIn        uint64 // bytes that entered the level from above
+	BytesRead      uint64 // bytes read by compactions out of the level
+	BytesFlushed   uint64 // bytes written to the level by memtable flushes
+	BytesCompacted uint64 // bytes written to the level by compactions
+}
+
+// LevelMetrics returns metrics for each level of the LSM tree, taken from
+// pebble's db.Metrics. It returns nil once the database is closed.
+func (p *PebbleDB) LevelMetrics() []LevelMetric {
+	if p.closed.Load() {
+		return nil
+	}
+	m := p.db.Metrics()
+	levels := make([]LevelMetric, len(m.Levels))
+	for i := range m.Levels {
+		l := &m.Levels[i]
+		levels[i] = LevelMetric{
+			Level:          i,
+			NumFiles:       l.NumFiles,
+			Size:           l.Size,
+			Score:          l.Score,
+			ReadAmp:        int(l.Sublevels),
+			WriteAmp:       l.WriteAmp(),
+			BytesIn:        l.BytesIn,
+			BytesRead:      l.BytesRead,
+			BytesFlushed:   l.BytesFlushed,
+			BytesCompacted: l.BytesCompacted,
+		}
+	}
+	return levels
+}
@@ -1,15 +1,202 @@
 package pebbledb
 
 import (
+	"net/url"
+	"time"
+
 	"github.com/cockroachdb/pebble"
+	"github.com/rawbytedev/zerokv"
 )
 
 // specific Pebbledb options
 type Config struct {
 	Dir           string
 	PebbleConfigs *pebble.Options
+	// ReadOnly opens the database without write access; writes return
+	// zerokv.ErrReadOnly. It is also set by PebbleConfigs.ReadOnly.
+	ReadOnly bool
+	// EnableTTL stores an expiry header with every value so PutWithTTL can be
+	// emulated. It changes the on-disk value format: a database must always be
+	// opened with the same setting.
+	EnableTTL bool
+	// SweepExpired starts a background goroutine that deletes expired
+	// entries every SweepInterval, so they stop using disk space without
+	// being read. It requires EnableTTL and is stopped by Close.
+	SweepExpired bool
+	// SweepInterval is the time between sweeps; zero means one minute.
+	SweepInterval time.Duration
+	// CacheSize sets the size in bytes of a block cache created for this
+	// database. It is ignored when PebbleConfigs already has a Cache.
+	CacheSize int64
+	// NoSync makes writes, batches and transactions return without waiting
+	// for the WAL to reach stable storage. Acknowledged writes survive a
+	// process crash but can be lost if the machine fails. PutSync overrides
+	// it per call.
+	NoSync bool
+	// WriteTimeout bounds how long a synced Put, PutSync or PutWithTTL waits
+	// for the WAL sync before returning context.DeadlineExceeded, so a
+	// stalled disk cannot block the caller indefinitely. The write itself
+	// is not cancelled and may still be applied afterwards. Zero, the
+	// default, disables the timeout.
+	WriteTimeout time.Duration
+	// MaxKeySize and MaxValueSize make writes of a longer key or value fail
+	// with zerokv.ErrKeyTooLarge or zerokv.ErrValueTooLarge before they
+	// reach Pebble. Zero means unlimited.
+	MaxKeySize   int
+	MaxValueSize int
+	// RefreshInterval is how often a database opened with NewPebbleReplica
+	// reopens itself to catch up with the primary; zero means one second.
+	// Other databases ignore it.
+	RefreshInterval time.Duration
 }
 
 func DefaultOptions(Dir string) *Config {
-	return &Config{Dir, nil}
+	return &Config{Dir: Dir}
+}
+
+// Option adjusts the Config built by New.
+type Option func(*Config)
+
+// New opens a PebbleDB at dir, starting from Pebble's defaults and applying
+// opts in order. It is shorthand for building a Config by hand.
+func New(dir string, opts ...Option) (zerokv.Core, error) {
+	cfg := DefaultOptions(dir)
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	return NewPebbleDB(*cfg)
+}
+
+// WithPebbleOptions replaces the engine options wholesale. Options applied
+// after it modify the given value.
+func WithPebbleOptions(opts *pebble.Options) Option {
+	return func(c *Config) {
+		c.PebbleConfigs = opts
+	}
+}
+
+// WithReadOnly opens the database without write access.
+func WithReadOnly() Option {
+	return func(c *Config) {
+		c.ReadOnly = true
+	}
+}
+
+// WithLogger sets the logger Pebble reports to.
+func WithLogger(l pebble.Logger) Option {
+	return func(c *Config) {
+		c.pebbleOptions().Logger = l
+	}
+}
+
+// WithMemTableSize sets the size in bytes of each memtable.
+func WithMemTableSize(n uint64) Option {
+	return func(c *Config) {
+		c.pebbleOptions().MemTableSize = n
+	}
+}
+
+// WithSyncWrites sets whether writes wait for the WAL to be synced, which is
+// the default. WithSyncWrites(false) sets Config.NoSync.
+func WithSyncWrites(sync bool) Option {
+	return func(c *Config) {
+		c.NoSync = !sync
+	}
+}
+
+// WithWriteTimeout sets Config.WriteTimeout, the longest a synced
+// single-key write waits before giving up.
+func WithWriteTimeout(d time.Duration) Option {
+	return func(c *Config) {
+		c.WriteTimeout = d
+	}
+}
+
+// WithMaxKeySize sets Config.MaxKeySize.
+func WithMaxKeySize(n int) Option {
+	return func(c *Config) {
+		c.MaxKeySize = n
+	}
+}
+
+// WithMaxValueSize sets Config.MaxValueSize.
+func WithMaxValueSize(n int) Option {
+	return func(c *Config) {
+		c.MaxValueSize = n
+	}
+}
+
+// WithRefreshInterval sets Config.RefreshInterval, the catch-up interval
+// of a replica.
+func WithRefreshInterval(d time.Duration) Option {
+	return func(c *Config) {
+		c.RefreshInterval = d
+	}
+}
+
+// WithCacheSize sets the size in bytes of the block cache.
+func WithCacheSize(n int64) Option {
+	return func(c *Config) {
+		c.CacheSize = n
+	}
+}
+
+// WithTTL sets EnableTTL so PutWithTTL is supported.
+func WithTTL() Option {
+	return func(c *Config) {
+		c.EnableTTL = true
+	}
+}
+
+// WithSweepExpired sets EnableTTL and starts the expiry sweeper with the
+// given interval; zero means one minute.
+func WithSweepExpired(interval time.Duration) Option {
+	return func(c *Config) {
+		c.EnableTTL = true
+		c.SweepExpired = true
+		c.SweepInterval = interval
+	}
+}
+
+// paramOptions maps the DSN parameters accepted by the "pebble" backend:
+// readOnly, syncWrites, writeTimeout, cache (block cache size),
+// memTableSize, maxKeySize, maxValueSize, ttl (EnableTTL) and sweepInterval, which also enables TTL
+// and the expiry sweeper.
+func paramOptions(params url.Values) ([]Option, error) {
+	var opts []Option
+	err := zerokv.ApplyParams(params, map[string]func(string) error{
+		"readOnly": zerokv.BoolParam(func(b bool) {
+			if b {
+				opts = append(opts, WithReadOnly())
+			}
+		}),
+		"syncWrites": zerokv.BoolParam(func(b bool) { opts = append(opts, WithSyncWrites(b)) }),
+		"writeTimeout": zerokv.DurationParam(func(d time.Duration) {
+			opts = append(opts, WithWriteTimeout(d))
+		}),
+		"cache": zerokv.SizeParam(func(n int64) { opts = append(opts, WithCacheSize(n)) }),
+		"memTableSize": zerokv.SizeParam(func(n int64) {
+			opts = append(opts, WithMemTableSize(uint64(n)))
+		}),
+		"maxKeySize":   zerokv.SizeParam(func(n int64) { opts = append(opts, WithMaxKeySize(int(n))) }),
+		"maxValueSize": zerokv.SizeParam(func(n int64) { opts = append(opts, WithMaxValueSize(int(n))) }),
+		"ttl": zerokv.BoolParam(func(b bool) {
+			if b {
+				opts = append(opts, WithTTL())
+			}
+		}),
+		"sweepInterval": zerokv.DurationParam(func(d time.Duration) {
+			opts = append(opts, WithSweepExpired(d))
+		}),
+	})
+	return opts, err
+}
+
+// pebbleOptions returns the engine options for an Option to modify,
+// creating empty ones on first use.
+func (c *Config) pebbleOptions() *pebble.Options {
+	if c.PebbleConfigs == nil {
+		c.PebbleConfigs = &pebble.Options{}
+	}
+	return c.PebbleConfigs
 }
@@ -1,21 +1,76 @@
 package pebbledb
 
 import (
+	"bytes"
 	"context"
 	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/cockroachdb/pebble"
 	"github.com/rawbytedev/zerokv"
 )
 
+// restoreBatchSize is the number of entries Restore commits at a time.
+const restoreBatchSize = 1000
+
 type PebbleDB struct {
-	db *pebble.DB
+	db       *pebble.DB
+	codec    valueCodec
+	readOnly bool
+	// writeOpts is pebble.Sync unless the database was opened with NoSync
+	writeOpts *pebble.WriteOptions
+	// writeTimeout is Config.WriteTimeout; logger reports writes that
+	// overran it
+	writeTimeout time.Duration
+	logger       pebble.Logger
+	// limits are Config.MaxKeySize and Config.MaxValueSize
+	limits zerokv.SizeLimits
+	// writes that set values hold sweepMu for reading, so the TTL sweeper
+	// can check expiry and delete without racing a fresh write
+	sweepMu sync.RWMutex
+	// modifyMu serializes read-modify-write operations such as CompareAndSwap
+	modifyMu  sync.Mutex
+	fills     zerokv.KeyedMutex // serializes GetOrPut per key
+	stopSweep chan struct{}
+	sweepDone chan struct{}
+	closeOnce sync.Once
+	closed    atomic.Bool
+	watchers  zerokv.Watchers
+	// PutAsync, GetAsync and writes bounded by writeTimeout hold asyncMu for
+	// reading until the engine call returns, so Close waits for operations
+	// their callers gave up on
+	asyncMu sync.RWMutex
 }
 type pebbleBatch struct {
-	batch *pebble.Batch
+	db        *pebble.DB
+	batch     *pebble.Batch
+	codec     valueCodec
+	sweepMu   *sync.RWMutex
+	readOnly  bool
+	committed bool
+	writeOpts *pebble.WriteOptions
+	watchers  *zerokv.Watchers
+	events    []zerokv.Event
+	limits    zerokv.SizeLimits
+	ifAbsent  []pendingPut // PutIfAbsent calls, resolved by Commit
+	check     zerokv.BatchValidator
+}
+
+// pendingPut is a PutIfAbsent staged after the first at operations of a
+// batch.
+type pendingPut struct {
+	key, value []byte
+	at         int
 }
+
 type pebbleIterator struct {
 	Iterator *pebble.Iterator
+	codec    valueCodec
+	keysOnly bool
 	started  bool
 	valid    bool
 	err      []error
@@ -23,11 +78,22 @@ type pebbleIterator struct {
 
 type pebbleReverseIterator struct {
 	Iterator *pebble.Iterator
+	codec    valueCodec
 	started  bool
 	valid    bool
 	err      []error
 }
 
+func init() {
+	zerokv.Register("pebble", func(cfg zerokv.Config) (zerokv.Core, error) {
+		opts, err := paramOptions(cfg.Params)
+		if err != nil {
+			return nil, err
+		}
+		return New(cfg.Dir, opts...)
+	})
+}
+
 // NewPebbleDB initializes and returns a zerokv.Core instance at the specified path(PebbleDB).
 func NewPebbleDB(cfg Config) (zerokv.Core, error) {
 	opts := &pebble.Options{}
@@ -36,21 +102,158 @@ func NewPebbleDB(cfg Config) (zerokv.Core, error) {
 	} else {
 		opts = &pebble.Options{}
 	}
+	if cfg.ReadOnly && !opts.ReadOnly {
+		copied := *opts
+		copied.ReadOnly = true
+		opts = &copied
+	}
+	if cfg.CacheSize > 0 && opts.Cache == nil {
+		copied := *opts
+		copied.Cache = pebble.NewCache(cfg.CacheSize)
+		// Open takes its own reference.
+		defer copied.Cache.Unref()
+		opts = &copied
+	}
 	db, err := pebble.Open(cfg.Dir, opts)
 	if err != nil {
 		return nil, err
 	}
-	return &PebbleDB{db: db}, nil
+	p := &PebbleDB{db: db, codec: valueCodec{ttl: cfg.EnableTTL}, readOnly: opts.ReadOnly, writeOpts: pebble.Sync}
+	p.writeTimeout, p.logger = cfg.WriteTimeout, opts.Logger
+	p.limits = zerokv.SizeLimits{MaxKeySize: cfg.MaxKeySize, MaxValueSize: cfg.MaxValueSize}
+	if p.logger == nil {
+		p.logger = pebble.DefaultLogger
+	}
+	if cfg.NoSync {
+		p.writeOpts = pebble.NoSync
+	}
+	if cfg.EnableTTL && cfg.SweepExpired && !p.readOnly {
+		interval := cfg.SweepInterval
+		if interval <= 0 {
+			interval = defaultSweepInterval
+		}
+		p.stopSweep = make(chan struct{})
+		p.sweepDone = make(chan struct{})
+		go p.sweepLoop(interval)
+	}
+	return p, nil
 }
 
 // --- Basic CRUD operations ---
 
 // Put inserts or updates a key-value pair in the database.
 func (p *PebbleDB) Put(ctx context.Context, key []byte, data []byte) error {
+	return p.put(ctx, key, data, p.writeOpts)
+}
+
+// PutSync inserts or updates a key-value pair, waiting for the WAL to be
+// synced when sync is true whatever the database default. With sync false
+// the write survives a process crash but may be lost if the machine fails.
+func (p *PebbleDB) PutSync(ctx context.Context, key, value []byte, sync bool) error {
+	if sync {
+		return p.put(ctx, key, value, pebble.Sync)
+	}
+	return p.put(ctx, key, value, pebble.NoSync)
+}
+
+func (p *PebbleDB) put(ctx context.Context, key, data []byte, wo *pebble.WriteOptions) error {
 	if err := ctx.Err(); err != nil {
 		return err
 	}
-	return p.db.Set(key, data, pebble.Sync)
+	if p.readOnly {
+		return zerokv.ErrReadOnly
+	}
+	if err := p.limits.Check(key, data); err != nil {
+		return err
+	}
+	return p.write(ctx, key, data, 0, wo)
+}
+
+// write stores a single value. A synced write is bounded by
+// Config.WriteTimeout: it runs through runAsync and is abandoned, though not
+// cancelled, once the timeout or ctx expires.
+func (p *PebbleDB) write(ctx context.Context, key, data []byte, expiresAt int64, wo *pebble.WriteOptions) error {
+	if !wo.Sync || p.writeTimeout <= 0 {
+		return p.set(key, data, expiresAt, wo)
+	}
+	ctx, cancel := context.WithTimeout(ctx, p.writeTimeout)
+	defer cancel()
+	// the write may outlive this call, so it must not share caller buffers
+	key, data = bytes.Clone(key), bytes.Clone(data)
+	_, err := p.runAsync(ctx, func() ([]byte, error) {
+		return nil, p.set(key, data, expiresAt, wo)
+	})
+	if err != nil && ctx.Err() != nil {
+		// only the key's size is logged, as by zerokv.WithSlowLog, since
+		// keys may hold sensitive data
+		p.logger.Infof("pebbledb: synced write of a %d-byte key abandoned after %s: %v; it may still be applied",
+			len(key), p.writeTimeout, err)
+	}
+	return err
+}
+
+// set writes the encoded value and publishes the put to watchers.
+func (p *PebbleDB) set(key, data []byte, expiresAt int64, wo *pebble.WriteOptions) error {
+	p.sweepMu.RLock()
+	defer p.sweepMu.RUnlock()
+	if err := p.db.Set(key, p.codec.encode(data, expiresAt), wo); err != nil {
+		return err
+	}
+	p.watchers.Publish(zerokv.Event{Op: zerokv.OpPut, Key: key, Value: data})
+	return nil
+}
+
+// PutWithTTL inserts or updates a key-value pair that expires after ttl.
+// Pebble has no native expiry: the database must be opened with
+// Config.EnableTTL, and expired entries are hidden from reads but keep
+// their disk space until overwritten, deleted or swept.
+func (p *PebbleDB) PutWithTTL(ctx context.Context, key, value []byte, ttl time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if p.readOnly {
+		return zerokv.ErrReadOnly
+	}
+	if !p.codec.ttl {
+		return fmt.Errorf("pebbledb: PutWithTTL requires Config.EnableTTL: %w", zerokv.ErrNotSupported)
+	}
+	if err := p.limits.Check(key, value); err != nil {
+		return err
+	}
+	expiresAt := time.Now().Add(ttl).UnixNano()
+	return p.write(ctx, key, value, expiresAt, p.writeOpts)
+}
+
+// PutMany writes all pairs atomically in one batch with a single synced commit.
+func (p *PebbleDB) PutMany(ctx context.Context, keys, values [][]byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if p.readOnly {
+		return zerokv.ErrReadOnly
+	}
+	if len(keys) != len(values) {
+		return fmt.Errorf("PutMany: %d keys but %d values", len(keys), len(values))
+	}
+	if err := p.limits.CheckAll(keys, values); err != nil {
+		return err
+	}
+	batch := p.db.NewBatch()
+	defer batch.Close()
+	for i := range keys {
+		if err := batch.Set(keys[i], p.codec.encode(values[i], 0), nil); err != nil {
+			return err
+		}
+	}
+	p.sweepMu.RLock()
+	defer p.sweepMu.RUnlock()
+	if err := batch.Commit(p.writeOpts); err != nil {
+		return err
+	}
+	if p.watchers.Active() {
+		p.watchers.Publish(zerokv.PutEvents(keys, values)...)
+	}
+	return nil
 }
 
 // Get retrieves the value for a given key. Returns an error if not found.
@@ -58,12 +261,152 @@ func (p *PebbleDB) Get(ctx context.Context, key []byte) ([]byte, error) {
 	if err := ctx.Err(); err != nil {
 		return nil, err
 	}
-	val, closer, err := p.db.Get(key)
-	if err != nil {
+	val, err := p.codec.get(p.db, key)
+	return val, notFound(err)
+}
+
+// GetOrDefault returns a copy of def if key is missing.
+func (p *PebbleDB) GetOrDefault(ctx context.Context, key, def []byte) ([]byte, error) {
+	value, err := p.Get(ctx, key)
+	if errors.Is(err, zerokv.ErrKeyNotFound) {
+		return bytes.Clone(def), nil
+	}
+	return value, err
+}
+
+// GetInto copies the value of key into dst before Pebble's closer is
+// closed, without allocating when dst is large enough.
+func (p *PebbleDB) GetInto(ctx context.Context, key, dst []byte) ([]byte, error) {
+	dst = dst[:0]
+	err := p.View(ctx, key, func(value []byte) error {
+		dst = append(dst, value...)
+		return nil
+	})
+	return dst, err
+}
+
+// View passes fn the value returned by Pebble before its closer is closed,
+// without copying it.
+func (p *PebbleDB) View(ctx context.Context, key []byte, fn func(value []byte) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return p.codec.view(p.db, key, fn)
+}
+
+// GetAsync is Get that returns ctx.Err() as soon as ctx is done instead of
+// waiting for the engine. The read keeps running in the background until it
+// finishes, and Close waits for it.
+func (p *PebbleDB) GetAsync(ctx context.Context, key []byte) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
 		return nil, err
 	}
-	defer closer.Close()
-	return val, nil
+	key = bytes.Clone(key)
+	return p.runAsync(ctx, func() ([]byte, error) {
+		return p.Get(context.Background(), key)
+	})
+}
+
+// PutAsync is Put that returns ctx.Err() as soon as ctx is done, even while
+// the synced write is blocked on fsync. The early return does not cancel
+// the write: it may still be applied after PutAsync returns, so callers
+// must not treat a context error as proof that nothing was written. key
+// and data are copied and may be reused once PutAsync returns.
+func (p *PebbleDB) PutAsync(ctx context.Context, key []byte, data []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if p.readOnly {
+		return zerokv.ErrReadOnly
+	}
+	if err := p.limits.Check(key, data); err != nil {
+		return err
+	}
+	key, data = bytes.Clone(key), bytes.Clone(data)
+	_, err := p.runAsync(ctx, func() ([]byte, error) {
+		return nil, p.set(key, data, 0, p.writeOpts)
+	})
+	return err
+}
+
+// runAsync runs fn through zerokv.RunWithContext, keeping Close from
+// closing the engine under it.
+func (p *PebbleDB) runAsync(ctx context.Context, fn func() ([]byte, error)) ([]byte, error) {
+	return zerokv.RunWithContext(ctx, func() ([]byte, error) {
+		p.asyncMu.RLock()
+		defer p.asyncMu.RUnlock()
+		if p.closed.Load() {
+			return nil, pebble.ErrClosed
+		}
+		return fn()
+	})
+}
+
+// notFound lets the error pebble reports for a missing key also match
+// zerokv.ErrKeyNotFound. Other errors are returned unchanged.
+func notFound(err error) error {
+	if errors.Is(err, pebble.ErrNotFound) {
+		return zerokv.KeyNotFound(err)
+	}
+	return err
+}
+
+// GetMany retrieves the values for keys under a single snapshot.
+// Missing keys yield a nil entry at the same position.
+func (p *PebbleDB) GetMany(ctx context.Context, keys [][]byte) ([][]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	snap := p.db.NewSnapshot()
+	defer snap.Close()
+	values := make([][]byte, len(keys))
+	for i, key := range keys {
+		val, err := p.codec.get(snap, key)
+		if errors.Is(err, pebble.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		values[i] = val
+	}
+	return values, nil
+}
+
+// HasMany reports whether each key exists, all checked against one
+// snapshot.
+func (p *PebbleDB) HasMany(ctx context.Context, keys [][]byte) ([]bool, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	snap := p.db.NewSnapshot()
+	defer snap.Close()
+	found := make([]bool, len(keys))
+	for i, key := range keys {
+		ok, err := p.codec.has(snap, key)
+		if err != nil {
+			return nil, err
+		}
+		found[i] = ok
+	}
+	return found, nil
+}
+
+// Has reports whether a key exists. A missing key returns (false, nil).
+func (p *PebbleDB) Has(ctx context.Context, key []byte) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	return p.codec.has(p.db, key)
+}
+
+// SizeOf returns the length of a key's value. Pebble has to read the value,
+// but it is measured in place and the buffer released without a copy.
+func (p *PebbleDB) SizeOf(ctx context.Context, key []byte) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	return p.codec.size(p.db, key)
 }
 
 // Del deletes a key-value pair from the database.
@@ -71,12 +414,586 @@ func (p *PebbleDB) Delete(ctx context.Context, key []byte) error {
 	if err := ctx.Err(); err != nil {
 		return err
 	}
-	return p.db.Delete(key, pebble.Sync)
+	if p.readOnly {
+		return zerokv.ErrReadOnly
+	}
+	if err := p.db.Delete(key, p.writeOpts); err != nil {
+		return err
+	}
+	p.watchers.Publish(zerokv.Event{Op: zerokv.OpDelete, Key: key})
+	return nil
+}
+
+// DeletePrefix removes every key with the given prefix with a single
+// DeleteRange tombstone between the prefix and its successor. Prefixes
+// without a successor (empty or all 0xFF) fall back to per-key deletes.
+func (p *PebbleDB) DeletePrefix(ctx context.Context, prefix []byte) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	if p.readOnly {
+		return 0, zerokv.ErrReadOnly
+	}
+	upbound := zerokv.PrefixUpperBound(prefix)
+	snap := p.db.NewSnapshot()
+	defer snap.Close()
+	it, err := snap.NewIter(&pebble.IterOptions{LowerBound: prefix, UpperBound: upbound})
+	if err != nil {
+		return 0, err
+	}
+	batch := p.db.NewBatch()
+	defer batch.Close()
+	count, found := 0, false
+	now := time.Now().UnixNano()
+	for valid := it.First(); valid; valid = it.Next() {
+		if upbound == nil {
+			if err := batch.Delete(it.Key(), nil); err != nil {
+				return 0, errors.Join(err, it.Close())
+			}
+		}
+		found = true
+		// expired entries are removed too but were already invisible
+		if !p.codec.expired(it, now) {
+			count++
+		}
+	}
+	if err := it.Close(); err != nil {
+		return 0, err
+	}
+	if !found {
+		return 0, nil
+	}
+	if upbound != nil {
+		if err := batch.DeleteRange(prefix, upbound, nil); err != nil {
+			return 0, err
+		}
+	}
+	if err := batch.Commit(p.writeOpts); err != nil {
+		return 0, err
+	}
+	p.watchers.Publish(zerokv.Event{Op: zerokv.OpDeleteRange, Key: prefix, End: upbound})
+	return count, nil
+}
+
+// DeleteRange removes every key in [start, end) with a single range
+// tombstone, so the cost does not depend on the number of keys. A nil end
+// has no successor to bound the tombstone and falls back to per-key deletes.
+func (p *PebbleDB) DeleteRange(ctx context.Context, start, end []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if p.readOnly {
+		return zerokv.ErrReadOnly
+	}
+	if end != nil {
+		if bytes.Compare(start, end) >= 0 {
+			return nil
+		}
+		if err := p.db.DeleteRange(start, end, p.writeOpts); err != nil {
+			return err
+		}
+		p.watchers.Publish(zerokv.Event{Op: zerokv.OpDeleteRange, Key: start, End: end})
+		return nil
+	}
+	it, err := p.db.NewIter(&pebble.IterOptions{LowerBound: start})
+	if err != nil {
+		return err
+	}
+	batch := p.db.NewBatch()
+	defer batch.Close()
+	for valid := it.First(); valid; valid = it.Next() {
+		if err := batch.Delete(it.Key(), nil); err != nil {
+			return errors.Join(err, it.Close())
+		}
+	}
+	if err := it.Close(); err != nil {
+		return err
+	}
+	if err := batch.Commit(p.writeOpts); err != nil {
+		return err
+	}
+	p.watchers.Publish(zerokv.Event{Op: zerokv.OpDeleteRange, Key: start})
+	return nil
+}
+
+// Truncate removes every key with one range tombstone from the empty key to
+// just past the current last key, then compacts the whole keyspace so the
+// deleted data leaves the disk. The tombstone is cheap; the compaction
+// rewrites every table and takes time proportional to the store's size.
+// Keys written concurrently past the last key can survive.
+func (p *PebbleDB) Truncate(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if p.readOnly {
+		return zerokv.ErrReadOnly
+	}
+	it, err := p.db.NewIter(nil)
+	if err != nil {
+		return err
+	}
+	var end []byte
+	if it.Last() {
+		end = append(bytes.Clone(it.Key()), 0)
+	}
+	if err := it.Close(); err != nil {
+		return err
+	}
+	if end == nil {
+		return nil
+	}
+	if err := p.db.DeleteRange([]byte{}, end, p.writeOpts); err != nil {
+		return err
+	}
+	p.watchers.Publish(zerokv.Event{Op: zerokv.OpDeleteRange})
+	return p.Compact(ctx, nil, nil)
+}
+
+// TruncatePrefix removes the prefix with a range tombstone, as DeletePrefix
+// does, then compacts the prefix's key range so the tables holding it are
+// rewritten without the deleted keys.
+func (p *PebbleDB) TruncatePrefix(ctx context.Context, prefix []byte) error {
+	if _, err := p.DeletePrefix(ctx, prefix); err != nil {
+		return err
+	}
+	return p.Compact(ctx, prefix, zerokv.PrefixUpperBound(prefix))
+}
+
+// Count returns the number of keys with the given prefix without reading
+// values, unless Config.EnableTTL requires checking them for expiry.
+// It returns ctx.Err() if ctx is cancelled during the scan.
+func (p *PebbleDB) Count(ctx context.Context, prefix []byte) (int64, error) {
+	it, err := p.db.NewIter(&pebble.IterOptions{LowerBound: prefix, UpperBound: zerokv.PrefixUpperBound(prefix)})
+	if err != nil {
+		return 0, err
+	}
+	var count int64
+	now := time.Now().UnixNano()
+	for valid := it.First(); valid; valid = it.Next() {
+		if err := ctx.Err(); err != nil {
+			return 0, errors.Join(err, it.Close())
+		}
+		if !p.codec.expired(it, now) {
+			count++
+		}
+	}
+	if err := it.Close(); err != nil {
+		return 0, err
+	}
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// EstimateSize returns pebble.DB.EstimateDiskUsage for the range covered by
+// the prefix. Only sstables are counted, so recent writes still in the
+// memtable do not show up until they are flushed.
+func (p *PebbleDB) EstimateSize(prefix []byte) (int64, error) {
+	if p.closed.Load() {
+		return 0, pebble.ErrClosed
+	}
+	end := zerokv.PrefixUpperBound(prefix)
+	if end == nil {
+		// the prefix has no upper bound, so stop just past the last key
+		it, err := p.db.NewIter(&pebble.IterOptions{LowerBound: prefix})
+		if err != nil {
+			return 0, err
+		}
+		if it.Last() {
+			end = append(bytes.Clone(it.Key()), 0)
+		}
+		if err := it.Close(); err != nil {
+			return 0, err
+		}
+		if end == nil {
+			return 0, nil
+		}
+	}
+	size, err := p.db.EstimateDiskUsage(prefix, end)
+	if err != nil {
+		return 0, err
+	}
+	return int64(size), nil
+}
+
+// CompareAndSwap sets key to new if its current value equals old, or if it
+// is absent when old is nil. Pebble has no transactions, so the read and the
+// write run under modifyMu: the swap is atomic with respect to other
+// CompareAndSwap calls, but not to a plain Put of the same key.
+func (p *PebbleDB) CompareAndSwap(ctx context.Context, key, old, new []byte) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	if p.readOnly {
+		return false, zerokv.ErrReadOnly
+	}
+	var swapped bool
+	err := p.modify(key, func(current []byte, found bool) ([]byte, bool, error) {
+		swapped = found == (old != nil) && bytes.Equal(current, old)
+		return new, swapped, nil
+	})
+	return swapped && err == nil, err
+}
+
+// Increment adds delta to the counter at key under modifyMu. Concurrent
+// increments never lose updates, but a plain Put of the key can.
+func (p *PebbleDB) Increment(ctx context.Context, key []byte, delta int64) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	if p.readOnly {
+		return 0, zerokv.ErrReadOnly
+	}
+	var n int64
+	err := p.modify(key, func(current []byte, found bool) ([]byte, bool, error) {
+		n = 0
+		if found {
+			var err error
+			if n, err = zerokv.DecodeCounter(current); err != nil {
+				return nil, false, err
+			}
+		}
+		n += delta
+		return zerokv.EncodeCounter(n), true, nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// GetOrPut returns the value of key, or stores and returns the result of fill
+// when the key is missing or expired. Callers for the same key wait on a
+// per-key lock, so fill runs once and outside modifyMu; the value is then
+// stored only if the key is still absent.
+func (p *PebbleDB) GetOrPut(ctx context.Context, key []byte, fill func() ([]byte, error)) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if p.readOnly {
+		return nil, zerokv.ErrReadOnly
+	}
+	unlock := p.fills.Lock(key)
+	defer unlock()
+	value, err := p.Get(ctx, key)
+	if !errors.Is(err, zerokv.ErrKeyNotFound) {
+		return value, err
+	}
+	filled, err := fill()
+	if err != nil {
+		return nil, err
+	}
+	err = p.modify(key, func(current []byte, found bool) ([]byte, bool, error) {
+		if found {
+			value = current
+			return nil, false, nil
+		}
+		value = filled
+		return filled, true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// Merge replaces the value of key with merge(existing, operand), where
+// existing is nil for a missing or expired key. Pebble's native merge
+// operator is fixed when the database is opened and cannot take a per-call
+// function, so this is a read-modify-write under modifyMu instead.
+func (p *PebbleDB) Merge(ctx context.Context, key, operand []byte, merge func(existing, operand []byte) []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if p.readOnly {
+		return zerokv.ErrReadOnly
+	}
+	return p.modify(key, func(current []byte, found bool) ([]byte, bool, error) {
+		return merge(current, operand), true, nil
+	})
+}
+
+// modify runs a read-modify-write of key under modifyMu. fn gets the current
+// value and whether the key exists (expired keys count as absent), and
+// returns the value to store and whether to store it. The value is written
+// without an expiry.
+func (p *PebbleDB) modify(key []byte, fn func(current []byte, found bool) ([]byte, bool, error)) error {
+	p.modifyMu.Lock()
+	defer p.modifyMu.Unlock()
+	current, err := p.codec.get(p.db, key)
+	found := err == nil
+	if err != nil && !errors.Is(err, pebble.ErrNotFound) {
+		return err
+	}
+	value, write, err := fn(current, found)
+	if err != nil || !write {
+		return err
+	}
+	if err := p.limits.Check(key, value); err != nil {
+		return err
+	}
+	p.sweepMu.RLock()
+	defer p.sweepMu.RUnlock()
+	if err := p.db.Set(key, p.codec.encode(value, 0), p.writeOpts); err != nil {
+		return err
+	}
+	p.watchers.Publish(zerokv.Event{Op: zerokv.OpPut, Key: key, Value: value})
+	return nil
+}
+
+// Stats reports metrics from pebble.Metrics. PendingCompactions is the
+// number of compactions in progress. Pebble keeps no key count, so KeyCount
+// is always zero; use Count when an exact figure is needed.
+func (p *PebbleDB) Stats(ctx context.Context) (zerokv.Stats, error) {
+	if err := ctx.Err(); err != nil {
+		return zerokv.Stats{}, err
+	}
+	m := p.db.Metrics()
+	return zerokv.Stats{
+		OnDiskBytes:        int64(m.DiskSpaceUsage()),
+		MemtableBytes:      int64(m.MemTable.Size),
+		PendingCompactions: m.Compact.NumInProgress,
+	}, nil
+}
+
+// Sync makes every committed write durable. It syncs the WAL with an empty
+// LogData record and then flushes the memtable to an sstable. It does
+// nothing on a read-only database.
+func (p *PebbleDB) Sync(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if p.readOnly {
+		return nil
+	}
+	if err := p.db.LogData(nil, pebble.Sync); err != nil {
+		return err
+	}
+	return p.db.Flush()
+}
+
+// Compact flushes the memtable and compacts the sstables overlapping
+// [start, end) in parallel. A nil start or end is replaced by the smallest
+// or largest key in any sstable, so deleted keys are reclaimed too. Pebble
+// cannot interrupt a compaction once it has started.
+func (p *PebbleDB) Compact(ctx context.Context, start, end []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if p.readOnly {
+		return zerokv.ErrReadOnly
+	}
+	if p.closed.Load() {
+		return pebble.ErrClosed
+	}
+	if err := p.db.Flush(); err != nil {
+		return err
+	}
+	if start == nil || end == nil {
+		levels, err := p.db.SSTables()
+		if err != nil {
+			return err
+		}
+		var first, last []byte
+		for _, tables := range levels {
+			for _, t := range tables {
+				if first == nil || bytes.Compare(t.Smallest.UserKey, first) < 0 {
+					first = t.Smallest.UserKey
+				}
+				if last == nil || bytes.Compare(t.Largest.UserKey, last) > 0 {
+					last = t.Largest.UserKey
+				}
+			}
+		}
+		if last == nil {
+			return nil // nothing on disk yet
+		}
+		if start == nil {
+			start = first
+		}
+		if end == nil {
+			end = append(bytes.Clone(last), 0)
+		}
+	}
+	if bytes.Compare(start, end) >= 0 {
+		return nil
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return p.db.Compact(start, end, true)
+}
+
+// Optimize compacts the whole keyspace, as Compact with nil bounds does.
+func (p *PebbleDB) Optimize(ctx context.Context) error {
+	return p.Compact(ctx, nil, nil)
+}
+
+// CompactAll is Compact over the whole keyspace.
+func (p *PebbleDB) CompactAll(ctx context.Context) error {
+	return p.Compact(ctx, nil, nil)
+}
+
+// RunValueLogGC does nothing: Pebble keeps values inline in its sstables,
+// which Compact reclaims.
+func (p *PebbleDB) RunValueLogGC(discardRatio float64) error {
+	return nil
+}
+
+// Ping reads the engine metrics. Pebble panics on most calls after Close,
+// so a closed database is detected with a flag and reported as
+// pebble.ErrClosed.
+func (p *PebbleDB) Ping(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if p.closed.Load() {
+		return pebble.ErrClosed
+	}
+	_ = p.db.Metrics()
+	return nil
+}
+
+// Backup streams every live entry from a consistent snapshot to w in the
+// portable zerokv backup format, keeping each key's expiry.
+func (p *PebbleDB) Backup(ctx context.Context, w io.Writer) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	bw, err := zerokv.NewBackupWriter(w)
+	if err != nil {
+		return err
+	}
+	snap := p.db.NewSnapshot()
+	defer snap.Close()
+	it, err := snap.NewIter(nil)
+	if err != nil {
+		return err
+	}
+	now := time.Now().UnixNano()
+	for valid := it.First(); valid; valid = it.Next() {
+		if err := ctx.Err(); err != nil {
+			return errors.Join(err, it.Close())
+		}
+		raw, err := it.ValueAndErr()
+		if err != nil {
+			return errors.Join(err, it.Close())
+		}
+		value, expiresAt, err := p.codec.split(raw)
+		if err != nil {
+			return errors.Join(err, it.Close())
+		}
+		if expiresAt != 0 && expiresAt <= now {
+			continue
+		}
+		if err := bw.Write(it.Key(), value, expiresAt); err != nil {
+			return errors.Join(err, it.Close())
+		}
+	}
+	if err := it.Close(); err != nil {
+		return err
+	}
+	return bw.Close()
+}
+
+// Restore writes every entry from a backup produced by Backup, committing
+// in batches of restoreBatchSize. Existing keys not in the backup are kept.
+// Entries with an expiry require Config.EnableTTL.
+func (p *PebbleDB) Restore(ctx context.Context, r io.Reader) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if p.readOnly {
+		return zerokv.ErrReadOnly
+	}
+	br, err := zerokv.NewBackupReader(r)
+	if err != nil {
+		return err
+	}
+	batch := p.db.NewBatch()
+	defer func() { batch.Close() }()
+	now := time.Now().UnixNano()
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		key, value, expiresAt, err := br.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if expiresAt != 0 {
+			if !p.codec.ttl {
+				return fmt.Errorf("pebbledb: backup has keys with a TTL, which requires Config.EnableTTL: %w", zerokv.ErrNotSupported)
+			}
+			if expiresAt <= now {
+				continue
+			}
+		}
+		if err := batch.Set(key, p.codec.encode(value, expiresAt), nil); err != nil {
+			return err
+		}
+		if batch.Count() >= restoreBatchSize {
+			if err := p.commitRestore(batch); err != nil {
+				return err
+			}
+			batch.Close()
+			batch = p.db.NewBatch()
+		}
+	}
+	return p.commitRestore(batch)
+}
+
+// Checkpoint writes a consistent copy of the database to destDir with
+// Pebble's db.Checkpoint, after flushing the WAL. Sstables are hard-linked
+// where the filesystem allows, so a checkpoint on the same volume is cheap.
+// The checkpoint keeps the TTL header of every value, so open it with the
+// same Config.EnableTTL setting.
+func (p *PebbleDB) Checkpoint(ctx context.Context, destDir string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if p.closed.Load() {
+		return pebble.ErrClosed
+	}
+	if err := zerokv.CheckCheckpointDir(destDir); err != nil {
+		return err
+	}
+	return p.db.Checkpoint(destDir, pebble.WithFlushedWAL())
+}
+
+// commitRestore commits one restore batch alongside other value writes.
+func (p *PebbleDB) commitRestore(batch *pebble.Batch) error {
+	p.sweepMu.RLock()
+	defer p.sweepMu.RUnlock()
+	return batch.Commit(pebble.Sync)
+}
+
+// Watch streams the changes made through this PebbleDB to keys with the
+// given prefix until ctx is done or the database is closed. Pebble has no
+// change feed, so every write method publishes its changes once committed.
+// Restore and TTL expiry produce no events.
+func (p *PebbleDB) Watch(ctx context.Context, prefix []byte) (<-chan zerokv.Event, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return p.watchers.Watch(ctx, prefix), nil
 }
 
 // Close closes the database and releases all resources.
 func (p *PebbleDB) Close() error {
 	var errs []error
+	p.closed.Store(true)
+	p.asyncMu.Lock()
+	p.asyncMu.Unlock()
+	p.watchers.Close()
+	if p.stopSweep != nil {
+		p.closeOnce.Do(func() { close(p.stopSweep) })
+		<-p.sweepDone
+	}
 	if err := p.db.Close(); err != nil {
 		errs = append(errs, err)
 	}
@@ -88,38 +1005,464 @@ func (p *PebbleDB) Close() error {
 
 // -- Batch operations
 
+// batchLimits are the constraints batches validate against: Config's key
+// and value sizes, with empty keys allowed since Pebble stores them.
+func (p *PebbleDB) batchLimits() zerokv.BatchLimits {
+	return zerokv.BatchLimits{MaxKeySize: p.limits.MaxKeySize, MaxValueSize: p.limits.MaxValueSize, AllowEmptyKey: true}
+}
+
 func (p *PebbleDB) Batch() zerokv.Batch {
-	return &pebbleBatch{batch: p.db.NewBatch()}
+	return &pebbleBatch{db: p.db, batch: p.db.NewBatch(), codec: p.codec, sweepMu: &p.sweepMu, readOnly: p.readOnly, writeOpts: p.writeOpts, watchers: &p.watchers, limits: p.limits, check: zerokv.BatchValidator{Limits: p.batchLimits()}}
+}
+
+// BulkLoad gives fn a batch that commits every zerokv.BulkLoadSegmentSize
+// bytes without syncing, then syncs the WAL and flushes the memtable once
+// at the end.
+func (p *PebbleDB) BulkLoad(ctx context.Context, fn func(zerokv.Batch) error) error {
+	if p.readOnly {
+		return zerokv.ErrReadOnly
+	}
+	newBatch := func() zerokv.Batch {
+		return &pebbleBatch{db: p.db, batch: p.db.NewBatch(), codec: p.codec, sweepMu: &p.sweepMu, writeOpts: pebble.NoSync, watchers: &p.watchers, limits: p.limits, check: zerokv.BatchValidator{Limits: p.batchLimits()}}
+	}
+	batch := zerokv.NewAutoFlushBatch(newBatch, 0, zerokv.BulkLoadSegmentSize)
+	if err := zerokv.RunBulkLoad(ctx, batch, fn); err != nil {
+		return err
+	}
+	return p.Sync(ctx)
+}
+
+// BatchWithOptions creates a batch that commits itself once it holds maxOps
+// operations or maxBytes bytes, capping the memory a bulk load holds in a
+// single pebble.Batch.
+func (p *PebbleDB) BatchWithOptions(maxOps, maxBytes int) zerokv.Batch {
+	return zerokv.NewAutoFlushBatch(p.Batch, maxOps, maxBytes)
 }
 
 func (p *pebbleBatch) Put(key []byte, data []byte) error {
-	return p.batch.Set(key, data, pebble.NoSync)
+	if err := p.limits.Check(key, data); err != nil {
+		return err
+	}
+	if err := p.batch.Set(key, p.codec.encode(data, 0), pebble.NoSync); err != nil {
+		return err
+	}
+	p.events = p.watchers.Record(p.events, zerokv.Event{Op: zerokv.OpPut, Key: key, Value: data})
+	p.check.Put(key, data)
+	return nil
+}
+
+// PutIfAbsent stages a put that Commit applies only if key is absent once
+// the operations staged before it are applied. A pebble.Batch cannot read,
+// so the put is kept aside and resolved by Commit.
+func (p *pebbleBatch) PutIfAbsent(key []byte, data []byte) error {
+	if err := p.limits.Check(key, data); err != nil {
+		return err
+	}
+	at := int(p.batch.Count()) + len(p.ifAbsent)
+	p.ifAbsent = append(p.ifAbsent, pendingPut{key: bytes.Clone(key), value: bytes.Clone(data), at: at})
+	p.check.Put(key, data)
+	return nil
 }
 
 // BatchDel adds a delete operation to the current batch.
 func (p *pebbleBatch) Delete(key []byte) error {
-	return p.batch.Delete(key, pebble.NoSync)
+	if err := p.batch.Delete(key, pebble.NoSync); err != nil {
+		return err
+	}
+	p.events = p.watchers.Record(p.events, zerokv.Event{Op: zerokv.OpDelete, Key: key})
+	p.check.Delete(key)
+	return nil
+}
+
+// PutCtx adds a set operation to the batch unless ctx is done.
+func (p *pebbleBatch) PutCtx(ctx context.Context, key []byte, data []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return p.Put(key, data)
+}
+
+// DeleteCtx adds a delete operation to the batch unless ctx is done.
+func (p *pebbleBatch) DeleteCtx(ctx context.Context, key []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return p.Delete(key)
+}
+
+// Len returns the number of operations in the pebble.Batch, or zero once it
+// has been committed.
+func (p *pebbleBatch) Len() int {
+	if p.committed {
+		return 0
+	}
+	return int(p.batch.Count()) + len(p.ifAbsent)
+}
+
+// Size returns the encoded size of the pebble.Batch, which includes its
+// header and per-record overhead, or zero if it is empty or committed.
+func (p *pebbleBatch) Size() int {
+	if p.committed {
+		return 0
+	}
+	size := 0
+	if !p.batch.Empty() {
+		size = p.batch.Len()
+	}
+	for _, put := range p.ifAbsent {
+		size += len(put.key) + len(put.value)
+	}
+	return size
+}
+
+// Reset empties the pebble.Batch, keeping its buffer, so it can be
+// committed again.
+func (p *pebbleBatch) Reset() {
+	p.batch.Reset()
+	p.committed = false
+	p.events = nil
+	p.ifAbsent = nil
+	p.check.Reset()
+}
+
+// Validate reports the first queued operation whose key or value is larger
+// than Config allows. Empty keys are accepted.
+func (p *pebbleBatch) Validate() error {
+	return p.check.Err()
 }
 
 // flushBatch flushes any pending batch operations.
 func (p *pebbleBatch) Commit(ctx context.Context) error {
-	return p.batch.Commit(pebble.Sync)
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if p.readOnly {
+		return zerokv.ErrReadOnly
+	}
+	p.sweepMu.RLock()
+	defer p.sweepMu.RUnlock()
+	if len(p.ifAbsent) > 0 {
+		return p.commitIfAbsent()
+	}
+	if err := p.batch.Commit(p.writeOpts); err != nil {
+		return err
+	}
+	p.committed = true
+	p.watchers.Publish(p.events...)
+	p.events = nil
+	p.check.Reset()
+	return nil
+}
+
+// commitIfAbsent replays the batch into an indexed batch, which reads
+// through its own writes to the database, inserting each PutIfAbsent at
+// its position if its key is absent there, and commits the result. The
+// reads are not isolated from concurrent writers.
+func (p *pebbleBatch) commitIfAbsent() error {
+	ib := p.db.NewIndexedBatch()
+	defer ib.Close()
+	publish := p.watchers.Active()
+	var events []zerokv.Event
+	pending := p.ifAbsent
+	n := 0 // operations replayed so far, counting the conditional ones
+	resolve := func() error {
+		for ; len(pending) > 0 && pending[0].at == n; pending = pending[1:] {
+			n++
+			put := pending[0]
+			raw, closer, err := ib.Get(put.key)
+			if err == nil {
+				_, live, err := p.codec.decode(raw, time.Now().UnixNano())
+				closer.Close()
+				if err != nil {
+					return err
+				}
+				if live {
+					continue
+				}
+			} else if !errors.Is(err, pebble.ErrNotFound) {
+				return err
+			}
+			if err := ib.Set(put.key, p.codec.encode(put.value, 0), nil); err != nil {
+				return err
+			}
+			if publish {
+				events = append(events, zerokv.Event{Op: zerokv.OpPut, Key: put.key, Value: put.value})
+			}
+		}
+		return nil
+	}
+	r := p.batch.Reader()
+	for ; ; n++ {
+		if err := resolve(); err != nil {
+			return err
+		}
+		kind, key, value, ok, err := r.Next()
+		if err != nil {
+			return err
+		}
+		if !ok {
+			break
+		}
+		switch kind {
+		case pebble.InternalKeyKindSet:
+			if publish {
+				stored, _, _ := p.codec.decode(value, 0)
+				events = append(events, zerokv.Event{Op: zerokv.OpPut, Key: key, Value: stored})
+			}
+			err = ib.Set(key, value, nil)
+		case pebble.InternalKeyKindDelete:
+			if publish {
+				events = append(events, zerokv.Event{Op: zerokv.OpDelete, Key: key})
+			}
+			err = ib.Delete(key, nil)
+		default:
+			err = fmt.Errorf("pebbledb: unexpected batch record kind %v", kind)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if err := ib.Commit(p.writeOpts); err != nil {
+		return err
+	}
+	p.committed = true
+	p.watchers.Publish(events...)
+	p.events, p.ifAbsent = nil, nil
+	p.check.Reset()
+	return nil
+}
+
+// -- Transaction operations
+
+type pebbleTxn struct {
+	batch     *pebble.Batch
+	codec     valueCodec
+	sweepMu   *sync.RWMutex
+	writeOpts *pebble.WriteOptions
+	done      bool
+	watchers  *zerokv.Watchers
+	events    []zerokv.Event
+	limits    zerokv.SizeLimits
+}
+
+// NewTransaction starts a transaction backed by an indexed batch, so reads
+// see pending writes. Pebble has no conflict detection: reads are not
+// isolated from concurrent commits and Commit applies writes last-writer-wins.
+func (p *PebbleDB) NewTransaction(ctx context.Context) (zerokv.Txn, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if p.readOnly {
+		return nil, zerokv.ErrReadOnly
+	}
+	return &pebbleTxn{batch: p.db.NewIndexedBatch(), codec: p.codec, sweepMu: &p.sweepMu, writeOpts: p.writeOpts, watchers: &p.watchers, limits: p.limits}, nil
+}
+
+// Get retrieves the value for a given key, including pending writes.
+func (t *pebbleTxn) Get(ctx context.Context, key []byte) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if t.done {
+		return nil, zerokv.ErrTxnDone
+	}
+	val, err := t.codec.get(t.batch, key)
+	return val, notFound(err)
+}
+
+// Put stages a key-value pair in the transaction.
+func (t *pebbleTxn) Put(ctx context.Context, key []byte, data []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if t.done {
+		return zerokv.ErrTxnDone
+	}
+	if err := t.limits.Check(key, data); err != nil {
+		return err
+	}
+	if err := t.batch.Set(key, t.codec.encode(data, 0), nil); err != nil {
+		return err
+	}
+	t.events = t.watchers.Record(t.events, zerokv.Event{Op: zerokv.OpPut, Key: key, Value: data})
+	return nil
+}
+
+// Delete stages a key removal in the transaction.
+func (t *pebbleTxn) Delete(ctx context.Context, key []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if t.done {
+		return zerokv.ErrTxnDone
+	}
+	if err := t.batch.Delete(key, nil); err != nil {
+		return err
+	}
+	t.events = t.watchers.Record(t.events, zerokv.Event{Op: zerokv.OpDelete, Key: key})
+	return nil
+}
+
+// Commit writes the indexed batch to the database.
+func (t *pebbleTxn) Commit(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if t.done {
+		return zerokv.ErrTxnDone
+	}
+	t.done = true
+	t.sweepMu.RLock()
+	err := t.batch.Commit(t.writeOpts)
+	t.sweepMu.RUnlock()
+	if err == nil {
+		t.watchers.Publish(t.events...)
+	}
+	t.events = nil
+	return errors.Join(err, t.batch.Close())
+}
+
+// Discard drops the transaction's staged writes.
+func (t *pebbleTxn) Discard() {
+	if t.done {
+		return
+	}
+	t.done = true
+	t.batch.Close()
+}
+
+// -- Snapshot operations
+
+type pebbleSnapshot struct {
+	mu       sync.RWMutex
+	snap     *pebble.Snapshot
+	codec    valueCodec
+	released bool
+}
+
+// Snapshot returns a read-only view backed by a pebble.Snapshot.
+func (p *PebbleDB) Snapshot() (zerokv.Snapshot, error) {
+	return &pebbleSnapshot{snap: p.db.NewSnapshot(), codec: p.codec}, nil
+}
+
+// Get retrieves the value for a given key as of the snapshot.
+func (s *pebbleSnapshot) Get(ctx context.Context, key []byte) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.released {
+		return nil, zerokv.ErrSnapshotReleased
+	}
+	val, err := s.codec.get(s.snap, key)
+	return val, notFound(err)
+}
+
+// Has reports whether a key existed when the snapshot was taken.
+func (s *pebbleSnapshot) Has(ctx context.Context, key []byte) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.released {
+		return false, zerokv.ErrSnapshotReleased
+	}
+	return s.codec.has(s.snap, key)
+}
+
+// Scan returns an iterator over keys with the given prefix as of the snapshot.
+// The iterator pins its own view, so it stays valid after Release.
+func (s *pebbleSnapshot) Scan(prefix []byte) zerokv.Iterator {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.released {
+		return zerokv.NewErrIterator(zerokv.ErrSnapshotReleased)
+	}
+	it, err := s.snap.NewIter(&pebble.IterOptions{
+		LowerBound: prefix,
+		UpperBound: zerokv.PrefixUpperBound(prefix),
+	})
+	if err != nil {
+		return zerokv.NewErrIterator(err)
+	}
+	return &pebbleIterator{Iterator: it, codec: s.codec}
+}
+
+// Release closes the underlying pebble.Snapshot.
+func (s *pebbleSnapshot) Release() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.released {
+		return
+	}
+	s.released = true
+	s.snap.Close()
 }
 
 // -- Iterator operations
 
 func (p *PebbleDB) Scan(prefix []byte) zerokv.Iterator {
-	upbound := make([]byte, len(prefix))
-	copy(upbound, prefix)
-	upbound[len(upbound)-1]++
+	return NewPrefixIterator(p, prefix)
+}
+
+// ScanWithPrefetch is Scan. Pebble reads blocks ahead on its own, so
+// prefetch is ignored.
+func (p *PebbleDB) ScanWithPrefetch(prefix []byte, prefetch int) zerokv.Iterator {
+	return p.Scan(prefix)
+}
+
+// ReverseScan returns an iterator over keys with the given prefix in descending order.
+func (p *PebbleDB) ReverseScan(prefix []byte) zerokv.Iterator {
+	return NewReversePrefixIterator(p, prefix)
+}
+
+// RangeScan returns an iterator over keys in [start, end).
+func (p *PebbleDB) RangeScan(start, end []byte) zerokv.Iterator {
 	it, err := p.db.NewIter(&pebble.IterOptions{
-		LowerBound: prefix,
-		UpperBound: upbound,
+		LowerBound: start,
+		UpperBound: end,
+	})
+	if err != nil {
+		return zerokv.NewErrIterator(err)
+	}
+	return &pebbleIterator{Iterator: it, codec: p.codec, valid: false, started: false}
+}
+
+// ScanFrom returns an iterator over keys with the given prefix, starting at
+// the first one >= start.
+func (p *PebbleDB) ScanFrom(prefix, start []byte) zerokv.Iterator {
+	if bytes.Compare(start, prefix) < 0 {
+		start = prefix
+	}
+	it, err := p.db.NewIter(&pebble.IterOptions{
+		LowerBound: start,
+		UpperBound: zerokv.PrefixUpperBound(prefix),
 	})
 	if err != nil {
+		return zerokv.NewErrIterator(err)
+	}
+	return &pebbleIterator{Iterator: it, codec: p.codec, valid: false, started: false}
+}
+
+// ScanKeys returns an iterator over keys with the given prefix that never reads values.
+func (p *PebbleDB) ScanKeys(prefix []byte) zerokv.Iterator {
+	it := NewPrefixIterator(p, prefix)
+	if it == nil {
 		return nil
 	}
-	return &pebbleIterator{Iterator: it, valid: false, started: false}
+	it.(*pebbleIterator).keysOnly = true
+	return it
+}
+
+// ScanContext returns a prefix iterator that stops once ctx is done.
+func (p *PebbleDB) ScanContext(ctx context.Context, prefix []byte) zerokv.Iterator {
+	it := p.Scan(prefix)
+	if it == nil {
+		return nil
+	}
+	return zerokv.NewContextIterator(ctx, it)
 }
 
 func (it *pebbleIterator) Next() bool {
@@ -130,6 +1473,47 @@ func (it *pebbleIterator) Next() bool {
 	} else {
 		it.valid = it.Iterator.Next()
 	}
+	it.skipExpired()
+	return it.valid
+}
+
+// Seek moves to the first key >= key; pebble clamps the key to the iterator bounds.
+func (it *pebbleIterator) Seek(key []byte) bool {
+	it.valid = it.Iterator.SeekGE(key)
+	it.started = true
+	it.skipExpired()
+	return it.valid
+}
+
+// First moves to the smallest key within the iterator bounds.
+func (it *pebbleIterator) First() bool {
+	it.valid = it.Iterator.First()
+	it.started = true
+	it.skipExpired()
+	return it.valid
+}
+
+// Last moves to the greatest key within the iterator bounds, stepping back
+// over expired entries.
+func (it *pebbleIterator) Last() bool {
+	it.valid = it.Iterator.Last()
+	it.started = true
+	now := time.Now().UnixNano()
+	for it.valid && it.codec.expired(it.Iterator, now) {
+		it.valid = it.Iterator.Prev()
+	}
+	return it.valid
+}
+
+// skipExpired advances past entries whose TTL has passed.
+func (it *pebbleIterator) skipExpired() {
+	now := time.Now().UnixNano()
+	for it.valid && it.codec.expired(it.Iterator, now) {
+		it.valid = it.Iterator.Next()
+	}
+}
+
+func (it *pebbleIterator) Valid() bool {
 	return it.valid
 }
 
@@ -137,28 +1521,20 @@ func (it *pebbleIterator) Key() []byte {
 	if !it.valid {
 		return nil
 	}
-	return it.Iterator.Key() // safer, doesn't make changes to key
+	return bytes.Clone(it.Iterator.Key()) // pebble reuses the slice on the next move
 }
 func (it *pebbleIterator) Value() []byte {
-	if !it.valid {
+	if !it.valid || it.keysOnly {
 		return nil
 	}
-	data, err := it.Iterator.ValueAndErr()
-	if err != nil {
-		it.err = append(it.err, err)
-		return nil
-	}
-	return data
+	return valueAt(it.Iterator, it.codec, &it.err)
 }
 func (it *pebbleIterator) Release() {
 	it.valid = false
 	it.Iterator.Close()
 }
 func (it *pebbleIterator) Error() error {
-	if len(it.err) == 0 {
-		return nil
-	}
-	return it.err[len(it.err)-1] // returns the most recent error
+	return errors.Join(it.err...)
 }
 
 // --- specials methods to use with an instance of badgerdb for some other operations
@@ -168,21 +1544,22 @@ func NewIterator(p *PebbleDB) zerokv.Iterator {
 	if err != nil {
 		return nil
 	}
-	return &pebbleIterator{Iterator: it, valid: false, started: false}
+	return &pebbleIterator{Iterator: it, codec: p.codec, valid: false, started: false}
 }
 
 func NewPrefixIterator(p *PebbleDB, prefix []byte) zerokv.Iterator {
-	upbound := make([]byte, len(prefix))
-	copy(upbound, prefix)
-	upbound[len(upbound)-1]++
+	if len(prefix) == 0 {
+		// an empty prefix matches every key, so iterate without bounds
+		return NewIterator(p)
+	}
 	it, err := p.db.NewIter(&pebble.IterOptions{
 		LowerBound: prefix,
-		UpperBound: upbound,
+		UpperBound: zerokv.PrefixUpperBound(prefix),
 	})
 	if err != nil {
 		return nil
 	}
-	return &pebbleIterator{Iterator: it, valid: false, started: false}
+	return &pebbleIterator{Iterator: it, codec: p.codec, valid: false, started: false}
 }
 
 // --- Reverse Iterators ---
@@ -192,23 +1569,18 @@ func NewReverseIterator(p *PebbleDB) zerokv.Iterator {
 	if err != nil {
 		return nil
 	}
-	return &pebbleReverseIterator{Iterator: it, valid: false, started: false}
+	return &pebbleReverseIterator{Iterator: it, codec: p.codec, valid: false, started: false}
 }
 
 func NewReversePrefixIterator(p *PebbleDB, prefix []byte) zerokv.Iterator {
-	upbound := make([]byte, len(prefix))
-	copy(upbound, prefix)
-	if len(upbound) > 0 {
-		upbound[len(upbound)-1]++
-	}
 	it, err := p.db.NewIter(&pebble.IterOptions{
 		LowerBound: prefix,
-		UpperBound: upbound,
+		UpperBound: zerokv.PrefixUpperBound(prefix),
 	})
 	if err != nil {
 		return nil
 	}
-	return &pebbleReverseIterator{Iterator: it, valid: false, started: false}
+	return &pebbleReverseIterator{Iterator: it, codec: p.codec, valid: false, started: false}
 }
 
 func (it *pebbleReverseIterator) Next() bool {
@@ -218,6 +1590,50 @@ func (it *pebbleReverseIterator) Next() bool {
 	} else {
 		it.valid = it.Iterator.Prev()
 	}
+	it.skipExpired()
+	return it.valid
+}
+
+// Seek moves to the last key <= key.
+func (it *pebbleReverseIterator) Seek(key []byte) bool {
+	// key+0x00 is the immediate successor of key, so SeekLT on it includes key
+	succ := make([]byte, len(key)+1)
+	copy(succ, key)
+	it.valid = it.Iterator.SeekLT(succ)
+	it.started = true
+	it.skipExpired()
+	return it.valid
+}
+
+// First moves to the smallest key within the iterator bounds, stepping
+// forward over expired entries.
+func (it *pebbleReverseIterator) First() bool {
+	it.valid = it.Iterator.First()
+	it.started = true
+	now := time.Now().UnixNano()
+	for it.valid && it.codec.expired(it.Iterator, now) {
+		it.valid = it.Iterator.Next()
+	}
+	return it.valid
+}
+
+// Last moves to the greatest key within the iterator bounds.
+func (it *pebbleReverseIterator) Last() bool {
+	it.valid = it.Iterator.Last()
+	it.started = true
+	it.skipExpired()
+	return it.valid
+}
+
+// skipExpired moves back past entries whose TTL has passed.
+func (it *pebbleReverseIterator) skipExpired() {
+	now := time.Now().UnixNano()
+	for it.valid && it.codec.expired(it.Iterator, now) {
+		it.valid = it.Iterator.Prev()
+	}
+}
+
+func (it *pebbleReverseIterator) Valid() bool {
 	return it.valid
 }
 
@@ -225,19 +1641,14 @@ func (it *pebbleReverseIterator) Key() []byte {
 	if !it.valid {
 		return nil
 	}
-	return it.Iterator.Key()
+	return bytes.Clone(it.Iterator.Key())
 }
 
 func (it *pebbleReverseIterator) Value() []byte {
 	if !it.valid {
 		return nil
 	}
-	data, err := it.Iterator.ValueAndErr()
-	if err != nil {
-		it.err = append(it.err, err)
-		return nil
-	}
-	return data
+	return valueAt(it.Iterator, it.codec, &it.err)
 }
 
 func (it *pebbleReverseIterator) Release() {
@@ -246,8 +1657,5 @@ func (it *pebbleReverseIterator) Release() {
 }
 
 func (it *pebbleReverseIterator) Error() error {
-	if len(it.err) == 0 {
-		return nil
-	}
-	return it.err[len(it.err)-1]
+	return errors.Join(it.err...)
 }
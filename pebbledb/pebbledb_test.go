@@ -2,7 +2,16 @@ package pebbledb_test
 
 import (
 	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
+
+	"github.com/cockroachdb/pebble"
+	"github.com/cockroachdb/pebble/vfs"
 
 	"github.com/rawbytedev/zerokv"
 	"github.com/rawbytedev/zerokv/helpers"
@@ -41,6 +50,107 @@ func TestPebbleBatchOperations(t *testing.T) {
 	defer db.Close()
 }
 
+// TestPebbleConcurrentGetCopies verifies values returned by Get stay intact
+// after the underlying closer has been released and other reads/writes happen.
+func TestPebbleConcurrentGetCopies(t *testing.T) {
+	db := helpers.SetupDB(t, "pebbledb")
+	defer db.Close()
+	const workers = 8
+	const rounds = 200
+	var wg sync.WaitGroup
+	errs := make(chan error, workers)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			key := helpers.RandomBytes(16)
+			kept := make([][]byte, 0, rounds)
+			expected := make([][]byte, 0, rounds)
+			for i := 0; i < rounds; i++ {
+				value := helpers.RandomBytes(64)
+				if err := db.Put(t.Context(), key, value); err != nil {
+					errs <- err
+					return
+				}
+				got, err := db.Get(t.Context(), key)
+				if err != nil {
+					errs <- err
+					return
+				}
+				kept = append(kept, got)
+				expected = append(expected, value)
+			}
+			// every previously returned slice must still hold its original bytes
+			for i := range kept {
+				if !bytes.Equal(kept[i], expected[i]) {
+					errs <- fmt.Errorf("value %d was modified after Get returned", i)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		require.NoError(t, err)
+	}
+}
+
+// TestPebbleScanEmptyPrefix verifies an empty prefix scans the whole keyspace.
+func TestPebbleScanEmptyPrefix(t *testing.T) {
+	db := helpers.SetupDB(t, "pebbledb")
+	defer db.Close()
+	keys := [][]byte{[]byte("a"), []byte("b/1"), []byte("c"), {0xFF}}
+	for _, key := range keys {
+		require.NoError(t, db.Put(t.Context(), key, []byte("value")))
+	}
+	for _, prefix := range [][]byte{nil, []byte("")} {
+		it := db.Scan(prefix)
+		require.NotNil(t, it, "Iterator should not be nil for an empty prefix")
+		found := make([][]byte, 0)
+		for it.Next() {
+			key := make([]byte, len(it.Key()))
+			copy(key, it.Key())
+			found = append(found, key)
+		}
+		require.NoError(t, it.Error())
+		it.Release()
+		require.Equal(t, keys, found, "Empty prefix should return every key")
+	}
+}
+
+// TestPebbleScanPrefixEndingInFF verifies prefixes ending in 0xFF get a valid upper bound.
+func TestPebbleScanPrefixEndingInFF(t *testing.T) {
+	db := helpers.SetupDB(t, "pebbledb")
+	defer db.Close()
+	prefix := []byte{0x10, 0xFF}
+	keys := [][]byte{{0x10, 0xFF}, {0x10, 0xFF, 0x00}, {0x10, 0xFF, 0x01}, {0x10, 0xFF, 0xFF}}
+	for _, key := range keys {
+		require.NoError(t, db.Put(t.Context(), key, []byte("value")))
+	}
+	// keys just outside the prefix on either side
+	require.NoError(t, db.Put(t.Context(), []byte{0x10, 0xFE}, []byte("value")))
+	require.NoError(t, db.Put(t.Context(), []byte{0x11}, []byte("value")))
+
+	it := db.Scan(prefix)
+	count := 0
+	for it.Next() {
+		require.True(t, bytes.HasPrefix(it.Key(), prefix), "Key should have prefix")
+		count++
+	}
+	require.NoError(t, it.Error())
+	it.Release()
+	require.Equal(t, len(keys), count, "Should iterate every key under the prefix")
+
+	// an all-0xFF prefix has no upper bound
+	require.NoError(t, db.Put(t.Context(), []byte{0xFF, 0xFF, 0x01}, []byte("value")))
+	it = db.Scan([]byte{0xFF, 0xFF})
+	require.True(t, it.Next())
+	require.Equal(t, []byte{0xFF, 0xFF, 0x01}, it.Key())
+	require.False(t, it.Next())
+	it.Release()
+}
+
 // Helper to fill database with test values
 func fillPebbleValues(t *testing.T, db zerokv.Core) ([][]byte, [][]byte) {
 	keys := make([][]byte, 10)
@@ -180,3 +290,387 @@ func TestPebbleReverseIteratorOrder(t *testing.T) {
 
 	defer pdb.Close()
 }
+
+// TestPebbleTTLSweeper verifies the sweeper removes expired entries from disk
+// without any reads triggering it.
+func TestPebbleTTLSweeper(t *testing.T) {
+	dir := t.TempDir()
+	db, err := pebbledb.NewPebbleDB(pebbledb.Config{
+		Dir:           dir,
+		EnableTTL:     true,
+		SweepExpired:  true,
+		SweepInterval: 50 * time.Millisecond,
+	})
+	require.NoError(t, err)
+	for i := 0; i < 20; i++ {
+		key := []byte(fmt.Sprintf("session_%02d", i))
+		require.NoError(t, db.PutWithTTL(t.Context(), key, []byte("value"), 10*time.Millisecond))
+	}
+	require.NoError(t, db.Put(t.Context(), []byte("user"), []byte("value")))
+
+	time.Sleep(300 * time.Millisecond)
+	require.NoError(t, db.Close())
+
+	raw, err := pebble.Open(dir, &pebble.Options{})
+	require.NoError(t, err)
+	defer raw.Close()
+	it, err := raw.NewIter(nil)
+	require.NoError(t, err)
+	var keys [][]byte
+	for valid := it.First(); valid; valid = it.Next() {
+		keys = append(keys, bytes.Clone(it.Key()))
+	}
+	require.NoError(t, it.Close())
+	require.Equal(t, [][]byte{[]byte("user")}, keys, "Only the key without a TTL should remain on disk")
+}
+
+// TestPebbleNewOptions verifies New applies functional options, including a
+// read-only reopen.
+func TestPebbleNewOptions(t *testing.T) {
+	tmp := t.TempDir()
+	db, err := pebbledb.New(tmp, pebbledb.WithTTL(), pebbledb.WithMemTableSize(4<<20), pebbledb.WithSyncWrites(false))
+	require.NoError(t, err)
+	require.NoError(t, db.PutWithTTL(t.Context(), []byte("key"), []byte("value"), time.Hour))
+	require.NoError(t, db.Close())
+
+	db, err = pebbledb.New(tmp, pebbledb.WithTTL(), pebbledb.WithReadOnly())
+	require.NoError(t, err)
+	defer db.Close()
+	got, err := db.Get(t.Context(), []byte("key"))
+	require.NoError(t, err)
+	require.Equal(t, []byte("value"), got)
+	require.ErrorIs(t, db.Put(t.Context(), []byte("key"), []byte("value")), zerokv.ErrReadOnly)
+}
+
+// TestPebbleReadOnly verifies a read-only database serves reads and rejects
+// every write with zerokv.ErrReadOnly.
+func TestPebbleReadOnly(t *testing.T) {
+	tmp := t.TempDir()
+	db, err := pebbledb.NewPebbleDB(pebbledb.Config{Dir: tmp})
+	require.NoError(t, err)
+	require.NoError(t, db.Put(t.Context(), []byte("key"), []byte("value")))
+	require.NoError(t, db.Close())
+
+	db, err = pebbledb.NewPebbleDB(pebbledb.Config{Dir: tmp, ReadOnly: true})
+	require.NoError(t, err)
+	defer db.Close()
+	value, err := db.Get(t.Context(), []byte("key"))
+	require.NoError(t, err)
+	require.Equal(t, []byte("value"), value)
+
+	require.ErrorIs(t, db.Put(t.Context(), []byte("key"), []byte("new")), zerokv.ErrReadOnly)
+	require.ErrorIs(t, db.Delete(t.Context(), []byte("key")), zerokv.ErrReadOnly)
+	require.ErrorIs(t, db.PutMany(t.Context(), [][]byte{[]byte("a")}, [][]byte{[]byte("b")}), zerokv.ErrReadOnly)
+	_, err = db.DeletePrefix(t.Context(), nil)
+	require.ErrorIs(t, err, zerokv.ErrReadOnly)
+	_, err = db.CompareAndSwap(t.Context(), []byte("key"), []byte("value"), []byte("new"))
+	require.ErrorIs(t, err, zerokv.ErrReadOnly)
+	_, err = db.NewTransaction(t.Context())
+	require.ErrorIs(t, err, zerokv.ErrReadOnly)
+	batch := db.Batch()
+	_ = batch.Put([]byte("key"), []byte("new"))
+	require.ErrorIs(t, batch.Commit(t.Context()), zerokv.ErrReadOnly)
+	require.NoError(t, db.Sync(t.Context()))
+
+	value, err = db.Get(t.Context(), []byte("key"))
+	require.NoError(t, err)
+	require.Equal(t, []byte("value"), value)
+}
+
+// TestPebbleIteratorJoinsErrors verifies Error reports every failure seen
+// during a scan, not just the last one. Values written without EnableTTL
+// have no TTL header, so reading them through a TTL database fails.
+func TestPebbleIteratorJoinsErrors(t *testing.T) {
+	tmp := t.TempDir()
+	db, err := pebbledb.NewPebbleDB(pebbledb.Config{Dir: tmp})
+	require.NoError(t, err)
+	require.NoError(t, db.Put(t.Context(), []byte("bad_1"), []byte("\x07one")))
+	require.NoError(t, db.Put(t.Context(), []byte("bad_2"), []byte("\x07two")))
+	require.NoError(t, db.Close())
+
+	db, err = pebbledb.NewPebbleDB(pebbledb.Config{Dir: tmp, EnableTTL: true})
+	require.NoError(t, err)
+	defer db.Close()
+	it := db.Scan([]byte("bad_"))
+	for it.Next() {
+		require.Nil(t, it.Value())
+	}
+	err = it.Error()
+	it.Release()
+	require.Error(t, err)
+	joined, ok := err.(interface{ Unwrap() []error })
+	require.True(t, ok, "Error should join every failure")
+	require.Len(t, joined.Unwrap(), 2)
+}
+
+// TestPebbleCompactReclaimsSpace verifies Compact drops the sstable data of a
+// deleted range.
+func TestPebbleCompactReclaimsSpace(t *testing.T) {
+	db := helpers.SetupDB(t, "pebbledb")
+	defer db.Close()
+	for i := 0; i < 1000; i++ {
+		require.NoError(t, db.Put(t.Context(), []byte(fmt.Sprintf("key_%04d", i)), helpers.RandomBytes(1024)))
+	}
+	require.NoError(t, db.Sync(t.Context()))
+	before, err := db.EstimateSize([]byte("key_"))
+	require.NoError(t, err)
+	require.Positive(t, before)
+
+	require.NoError(t, db.DeleteRange(t.Context(), []byte("key_"), []byte("key`")))
+	require.NoError(t, db.Compact(t.Context(), nil, nil))
+	after, err := db.EstimateSize([]byte("key_"))
+	require.NoError(t, err)
+	require.Less(t, after, before)
+}
+
+// TestPebbleAsync tests that PutAsync and GetAsync round-trip, return the
+// context error once it is done and refuse to run after Close.
+func TestPebbleAsync(t *testing.T) {
+	db := helpers.SetupDB(t, "pebbledb").(*pebbledb.PebbleDB)
+	require.NoError(t, db.PutAsync(t.Context(), []byte("key"), []byte("value")))
+	value, err := db.GetAsync(t.Context(), []byte("key"))
+	require.NoError(t, err)
+	require.Equal(t, []byte("value"), value)
+	_, err = db.GetAsync(t.Context(), []byte("missing"))
+	require.ErrorIs(t, err, zerokv.ErrKeyNotFound)
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	require.ErrorIs(t, db.PutAsync(ctx, []byte("key"), []byte("other")), context.Canceled)
+	_, err = db.GetAsync(ctx, []byte("key"))
+	require.ErrorIs(t, err, context.Canceled)
+
+	require.NoError(t, db.Close())
+	require.ErrorIs(t, db.PutAsync(t.Context(), []byte("key"), []byte("late")), pebble.ErrClosed)
+}
+
+func TestPebbleLevelMetrics(t *testing.T) {
+	db := helpers.SetupDB(t, "pebbledb")
+	reporter, ok := db.(pebbledb.LevelMetricsReporter)
+	require.True(t, ok, "PebbleDB should report level metrics")
+
+	for i := 0; i < 1000; i++ {
+		require.NoError(t, db.Put(t.Context(), []byte(fmt.Sprintf("key_%04d", i)), helpers.RandomBytes(1024)))
+	}
+	// Sync flushes the memtable, which writes the data to L0
+	require.NoError(t, db.Sync(t.Context()))
+	levels := reporter.LevelMetrics()
+	require.Len(t, levels, 7)
+	for i, l := range levels {
+		require.Equal(t, i, l.Level)
+	}
+	l0 := levels[0]
+	require.Positive(t, l0.NumFiles)
+	require.Positive(t, l0.Size)
+	require.Positive(t, l0.ReadAmp)
+	require.Positive(t, l0.BytesFlushed)
+
+	require.NoError(t, db.Close())
+	require.Nil(t, reporter.LevelMetrics())
+}
+
+// stallFS is an in-memory filesystem whose syncs block while stalled, to
+// imitate a disk that stops responding.
+type stallFS struct {
+	vfs.FS
+	stalled atomic.Bool
+	release chan struct{}
+}
+
+func (fs *stallFS) Create(name string) (vfs.File, error) {
+	f, err := fs.FS.Create(name)
+	return &stallFile{File: f, fs: fs}, err
+}
+
+func (fs *stallFS) ReuseForWrite(oldname, newname string) (vfs.File, error) {
+	f, err := fs.FS.ReuseForWrite(oldname, newname)
+	return &stallFile{File: f, fs: fs}, err
+}
+
+type stallFile struct {
+	vfs.File
+	fs *stallFS
+}
+
+func (f *stallFile) wait() {
+	if f.fs.stalled.Load() {
+		<-f.fs.release
+	}
+}
+
+func (f *stallFile) Sync() error     { f.wait(); return f.File.Sync() }
+func (f *stallFile) SyncData() error { f.wait(); return f.File.SyncData() }
+func (f *stallFile) SyncTo(length int64) (bool, error) {
+	f.wait()
+	return f.File.SyncTo(length)
+}
+
+// logRecorder is a pebble.Logger that keeps what it is given.
+type logRecorder struct {
+	mu   sync.Mutex
+	msgs []string
+}
+
+func (l *logRecorder) Infof(format string, args ...any) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.msgs = append(l.msgs, fmt.Sprintf(format, args...))
+}
+
+func (l *logRecorder) Fatalf(format string, args ...any) { panic(fmt.Sprintf(format, args...)) }
+
+func (l *logRecorder) contains(s string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, msg := range l.msgs {
+		if strings.Contains(msg, s) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestPebbleWriteTimeout(t *testing.T) {
+	fs := &stallFS{FS: vfs.NewMem(), release: make(chan struct{})}
+	logs := &logRecorder{}
+	db, err := pebbledb.New("db",
+		pebbledb.WithPebbleOptions(&pebble.Options{FS: fs}),
+		pebbledb.WithLogger(logs),
+		pebbledb.WithWriteTimeout(50*time.Millisecond),
+	)
+	require.NoError(t, err)
+	defer db.Close()
+	require.NoError(t, db.Put(t.Context(), []byte("fast"), []byte("value")))
+
+	fs.stalled.Store(true)
+	start := time.Now()
+	err = db.Put(t.Context(), []byte("slow"), []byte("value"))
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.Less(t, time.Since(start), 5*time.Second)
+	require.True(t, logs.contains("may still be applied"), "an abandoned write should be logged")
+	require.True(t, logs.contains("4-byte key"))
+	require.False(t, logs.contains("slow"), "the key itself should not be logged")
+
+	// the abandoned write lands once the disk recovers
+	fs.stalled.Store(false)
+	close(fs.release)
+	require.Eventually(t, func() bool {
+		got, err := db.Get(t.Context(), []byte("slow"))
+		return err == nil && string(got) == "value"
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+// TestPebbleSizeLimits verifies writes over MaxKeySize or MaxValueSize fail
+// with the typed errors and store nothing.
+func TestPebbleSizeLimits(t *testing.T) {
+	db, err := pebbledb.New(t.TempDir(), pebbledb.WithTTL(), pebbledb.WithMaxKeySize(8), pebbledb.WithMaxValueSize(16))
+	require.NoError(t, err)
+	defer db.Close()
+	longKey, bigValue := []byte("key_too_long"), bytes.Repeat([]byte("v"), 17)
+
+	require.NoError(t, db.Put(t.Context(), []byte("key"), bytes.Repeat([]byte("v"), 16)))
+	require.ErrorIs(t, db.Put(t.Context(), longKey, []byte("value")), zerokv.ErrKeyTooLarge)
+	require.ErrorIs(t, db.Put(t.Context(), []byte("key"), bigValue), zerokv.ErrValueTooLarge)
+	require.ErrorIs(t, db.PutWithTTL(t.Context(), longKey, []byte("value"), time.Hour), zerokv.ErrKeyTooLarge)
+	require.ErrorIs(t, db.PutMany(t.Context(), [][]byte{[]byte("a"), []byte("b")}, [][]byte{[]byte("v"), bigValue}), zerokv.ErrValueTooLarge)
+	_, err = db.CompareAndSwap(t.Context(), []byte("key"), bytes.Repeat([]byte("v"), 16), bigValue)
+	require.ErrorIs(t, err, zerokv.ErrValueTooLarge)
+
+	batch := db.Batch()
+	require.ErrorIs(t, batch.Put(longKey, []byte("value")), zerokv.ErrKeyTooLarge)
+	require.ErrorIs(t, batch.Put([]byte("key"), bigValue), zerokv.ErrValueTooLarge)
+	require.Zero(t, batch.Len())
+	require.NoError(t, batch.Delete(longKey))
+	require.ErrorIs(t, batch.Validate(), zerokv.ErrKeyTooLarge, "Validate should report the key Delete let through")
+	require.NoError(t, batch.Commit(t.Context()))
+	require.NoError(t, batch.Validate())
+
+	txn, err := db.NewTransaction(t.Context())
+	require.NoError(t, err)
+	require.ErrorIs(t, txn.Put(t.Context(), []byte("key"), bigValue), zerokv.ErrValueTooLarge)
+	txn.Discard()
+
+	has, err := db.Has(t.Context(), longKey)
+	require.NoError(t, err)
+	require.False(t, has)
+	has, err = db.Has(t.Context(), []byte("a"))
+	require.NoError(t, err)
+	require.False(t, has, "PutMany should not write part of the pairs")
+	got, err := db.Get(t.Context(), []byte("key"))
+	require.NoError(t, err)
+	require.Len(t, got, 16)
+}
+
+// TestPebbleReplica verifies a replica opened over a primary's directory
+// rejects writes, sees new data after a refresh, and catches up on its own.
+func TestPebbleReplica(t *testing.T) {
+	dir := t.TempDir()
+	primary, err := pebbledb.New(dir)
+	require.NoError(t, err)
+	defer primary.Close()
+	ctx := t.Context()
+	require.NoError(t, primary.Put(ctx, []byte("first"), []byte("1")))
+
+	db, err := pebbledb.NewPebbleReplica(dir, pebbledb.WithRefreshInterval(time.Hour))
+	require.NoError(t, err)
+	replica := db.(*pebbledb.Replica)
+	got, err := replica.Get(ctx, []byte("first"))
+	require.NoError(t, err)
+	require.Equal(t, []byte("1"), got)
+	require.ErrorIs(t, replica.Put(ctx, []byte("key"), []byte("value")), zerokv.ErrReadOnly)
+	require.ErrorIs(t, replica.Delete(ctx, []byte("first")), zerokv.ErrReadOnly)
+	batch := replica.Batch()
+	require.NoError(t, batch.Put([]byte("key"), []byte("value")))
+	require.ErrorIs(t, batch.Commit(ctx), zerokv.ErrReadOnly)
+
+	// new writes stay invisible until the replica refreshes, and an
+	// iterator opened before the refresh keeps working on its old view
+	require.NoError(t, primary.Put(ctx, []byte("second"), []byte("2")))
+	require.NoError(t, primary.Sync(ctx))
+	_, err = replica.Get(ctx, []byte("second"))
+	require.ErrorIs(t, err, zerokv.ErrKeyNotFound)
+	it := replica.Scan(nil)
+	require.NoError(t, replica.Refresh())
+	got, err = replica.Get(ctx, []byte("second"))
+	require.NoError(t, err)
+	require.Equal(t, []byte("2"), got)
+	entries, err := zerokv.CollectAll(it)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	require.NoError(t, replica.Close())
+
+	// with a short interval the replica catches up on its own, including
+	// writes still only in the primary's WAL
+	db, err = pebbledb.NewPebbleReplica(dir, pebbledb.WithRefreshInterval(10*time.Millisecond))
+	require.NoError(t, err)
+	defer db.Close()
+	require.NoError(t, primary.Put(ctx, []byte("third"), []byte("3")))
+	require.Eventually(t, func() bool {
+		has, err := db.Has(ctx, []byte("third"))
+		return err == nil && has
+	}, 5*time.Second, 10*time.Millisecond)
+	n, err := db.Count(ctx, nil)
+	require.NoError(t, err)
+	require.EqualValues(t, 3, n)
+}
+
+// TestPebbleScanWithPrefetch verifies prefetch is ignored and the scan
+// matches Scan.
+func TestPebbleScanWithPrefetch(t *testing.T) {
+	db := helpers.SetupDB(t, "pebbledb")
+	defer db.Close()
+	require.NoError(t, db.Put(t.Context(), []byte("pre_1"), []byte("a")))
+	require.NoError(t, db.Put(t.Context(), []byte("pre_2"), []byte("b")))
+	require.NoError(t, db.Put(t.Context(), []byte("other"), []byte("c")))
+
+	scanner, ok := db.(zerokv.PrefetchScanner)
+	require.True(t, ok)
+	it := scanner.ScanWithPrefetch([]byte("pre_"), 1000)
+	defer it.Release()
+	var values []string
+	for it.Next() {
+		values = append(values, string(it.Value()))
+	}
+	require.NoError(t, it.Error())
+	require.Equal(t, []string{"a", "b"}, values)
+}
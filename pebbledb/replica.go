@@ -0,0 +1,424 @@
+package pebbledb
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/cockroachdb/pebble"
+	"github.com/cockroachdb/pebble/vfs"
+	"github.com/rawbytedev/zerokv"
+)
+
+// defaultRefreshInterval is the catch-up interval of a replica whose
+// Config.RefreshInterval is zero.
+const defaultRefreshInterval = time.Second
+
+// Replica is a read-only view of a Pebble directory that another process,
+// the primary, keeps writing to. Pebble cannot pick up another process's
+// writes in a database it already has open, so the replica reopens the
+// directory read-only every Config.RefreshInterval and switches reads over
+// to the new view. Reads already running, and iterators and snapshots
+// opened earlier, keep the view they started on until they finish.
+//
+// Writes return zerokv.ErrReadOnly and Watch returns zerokv.ErrNotSupported,
+// since the replica never sees individual writes.
+type Replica struct {
+	cfg Config
+
+	mu     sync.RWMutex
+	view   *replicaView // the latest view, kept after Close
+	closed bool
+
+	stop     chan struct{}
+	loopDone chan struct{}
+	retiring sync.WaitGroup // views waiting for their readers to close them
+}
+
+// replicaView is one read-only opening of the directory. refs counts the
+// calls, iterators and snapshots using it; a view that has been replaced is
+// closed once refs drops to zero.
+type replicaView struct {
+	db   *PebbleDB
+	refs sync.WaitGroup
+}
+
+// NewPebbleReplica opens dir, which a primary PebbleDB in another process
+// may hold open, as a Replica and starts reopening it every
+// Config.RefreshInterval (see WithRefreshInterval). Options such as WithTTL
+// must match the primary's.
+//
+// A read sees the writes the primary had made when the current view was
+// opened, so it lags the primary by up to RefreshInterval plus the time a
+// reopen takes, which grows with the primary's unflushed WAL. Writes the
+// primary made with NoSync are visible as soon as they reach the file
+// system, not only once synced. Call Refresh to catch up immediately.
+//
+// The replica bypasses Pebble's directory lock, which is otherwise held by
+// the primary. A view that overlaps a compaction on the primary can fail a
+// read whose sstable the primary just deleted; the next refresh recovers.
+func NewPebbleReplica(dir string, opts ...Option) (zerokv.Core, error) {
+	cfg := DefaultOptions(dir)
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	view, err := openReplicaView(*cfg)
+	if err != nil {
+		return nil, err
+	}
+	interval := cfg.RefreshInterval
+	if interval <= 0 {
+		interval = defaultRefreshInterval
+	}
+	r := &Replica{cfg: *cfg, view: view, stop: make(chan struct{}), loopDone: make(chan struct{})}
+	go r.refreshLoop(interval)
+	return r, nil
+}
+
+// sharedFS lets a replica open a directory whose lock the primary holds.
+type sharedFS struct {
+	vfs.FS
+}
+
+func (sharedFS) Lock(name string) (io.Closer, error) {
+	return nopCloser{}, nil
+}
+
+type nopCloser struct{}
+
+func (nopCloser) Close() error { return nil }
+
+// openReplicaView opens cfg.Dir read-only without taking its lock.
+func openReplicaView(cfg Config) (*replicaView, error) {
+	var opts pebble.Options
+	if cfg.PebbleConfigs != nil {
+		opts = *cfg.PebbleConfigs
+	}
+	opts.ReadOnly = true
+	if opts.FS == nil {
+		opts.FS = vfs.Default
+	}
+	opts.FS = sharedFS{opts.FS}
+	cfg.PebbleConfigs = &opts
+	cfg.SweepExpired = false
+	db, err := NewPebbleDB(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &replicaView{db: db.(*PebbleDB)}, nil
+}
+
+func (r *Replica) refreshLoop(interval time.Duration) {
+	defer close(r.loopDone)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.stop:
+			return
+		case <-ticker.C:
+		}
+		if err := r.Refresh(); err != nil {
+			r.logf("pebbledb: replica refresh of %s failed, still serving the previous view: %v", r.cfg.Dir, err)
+		}
+	}
+}
+
+// logf reports through the current view's logger.
+func (r *Replica) logf(format string, args ...any) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	r.view.db.logger.Infof(format, args...)
+}
+
+// Refresh reopens the directory now and serves reads from the new view.
+// On error the previous view stays in use.
+func (r *Replica) Refresh() error {
+	view, err := openReplicaView(r.cfg)
+	if err != nil {
+		return err
+	}
+	r.mu.Lock()
+	if r.closed {
+		r.mu.Unlock()
+		view.db.Close()
+		return pebble.ErrClosed
+	}
+	old := r.view
+	r.view = view
+	r.mu.Unlock()
+	r.retiring.Add(1)
+	go func() {
+		defer r.retiring.Done()
+		old.refs.Wait()
+		old.db.Close()
+	}()
+	return nil
+}
+
+// acquire returns the current view with a reference the caller must
+// release with refs.Done.
+func (r *Replica) acquire() (*replicaView, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if r.closed {
+		return nil, pebble.ErrClosed
+	}
+	r.view.refs.Add(1)
+	return r.view, nil
+}
+
+// read runs fn on the current view.
+func read[T any](r *Replica, fn func(db *PebbleDB) (T, error)) (T, error) {
+	view, err := r.acquire()
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	defer view.refs.Done()
+	return fn(view.db)
+}
+
+// iterator opens an iterator on the current view that holds it until
+// released.
+func (r *Replica) iterator(open func(db *PebbleDB) zerokv.Iterator) zerokv.Iterator {
+	view, err := r.acquire()
+	if err != nil {
+		return zerokv.NewErrIterator(err)
+	}
+	return &replicaIterator{Iterator: open(view.db), view: view}
+}
+
+func (r *Replica) Put(ctx context.Context, key []byte, data []byte) error {
+	return zerokv.ErrReadOnly
+}
+
+func (r *Replica) PutWithTTL(ctx context.Context, key, value []byte, ttl time.Duration) error {
+	return zerokv.ErrReadOnly
+}
+
+func (r *Replica) Get(ctx context.Context, key []byte) ([]byte, error) {
+	return read(r, func(db *PebbleDB) ([]byte, error) { return db.Get(ctx, key) })
+}
+
+func (r *Replica) GetOrDefault(ctx context.Context, key, def []byte) ([]byte, error) {
+	return read(r, func(db *PebbleDB) ([]byte, error) { return db.GetOrDefault(ctx, key, def) })
+}
+
+func (r *Replica) GetInto(ctx context.Context, key, dst []byte) ([]byte, error) {
+	value, err := read(r, func(db *PebbleDB) ([]byte, error) { return db.GetInto(ctx, key, dst) })
+	if err != nil {
+		return dst[:0], err
+	}
+	return value, nil
+}
+
+func (r *Replica) View(ctx context.Context, key []byte, fn func(value []byte) error) error {
+	_, err := read(r, func(db *PebbleDB) (struct{}, error) { return struct{}{}, db.View(ctx, key, fn) })
+	return err
+}
+
+func (r *Replica) Has(ctx context.Context, key []byte) (bool, error) {
+	return read(r, func(db *PebbleDB) (bool, error) { return db.Has(ctx, key) })
+}
+
+func (r *Replica) SizeOf(ctx context.Context, key []byte) (int, error) {
+	return read(r, func(db *PebbleDB) (int, error) { return db.SizeOf(ctx, key) })
+}
+
+func (r *Replica) GetMany(ctx context.Context, keys [][]byte) ([][]byte, error) {
+	return read(r, func(db *PebbleDB) ([][]byte, error) { return db.GetMany(ctx, keys) })
+}
+
+func (r *Replica) HasMany(ctx context.Context, keys [][]byte) ([]bool, error) {
+	return read(r, func(db *PebbleDB) ([]bool, error) { return db.HasMany(ctx, keys) })
+}
+
+func (r *Replica) PutMany(ctx context.Context, keys, values [][]byte) error {
+	return zerokv.ErrReadOnly
+}
+
+func (r *Replica) Delete(ctx context.Context, key []byte) error {
+	return zerokv.ErrReadOnly
+}
+
+func (r *Replica) DeletePrefix(ctx context.Context, prefix []byte) (int, error) {
+	return 0, zerokv.ErrReadOnly
+}
+
+func (r *Replica) DeleteRange(ctx context.Context, start, end []byte) error {
+	return zerokv.ErrReadOnly
+}
+
+func (r *Replica) Truncate(ctx context.Context) error {
+	return zerokv.ErrReadOnly
+}
+
+func (r *Replica) TruncatePrefix(ctx context.Context, prefix []byte) error {
+	return zerokv.ErrReadOnly
+}
+
+func (r *Replica) Count(ctx context.Context, prefix []byte) (int64, error) {
+	return read(r, func(db *PebbleDB) (int64, error) { return db.Count(ctx, prefix) })
+}
+
+func (r *Replica) EstimateSize(prefix []byte) (int64, error) {
+	return read(r, func(db *PebbleDB) (int64, error) { return db.EstimateSize(prefix) })
+}
+
+func (r *Replica) NewTransaction(ctx context.Context) (zerokv.Txn, error) {
+	return nil, zerokv.ErrReadOnly
+}
+
+func (r *Replica) CompareAndSwap(ctx context.Context, key, old, new []byte) (bool, error) {
+	return false, zerokv.ErrReadOnly
+}
+
+func (r *Replica) Increment(ctx context.Context, key []byte, delta int64) (int64, error) {
+	return 0, zerokv.ErrReadOnly
+}
+
+func (r *Replica) GetOrPut(ctx context.Context, key []byte, fill func() ([]byte, error)) ([]byte, error) {
+	return nil, zerokv.ErrReadOnly
+}
+
+func (r *Replica) Merge(ctx context.Context, key, operand []byte, merge func(existing, operand []byte) []byte) error {
+	return zerokv.ErrReadOnly
+}
+
+func (r *Replica) Stats(ctx context.Context) (zerokv.Stats, error) {
+	return read(r, func(db *PebbleDB) (zerokv.Stats, error) { return db.Stats(ctx) })
+}
+
+// Sync does nothing: a replica has no writes of its own to sync.
+func (r *Replica) Sync(ctx context.Context) error {
+	return ctx.Err()
+}
+
+func (r *Replica) Compact(ctx context.Context, start, end []byte) error {
+	return zerokv.ErrReadOnly
+}
+
+func (r *Replica) Ping(ctx context.Context) error {
+	_, err := read(r, func(db *PebbleDB) (struct{}, error) { return struct{}{}, db.Ping(ctx) })
+	return err
+}
+
+func (r *Replica) Backup(ctx context.Context, w io.Writer) error {
+	_, err := read(r, func(db *PebbleDB) (struct{}, error) { return struct{}{}, db.Backup(ctx, w) })
+	return err
+}
+
+func (r *Replica) Restore(ctx context.Context, rd io.Reader) error {
+	return zerokv.ErrReadOnly
+}
+
+func (r *Replica) Checkpoint(ctx context.Context, destDir string) error {
+	_, err := read(r, func(db *PebbleDB) (struct{}, error) { return struct{}{}, db.Checkpoint(ctx, destDir) })
+	return err
+}
+
+// Snapshot pins the current view until the snapshot is released.
+func (r *Replica) Snapshot() (zerokv.Snapshot, error) {
+	view, err := r.acquire()
+	if err != nil {
+		return nil, err
+	}
+	snap, err := view.db.Snapshot()
+	if err != nil {
+		view.refs.Done()
+		return nil, err
+	}
+	return &replicaSnapshot{Snapshot: snap, view: view}, nil
+}
+
+// Batch returns a batch whose Commit fails with zerokv.ErrReadOnly.
+func (r *Replica) Batch() zerokv.Batch {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.view.db.Batch()
+}
+
+func (r *Replica) BatchWithOptions(maxOps, maxBytes int) zerokv.Batch {
+	return zerokv.NewAutoFlushBatch(r.Batch, maxOps, maxBytes)
+}
+
+func (r *Replica) BulkLoad(ctx context.Context, fn func(zerokv.Batch) error) error {
+	return zerokv.ErrReadOnly
+}
+
+func (r *Replica) Scan(prefix []byte) zerokv.Iterator {
+	return r.iterator(func(db *PebbleDB) zerokv.Iterator { return db.Scan(prefix) })
+}
+
+func (r *Replica) ReverseScan(prefix []byte) zerokv.Iterator {
+	return r.iterator(func(db *PebbleDB) zerokv.Iterator { return db.ReverseScan(prefix) })
+}
+
+func (r *Replica) RangeScan(start, end []byte) zerokv.Iterator {
+	return r.iterator(func(db *PebbleDB) zerokv.Iterator { return db.RangeScan(start, end) })
+}
+
+func (r *Replica) ScanFrom(prefix, start []byte) zerokv.Iterator {
+	return r.iterator(func(db *PebbleDB) zerokv.Iterator { return db.ScanFrom(prefix, start) })
+}
+
+func (r *Replica) ScanKeys(prefix []byte) zerokv.Iterator {
+	return r.iterator(func(db *PebbleDB) zerokv.Iterator { return db.ScanKeys(prefix) })
+}
+
+func (r *Replica) ScanContext(ctx context.Context, prefix []byte) zerokv.Iterator {
+	return r.iterator(func(db *PebbleDB) zerokv.Iterator { return db.ScanContext(ctx, prefix) })
+}
+
+// Watch is not supported: the primary's writes reach the replica only as
+// whole new views.
+func (r *Replica) Watch(ctx context.Context, prefix []byte) (<-chan zerokv.Event, error) {
+	return nil, fmt.Errorf("pebbledb: Watch on a replica: %w", zerokv.ErrNotSupported)
+}
+
+// Close stops refreshing and closes every view once the iterators and
+// snapshots using it are released. Calling it again does nothing.
+func (r *Replica) Close() error {
+	r.mu.Lock()
+	if r.closed {
+		r.mu.Unlock()
+		return nil
+	}
+	r.closed = true
+	view := r.view
+	r.mu.Unlock()
+
+	close(r.stop)
+	<-r.loopDone
+	view.refs.Wait()
+	err := view.db.Close()
+	r.retiring.Wait()
+	return err
+}
+
+// replicaIterator holds its view until released.
+type replicaIterator struct {
+	zerokv.Iterator
+	view *replicaView
+	once sync.Once
+}
+
+func (it *replicaIterator) Release() {
+	it.Iterator.Release()
+	it.once.Do(it.view.refs.Done)
+}
+
+// replicaSnapshot holds its view until released.
+type replicaSnapshot struct {
+	zerokv.Snapshot
+	view *replicaView
+	once sync.Once
+}
+
+func (s *replicaSnapshot) Release() {
+	s.Snapshot.Release()
+	s.once.Do(s.view.refs.Done)
+}
@@ -0,0 +1,271 @@
+package pebbledb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"time"
+
+	"github.com/cockroachdb/pebble"
+)
+
+// Pebble has no native expiry, so a PebbleDB opened with Config.EnableTTL
+// stores every value behind a header: ttlNone, or ttlExpiry followed by the
+// expiry time as big-endian Unix nanoseconds. Expired entries stay on disk
+// until they are overwritten, deleted or swept, but reads skip them.
+const (
+	ttlNone   byte = 0
+	ttlExpiry byte = 1
+
+	ttlExpiryLen = 1 + 8
+)
+
+// errTTLHeader is returned when a value lacks a valid TTL header, usually
+// because it was written by a PebbleDB opened without Config.EnableTTL.
+var errTTLHeader = errors.New("pebbledb: value has no valid TTL header")
+
+// valueCodec converts between user values and their stored form.
+// The zero value stores values unchanged.
+type valueCodec struct {
+	ttl bool
+}
+
+// encode returns the stored form of value; expiresAt is Unix nanoseconds,
+// or 0 for no expiry.
+func (c valueCodec) encode(value []byte, expiresAt int64) []byte {
+	if !c.ttl {
+		return value
+	}
+	if expiresAt == 0 {
+		out := make([]byte, 1+len(value))
+		out[0] = ttlNone
+		copy(out[1:], value)
+		return out
+	}
+	out := make([]byte, ttlExpiryLen+len(value))
+	out[0] = ttlExpiry
+	binary.BigEndian.PutUint64(out[1:ttlExpiryLen], uint64(expiresAt))
+	copy(out[ttlExpiryLen:], value)
+	return out
+}
+
+// decode returns the user value held in raw, aliasing it, and whether the
+// entry is still live at now (Unix nanoseconds).
+func (c valueCodec) decode(raw []byte, now int64) ([]byte, bool, error) {
+	value, expiresAt, err := c.split(raw)
+	if err != nil {
+		return nil, false, err
+	}
+	return value, expiresAt == 0 || now < expiresAt, nil
+}
+
+// split separates raw into the user value, aliasing raw, and its expiry in
+// Unix nanoseconds (0 for none).
+func (c valueCodec) split(raw []byte) ([]byte, int64, error) {
+	if !c.ttl {
+		return raw, 0, nil
+	}
+	switch {
+	case len(raw) >= 1 && raw[0] == ttlNone:
+		return raw[1:], 0, nil
+	case len(raw) >= ttlExpiryLen && raw[0] == ttlExpiry:
+		return raw[ttlExpiryLen:], int64(binary.BigEndian.Uint64(raw[1:ttlExpiryLen])), nil
+	}
+	return nil, 0, errTTLHeader
+}
+
+// get reads key from r and returns an owned copy of its value.
+// Expired entries are reported as pebble.ErrNotFound.
+func (c valueCodec) get(r pebble.Reader, key []byte) ([]byte, error) {
+	raw, closer, err := r.Get(key)
+	if err != nil {
+		return nil, err
+	}
+	// raw is only valid until closer is closed, so hand back an owned copy
+	val, live, err := c.decode(raw, time.Now().UnixNano())
+	var data []byte
+	if err == nil && live {
+		data = make([]byte, len(val))
+		copy(data, val)
+	}
+	if cerr := closer.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !live {
+		return nil, pebble.ErrNotFound
+	}
+	return data, nil
+}
+
+// view calls fn with the live value of key in r, aliasing Pebble's buffer,
+// and closes the buffer once fn returns. Expired entries are reported as
+// not found.
+func (c valueCodec) view(r pebble.Reader, key []byte, fn func(value []byte) error) error {
+	raw, closer, err := r.Get(key)
+	if err != nil {
+		return notFound(err)
+	}
+	defer closer.Close()
+	val, live, err := c.decode(raw, time.Now().UnixNano())
+	if err != nil {
+		return err
+	}
+	if !live {
+		return notFound(pebble.ErrNotFound)
+	}
+	return fn(val)
+}
+
+// size returns the length of key's live value in r without copying it.
+// Expired entries are reported as not found.
+func (c valueCodec) size(r pebble.Reader, key []byte) (int, error) {
+	raw, closer, err := r.Get(key)
+	if err != nil {
+		return 0, notFound(err)
+	}
+	val, live, err := c.decode(raw, time.Now().UnixNano())
+	if cerr := closer.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return 0, err
+	}
+	if !live {
+		return 0, notFound(pebble.ErrNotFound)
+	}
+	return len(val), nil
+}
+
+// has reports whether key holds a live entry in r without copying its value.
+func (c valueCodec) has(r pebble.Reader, key []byte) (bool, error) {
+	raw, closer, err := r.Get(key)
+	if errors.Is(err, pebble.ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	_, live, err := c.decode(raw, time.Now().UnixNano())
+	if cerr := closer.Close(); err == nil {
+		err = cerr
+	}
+	return live && err == nil, err
+}
+
+// expired reports whether the entry under it has expired. Entries with an
+// invalid header are treated as live so that reading them surfaces the error.
+func (c valueCodec) expired(it *pebble.Iterator, now int64) bool {
+	if !c.ttl {
+		return false
+	}
+	raw, err := it.ValueAndErr()
+	if err != nil {
+		return false
+	}
+	_, live, err := c.decode(raw, now)
+	return err == nil && !live
+}
+
+// valueAt returns a copy of the decoded value under it, recording failures
+// in errs. The iterator's own slice is only valid until it moves.
+func valueAt(it *pebble.Iterator, c valueCodec, errs *[]error) []byte {
+	raw, err := it.ValueAndErr()
+	if err != nil {
+		*errs = append(*errs, err)
+		return nil
+	}
+	data, _, err := c.decode(raw, 0)
+	if err != nil {
+		*errs = append(*errs, err)
+		return nil
+	}
+	return bytes.Clone(data)
+}
+
+const (
+	defaultSweepInterval = time.Minute
+	// sweepBatchSize bounds how long a sweep holds up writers at a time.
+	sweepBatchSize = 1000
+)
+
+// sweepLoop deletes expired entries every interval until Close.
+func (p *PebbleDB) sweepLoop(interval time.Duration) {
+	defer close(p.sweepDone)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.stopSweep:
+			return
+		case <-ticker.C:
+			// a failed sweep is retried on the next tick
+			_ = p.sweep()
+		}
+	}
+}
+
+// sweep scans the whole keyspace and deletes entries that have expired.
+func (p *PebbleDB) sweep() error {
+	it, err := p.db.NewIter(nil)
+	if err != nil {
+		return err
+	}
+	var expired [][]byte
+	now := time.Now().UnixNano()
+	for valid := it.First(); valid; valid = it.Next() {
+		select {
+		case <-p.stopSweep:
+			return it.Close()
+		default:
+		}
+		if !p.codec.expired(it, now) {
+			continue
+		}
+		expired = append(expired, bytes.Clone(it.Key()))
+		if len(expired) == sweepBatchSize {
+			if err := p.deleteExpired(expired); err != nil {
+				return errors.Join(err, it.Close())
+			}
+			expired = expired[:0]
+		}
+	}
+	if err := it.Close(); err != nil {
+		return err
+	}
+	return p.deleteExpired(expired)
+}
+
+// deleteExpired deletes the keys that are still expired. It holds sweepMu
+// so that a key rewritten since the scan is never removed.
+func (p *PebbleDB) deleteExpired(keys [][]byte) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	p.sweepMu.Lock()
+	defer p.sweepMu.Unlock()
+	batch := p.db.NewBatch()
+	defer batch.Close()
+	now := time.Now().UnixNano()
+	for _, key := range keys {
+		raw, closer, err := p.db.Get(key)
+		if errors.Is(err, pebble.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		_, live, err := p.codec.decode(raw, now)
+		closer.Close()
+		if err != nil || live {
+			continue
+		}
+		if err := batch.Delete(key, nil); err != nil {
+			return err
+		}
+	}
+	// sweeping is idempotent, so losing an unsynced sweep on crash is harmless
+	return batch.Commit(pebble.NoSync)
+}
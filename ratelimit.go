@@ -0,0 +1,343 @@
+package zerokv
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// rateLimited is a Core whose writes wait for a token from limiter.
+type rateLimited struct {
+	core    Core
+	limiter *rate.Limiter
+}
+
+// WithRateLimit returns a Core that lets at most writesPerSec writes through
+// per second, with bursts of up to writesPerSec, to keep bursty writers from
+// swamping a shared store. A write that finds no token waits for one; if
+// its context is done first it returns ctx.Err() without writing. A
+// writesPerSec of zero or less returns core unchanged.
+//
+// Each write call takes one token, whatever it writes: Put, PutWithTTL,
+// Delete, PutMany, DeletePrefix, DeleteRange, Truncate, TruncatePrefix,
+// CompareAndSwap, Increment, Merge, GetOrPut (even when the key exists),
+// Restore, BulkLoad and the Commit of a batch or transaction. Staging
+// writes on a batch or transaction is free. Reads, scans and maintenance
+// calls are not limited.
+//
+// The limit is client-side throttling of calls through the returned Core
+// only. It does not reflect the engine's load or provide backpressure from
+// it, and other processes or Cores sharing the store are not counted.
+func WithRateLimit(core Core, writesPerSec int) Core {
+	if writesPerSec <= 0 {
+		return core
+	}
+	return &rateLimited{core: core, limiter: rate.NewLimiter(rate.Limit(writesPerSec), writesPerSec)}
+}
+
+// wait blocks until a write may proceed or ctx is done.
+func (c *rateLimited) wait(ctx context.Context) error {
+	if err := c.limiter.Wait(ctx); err != nil {
+		// Wait also fails early when ctx's deadline is too close; report it
+		// as the deadline the caller set
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		if _, ok := ctx.Deadline(); ok {
+			return context.DeadlineExceeded
+		}
+		return err
+	}
+	return nil
+}
+
+func (c *rateLimited) Put(ctx context.Context, key []byte, data []byte) error {
+	if err := c.wait(ctx); err != nil {
+		return err
+	}
+	return c.core.Put(ctx, key, data)
+}
+
+func (c *rateLimited) PutWithTTL(ctx context.Context, key, value []byte, ttl time.Duration) error {
+	if err := c.wait(ctx); err != nil {
+		return err
+	}
+	return c.core.PutWithTTL(ctx, key, value, ttl)
+}
+
+func (c *rateLimited) Get(ctx context.Context, key []byte) ([]byte, error) {
+	return c.core.Get(ctx, key)
+}
+
+func (c *rateLimited) GetOrDefault(ctx context.Context, key, def []byte) ([]byte, error) {
+	return c.core.GetOrDefault(ctx, key, def)
+}
+
+func (c *rateLimited) GetInto(ctx context.Context, key, dst []byte) ([]byte, error) {
+	return c.core.GetInto(ctx, key, dst)
+}
+
+func (c *rateLimited) View(ctx context.Context, key []byte, fn func(value []byte) error) error {
+	return c.core.View(ctx, key, fn)
+}
+
+func (c *rateLimited) Has(ctx context.Context, key []byte) (bool, error) {
+	return c.core.Has(ctx, key)
+}
+
+func (c *rateLimited) SizeOf(ctx context.Context, key []byte) (int, error) {
+	return c.core.SizeOf(ctx, key)
+}
+
+func (c *rateLimited) GetMany(ctx context.Context, keys [][]byte) ([][]byte, error) {
+	return c.core.GetMany(ctx, keys)
+}
+
+func (c *rateLimited) HasMany(ctx context.Context, keys [][]byte) ([]bool, error) {
+	return c.core.HasMany(ctx, keys)
+}
+
+func (c *rateLimited) PutMany(ctx context.Context, keys, values [][]byte) error {
+	if err := c.wait(ctx); err != nil {
+		return err
+	}
+	return c.core.PutMany(ctx, keys, values)
+}
+
+func (c *rateLimited) Delete(ctx context.Context, key []byte) error {
+	if err := c.wait(ctx); err != nil {
+		return err
+	}
+	return c.core.Delete(ctx, key)
+}
+
+func (c *rateLimited) DeletePrefix(ctx context.Context, prefix []byte) (int, error) {
+	if err := c.wait(ctx); err != nil {
+		return 0, err
+	}
+	return c.core.DeletePrefix(ctx, prefix)
+}
+
+func (c *rateLimited) DeleteRange(ctx context.Context, start, end []byte) error {
+	if err := c.wait(ctx); err != nil {
+		return err
+	}
+	return c.core.DeleteRange(ctx, start, end)
+}
+
+func (c *rateLimited) Truncate(ctx context.Context) error {
+	if err := c.wait(ctx); err != nil {
+		return err
+	}
+	return c.core.Truncate(ctx)
+}
+
+func (c *rateLimited) TruncatePrefix(ctx context.Context, prefix []byte) error {
+	if err := c.wait(ctx); err != nil {
+		return err
+	}
+	return c.core.TruncatePrefix(ctx, prefix)
+}
+
+func (c *rateLimited) Count(ctx context.Context, prefix []byte) (int64, error) {
+	return c.core.Count(ctx, prefix)
+}
+
+func (c *rateLimited) EstimateSize(prefix []byte) (int64, error) {
+	return c.core.EstimateSize(prefix)
+}
+
+func (c *rateLimited) NewTransaction(ctx context.Context) (Txn, error) {
+	txn, err := c.core.NewTransaction(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &rateLimitedTxn{txn: txn, c: c}, nil
+}
+
+func (c *rateLimited) CompareAndSwap(ctx context.Context, key, old, new []byte) (bool, error) {
+	if err := c.wait(ctx); err != nil {
+		return false, err
+	}
+	return c.core.CompareAndSwap(ctx, key, old, new)
+}
+
+func (c *rateLimited) Increment(ctx context.Context, key []byte, delta int64) (int64, error) {
+	if err := c.wait(ctx); err != nil {
+		return 0, err
+	}
+	return c.core.Increment(ctx, key, delta)
+}
+
+func (c *rateLimited) GetOrPut(ctx context.Context, key []byte, fill func() ([]byte, error)) ([]byte, error) {
+	if err := c.wait(ctx); err != nil {
+		return nil, err
+	}
+	return c.core.GetOrPut(ctx, key, fill)
+}
+
+func (c *rateLimited) Merge(ctx context.Context, key, operand []byte, merge func(existing, operand []byte) []byte) error {
+	if err := c.wait(ctx); err != nil {
+		return err
+	}
+	return c.core.Merge(ctx, key, operand, merge)
+}
+
+func (c *rateLimited) Stats(ctx context.Context) (Stats, error) {
+	return c.core.Stats(ctx)
+}
+
+func (c *rateLimited) Sync(ctx context.Context) error {
+	return c.core.Sync(ctx)
+}
+
+func (c *rateLimited) Compact(ctx context.Context, start, end []byte) error {
+	return c.core.Compact(ctx, start, end)
+}
+
+func (c *rateLimited) Ping(ctx context.Context) error {
+	return c.core.Ping(ctx)
+}
+
+func (c *rateLimited) Backup(ctx context.Context, w io.Writer) error {
+	return c.core.Backup(ctx, w)
+}
+
+func (c *rateLimited) Restore(ctx context.Context, r io.Reader) error {
+	if err := c.wait(ctx); err != nil {
+		return err
+	}
+	return c.core.Restore(ctx, r)
+}
+
+func (c *rateLimited) Checkpoint(ctx context.Context, destDir string) error {
+	return c.core.Checkpoint(ctx, destDir)
+}
+
+func (c *rateLimited) Snapshot() (Snapshot, error) {
+	return c.core.Snapshot()
+}
+
+func (c *rateLimited) Batch() Batch {
+	return &rateLimitedBatch{batch: c.core.Batch(), c: c}
+}
+
+func (c *rateLimited) BatchWithOptions(maxOps, maxBytes int) Batch {
+	return &rateLimitedBatch{batch: c.core.BatchWithOptions(maxOps, maxBytes), c: c}
+}
+
+// BulkLoad takes one token for the whole load, however many segments the
+// backend commits.
+func (c *rateLimited) BulkLoad(ctx context.Context, fn func(b Batch) error) error {
+	if err := c.wait(ctx); err != nil {
+		return err
+	}
+	return c.core.BulkLoad(ctx, fn)
+}
+
+func (c *rateLimited) Scan(prefix []byte) Iterator {
+	return c.core.Scan(prefix)
+}
+
+func (c *rateLimited) ReverseScan(prefix []byte) Iterator {
+	return c.core.ReverseScan(prefix)
+}
+
+func (c *rateLimited) RangeScan(start, end []byte) Iterator {
+	return c.core.RangeScan(start, end)
+}
+
+func (c *rateLimited) ScanFrom(prefix, start []byte) Iterator {
+	return c.core.ScanFrom(prefix, start)
+}
+
+func (c *rateLimited) ScanKeys(prefix []byte) Iterator {
+	return c.core.ScanKeys(prefix)
+}
+
+func (c *rateLimited) ScanContext(ctx context.Context, prefix []byte) Iterator {
+	return c.core.ScanContext(ctx, prefix)
+}
+
+func (c *rateLimited) Watch(ctx context.Context, prefix []byte) (<-chan Event, error) {
+	return c.core.Watch(ctx, prefix)
+}
+
+func (c *rateLimited) Close() error {
+	return c.core.Close()
+}
+
+// Unwrap returns the wrapped Core.
+func (c *rateLimited) Unwrap() Core {
+	return c.core
+}
+
+// rateLimitedBatch takes a token on Commit; staging writes is free.
+type rateLimitedBatch struct {
+	batch Batch
+	c     *rateLimited
+}
+
+func (b *rateLimitedBatch) Put(key []byte, data []byte) error  { return b.batch.Put(key, data) }
+func (b *rateLimitedBatch) Delete(key []byte) error            { return b.batch.Delete(key) }
+func (b *rateLimitedBatch) PutIfAbsent(key, data []byte) error { return b.batch.PutIfAbsent(key, data) }
+func (b *rateLimitedBatch) Len() int                           { return b.batch.Len() }
+func (b *rateLimitedBatch) Size() int                          { return b.batch.Size() }
+func (b *rateLimitedBatch) Reset()                             { b.batch.Reset() }
+func (b *rateLimitedBatch) Validate() error                    { return b.batch.Validate() }
+
+func (b *rateLimitedBatch) PutCtx(ctx context.Context, key []byte, data []byte) error {
+	if cb, ok := b.batch.(ContextBatch); ok {
+		return cb.PutCtx(ctx, key, data)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return b.batch.Put(key, data)
+}
+
+func (b *rateLimitedBatch) DeleteCtx(ctx context.Context, key []byte) error {
+	if cb, ok := b.batch.(ContextBatch); ok {
+		return cb.DeleteCtx(ctx, key)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return b.batch.Delete(key)
+}
+
+func (b *rateLimitedBatch) Commit(ctx context.Context) error {
+	if err := b.c.wait(ctx); err != nil {
+		return err
+	}
+	return b.batch.Commit(ctx)
+}
+
+// rateLimitedTxn takes a token on Commit; reads and staged writes are free.
+type rateLimitedTxn struct {
+	txn Txn
+	c   *rateLimited
+}
+
+func (t *rateLimitedTxn) Get(ctx context.Context, key []byte) ([]byte, error) {
+	return t.txn.Get(ctx, key)
+}
+
+func (t *rateLimitedTxn) Put(ctx context.Context, key []byte, data []byte) error {
+	return t.txn.Put(ctx, key, data)
+}
+
+func (t *rateLimitedTxn) Delete(ctx context.Context, key []byte) error {
+	return t.txn.Delete(ctx, key)
+}
+
+func (t *rateLimitedTxn) Discard() { t.txn.Discard() }
+
+func (t *rateLimitedTxn) Commit(ctx context.Context) error {
+	if err := t.c.wait(ctx); err != nil {
+		return err
+	}
+	return t.txn.Commit(ctx)
+}
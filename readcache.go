@@ -0,0 +1,383 @@
+package zerokv
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"time"
+)
+
+// readCache is a Core that memoizes Get results in memory.
+type readCache struct {
+	core Core
+	max  int
+	ttl  time.Duration
+
+	mu    sync.Mutex
+	lru   *list.List               // *cacheEntry, most recently used first
+	elems map[string]*list.Element // key -> element in lru
+	// gen is bumped by every invalidation. A Get stores what it read only
+	// if gen is unchanged, so a read that raced a write cannot cache the
+	// value the write replaced.
+	gen uint64
+}
+
+// cacheEntry is a cached Get result.
+type cacheEntry struct {
+	key     string
+	value   []byte
+	expires time.Time // zero for no expiry
+}
+
+// WithReadCache returns a Core that serves repeated Get calls for the same
+// key from memory. A value is cached for up to ttl after it was read, and
+// at most maxEntries values are kept, the least recently used being evicted
+// first. A ttl or maxEntries of zero or less means no expiry or no limit.
+// Missing keys are not cached.
+//
+// Every write made through the returned Core drops the keys it touches from
+// the cache: single-key writes and PutMany once they return, DeletePrefix,
+// DeleteRange, Truncate, TruncatePrefix and Restore the whole affected
+// range, and batches and transactions their keys once Commit returns.
+// Writes made to core directly are not seen until the entry expires, and
+// PutWithTTL cannot shorten ttl, so an expired key may be served for up to
+// ttl after it expired.
+//
+// Only Get uses the cache. View, GetMany, scans and every other read go to
+// core, which keeps zero-copy reads and iterators consistent with it. Get
+// returns a copy of the cached value that the caller may modify.
+func WithReadCache(core Core, maxEntries int, ttl time.Duration) Core {
+	return &readCache{
+		core:  core,
+		max:   maxEntries,
+		ttl:   ttl,
+		lru:   list.New(),
+		elems: make(map[string]*list.Element),
+	}
+}
+
+// lookup returns a copy of the cached value of key, dropping it if it has
+// expired.
+func (c *readCache) lookup(key []byte) ([]byte, bool) {
+	value, ok := c.cached(key)
+	if !ok {
+		return nil, false
+	}
+	return bytes.Clone(value), true
+}
+
+// cached returns the cached value of key itself, dropping it if it has
+// expired. Cached values are replaced, never modified, so it may be read
+// without holding mu but must not be modified.
+func (c *readCache) cached(key []byte) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.elems[string(key)]
+	if !ok {
+		return nil, false
+	}
+	entry := e.Value.(*cacheEntry)
+	if !entry.expires.IsZero() && time.Now().After(entry.expires) {
+		c.lru.Remove(e)
+		delete(c.elems, entry.key)
+		return nil, false
+	}
+	c.lru.MoveToFront(e)
+	return entry.value, true
+}
+
+// store caches value for key unless an invalidation happened since gen was
+// read, then evicts the least recently used entries beyond the limit.
+func (c *readCache) store(gen uint64, key, value []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if gen != c.gen {
+		return
+	}
+	entry := &cacheEntry{key: string(key), value: bytes.Clone(value)}
+	if c.ttl > 0 {
+		entry.expires = time.Now().Add(c.ttl)
+	}
+	if e, ok := c.elems[entry.key]; ok {
+		e.Value = entry
+		c.lru.MoveToFront(e)
+	} else {
+		c.elems[entry.key] = c.lru.PushFront(entry)
+	}
+	for c.max > 0 && c.lru.Len() > c.max {
+		evicted := c.lru.Remove(c.lru.Back()).(*cacheEntry)
+		delete(c.elems, evicted.key)
+	}
+}
+
+// invalidate drops keys from the cache. It never fails; the error result
+// lets batches and transactions call it after Commit.
+func (c *readCache) invalidate(_ context.Context, keys ...[]byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.gen++
+	for _, key := range keys {
+		if e, ok := c.elems[string(key)]; ok {
+			c.lru.Remove(e)
+			delete(c.elems, string(key))
+		}
+	}
+	return nil
+}
+
+// invalidateRange drops every cached key in [start, end); a nil end has no
+// upper bound.
+func (c *readCache) invalidateRange(start, end []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.gen++
+	for k, e := range c.elems {
+		key := []byte(k)
+		if bytes.Compare(key, start) >= 0 && (end == nil || bytes.Compare(key, end) < 0) {
+			c.lru.Remove(e)
+			delete(c.elems, k)
+		}
+	}
+}
+
+// generation returns the current invalidation count for a Get to pass to
+// store.
+func (c *readCache) generation() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.gen
+}
+
+func (c *readCache) Get(ctx context.Context, key []byte) ([]byte, error) {
+	if value, ok := c.lookup(key); ok {
+		return value, nil
+	}
+	gen := c.generation()
+	value, err := c.core.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	c.store(gen, key, value)
+	return value, nil
+}
+
+// GetOrDefault is served from the cache like Get; def is not cached.
+func (c *readCache) GetOrDefault(ctx context.Context, key, def []byte) ([]byte, error) {
+	value, err := c.Get(ctx, key)
+	if errors.Is(err, ErrKeyNotFound) {
+		return bytes.Clone(def), nil
+	}
+	return value, err
+}
+
+// GetInto copies a cached value into dst, and caches a value read from
+// core like Get does.
+func (c *readCache) GetInto(ctx context.Context, key, dst []byte) ([]byte, error) {
+	if value, ok := c.cached(key); ok {
+		return append(dst[:0], value...), nil
+	}
+	gen := c.generation()
+	value, err := c.core.GetInto(ctx, key, dst)
+	if err != nil {
+		return value, err
+	}
+	c.store(gen, key, value)
+	return value, nil
+}
+
+// Put invalidates key even when the write fails, since a failed write may
+// still have been applied.
+func (c *readCache) Put(ctx context.Context, key []byte, data []byte) error {
+	defer c.invalidate(ctx, key)
+	return c.core.Put(ctx, key, data)
+}
+
+func (c *readCache) PutWithTTL(ctx context.Context, key, value []byte, ttl time.Duration) error {
+	defer c.invalidate(ctx, key)
+	return c.core.PutWithTTL(ctx, key, value, ttl)
+}
+
+func (c *readCache) View(ctx context.Context, key []byte, fn func(value []byte) error) error {
+	return c.core.View(ctx, key, fn)
+}
+
+func (c *readCache) Has(ctx context.Context, key []byte) (bool, error) {
+	return c.core.Has(ctx, key)
+}
+
+func (c *readCache) SizeOf(ctx context.Context, key []byte) (int, error) {
+	return c.core.SizeOf(ctx, key)
+}
+
+func (c *readCache) GetMany(ctx context.Context, keys [][]byte) ([][]byte, error) {
+	return c.core.GetMany(ctx, keys)
+}
+
+func (c *readCache) HasMany(ctx context.Context, keys [][]byte) ([]bool, error) {
+	return c.core.HasMany(ctx, keys)
+}
+
+func (c *readCache) PutMany(ctx context.Context, keys, values [][]byte) error {
+	defer c.invalidate(ctx, keys...)
+	return c.core.PutMany(ctx, keys, values)
+}
+
+func (c *readCache) Delete(ctx context.Context, key []byte) error {
+	defer c.invalidate(ctx, key)
+	return c.core.Delete(ctx, key)
+}
+
+func (c *readCache) DeletePrefix(ctx context.Context, prefix []byte) (int, error) {
+	defer c.invalidateRange(prefix, PrefixUpperBound(prefix))
+	return c.core.DeletePrefix(ctx, prefix)
+}
+
+func (c *readCache) DeleteRange(ctx context.Context, start, end []byte) error {
+	defer c.invalidateRange(start, end)
+	return c.core.DeleteRange(ctx, start, end)
+}
+
+func (c *readCache) Truncate(ctx context.Context) error {
+	defer c.invalidateRange(nil, nil)
+	return c.core.Truncate(ctx)
+}
+
+func (c *readCache) TruncatePrefix(ctx context.Context, prefix []byte) error {
+	defer c.invalidateRange(prefix, PrefixUpperBound(prefix))
+	return c.core.TruncatePrefix(ctx, prefix)
+}
+
+func (c *readCache) Count(ctx context.Context, prefix []byte) (int64, error) {
+	return c.core.Count(ctx, prefix)
+}
+
+func (c *readCache) EstimateSize(prefix []byte) (int64, error) {
+	return c.core.EstimateSize(prefix)
+}
+
+func (c *readCache) NewTransaction(ctx context.Context) (Txn, error) {
+	txn, err := c.core.NewTransaction(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &invalidatingTxn{txn: txn, invalidate: c.invalidate}, nil
+}
+
+func (c *readCache) CompareAndSwap(ctx context.Context, key, old, new []byte) (bool, error) {
+	defer c.invalidate(ctx, key)
+	return c.core.CompareAndSwap(ctx, key, old, new)
+}
+
+func (c *readCache) Increment(ctx context.Context, key []byte, delta int64) (int64, error) {
+	defer c.invalidate(ctx, key)
+	return c.core.Increment(ctx, key, delta)
+}
+
+// GetOrPut is answered from the cache when key is cached; otherwise it may
+// write, so key is invalidated rather than cached.
+func (c *readCache) GetOrPut(ctx context.Context, key []byte, fill func() ([]byte, error)) ([]byte, error) {
+	if value, ok := c.lookup(key); ok {
+		return value, nil
+	}
+	defer c.invalidate(ctx, key)
+	return c.core.GetOrPut(ctx, key, fill)
+}
+
+func (c *readCache) Merge(ctx context.Context, key, operand []byte, merge func(existing, operand []byte) []byte) error {
+	defer c.invalidate(ctx, key)
+	return c.core.Merge(ctx, key, operand, merge)
+}
+
+func (c *readCache) Stats(ctx context.Context) (Stats, error) {
+	return c.core.Stats(ctx)
+}
+
+func (c *readCache) Sync(ctx context.Context) error {
+	return c.core.Sync(ctx)
+}
+
+func (c *readCache) Compact(ctx context.Context, start, end []byte) error {
+	return c.core.Compact(ctx, start, end)
+}
+
+func (c *readCache) Ping(ctx context.Context) error {
+	return c.core.Ping(ctx)
+}
+
+func (c *readCache) Backup(ctx context.Context, w io.Writer) error {
+	return c.core.Backup(ctx, w)
+}
+
+// Restore empties the cache, since it can overwrite any key.
+func (c *readCache) Restore(ctx context.Context, r io.Reader) error {
+	defer c.invalidateRange(nil, nil)
+	return c.core.Restore(ctx, r)
+}
+
+func (c *readCache) Checkpoint(ctx context.Context, destDir string) error {
+	return c.core.Checkpoint(ctx, destDir)
+}
+
+func (c *readCache) Snapshot() (Snapshot, error) {
+	return c.core.Snapshot()
+}
+
+func (c *readCache) Batch() Batch {
+	return &invalidatingBatch{batch: c.core.Batch(), invalidate: c.invalidate}
+}
+
+func (c *readCache) BatchWithOptions(maxOps, maxBytes int) Batch {
+	return &invalidatingBatch{batch: c.core.BatchWithOptions(maxOps, maxBytes), invalidate: c.invalidate}
+}
+
+// BulkLoad invalidates every key fn wrote, even if the load failed partway.
+func (c *readCache) BulkLoad(ctx context.Context, fn func(b Batch) error) error {
+	b := &invalidatingBatch{invalidate: c.invalidate}
+	defer func() { c.invalidate(ctx, b.keys...) }()
+	return c.core.BulkLoad(ctx, func(batch Batch) error {
+		b.batch = batch
+		return fn(b)
+	})
+}
+
+func (c *readCache) Scan(prefix []byte) Iterator {
+	return c.core.Scan(prefix)
+}
+
+func (c *readCache) ReverseScan(prefix []byte) Iterator {
+	return c.core.ReverseScan(prefix)
+}
+
+func (c *readCache) RangeScan(start, end []byte) Iterator {
+	return c.core.RangeScan(start, end)
+}
+
+func (c *readCache) ScanFrom(prefix, start []byte) Iterator {
+	return c.core.ScanFrom(prefix, start)
+}
+
+func (c *readCache) ScanKeys(prefix []byte) Iterator {
+	return c.core.ScanKeys(prefix)
+}
+
+func (c *readCache) ScanContext(ctx context.Context, prefix []byte) Iterator {
+	return c.core.ScanContext(ctx, prefix)
+}
+
+func (c *readCache) Watch(ctx context.Context, prefix []byte) (<-chan Event, error) {
+	return c.core.Watch(ctx, prefix)
+}
+
+// Close empties the cache and closes core.
+func (c *readCache) Close() error {
+	c.invalidateRange(nil, nil)
+	return c.core.Close()
+}
+
+// Unwrap returns the wrapped Core.
+func (c *readCache) Unwrap() Core {
+	return c.core
+}
@@ -0,0 +1,340 @@
+package zerokv
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"time"
+)
+
+// slowLog is a Core that logs Get, Put, Delete and Commit calls slower than
+// threshold.
+type slowLog struct {
+	core      Core
+	threshold time.Duration
+	logger    *slog.Logger
+	fullKeys  bool
+}
+
+// SlowLogOption adjusts WithSlowLog.
+type SlowLogOption func(*slowLog)
+
+// WithFullKeys makes WithSlowLog log the key of a slow call, not just its
+// length. Keys often hold user identifiers or other sensitive data, so only
+// enable it where the logs may contain them.
+func WithFullKeys() SlowLogOption {
+	return func(c *slowLog) {
+		c.fullKeys = true
+	}
+}
+
+// WithSlowLog returns a Core that logs every Get, Put, Delete and batch or
+// transaction Commit taking at least threshold, to help track down latency
+// spikes. Each record is logged at slog.LevelWarn with the message
+// "zerokv: slow operation" and these attributes:
+//
+//   - op: the method, such as "Get" or "Batch.Commit"
+//   - duration: how long the call took
+//   - key_size: the key length in bytes, for single-key calls
+//   - keys and bytes: Len and Size of a batch before Commit
+//   - error: the error the call returned, if any
+//
+// The key itself is only logged, as key, with WithFullKeys. A nil logger
+// uses slog.Default(); a threshold of zero or less logs every call. Other
+// methods are forwarded untimed.
+func WithSlowLog(core Core, threshold time.Duration, logger *slog.Logger, opts ...SlowLogOption) Core {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	c := &slowLog{core: core, threshold: threshold, logger: logger}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// observe logs the call started at start if it took at least threshold.
+func (c *slowLog) observe(ctx context.Context, op string, start time.Time, err error, attrs ...slog.Attr) {
+	d := time.Since(start)
+	if d < c.threshold {
+		return
+	}
+	attrs = append([]slog.Attr{slog.String("op", op), slog.Duration("duration", d)}, attrs...)
+	if err != nil {
+		attrs = append(attrs, slog.String("error", err.Error()))
+	}
+	c.logger.LogAttrs(context.WithoutCancel(ctx), slog.LevelWarn, "zerokv: slow operation", attrs...)
+}
+
+// observeKey logs a slow single-key call.
+func (c *slowLog) observeKey(ctx context.Context, op string, key []byte, start time.Time, err error) {
+	if time.Since(start) < c.threshold {
+		return
+	}
+	attrs := []slog.Attr{slog.Int("key_size", len(key))}
+	if c.fullKeys {
+		attrs = append(attrs, slog.String("key", string(key)))
+	}
+	c.observe(ctx, op, start, err, attrs...)
+}
+
+func (c *slowLog) Put(ctx context.Context, key []byte, data []byte) error {
+	start := time.Now()
+	err := c.core.Put(ctx, key, data)
+	c.observeKey(ctx, "Put", key, start, err)
+	return err
+}
+
+func (c *slowLog) PutWithTTL(ctx context.Context, key, value []byte, ttl time.Duration) error {
+	return c.core.PutWithTTL(ctx, key, value, ttl)
+}
+
+// Get logs a slow miss without its ErrKeyNotFound, which is not a failure.
+func (c *slowLog) Get(ctx context.Context, key []byte) ([]byte, error) {
+	start := time.Now()
+	value, err := c.core.Get(ctx, key)
+	logged := err
+	if errors.Is(err, ErrKeyNotFound) {
+		logged = nil
+	}
+	c.observeKey(ctx, "Get", key, start, logged)
+	return value, err
+}
+
+func (c *slowLog) GetOrDefault(ctx context.Context, key, def []byte) ([]byte, error) {
+	return c.core.GetOrDefault(ctx, key, def)
+}
+
+func (c *slowLog) GetInto(ctx context.Context, key, dst []byte) ([]byte, error) {
+	return c.core.GetInto(ctx, key, dst)
+}
+
+func (c *slowLog) View(ctx context.Context, key []byte, fn func(value []byte) error) error {
+	return c.core.View(ctx, key, fn)
+}
+
+func (c *slowLog) Has(ctx context.Context, key []byte) (bool, error) {
+	return c.core.Has(ctx, key)
+}
+
+func (c *slowLog) SizeOf(ctx context.Context, key []byte) (int, error) {
+	return c.core.SizeOf(ctx, key)
+}
+
+func (c *slowLog) GetMany(ctx context.Context, keys [][]byte) ([][]byte, error) {
+	return c.core.GetMany(ctx, keys)
+}
+
+func (c *slowLog) HasMany(ctx context.Context, keys [][]byte) ([]bool, error) {
+	return c.core.HasMany(ctx, keys)
+}
+
+func (c *slowLog) PutMany(ctx context.Context, keys, values [][]byte) error {
+	return c.core.PutMany(ctx, keys, values)
+}
+
+func (c *slowLog) Delete(ctx context.Context, key []byte) error {
+	start := time.Now()
+	err := c.core.Delete(ctx, key)
+	c.observeKey(ctx, "Delete", key, start, err)
+	return err
+}
+
+func (c *slowLog) DeletePrefix(ctx context.Context, prefix []byte) (int, error) {
+	return c.core.DeletePrefix(ctx, prefix)
+}
+
+func (c *slowLog) DeleteRange(ctx context.Context, start, end []byte) error {
+	return c.core.DeleteRange(ctx, start, end)
+}
+
+func (c *slowLog) Truncate(ctx context.Context) error {
+	return c.core.Truncate(ctx)
+}
+
+func (c *slowLog) TruncatePrefix(ctx context.Context, prefix []byte) error {
+	return c.core.TruncatePrefix(ctx, prefix)
+}
+
+func (c *slowLog) Count(ctx context.Context, prefix []byte) (int64, error) {
+	return c.core.Count(ctx, prefix)
+}
+
+func (c *slowLog) EstimateSize(prefix []byte) (int64, error) {
+	return c.core.EstimateSize(prefix)
+}
+
+func (c *slowLog) NewTransaction(ctx context.Context) (Txn, error) {
+	txn, err := c.core.NewTransaction(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &slowLogTxn{txn: txn, c: c}, nil
+}
+
+func (c *slowLog) CompareAndSwap(ctx context.Context, key, old, new []byte) (bool, error) {
+	return c.core.CompareAndSwap(ctx, key, old, new)
+}
+
+func (c *slowLog) Increment(ctx context.Context, key []byte, delta int64) (int64, error) {
+	return c.core.Increment(ctx, key, delta)
+}
+
+func (c *slowLog) GetOrPut(ctx context.Context, key []byte, fill func() ([]byte, error)) ([]byte, error) {
+	return c.core.GetOrPut(ctx, key, fill)
+}
+
+func (c *slowLog) Merge(ctx context.Context, key, operand []byte, merge func(existing, operand []byte) []byte) error {
+	return c.core.Merge(ctx, key, operand, merge)
+}
+
+func (c *slowLog) Stats(ctx context.Context) (Stats, error) {
+	return c.core.Stats(ctx)
+}
+
+func (c *slowLog) Sync(ctx context.Context) error {
+	return c.core.Sync(ctx)
+}
+
+func (c *slowLog) Compact(ctx context.Context, start, end []byte) error {
+	return c.core.Compact(ctx, start, end)
+}
+
+func (c *slowLog) Ping(ctx context.Context) error {
+	return c.core.Ping(ctx)
+}
+
+func (c *slowLog) Backup(ctx context.Context, w io.Writer) error {
+	return c.core.Backup(ctx, w)
+}
+
+func (c *slowLog) Restore(ctx context.Context, r io.Reader) error {
+	return c.core.Restore(ctx, r)
+}
+
+func (c *slowLog) Checkpoint(ctx context.Context, destDir string) error {
+	return c.core.Checkpoint(ctx, destDir)
+}
+
+func (c *slowLog) Snapshot() (Snapshot, error) {
+	return c.core.Snapshot()
+}
+
+func (c *slowLog) Batch() Batch {
+	return &slowLogBatch{batch: c.core.Batch(), c: c}
+}
+
+func (c *slowLog) BatchWithOptions(maxOps, maxBytes int) Batch {
+	return &slowLogBatch{batch: c.core.BatchWithOptions(maxOps, maxBytes), c: c}
+}
+
+func (c *slowLog) BulkLoad(ctx context.Context, fn func(b Batch) error) error {
+	return c.core.BulkLoad(ctx, fn)
+}
+
+func (c *slowLog) Scan(prefix []byte) Iterator {
+	return c.core.Scan(prefix)
+}
+
+func (c *slowLog) ReverseScan(prefix []byte) Iterator {
+	return c.core.ReverseScan(prefix)
+}
+
+func (c *slowLog) RangeScan(start, end []byte) Iterator {
+	return c.core.RangeScan(start, end)
+}
+
+func (c *slowLog) ScanFrom(prefix, start []byte) Iterator {
+	return c.core.ScanFrom(prefix, start)
+}
+
+func (c *slowLog) ScanKeys(prefix []byte) Iterator {
+	return c.core.ScanKeys(prefix)
+}
+
+func (c *slowLog) ScanContext(ctx context.Context, prefix []byte) Iterator {
+	return c.core.ScanContext(ctx, prefix)
+}
+
+func (c *slowLog) Watch(ctx context.Context, prefix []byte) (<-chan Event, error) {
+	return c.core.Watch(ctx, prefix)
+}
+
+func (c *slowLog) Close() error {
+	return c.core.Close()
+}
+
+// Unwrap returns the wrapped Core.
+func (c *slowLog) Unwrap() Core {
+	return c.core
+}
+
+// slowLogBatch times Commit; staging writes is not timed.
+type slowLogBatch struct {
+	batch Batch
+	c     *slowLog
+}
+
+func (b *slowLogBatch) Put(key []byte, data []byte) error  { return b.batch.Put(key, data) }
+func (b *slowLogBatch) Delete(key []byte) error            { return b.batch.Delete(key) }
+func (b *slowLogBatch) PutIfAbsent(key, data []byte) error { return b.batch.PutIfAbsent(key, data) }
+func (b *slowLogBatch) Len() int                           { return b.batch.Len() }
+func (b *slowLogBatch) Size() int                          { return b.batch.Size() }
+func (b *slowLogBatch) Reset()                             { b.batch.Reset() }
+func (b *slowLogBatch) Validate() error                    { return b.batch.Validate() }
+
+func (b *slowLogBatch) PutCtx(ctx context.Context, key []byte, data []byte) error {
+	if cb, ok := b.batch.(ContextBatch); ok {
+		return cb.PutCtx(ctx, key, data)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return b.batch.Put(key, data)
+}
+
+func (b *slowLogBatch) DeleteCtx(ctx context.Context, key []byte) error {
+	if cb, ok := b.batch.(ContextBatch); ok {
+		return cb.DeleteCtx(ctx, key)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return b.batch.Delete(key)
+}
+
+func (b *slowLogBatch) Commit(ctx context.Context) error {
+	keys, size := b.batch.Len(), b.batch.Size()
+	start := time.Now()
+	err := b.batch.Commit(ctx)
+	b.c.observe(ctx, "Batch.Commit", start, err, slog.Int("keys", keys), slog.Int("bytes", size))
+	return err
+}
+
+// slowLogTxn times Commit; reads and staged writes are not timed.
+type slowLogTxn struct {
+	txn Txn
+	c   *slowLog
+}
+
+func (t *slowLogTxn) Get(ctx context.Context, key []byte) ([]byte, error) {
+	return t.txn.Get(ctx, key)
+}
+
+func (t *slowLogTxn) Put(ctx context.Context, key []byte, data []byte) error {
+	return t.txn.Put(ctx, key, data)
+}
+
+func (t *slowLogTxn) Delete(ctx context.Context, key []byte) error {
+	return t.txn.Delete(ctx, key)
+}
+
+func (t *slowLogTxn) Discard() { t.txn.Discard() }
+
+func (t *slowLogTxn) Commit(ctx context.Context) error {
+	start := time.Now()
+	err := t.txn.Commit(ctx)
+	t.c.observe(ctx, "Txn.Commit", start, err)
+	return err
+}
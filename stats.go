@@ -0,0 +1,11 @@
+package zerokv
+
+// Stats holds engine-level metrics returned by Core.Stats. Backends fill the
+// fields they can compute cheaply and leave the others at zero; see the
+// backend notes in API.md for which fields each one supports.
+type Stats struct {
+	KeyCount           int64 // number of keys; an estimate on some backends
+	OnDiskBytes        int64 // bytes used by data files
+	MemtableBytes      int64 // bytes held in memory before being flushed
+	PendingCompactions int64 // compactions waiting or running
+}
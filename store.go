@@ -0,0 +1,115 @@
+package zerokv
+
+import (
+	"context"
+	"fmt"
+)
+
+// Store is a typed view of a Core that encodes values of type T with a
+// Codec. Keys stay raw bytes; the underlying Core is left untouched and can
+// still be used directly.
+type Store[T any] struct {
+	core  Core
+	codec Codec
+}
+
+// NewStore returns a Store that reads and writes T values through core.
+func NewStore[T any](core Core, codec Codec) *Store[T] {
+	return &Store[T]{core: core, codec: codec}
+}
+
+// Core returns the underlying byte-level database.
+func (s *Store[T]) Core() Core {
+	return s.core
+}
+
+// Put encodes v and stores it under key.
+func (s *Store[T]) Put(ctx context.Context, key []byte, v T) error {
+	data, err := s.codec.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("zerokv: encode %q: %w", key, err)
+	}
+	return s.core.Put(ctx, key, data)
+}
+
+// Get reads and decodes the value stored under key. Errors from the
+// backend, such as its not-found error, are returned unchanged.
+func (s *Store[T]) Get(ctx context.Context, key []byte) (T, error) {
+	var v T
+	data, err := s.core.Get(ctx, key)
+	if err != nil {
+		return v, err
+	}
+	if err := s.codec.Unmarshal(data, &v); err != nil {
+		return v, fmt.Errorf("zerokv: decode %q: %w", key, err)
+	}
+	return v, nil
+}
+
+// Delete removes key.
+func (s *Store[T]) Delete(ctx context.Context, key []byte) error {
+	return s.core.Delete(ctx, key)
+}
+
+// Scan returns a typed iterator over keys with the given prefix.
+func (s *Store[T]) Scan(prefix []byte) *StoreIterator[T] {
+	return &StoreIterator[T]{it: s.core.Scan(prefix), codec: s.codec}
+}
+
+// StoreIterator walks a Store and decodes each value as it advances. A
+// value that fails to decode stops iteration and is reported by Error.
+type StoreIterator[T any] struct {
+	it    Iterator
+	codec Codec
+	value T
+	err   error
+}
+
+// Next advances to the next entry and decodes its value.
+func (it *StoreIterator[T]) Next() bool {
+	if it.err != nil || !it.it.Next() {
+		return false
+	}
+	return it.decode()
+}
+
+// Seek positions the iterator on the first key >= key and decodes its value.
+func (it *StoreIterator[T]) Seek(key []byte) bool {
+	if it.err != nil || !it.it.Seek(key) {
+		return false
+	}
+	return it.decode()
+}
+
+func (it *StoreIterator[T]) decode() bool {
+	var v T
+	if err := it.codec.Unmarshal(it.it.Value(), &v); err != nil {
+		it.err = fmt.Errorf("zerokv: decode %q: %w", it.it.Key(), err)
+		return false
+	}
+	it.value = v
+	return true
+}
+
+// Key returns the current key.
+func (it *StoreIterator[T]) Key() []byte {
+	return it.it.Key()
+}
+
+// Value returns the current decoded value.
+func (it *StoreIterator[T]) Value() T {
+	return it.value
+}
+
+// Release releases the underlying iterator.
+func (it *StoreIterator[T]) Release() {
+	it.it.Release()
+}
+
+// Error returns the first decode error, or the underlying iterator's error.
+func (it *StoreIterator[T]) Error() error {
+	if it.err != nil {
+		return it.err
+	}
+	return it.it.Error()
+}
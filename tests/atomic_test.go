@@ -0,0 +1,304 @@
+package tests
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/rawbytedev/zerokv"
+	"github.com/rawbytedev/zerokv/helpers"
+	"github.com/stretchr/testify/require"
+)
+
+func TestZeroKvAtomic(t *testing.T) {
+	dbs := []string{"badgerdb", "pebbledb", "memdb", "boltdb", "leveldb"}
+	list_test := []test{
+		{
+			name: "TestCompareAndSwap",
+			fn: func(t *testing.T, name string) {
+				testCompareAndSwap(t, name)
+			}},
+		{
+			name: "TestCompareAndSwapConcurrent",
+			fn: func(t *testing.T, name string) {
+				testCompareAndSwapConcurrent(t, name)
+			}},
+		{
+			name: "TestIncrement",
+			fn: func(t *testing.T, name string) {
+				testIncrement(t, name)
+			}},
+		{
+			name: "TestIncrementConcurrent",
+			fn: func(t *testing.T, name string) {
+				testIncrementConcurrent(t, name)
+			}},
+		{
+			name: "TestGetOrPut",
+			fn: func(t *testing.T, name string) {
+				testGetOrPut(t, name)
+			}},
+		{
+			name: "TestGetOrPutConcurrent",
+			fn: func(t *testing.T, name string) {
+				testGetOrPutConcurrent(t, name)
+			}},
+		{
+			name: "TestMerge",
+			fn: func(t *testing.T, name string) {
+				testMerge(t, name)
+			}},
+		{
+			name: "TestMergeConcurrent",
+			fn: func(t *testing.T, name string) {
+				testMergeConcurrent(t, name)
+			}},
+	}
+	for i := range dbs {
+		for tt := range list_test {
+			testname := fmt.Sprintf("%s%s", list_test[tt].name, dbs[i])
+			t.Run(testname, func(t *testing.T) {
+				list_test[tt].fn(t, dbs[i])
+			})
+		}
+	}
+}
+
+// testCompareAndSwap tests swapping on absent, matching and stale values.
+func testCompareAndSwap(t *testing.T, name string) {
+	db := helpers.SetupDB(t, name)
+	defer db.Close()
+	key := []byte("lock")
+
+	swapped, err := db.CompareAndSwap(t.Context(), key, nil, []byte("owner-1"))
+	require.NoError(t, err)
+	require.True(t, swapped, "nil old should swap when the key is absent")
+	swapped, err = db.CompareAndSwap(t.Context(), key, nil, []byte("owner-2"))
+	require.NoError(t, err)
+	require.False(t, swapped, "nil old should not swap when the key exists")
+
+	swapped, err = db.CompareAndSwap(t.Context(), key, []byte("owner-2"), []byte("owner-3"))
+	require.NoError(t, err)
+	require.False(t, swapped, "a stale old value should not swap")
+	value, err := db.Get(t.Context(), key)
+	require.NoError(t, err)
+	require.Equal(t, []byte("owner-1"), value)
+
+	swapped, err = db.CompareAndSwap(t.Context(), key, []byte("owner-1"), []byte{})
+	require.NoError(t, err)
+	require.True(t, swapped)
+	swapped, err = db.CompareAndSwap(t.Context(), key, nil, []byte("owner-4"))
+	require.NoError(t, err)
+	require.False(t, swapped, "an empty value still counts as present")
+	swapped, err = db.CompareAndSwap(t.Context(), key, []byte{}, []byte("owner-4"))
+	require.NoError(t, err)
+	require.True(t, swapped)
+
+	swapped, err = db.CompareAndSwap(t.Context(), []byte("missing"), []byte("value"), []byte("new"))
+	require.NoError(t, err)
+	require.False(t, swapped, "a non-nil old should not match an absent key")
+}
+
+// testCompareAndSwapConcurrent tests that exactly one of many concurrent
+// swaps from the same value wins each round.
+func testCompareAndSwapConcurrent(t *testing.T, name string) {
+	db := helpers.SetupDB(t, name)
+	defer db.Close()
+	const rounds, workers = 20, 8
+	key := []byte("version")
+	require.NoError(t, db.Put(t.Context(), key, []byte("v0")))
+	for r := 0; r < rounds; r++ {
+		old := []byte(fmt.Sprintf("v%d", r))
+		next := []byte(fmt.Sprintf("v%d", r+1))
+		var wins atomic.Int32
+		var wg sync.WaitGroup
+		for w := 0; w < workers; w++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				swapped, err := db.CompareAndSwap(t.Context(), key, old, next)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				if swapped {
+					wins.Add(1)
+				}
+			}()
+		}
+		wg.Wait()
+		require.Equal(t, int32(1), wins.Load(), "round %d", r)
+	}
+	value, err := db.Get(t.Context(), key)
+	require.NoError(t, err)
+	require.Equal(t, []byte(fmt.Sprintf("v%d", rounds)), value)
+}
+
+// testIncrement tests that a missing key starts at zero, that deltas can be
+// negative, and that a non-counter value is rejected.
+func testIncrement(t *testing.T, name string) {
+	db := helpers.SetupDB(t, name)
+	defer db.Close()
+	key := []byte("hits")
+
+	n, err := db.Increment(t.Context(), key, 5)
+	require.NoError(t, err)
+	require.Equal(t, int64(5), n)
+	n, err = db.Increment(t.Context(), key, -8)
+	require.NoError(t, err)
+	require.Equal(t, int64(-3), n)
+	value, err := db.Get(t.Context(), key)
+	require.NoError(t, err)
+	require.Equal(t, zerokv.EncodeCounter(-3), value)
+
+	require.NoError(t, db.Put(t.Context(), []byte("seeded"), zerokv.EncodeCounter(100)))
+	n, err = db.Increment(t.Context(), []byte("seeded"), 1)
+	require.NoError(t, err)
+	require.Equal(t, int64(101), n)
+
+	require.NoError(t, db.Put(t.Context(), []byte("text"), []byte("hello")))
+	_, err = db.Increment(t.Context(), []byte("text"), 1)
+	require.ErrorIs(t, err, zerokv.ErrInvalidCounter)
+	value, err = db.Get(t.Context(), []byte("text"))
+	require.NoError(t, err)
+	require.Equal(t, []byte("hello"), value, "A failed Increment must not change the value")
+}
+
+// testIncrementConcurrent tests that concurrent increments never lose updates.
+func testIncrementConcurrent(t *testing.T, name string) {
+	db := helpers.SetupDB(t, name)
+	defer db.Close()
+	const workers, perWorker = 16, 50
+	key := []byte("counter")
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < perWorker; i++ {
+				if _, err := db.Increment(t.Context(), key, 1); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	value, err := db.Get(t.Context(), key)
+	require.NoError(t, err)
+	n, err := zerokv.DecodeCounter(value)
+	require.NoError(t, err)
+	require.Equal(t, int64(workers*perWorker), n)
+}
+
+// testGetOrPut tests that fill only runs for a missing key and that its
+// errors are returned without storing anything.
+func testGetOrPut(t *testing.T, name string) {
+	db := helpers.SetupDB(t, name)
+	defer db.Close()
+	require.NoError(t, db.Put(t.Context(), []byte("cached"), []byte("old")))
+	value, err := db.GetOrPut(t.Context(), []byte("cached"), func() ([]byte, error) {
+		t.Error("fill must not run for an existing key")
+		return nil, nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, []byte("old"), value)
+
+	errFill := errors.New("fill failed")
+	_, err = db.GetOrPut(t.Context(), []byte("missing"), func() ([]byte, error) {
+		return nil, errFill
+	})
+	require.ErrorIs(t, err, errFill)
+	ok, err := db.Has(t.Context(), []byte("missing"))
+	require.NoError(t, err)
+	require.False(t, ok, "A failed fill must not store anything")
+
+	value, err = db.GetOrPut(t.Context(), []byte("missing"), func() ([]byte, error) {
+		return []byte("computed"), nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, []byte("computed"), value)
+	value, err = db.Get(t.Context(), []byte("missing"))
+	require.NoError(t, err)
+	require.Equal(t, []byte("computed"), value)
+}
+
+// testGetOrPutConcurrent tests that concurrent callers for one key run fill
+// once and all see its result.
+func testGetOrPutConcurrent(t *testing.T, name string) {
+	db := helpers.SetupDB(t, name)
+	defer db.Close()
+	const workers = 16
+	key := []byte("expensive")
+	var fills atomic.Int32
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			value, err := db.GetOrPut(t.Context(), key, func() ([]byte, error) {
+				fills.Add(1)
+				return []byte("result"), nil
+			})
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if string(value) != "result" {
+				t.Errorf("got %q, want %q", value, "result")
+			}
+		}()
+	}
+	wg.Wait()
+	require.Equal(t, int32(1), fills.Load(), "fill should run exactly once")
+}
+
+// testMerge tests the append and sum helpers, including a missing key.
+func testMerge(t *testing.T, name string) {
+	db := helpers.SetupDB(t, name)
+	defer db.Close()
+	require.NoError(t, db.Merge(t.Context(), []byte("log"), []byte("a"), zerokv.AppendMerge))
+	require.NoError(t, db.Merge(t.Context(), []byte("log"), []byte("bc"), zerokv.AppendMerge))
+	value, err := db.Get(t.Context(), []byte("log"))
+	require.NoError(t, err)
+	require.Equal(t, []byte("abc"), value)
+
+	require.NoError(t, db.Merge(t.Context(), []byte("sum"), zerokv.EncodeCounter(40), zerokv.SumInt64Merge))
+	require.NoError(t, db.Merge(t.Context(), []byte("sum"), zerokv.EncodeCounter(2), zerokv.SumInt64Merge))
+	value, err = db.Get(t.Context(), []byte("sum"))
+	require.NoError(t, err)
+	n, err := zerokv.DecodeCounter(value)
+	require.NoError(t, err)
+	require.Equal(t, int64(42), n)
+}
+
+// testMergeConcurrent tests that concurrent appends all land.
+func testMergeConcurrent(t *testing.T, name string) {
+	db := helpers.SetupDB(t, name)
+	defer db.Close()
+	const workers, perWorker = 16, 20
+	key := []byte("events")
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < perWorker; i++ {
+				if err := db.Merge(t.Context(), key, []byte{byte('a' + w)}, zerokv.AppendMerge); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	value, err := db.Get(t.Context(), key)
+	require.NoError(t, err)
+	require.Len(t, value, workers*perWorker)
+	for w := 0; w < workers; w++ {
+		require.Equal(t, perWorker, bytes.Count(value, []byte{byte('a' + w)}))
+	}
+}
@@ -0,0 +1,121 @@
+package tests
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/rawbytedev/zerokv"
+	"github.com/rawbytedev/zerokv/helpers"
+	"github.com/stretchr/testify/require"
+)
+
+func TestZeroKvAuditLog(t *testing.T) {
+	dbs := []string{"badgerdb", "pebbledb", "memdb", "boltdb", "leveldb"}
+	list_test := []test{
+		{
+			name: "TestAuditLogReplay",
+			fn: func(t *testing.T, name string) {
+				testAuditLogReplay(t, name)
+			}},
+		{
+			name: "TestAuditLogTruncated",
+			fn: func(t *testing.T, name string) {
+				testAuditLogTruncated(t, name)
+			}},
+	}
+	for i := range dbs {
+		for tt := range list_test {
+			testname := fmt.Sprintf("%s%s", list_test[tt].name, dbs[i])
+			t.Run(testname, func(t *testing.T) {
+				list_test[tt].fn(t, dbs[i])
+			})
+		}
+	}
+}
+
+// dump returns every key and value in core.
+func dump(t *testing.T, core zerokv.Core) map[string]string {
+	t.Helper()
+	out := make(map[string]string)
+	it := core.Scan(nil)
+	defer it.Release()
+	for it.Next() {
+		out[string(it.Key())] = string(it.Value())
+	}
+	require.NoError(t, it.Error())
+	return out
+}
+
+// testAuditLogReplay tests that replaying the log of a mix of writes into
+// an empty store reproduces the original store.
+func testAuditLogReplay(t *testing.T, name string) {
+	var log bytes.Buffer
+	src := helpers.SetupDB(t, name)
+	db := zerokv.WithAuditLog(src, &log)
+	defer db.Close()
+	ctx := t.Context()
+
+	require.NoError(t, db.Put(ctx, []byte("a"), []byte("1")))
+	require.NoError(t, db.Put(ctx, []byte("b"), []byte("2")))
+	require.NoError(t, db.Delete(ctx, []byte("a")))
+	require.NoError(t, db.PutMany(ctx, [][]byte{[]byte("p/1"), []byte("p/2")}, [][]byte{[]byte("x"), []byte("y")}))
+	_, err := db.DeletePrefix(ctx, []byte("p/"))
+	require.NoError(t, err)
+	batch := db.Batch()
+	require.NoError(t, batch.Put([]byte("c"), []byte("3")))
+	require.NoError(t, batch.Delete([]byte("b")))
+	require.NoError(t, batch.Commit(ctx))
+	txn, err := db.NewTransaction(ctx)
+	require.NoError(t, err)
+	require.NoError(t, txn.Put(ctx, []byte("d"), []byte("4")))
+	require.NoError(t, txn.Commit(ctx))
+	_, err = db.Increment(ctx, []byte("n"), 5)
+	require.NoError(t, err)
+	require.NoError(t, db.Merge(ctx, []byte("c"), []byte("!"), zerokv.AppendMerge))
+	require.NoError(t, db.Put(ctx, []byte("z/1"), []byte("gone")))
+	require.NoError(t, db.DeleteRange(ctx, []byte("z/"), nil))
+
+	dst := helpers.SetupDB(t, "memdb")
+	defer dst.Close()
+	require.NoError(t, zerokv.ReplayAuditLog(bytes.NewReader(log.Bytes()), dst))
+	want := dump(t, src)
+	require.Equal(t, map[string]string{"c": "3!", "d": "4", "n": string(zerokv.EncodeCounter(5))}, want)
+	require.Equal(t, want, dump(t, dst))
+
+	require.ErrorIs(t, db.Restore(ctx, &log), zerokv.ErrNotSupported)
+}
+
+// testAuditLogTruncated tests that a log cut short mid-record replays the
+// complete records and then reports ErrInvalidAuditLog.
+func testAuditLogTruncated(t *testing.T, name string) {
+	var log bytes.Buffer
+	db := zerokv.WithAuditLog(helpers.SetupDB(t, "memdb"), &log)
+	defer db.Close()
+	require.NoError(t, db.Put(t.Context(), []byte("kept"), []byte("1")))
+	require.NoError(t, db.Put(t.Context(), []byte("also"), []byte("2")))
+	require.NoError(t, db.Put(t.Context(), []byte("lost"), []byte("3")))
+
+	dst := helpers.SetupDB(t, name)
+	defer dst.Close()
+	err := zerokv.ReplayAuditLog(bytes.NewReader(log.Bytes()[:log.Len()-2]), dst)
+	require.ErrorIs(t, err, zerokv.ErrInvalidAuditLog)
+	require.Equal(t, map[string]string{"kept": "1", "also": "2"}, dump(t, dst))
+}
+
+// TestAuditLogTTL tests that replay gives a key written with PutWithTTL the
+// time it had left, and skips it once that time has passed.
+func TestAuditLogTTL(t *testing.T) {
+	var log bytes.Buffer
+	db := zerokv.WithAuditLog(helpers.SetupDB(t, "memdb"), &log)
+	defer db.Close()
+	require.NoError(t, db.PutWithTTL(t.Context(), []byte("long"), []byte("1"), time.Hour))
+	require.NoError(t, db.PutWithTTL(t.Context(), []byte("short"), []byte("2"), time.Millisecond))
+	time.Sleep(5 * time.Millisecond)
+
+	dst := helpers.SetupDB(t, "memdb")
+	defer dst.Close()
+	require.NoError(t, zerokv.ReplayAuditLog(&log, dst))
+	require.Equal(t, map[string]string{"long": "1"}, dump(t, dst))
+}
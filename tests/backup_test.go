@@ -0,0 +1,273 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rawbytedev/zerokv"
+	"github.com/rawbytedev/zerokv/helpers"
+	"github.com/stretchr/testify/require"
+)
+
+func TestZeroKvBackup(t *testing.T) {
+	dbs := []string{"badgerdb", "pebbledb", "memdb", "boltdb", "leveldb"}
+	list_test := []test{
+		{
+			name: "TestBackupRestore",
+			fn: func(t *testing.T, name string) {
+				testBackupRestore(t, name)
+			}},
+		{
+			name: "TestCheckpoint",
+			fn: func(t *testing.T, name string) {
+				testCheckpoint(t, name)
+			}},
+		{
+			name: "TestExportImportJSONL",
+			fn: func(t *testing.T, name string) {
+				testExportImportJSONL(t, name)
+			}},
+	}
+	for i := range dbs {
+		for tt := range list_test {
+			testname := fmt.Sprintf("%s%s", list_test[tt].name, dbs[i])
+			t.Run(testname, func(t *testing.T) {
+				list_test[tt].fn(t, dbs[i])
+			})
+		}
+	}
+}
+
+// checkpointBackends maps the test database names to the backend names
+// that open a checkpoint directory through zerokv.Open.
+var checkpointBackends = map[string]string{
+	"badgerdb": "badger",
+	"pebbledb": "pebble",
+	"boltdb":   "bolt",
+	"leveldb":  "leveldb",
+}
+
+// testCheckpoint tests that a checkpoint opens as a new database holding the
+// data as of the checkpoint, and that an existing destination is refused.
+func testCheckpoint(t *testing.T, name string) {
+	src := helpers.SetupDB(t, name)
+	defer src.Close()
+	keys, values := FillValues(t, src)
+	dir := filepath.Join(t.TempDir(), "checkpoint")
+
+	backend, ok := checkpointBackends[name]
+	if !ok {
+		require.ErrorIs(t, src.Checkpoint(t.Context(), dir), zerokv.ErrNotSupported)
+		return
+	}
+	require.NoError(t, src.Checkpoint(t.Context(), dir))
+	require.ErrorIs(t, src.Checkpoint(t.Context(), dir), zerokv.ErrCheckpointExists)
+	// writes after the checkpoint stay out of it
+	require.NoError(t, src.Put(t.Context(), []byte("later"), []byte("value")))
+
+	dst, err := zerokv.Open(backend, zerokv.Config{Dir: dir})
+	require.NoError(t, err)
+	defer dst.Close()
+	for i := range keys {
+		value, err := dst.Get(t.Context(), append([]byte("pre_"), keys[i]...))
+		require.NoError(t, err)
+		require.Equal(t, values[i], value)
+	}
+	_, err = dst.Get(t.Context(), []byte("later"))
+	require.ErrorIs(t, err, zerokv.ErrKeyNotFound)
+}
+
+// testBackupRestore tests that a backup restores every pair into a fresh database.
+func testBackupRestore(t *testing.T, name string) {
+	src := helpers.SetupDB(t, name)
+	defer src.Close()
+	keys, values := FillValues(t, src)
+	require.NoError(t, src.Put(t.Context(), []byte("empty"), []byte{}))
+
+	var buf bytes.Buffer
+	require.NoError(t, src.Backup(t.Context(), &buf))
+
+	dst := helpers.SetupDB(t, name)
+	defer dst.Close()
+	require.NoError(t, dst.Put(t.Context(), []byte("existing"), []byte("value")))
+	require.NoError(t, dst.Restore(t.Context(), &buf))
+	for i := range keys {
+		value, err := dst.Get(t.Context(), append([]byte("pre_"), keys[i]...))
+		require.NoError(t, err)
+		require.Equal(t, values[i], value)
+	}
+	value, err := dst.Get(t.Context(), []byte("empty"))
+	require.NoError(t, err)
+	require.Empty(t, value)
+	ok, err := dst.Has(t.Context(), []byte("existing"))
+	require.NoError(t, err)
+	require.True(t, ok, "Restore should keep keys that are not in the backup")
+}
+
+// testExportImportJSONL tests that a JSONL export of one prefix imports
+// into a fresh database unchanged and leaves other prefixes out.
+func testExportImportJSONL(t *testing.T, name string) {
+	src := helpers.SetupDB(t, name)
+	defer src.Close()
+	keys, values := FillValues(t, src)
+	require.NoError(t, src.Put(t.Context(), []byte("pre_empty"), []byte{}))
+	require.NoError(t, src.Put(t.Context(), []byte("other"), []byte("skipped")))
+
+	var buf bytes.Buffer
+	require.NoError(t, zerokv.ExportJSONL(t.Context(), src, []byte("pre_"), &buf))
+	require.Equal(t, len(keys)+1, bytes.Count(buf.Bytes(), []byte("\n")))
+
+	dst := helpers.SetupDB(t, name)
+	defer dst.Close()
+	n, err := zerokv.ImportJSONL(t.Context(), dst, &buf)
+	require.NoError(t, err)
+	require.Equal(t, len(keys)+1, n)
+	for i := range keys {
+		value, err := dst.Get(t.Context(), append([]byte("pre_"), keys[i]...))
+		require.NoError(t, err)
+		require.Equal(t, values[i], value)
+	}
+	value, err := dst.Get(t.Context(), []byte("pre_empty"))
+	require.NoError(t, err)
+	require.Empty(t, value)
+	ok, err := dst.Has(t.Context(), []byte("other"))
+	require.NoError(t, err)
+	require.False(t, ok, "Keys outside the prefix should not be exported")
+}
+
+// TestImportJSONLMalformed tests that a bad line is reported with its line
+// number after the lines before it were read.
+func TestImportJSONLMalformed(t *testing.T) {
+	db := helpers.SetupDB(t, "memdb")
+	defer db.Close()
+	input := `{"key":"YQ==","value":"MQ=="}
+
+{"key":"Yg==","value":"not base64!"}
+`
+	n, err := zerokv.ImportJSONL(t.Context(), db, strings.NewReader(input))
+	require.ErrorIs(t, err, zerokv.ErrInvalidImport)
+	require.Contains(t, err.Error(), "line 3")
+	require.Equal(t, 1, n)
+
+	_, err = zerokv.ImportJSONL(t.Context(), db, strings.NewReader(`{"value":"MQ=="}`))
+	require.ErrorIs(t, err, zerokv.ErrInvalidImport)
+	require.Contains(t, err.Error(), "missing key")
+}
+
+// TestImportCSV tests that hex and base64 rows are decoded and written.
+func TestImportCSV(t *testing.T) {
+	for _, name := range []string{"badgerdb", "pebbledb", "memdb", "boltdb", "leveldb"} {
+		t.Run(name, func(t *testing.T) {
+			db := helpers.SetupDB(t, name)
+			defer db.Close()
+			n, err := zerokv.ImportCSV(t.Context(), db, strings.NewReader("6b31,7631\n6b32,\n"), "hex")
+			require.NoError(t, err)
+			require.Equal(t, 2, n)
+			n, err = zerokv.ImportCSV(t.Context(), db, strings.NewReader("azM=,djM=\n"), "base64")
+			require.NoError(t, err)
+			require.Equal(t, 1, n)
+			for key, want := range map[string]string{"k1": "v1", "k2": "", "k3": "v3"} {
+				value, err := db.Get(t.Context(), []byte(key))
+				require.NoError(t, err)
+				require.Equal(t, want, string(value))
+			}
+		})
+	}
+}
+
+// TestImportCSVMalformed tests that bad rows are reported with their line
+// number and that unknown encodings are rejected up front.
+func TestImportCSVMalformed(t *testing.T) {
+	db := helpers.SetupDB(t, "memdb")
+	defer db.Close()
+	n, err := zerokv.ImportCSV(t.Context(), db, strings.NewReader("6b31,7631\n6b32,zz\n"), "hex")
+	require.ErrorIs(t, err, zerokv.ErrInvalidImport)
+	require.Contains(t, err.Error(), "line 2: value")
+	require.Equal(t, 1, n)
+
+	_, err = zerokv.ImportCSV(t.Context(), db, strings.NewReader("6b31,7631\n6b32\n"), "hex")
+	require.ErrorIs(t, err, zerokv.ErrInvalidImport)
+	require.Contains(t, err.Error(), "line 2")
+
+	_, err = zerokv.ImportCSV(t.Context(), db, strings.NewReader(""), "base32")
+	require.Error(t, err)
+	require.NotErrorIs(t, err, zerokv.ErrInvalidImport)
+}
+
+// TestExportJSONLCancelled tests that a cancelled context stops the export.
+func TestExportJSONLCancelled(t *testing.T) {
+	db := helpers.SetupDB(t, "memdb")
+	defer db.Close()
+	FillValues(t, db)
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	var buf bytes.Buffer
+	require.ErrorIs(t, zerokv.ExportJSONL(ctx, db, nil, &buf), context.Canceled)
+}
+
+// TestBackupPortable tests that the portable format moves data between
+// backends, and that expiries survive when the target supports them.
+func TestBackupPortable(t *testing.T) {
+	src := helpers.SetupDB(t, "memdb")
+	defer src.Close()
+	require.NoError(t, src.Put(t.Context(), []byte("user"), []byte("value")))
+	var buf bytes.Buffer
+	require.NoError(t, src.Backup(t.Context(), &buf))
+	backup := buf.Bytes()
+
+	for _, name := range []string{"pebbledb", "boltdb", "leveldb"} {
+		dst := helpers.SetupDB(t, name)
+		require.NoError(t, dst.Restore(t.Context(), bytes.NewReader(backup)), name)
+		value, err := dst.Get(t.Context(), []byte("user"))
+		require.NoError(t, err, name)
+		require.Equal(t, []byte("value"), value, name)
+		require.NoError(t, dst.Close())
+	}
+
+	require.NoError(t, src.PutWithTTL(t.Context(), []byte("session"), []byte("value"), time.Hour))
+	buf.Reset()
+	require.NoError(t, src.Backup(t.Context(), &buf))
+	backup = buf.Bytes()
+	dst := helpers.SetupDB(t, "pebbledb-ttl")
+	defer dst.Close()
+	require.NoError(t, dst.Restore(t.Context(), bytes.NewReader(backup)))
+	ok, err := dst.Has(t.Context(), []byte("session"))
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	plain := helpers.SetupDB(t, "boltdb")
+	defer plain.Close()
+	err = plain.Restore(t.Context(), bytes.NewReader(backup))
+	require.ErrorIs(t, err, zerokv.ErrNotSupported, "Backends without TTL should refuse expiring keys")
+}
+
+// TestBackupCorrupt tests that bad headers and truncated streams are detected.
+func TestBackupCorrupt(t *testing.T) {
+	src := helpers.SetupDB(t, "pebbledb")
+	defer src.Close()
+	FillValues(t, src)
+	var buf bytes.Buffer
+	require.NoError(t, src.Backup(t.Context(), &buf))
+	backup := buf.Bytes()
+
+	dst := helpers.SetupDB(t, "memdb")
+	defer dst.Close()
+	err := dst.Restore(t.Context(), bytes.NewReader(backup[:len(backup)-5]))
+	require.ErrorIs(t, err, zerokv.ErrInvalidBackup)
+	err = dst.Restore(t.Context(), bytes.NewReader([]byte("not a backup")))
+	require.ErrorIs(t, err, zerokv.ErrInvalidBackup)
+	count, err := dst.Count(t.Context(), nil)
+	require.NoError(t, err)
+	require.Zero(t, count, "A failed restore should not apply a partial stream on memdb")
+
+	versioned := bytes.Clone(backup)
+	versioned[7] = 99 // version byte
+	err = dst.Restore(t.Context(), bytes.NewReader(versioned))
+	require.ErrorIs(t, err, zerokv.ErrInvalidBackup)
+	require.Contains(t, err.Error(), "unsupported version 99")
+}
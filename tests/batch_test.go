@@ -0,0 +1,376 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/rawbytedev/zerokv"
+	"github.com/rawbytedev/zerokv/helpers"
+	"github.com/stretchr/testify/require"
+)
+
+func TestZeroKvBatch(t *testing.T) {
+	dbs := []string{"badgerdb", "pebbledb", "memdb", "boltdb", "leveldb"}
+	list_test := []test{
+		{
+			name: "TestBatchCommitCancelled",
+			fn: func(t *testing.T, name string) {
+				testBatchCommitCancelled(t, name)
+			}}, {
+			name: "TestBatchContextOperations",
+			fn: func(t *testing.T, name string) {
+				testBatchContextOperations(t, name)
+			}}, {
+			name: "TestBatchWithOptions",
+			fn: func(t *testing.T, name string) {
+				testBatchWithOptions(t, name)
+			}}, {
+			name: "TestBatchLenSize",
+			fn: func(t *testing.T, name string) {
+				testBatchLenSize(t, name)
+			}}, {
+			name: "TestBatchReset",
+			fn: func(t *testing.T, name string) {
+				testBatchReset(t, name)
+			}}, {
+			name: "TestBulkLoad",
+			fn: func(t *testing.T, name string) {
+				testBulkLoad(t, name)
+			}}, {
+			name: "TestBatchValidate",
+			fn: func(t *testing.T, name string) {
+				testBatchValidate(t, name)
+			}}, {
+			name: "TestBatchPutIfAbsent",
+			fn: func(t *testing.T, name string) {
+				testBatchPutIfAbsent(t, name)
+			}}, {
+			name: "TestConcurrentBatch",
+			fn: func(t *testing.T, name string) {
+				testConcurrentBatch(t, name)
+			}},
+	}
+	for i := range dbs {
+		for tt := range list_test {
+			testname := fmt.Sprintf("%s%s", list_test[tt].name, dbs[i])
+			t.Run(testname, func(t *testing.T) {
+				list_test[tt].fn(t, dbs[i])
+			})
+		}
+	}
+}
+
+// testBatchCommitCancelled tests that a cancelled context aborts the commit.
+func testBatchCommitCancelled(t *testing.T, name string) {
+	db := helpers.SetupDB(t, name)
+	key := helpers.RandomBytes(16)
+	batch := db.Batch()
+	require.NoError(t, batch.Put(key, helpers.RandomBytes(32)))
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	err := batch.Commit(ctx)
+	require.ErrorIs(t, err, context.Canceled, "Commit should observe the cancelled context")
+	ok, err := db.Has(t.Context(), key)
+	require.NoError(t, err)
+	require.False(t, ok, "Cancelled commit should not write anything")
+	defer db.Close()
+}
+
+// testBatchContextOperations tests the context-aware batch Put/Delete variants.
+func testBatchContextOperations(t *testing.T, name string) {
+	db := helpers.SetupDB(t, name)
+	batch, ok := db.Batch().(zerokv.ContextBatch)
+	require.True(t, ok, "Batch should implement zerokv.ContextBatch")
+	key1, key2 := helpers.RandomBytes(16), helpers.RandomBytes(16)
+	require.NoError(t, db.Put(t.Context(), key2, helpers.RandomBytes(32)))
+	require.NoError(t, batch.PutCtx(t.Context(), key1, helpers.RandomBytes(32)))
+	require.NoError(t, batch.DeleteCtx(t.Context(), key2))
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	require.ErrorIs(t, batch.PutCtx(ctx, helpers.RandomBytes(16), nil), context.Canceled)
+	require.ErrorIs(t, batch.DeleteCtx(ctx, key1), context.Canceled)
+
+	require.NoError(t, batch.Commit(t.Context()))
+	has, err := db.Has(t.Context(), key1)
+	require.NoError(t, err)
+	require.True(t, has, "PutCtx should have been applied")
+	has, err = db.Has(t.Context(), key2)
+	require.NoError(t, err)
+	require.False(t, has, "DeleteCtx should have been applied")
+	defer db.Close()
+}
+
+// testBatchWithOptions tests that a batch flushes itself at its thresholds
+// and that Commit flushes the remainder.
+func testBatchWithOptions(t *testing.T, name string) {
+	db := helpers.SetupDB(t, name)
+	defer db.Close()
+	batch := db.BatchWithOptions(3, 0)
+	for i := range 7 {
+		require.NoError(t, batch.Put([]byte(fmt.Sprintf("ops_%d", i)), []byte("value")))
+	}
+	count, err := db.Count(t.Context(), []byte("ops_"))
+	require.NoError(t, err)
+	require.Equal(t, int64(6), count, "Two full segments should already be flushed")
+	require.NoError(t, batch.Commit(t.Context()))
+	count, err = db.Count(t.Context(), []byte("ops_"))
+	require.NoError(t, err)
+	require.Equal(t, int64(7), count)
+
+	// each put stages 10 bytes, so the third one crosses 25
+	batch = db.BatchWithOptions(0, 25)
+	for i := range 3 {
+		require.NoError(t, batch.Put([]byte(fmt.Sprintf("byt_%d", i)), []byte("value")))
+	}
+	count, err = db.Count(t.Context(), []byte("byt_"))
+	require.NoError(t, err)
+	require.Equal(t, int64(3), count)
+	require.NoError(t, batch.Delete([]byte("byt_0")))
+	require.NoError(t, batch.Commit(t.Context()))
+	count, err = db.Count(t.Context(), []byte("byt_"))
+	require.NoError(t, err)
+	require.Equal(t, int64(2), count)
+}
+
+// testBatchLenSize tests that a batch reports its queued operations and at
+// least the bytes of their keys and values, and that both drop to zero once
+// it commits.
+func testBatchLenSize(t *testing.T, name string) {
+	db := helpers.SetupDB(t, name)
+	defer db.Close()
+	batch := db.Batch()
+	require.Zero(t, batch.Len())
+	require.Zero(t, batch.Size())
+
+	require.NoError(t, batch.Put([]byte("key_1"), []byte("value_1")))
+	require.NoError(t, batch.Put([]byte("key_2"), []byte("value_2")))
+	require.NoError(t, batch.Delete([]byte("key_3")))
+	require.Equal(t, 3, batch.Len())
+	require.GreaterOrEqual(t, batch.Size(), 5+7+5+7+5)
+
+	require.NoError(t, batch.Commit(t.Context()))
+	require.Zero(t, batch.Len())
+	require.Zero(t, batch.Size())
+
+	// an auto-flushing batch only reports what it has not flushed yet
+	batch = db.BatchWithOptions(2, 0)
+	for i := range 3 {
+		require.NoError(t, batch.Put([]byte(fmt.Sprintf("ops_%d", i)), []byte("value")))
+	}
+	require.Equal(t, 1, batch.Len())
+	require.NoError(t, batch.Commit(t.Context()))
+	require.Zero(t, batch.Len())
+}
+
+// testBatchReset tests that a batch can be committed, reset, filled again
+// and committed again, and that Reset discards uncommitted operations.
+func testBatchReset(t *testing.T, name string) {
+	db := helpers.SetupDB(t, name)
+	defer db.Close()
+	batch := db.Batch()
+	require.NoError(t, batch.Put([]byte("first"), []byte("1")))
+	require.NoError(t, batch.Commit(t.Context()))
+
+	batch.Reset()
+	require.NoError(t, batch.Put([]byte("second"), []byte("2")))
+	require.NoError(t, batch.Delete([]byte("first")))
+	require.NoError(t, batch.Commit(t.Context()))
+
+	has, err := db.Has(t.Context(), []byte("first"))
+	require.NoError(t, err)
+	require.False(t, has, "Delete after Reset should have been applied")
+	val, err := db.Get(t.Context(), []byte("second"))
+	require.NoError(t, err)
+	require.Equal(t, []byte("2"), val)
+
+	batch.Reset()
+	require.NoError(t, batch.Put([]byte("dropped"), []byte("3")))
+	batch.Reset()
+	require.Zero(t, batch.Len())
+	require.NoError(t, batch.Commit(t.Context()))
+	has, err = db.Has(t.Context(), []byte("dropped"))
+	require.NoError(t, err)
+	require.False(t, has, "Reset should discard queued operations")
+}
+
+// testBulkLoad tests that BulkLoad commits everything fn stages, and that
+// an error from fn is returned with the staged writes discarded.
+func testBulkLoad(t *testing.T, name string) {
+	db := helpers.SetupDB(t, name)
+	defer db.Close()
+	err := db.BulkLoad(t.Context(), func(b zerokv.Batch) error {
+		for i := range 1000 {
+			if err := b.Put([]byte(fmt.Sprintf("bulk_%04d", i)), []byte("value")); err != nil {
+				return err
+			}
+		}
+		return b.Delete([]byte("bulk_0000"))
+	})
+	require.NoError(t, err)
+	count, err := db.Count(t.Context(), []byte("bulk_"))
+	require.NoError(t, err)
+	require.Equal(t, int64(999), count)
+
+	errStop := errors.New("stop")
+	err = db.BulkLoad(t.Context(), func(b zerokv.Batch) error {
+		require.NoError(t, b.Put([]byte("failed"), []byte("value")))
+		return errStop
+	})
+	require.ErrorIs(t, err, errStop)
+	has, err := db.Has(t.Context(), []byte("failed"))
+	require.NoError(t, err)
+	require.False(t, has, "Writes staged by a failed load should be discarded")
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	require.ErrorIs(t, db.BulkLoad(ctx, func(b zerokv.Batch) error { return nil }), context.Canceled)
+}
+
+// testBatchValidate tests that Validate reports the first operation breaking
+// the limits given to NewBatch without writing anything, that Reset clears
+// the violation, and that a plain batch checks its engine's own limits.
+func testBatchValidate(t *testing.T, name string) {
+	db := helpers.SetupDB(t, name)
+	defer db.Close()
+	plain := db.Batch()
+	err := plain.Put(nil, []byte("value"))
+	switch name {
+	case "badgerdb":
+		require.Error(t, err, "Badger rejects an empty key as it is staged")
+	case "boltdb":
+		require.NoError(t, err)
+		require.ErrorIs(t, plain.Validate(), zerokv.ErrInvalidBatch)
+	default:
+		require.NoError(t, err)
+		require.NoError(t, plain.Validate())
+	}
+	plain.Reset()
+	require.NoError(t, plain.Validate())
+	batch := zerokv.NewBatch(db, zerokv.WithMaxKeySize(8), zerokv.WithMaxValueSize(16))
+	require.NoError(t, batch.Put([]byte("ok"), []byte("value")))
+	require.NoError(t, batch.Validate())
+
+	require.NoError(t, batch.Put([]byte("big"), make([]byte, 17)))
+	require.NoError(t, batch.Delete([]byte("much_too_long")))
+	err = batch.Validate()
+	require.ErrorIs(t, err, zerokv.ErrInvalidBatch)
+	require.ErrorIs(t, err, zerokv.ErrValueTooLarge, "the first violation should be reported")
+	require.ErrorContains(t, err, "operation 1:")
+
+	has, err := db.Has(t.Context(), []byte("ok"))
+	require.NoError(t, err)
+	require.False(t, has, "Validate should not write anything")
+
+	batch.Reset()
+	require.NoError(t, batch.Validate())
+	require.NoError(t, batch.Delete([]byte("much_too_long")))
+	require.ErrorIs(t, batch.Validate(), zerokv.ErrKeyTooLarge)
+	batch.Reset()
+	require.NoError(t, batch.Put([]byte("ok"), []byte("value")))
+	require.NoError(t, batch.Validate())
+	require.NoError(t, batch.Commit(t.Context()))
+	val, err := db.Get(t.Context(), []byte("ok"))
+	require.NoError(t, err)
+	require.Equal(t, []byte("value"), val)
+}
+
+// testBatchPutIfAbsent tests that PutIfAbsent writes missing keys, leaves
+// existing ones alone, and sees the operations staged before it.
+func testBatchPutIfAbsent(t *testing.T, name string) {
+	db := helpers.SetupDB(t, name)
+	defer db.Close()
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+	require.NoError(t, db.Put(ctx, []byte("present"), []byte("old")))
+	require.NoError(t, db.Put(ctx, []byte("deleted"), []byte("old")))
+	ch, err := db.Watch(ctx, []byte("absent"))
+	require.NoError(t, err)
+
+	batch := db.Batch()
+	require.NoError(t, batch.PutIfAbsent([]byte("present"), []byte("new")))
+	require.NoError(t, batch.PutIfAbsent([]byte("absent"), []byte("new")))
+	// later operations on the same key still win
+	require.NoError(t, batch.PutIfAbsent([]byte("overwritten"), []byte("new")))
+	require.NoError(t, batch.Put([]byte("overwritten"), []byte("put")))
+	// earlier operations in the batch decide whether the key exists
+	require.NoError(t, batch.Put([]byte("staged"), []byte("put")))
+	require.NoError(t, batch.PutIfAbsent([]byte("staged"), []byte("new")))
+	require.NoError(t, batch.Delete([]byte("deleted")))
+	require.NoError(t, batch.PutIfAbsent([]byte("deleted"), []byte("new")))
+	require.NoError(t, batch.PutIfAbsent([]byte("absent"), []byte("again")))
+	require.Equal(t, 9, batch.Len())
+	require.NoError(t, batch.Commit(ctx))
+
+	want := map[string]string{"present": "old", "absent": "new", "overwritten": "put", "staged": "put", "deleted": "new"}
+	for key, value := range want {
+		got, err := db.Get(ctx, []byte(key))
+		require.NoError(t, err, "key %s", key)
+		require.Equal(t, value, string(got), "key %s", key)
+	}
+	// only the put that was applied is published
+	ev := nextEvent(t, ch)
+	require.Equal(t, zerokv.Event{Op: zerokv.OpPut, Key: []byte("absent"), Value: []byte("new")}, ev)
+
+	// the batch can be reused, and the key now exists
+	batch.Reset()
+	require.NoError(t, batch.PutIfAbsent([]byte("absent"), []byte("newer")))
+	require.NoError(t, batch.Commit(ctx))
+	got, err := db.Get(ctx, []byte("absent"))
+	require.NoError(t, err)
+	require.Equal(t, []byte("new"), got)
+
+	// segments of an auto-flushing batch check the store as they commit
+	batch = db.BatchWithOptions(1, 0)
+	require.NoError(t, batch.PutIfAbsent([]byte("auto"), []byte("first")))
+	require.NoError(t, batch.PutIfAbsent([]byte("auto"), []byte("second")))
+	require.NoError(t, batch.Commit(ctx))
+	got, err = db.Get(ctx, []byte("auto"))
+	require.NoError(t, err)
+	require.Equal(t, []byte("first"), got)
+}
+
+// testConcurrentBatch tests that goroutines writing to one concurrent batch
+// all land, across auto-flushes and the final Commit. Run it with -race.
+func testConcurrentBatch(t *testing.T, name string) {
+	db := helpers.SetupDB(t, name)
+	defer db.Close()
+	const writers, perWriter = 16, 200
+	batch := zerokv.NewConcurrentBatch(db, 4<<10)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, writers)
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < perWriter; i++ {
+				key := []byte(fmt.Sprintf("cb_%02d_%03d", w, i))
+				if err := batch.Put(key, key); err != nil {
+					errs <- err
+					return
+				}
+				if i%50 == 0 {
+					_ = batch.Len() // reads share the lock with writes
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		require.NoError(t, err)
+	}
+	require.Less(t, batch.Size(), writers*perWriter*len("cb_00_000")*2, "The batch should have auto-flushed")
+	require.NoError(t, batch.Commit(t.Context()))
+
+	n, err := db.Count(t.Context(), []byte("cb_"))
+	require.NoError(t, err)
+	require.Equal(t, int64(writers*perWriter), n)
+	value, err := db.Get(t.Context(), []byte("cb_15_199"))
+	require.NoError(t, err)
+	require.Equal(t, []byte("cb_15_199"), value)
+}
@@ -0,0 +1,151 @@
+package tests
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/rawbytedev/zerokv"
+	"github.com/rawbytedev/zerokv/helpers"
+)
+
+// fillBench writes n keys under "bench_" with values of the given size using a single batch.
+func fillBench(b *testing.B, db zerokv.Core, n int, valueSize int) {
+	batch := db.Batch()
+	value := helpers.RandomBytes(valueSize)
+	for i := 0; i < n; i++ {
+		key := binary.BigEndian.AppendUint64([]byte("bench_"), uint64(i))
+		if err := batch.Put(key, value); err != nil {
+			b.Fatalf("Failed to queue key: %v", err)
+		}
+	}
+	if err := batch.Commit(b.Context()); err != nil {
+		b.Fatalf("Failed to commit batch: %v", err)
+	}
+}
+
+// BenchmarkScan compares keys-only scans against full scans over 100k keys.
+func BenchmarkScan(b *testing.B) {
+	for _, name := range []string{"badgerdb", "pebbledb", "memdb", "boltdb", "leveldb"} {
+		db := helpers.SetupDB(b, name)
+		fillBench(b, db, 100_000, 256)
+		b.Run(name+"/Full", func(b *testing.B) {
+			for b.Loop() {
+				it := db.Scan([]byte("bench_"))
+				for it.Next() {
+					_ = it.Value()
+				}
+				it.Release()
+			}
+		})
+		b.Run(name+"/KeysOnly", func(b *testing.B) {
+			for b.Loop() {
+				it := db.ScanKeys([]byte("bench_"))
+				for it.Next() {
+					_ = it.Value()
+				}
+				it.Release()
+			}
+		})
+		db.Close()
+	}
+}
+
+// BenchmarkPutSync compares synced against unsynced single-key writes. The
+// gap is the cost of an fsync per write; memdb and boltdb ignore sync and
+// serve as a baseline.
+func BenchmarkPutSync(b *testing.B) {
+	for _, name := range []string{"badgerdb", "pebbledb", "memdb", "boltdb", "leveldb"} {
+		db := helpers.SetupDB(b, name)
+		sw := db.(zerokv.SyncWriter)
+		value := helpers.RandomBytes(256)
+		for _, sync := range []bool{true, false} {
+			mode := "NoSync"
+			if sync {
+				mode = "Sync"
+			}
+			b.Run(name+"/"+mode, func(b *testing.B) {
+				var i uint64
+				for b.Loop() {
+					key := binary.BigEndian.AppendUint64([]byte("bench_"), i)
+					i++
+					if err := sw.PutSync(b.Context(), key, value, sync); err != nil {
+						b.Fatalf("PutSync failed: %v", err)
+					}
+				}
+			})
+		}
+		db.Close()
+	}
+}
+
+// bulkBenchKeys is the number of keys each BenchmarkBulkLoad iteration writes.
+const bulkBenchKeys = 1_000_000
+
+// BenchmarkBulkLoad compares loading 1M keys with BulkLoad against one Put
+// per key. The Put runs sync every write on pebbledb and boltdb and take
+// minutes there; use -bench 'BulkLoad/.*/BulkLoad' to time BulkLoad alone.
+func BenchmarkBulkLoad(b *testing.B) {
+	for _, name := range []string{"badgerdb", "pebbledb", "memdb", "boltdb", "leveldb"} {
+		db := helpers.SetupDB(b, name)
+		value := helpers.RandomBytes(64)
+		var round uint64
+		b.Run(name+"/BulkLoad", func(b *testing.B) {
+			for b.Loop() {
+				round++
+				err := db.BulkLoad(b.Context(), func(batch zerokv.Batch) error {
+					for i := range uint64(bulkBenchKeys) {
+						key := binary.BigEndian.AppendUint64(binary.BigEndian.AppendUint64([]byte("bulk_"), round), i)
+						if err := batch.Put(key, value); err != nil {
+							return err
+						}
+					}
+					return nil
+				})
+				if err != nil {
+					b.Fatalf("BulkLoad failed: %v", err)
+				}
+			}
+		})
+		b.Run(name+"/Put", func(b *testing.B) {
+			for b.Loop() {
+				round++
+				for i := range uint64(bulkBenchKeys) {
+					key := binary.BigEndian.AppendUint64(binary.BigEndian.AppendUint64([]byte("bulk_"), round), i)
+					if err := db.Put(b.Context(), key, value); err != nil {
+						b.Fatalf("Put failed: %v", err)
+					}
+				}
+			}
+		})
+		db.Close()
+	}
+}
+
+// BenchmarkGetInto compares Get against GetInto with one reused buffer on
+// 256-byte values; run it with -benchmem to see the allocations saved.
+func BenchmarkGetInto(b *testing.B) {
+	for _, name := range []string{"badgerdb", "pebbledb", "memdb", "boltdb", "leveldb"} {
+		db := helpers.SetupDB(b, name)
+		fillBench(b, db, 1000, 256)
+		key := binary.BigEndian.AppendUint64([]byte("bench_"), 500)
+		b.Run(name+"/Get", func(b *testing.B) {
+			b.ReportAllocs()
+			for b.Loop() {
+				if _, err := db.Get(b.Context(), key); err != nil {
+					b.Fatalf("Get failed: %v", err)
+				}
+			}
+		})
+		b.Run(name+"/GetInto", func(b *testing.B) {
+			b.ReportAllocs()
+			var buf []byte
+			for b.Loop() {
+				var err error
+				if buf, err = db.GetInto(b.Context(), key, buf); err != nil {
+					b.Fatalf("GetInto failed: %v", err)
+				}
+			}
+		})
+		db.Close()
+	}
+}
@@ -1,9 +1,13 @@
 package tests
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"testing"
 
+	"github.com/rawbytedev/zerokv"
 	"github.com/rawbytedev/zerokv/helpers"
 	"github.com/stretchr/testify/require"
 )
@@ -14,7 +18,7 @@ type test struct {
 }
 
 func TestZeroKvImplementation(t *testing.T) {
-	dbs := []string{"badgerdb", "pebbledb"}
+	dbs := []string{"badgerdb", "pebbledb", "memdb", "boltdb", "leveldb"}
 	list_test := []test{
 		{name: "TestGetPutDelete",
 			fn: func(t *testing.T, name string) {
@@ -28,6 +32,86 @@ func TestZeroKvImplementation(t *testing.T) {
 			fn: func(t *testing.T, name string) {
 				testOverwriteKey(t, name)
 			}},
+		{
+			name: "TestGetOrDefault",
+			fn: func(t *testing.T, name string) {
+				testGetOrDefault(t, name)
+			}},
+		{
+			name: "TestGetInto",
+			fn: func(t *testing.T, name string) {
+				testGetInto(t, name)
+			}},
+		{
+			name: "TestView",
+			fn: func(t *testing.T, name string) {
+				testView(t, name)
+			}},
+		{
+			name: "TestPutSync",
+			fn: func(t *testing.T, name string) {
+				testPutSync(t, name)
+			}},
+		{
+			name: "TestHas",
+			fn: func(t *testing.T, name string) {
+				testHas(t, name)
+			}},
+		{
+			name: "TestSizeOf",
+			fn: func(t *testing.T, name string) {
+				testSizeOf(t, name)
+			}},
+		{
+			name: "TestGetMany",
+			fn: func(t *testing.T, name string) {
+				testGetMany(t, name)
+			}},
+		{
+			name: "TestHasMany",
+			fn: func(t *testing.T, name string) {
+				testHasMany(t, name)
+			}},
+		{
+			name: "TestPutMany",
+			fn: func(t *testing.T, name string) {
+				testPutMany(t, name)
+			}},
+		{
+			name: "TestDeletePrefix",
+			fn: func(t *testing.T, name string) {
+				testDeletePrefix(t, name)
+			}},
+		{
+			name: "TestDeleteRange",
+			fn: func(t *testing.T, name string) {
+				testDeleteRange(t, name)
+			}},
+		{
+			name: "TestTruncate",
+			fn: func(t *testing.T, name string) {
+				testTruncate(t, name)
+			}},
+		{
+			name: "TestTruncatePrefix",
+			fn: func(t *testing.T, name string) {
+				testTruncatePrefix(t, name)
+			}},
+		{
+			name: "TestStats",
+			fn: func(t *testing.T, name string) {
+				testStats(t, name)
+			}},
+		{
+			name: "TestSync",
+			fn: func(t *testing.T, name string) {
+				testSync(t, name)
+			}},
+		{
+			name: "TestPing",
+			fn: func(t *testing.T, name string) {
+				testPing(t, name)
+			}},
 		{
 			name: "TestClose",
 			fn: func(t *testing.T, name string) {
@@ -77,7 +161,21 @@ func testGetNonExistentKey(t *testing.T, name string) {
 	nonExistentKey := helpers.RandomBytes(16)
 	_, err := db.Get(t.Context(), nonExistentKey)
 	require.Error(t, err, "Expected error when getting non-existent key")
+	require.ErrorIs(t, err, zerokv.ErrKeyNotFound)
+	require.Error(t, errors.Unwrap(err), "Backend error should stay wrapped")
 	defer db.Close()
+
+	txn, err := db.NewTransaction(t.Context())
+	require.NoError(t, err)
+	_, err = txn.Get(t.Context(), nonExistentKey)
+	require.ErrorIs(t, err, zerokv.ErrKeyNotFound)
+	txn.Discard()
+
+	snap, err := db.Snapshot()
+	require.NoError(t, err)
+	defer snap.Release()
+	_, err = snap.Get(t.Context(), nonExistentKey)
+	require.ErrorIs(t, err, zerokv.ErrKeyNotFound)
 }
 
 // TestOverwriteKey tests overwriting an existing key.
@@ -99,9 +197,440 @@ func testOverwriteKey(t *testing.T, name string) {
 	defer db.Close()
 }
 
+// testGetOrDefault tests that GetOrDefault returns a copy of the default
+// for a missing key and the stored value otherwise.
+func testGetOrDefault(t *testing.T, name string) {
+	db := helpers.SetupDB(t, name)
+	defer db.Close()
+	key, value := helpers.RandomBytes(16), helpers.RandomBytes(32)
+	def := []byte("default")
+
+	got, err := db.GetOrDefault(t.Context(), key, def)
+	require.NoError(t, err)
+	require.Equal(t, def, got)
+	got[0] = 'X'
+	require.Equal(t, []byte("default"), def, "The default should be copied")
+	got, err = db.GetOrDefault(t.Context(), key, nil)
+	require.NoError(t, err)
+	require.Empty(t, got)
+
+	require.NoError(t, db.Put(t.Context(), key, value))
+	got, err = db.GetOrDefault(t.Context(), key, def)
+	require.NoError(t, err)
+	require.Equal(t, value, got)
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	_, err = db.GetOrDefault(ctx, []byte("missing"), def)
+	require.ErrorIs(t, err, context.Canceled)
+}
+
+// testGetInto tests that GetInto fills dst in place when it is large
+// enough, grows it otherwise, and keeps it on a miss.
+func testGetInto(t *testing.T, name string) {
+	db := helpers.SetupDB(t, name)
+	defer db.Close()
+	key, value := helpers.RandomBytes(16), helpers.RandomBytes(64)
+	require.NoError(t, db.Put(t.Context(), key, value))
+
+	buf := make([]byte, 3, 128)
+	got, err := db.GetInto(t.Context(), key, buf)
+	require.NoError(t, err)
+	require.Equal(t, value, got)
+	require.Same(t, &buf[:1][0], &got[0], "A large enough dst should be reused")
+
+	small := make([]byte, 0, 8)
+	got, err = db.GetInto(t.Context(), key, small)
+	require.NoError(t, err)
+	require.Equal(t, value, got)
+	got, err = db.GetInto(t.Context(), key, nil)
+	require.NoError(t, err)
+	require.Equal(t, value, got)
+
+	got, err = db.GetInto(t.Context(), []byte("missing"), buf)
+	require.ErrorIs(t, err, zerokv.ErrKeyNotFound)
+	require.Empty(t, got)
+	require.Equal(t, 128, cap(got), "dst should be kept on a miss")
+}
+
+// testView tests that View passes the stored value to fn, returns fn's
+// error unchanged and reports a missing key without calling fn.
+func testView(t *testing.T, name string) {
+	db := helpers.SetupDB(t, name)
+	defer db.Close()
+	key, value := helpers.RandomBytes(16), helpers.RandomBytes(64)
+	require.NoError(t, db.Put(t.Context(), key, value))
+
+	var seen []byte
+	err := db.View(t.Context(), key, func(v []byte) error {
+		seen = bytes.Clone(v)
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, value, seen)
+
+	errStop := errors.New("stop")
+	err = db.View(t.Context(), key, func(v []byte) error { return errStop })
+	require.ErrorIs(t, err, errStop)
+
+	called := false
+	err = db.View(t.Context(), []byte("missing"), func(v []byte) error {
+		called = true
+		return nil
+	})
+	require.ErrorIs(t, err, zerokv.ErrKeyNotFound)
+	require.False(t, called, "fn called for a missing key")
+}
+
+// testPutSync tests that every backend accepts synced and unsynced writes
+// through zerokv.SyncWriter and that both are readable.
+func testPutSync(t *testing.T, name string) {
+	db := helpers.SetupDB(t, name)
+	defer db.Close()
+	sw, ok := db.(zerokv.SyncWriter)
+	require.True(t, ok, "backend should implement zerokv.SyncWriter")
+	require.NoError(t, sw.PutSync(t.Context(), []byte("synced"), []byte("1"), true))
+	require.NoError(t, sw.PutSync(t.Context(), []byte("unsynced"), []byte("2"), false))
+	for key, want := range map[string]string{"synced": "1", "unsynced": "2"} {
+		value, err := db.Get(t.Context(), []byte(key))
+		require.NoError(t, err)
+		require.Equal(t, []byte(want), value)
+	}
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	require.ErrorIs(t, sw.PutSync(ctx, []byte("cancelled"), []byte("3"), true), context.Canceled)
+}
+
+// testHas tests existence checks for present, missing and deleted keys.
+func testHas(t *testing.T, name string) {
+	db := helpers.SetupDB(t, name)
+	key := helpers.RandomBytes(16)
+	ok, err := db.Has(t.Context(), key)
+	require.NoError(t, err, "Missing key should not return an error")
+	require.False(t, ok, "Missing key should not exist")
+	err = db.Put(t.Context(), key, helpers.RandomBytes(32))
+	require.NoError(t, err)
+	ok, err = db.Has(t.Context(), key)
+	require.NoError(t, err)
+	require.True(t, ok, "Key should exist after Put")
+	err = db.Delete(t.Context(), key)
+	require.NoError(t, err)
+	ok, err = db.Has(t.Context(), key)
+	require.NoError(t, err)
+	require.False(t, ok, "Key should not exist after Delete")
+	defer db.Close()
+}
+
+// testSizeOf tests reading value lengths for present, empty, overwritten
+// and missing keys.
+func testSizeOf(t *testing.T, name string) {
+	db := helpers.SetupDB(t, name)
+	defer db.Close()
+	for _, size := range []int{1, 100, 4096} {
+		key := helpers.RandomBytes(16)
+		require.NoError(t, db.Put(t.Context(), key, helpers.RandomBytes(size)))
+		n, err := db.SizeOf(t.Context(), key)
+		require.NoError(t, err)
+		require.Equal(t, size, n)
+	}
+
+	key := helpers.RandomBytes(16)
+	require.NoError(t, db.Put(t.Context(), key, helpers.RandomBytes(64)))
+	require.NoError(t, db.Put(t.Context(), key, helpers.RandomBytes(8)))
+	n, err := db.SizeOf(t.Context(), key)
+	require.NoError(t, err)
+	require.Equal(t, 8, n, "SizeOf should report the latest value")
+
+	_, err = db.SizeOf(t.Context(), helpers.RandomBytes(16))
+	require.ErrorIs(t, err, zerokv.ErrKeyNotFound)
+	require.NoError(t, db.Delete(t.Context(), key))
+	_, err = db.SizeOf(t.Context(), key)
+	require.ErrorIs(t, err, zerokv.ErrKeyNotFound)
+}
+
+// testGetMany tests multi-key reads, including missing keys and empty values.
+func testGetMany(t *testing.T, name string) {
+	db := helpers.SetupDB(t, name)
+	keys := make([][]byte, 5)
+	values := make([][]byte, 5)
+	for i := range keys {
+		keys[i] = helpers.RandomBytes(16)
+		values[i] = helpers.RandomBytes(32)
+		require.NoError(t, db.Put(t.Context(), keys[i], values[i]))
+	}
+	emptyKey := helpers.RandomBytes(16)
+	require.NoError(t, db.Put(t.Context(), emptyKey, []byte{}))
+	missing := helpers.RandomBytes(16)
+
+	query := [][]byte{keys[3], missing, keys[0], emptyKey, keys[4]}
+	got, err := db.GetMany(t.Context(), query)
+	require.NoError(t, err)
+	require.Len(t, got, len(query), "Should return one entry per key")
+	require.Equal(t, values[3], got[0], "Results should preserve input order")
+	require.Nil(t, got[1], "Missing key should yield nil")
+	require.Equal(t, values[0], got[2])
+	require.NotNil(t, got[3], "Empty value should be distinguishable from a missing key")
+	require.Empty(t, got[3])
+	require.Equal(t, values[4], got[4])
+
+	got, err = db.GetMany(t.Context(), nil)
+	require.NoError(t, err)
+	require.Empty(t, got)
+	defer db.Close()
+}
+
+// testHasMany tests multi-key existence checks, including missing, deleted
+// and empty-valued keys, and that results keep the input order.
+func testHasMany(t *testing.T, name string) {
+	db := helpers.SetupDB(t, name)
+	defer db.Close()
+	present := helpers.RandomBytes(16)
+	require.NoError(t, db.Put(t.Context(), present, helpers.RandomBytes(32)))
+	emptyKey := helpers.RandomBytes(16)
+	require.NoError(t, db.Put(t.Context(), emptyKey, []byte{}))
+	deleted := helpers.RandomBytes(16)
+	require.NoError(t, db.Put(t.Context(), deleted, helpers.RandomBytes(32)))
+	require.NoError(t, db.Delete(t.Context(), deleted))
+	missing := helpers.RandomBytes(16)
+
+	query := [][]byte{missing, present, deleted, emptyKey, present}
+	got, err := db.HasMany(t.Context(), query)
+	require.NoError(t, err)
+	require.Equal(t, []bool{false, true, false, true, true}, got)
+
+	got, err = db.HasMany(t.Context(), nil)
+	require.NoError(t, err)
+	require.Empty(t, got)
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	_, err = db.HasMany(ctx, query)
+	require.ErrorIs(t, err, context.Canceled)
+}
+
+// testPutMany tests atomic multi-key writes and length validation.
+func testPutMany(t *testing.T, name string) {
+	db := helpers.SetupDB(t, name)
+	keys := make([][]byte, 10)
+	values := make([][]byte, 10)
+	for i := range keys {
+		keys[i] = helpers.RandomBytes(16)
+		values[i] = helpers.RandomBytes(32)
+	}
+	require.NoError(t, db.PutMany(t.Context(), keys, values))
+	got, err := db.GetMany(t.Context(), keys)
+	require.NoError(t, err)
+	require.Equal(t, values, got, "Every pair should be written")
+
+	err = db.PutMany(t.Context(), keys, values[:9])
+	require.ErrorContains(t, err, "10 keys but 9 values", "Mismatched lengths should be rejected")
+	require.NoError(t, db.PutMany(t.Context(), nil, nil))
+	defer db.Close()
+}
+
+// testDeletePrefix tests that only keys under the prefix are removed.
+func testDeletePrefix(t *testing.T, name string) {
+	db := helpers.SetupDB(t, name)
+	_, _ = FillValues(t, db)
+	unrelated := [][]byte{[]byte("pre"), []byte("pra_1"), []byte("pre`"), []byte("zzz")}
+	for _, key := range unrelated {
+		require.NoError(t, db.Put(t.Context(), key, []byte("value")))
+	}
+
+	n, err := db.DeletePrefix(t.Context(), []byte("pre_"))
+	require.NoError(t, err)
+	require.Equal(t, 10, n, "Should report every removed key")
+	it := db.Scan([]byte("pre_"))
+	require.False(t, it.Next(), "No key should remain under the prefix")
+	it.Release()
+	for _, key := range unrelated {
+		ok, err := db.Has(t.Context(), key)
+		require.NoError(t, err)
+		require.True(t, ok, "Key %q outside the prefix should survive", key)
+	}
+
+	n, err = db.DeletePrefix(t.Context(), []byte("pre_"))
+	require.NoError(t, err)
+	require.Zero(t, n, "Deleting an empty prefix range should remove nothing")
+	defer db.Close()
+}
+
+// testDeleteRange tests that start is removed and end is kept.
+func testDeleteRange(t *testing.T, name string) {
+	db := helpers.SetupDB(t, name)
+	for _, key := range []string{"a", "b", "b1", "c", "c1", "d"} {
+		require.NoError(t, db.Put(t.Context(), []byte(key), []byte("value")))
+	}
+	require.NoError(t, db.DeleteRange(t.Context(), []byte("b"), []byte("c")))
+	expected := map[string]bool{"a": true, "b": false, "b1": false, "c": true, "c1": true, "d": true}
+	for key, want := range expected {
+		ok, err := db.Has(t.Context(), []byte(key))
+		require.NoError(t, err)
+		require.Equal(t, want, ok, "Unexpected presence for key %q", key)
+	}
+
+	// an empty range removes nothing
+	require.NoError(t, db.DeleteRange(t.Context(), []byte("d"), []byte("a")))
+	ok, err := db.Has(t.Context(), []byte("a"))
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	// a nil end removes everything from start onwards
+	require.NoError(t, db.DeleteRange(t.Context(), []byte("c1"), nil))
+	keys := collectKeys(t, db.Scan(nil))
+	require.Equal(t, [][]byte{[]byte("a"), []byte("c")}, keys)
+	defer db.Close()
+}
+
+// testTruncate tests that Truncate empties the store and leaves it usable.
+func testTruncate(t *testing.T, name string) {
+	db := helpers.SetupDB(t, name)
+	defer db.Close()
+	require.NoError(t, db.Truncate(t.Context()), "Truncating an empty store should succeed")
+
+	_, _ = FillValues(t, db)
+	require.NoError(t, db.Put(t.Context(), []byte{0xFF, 0xFF}, []byte("last")))
+	require.NoError(t, db.Truncate(t.Context()))
+	require.Empty(t, collectKeys(t, db.Scan(nil)))
+	count, err := db.Count(t.Context(), nil)
+	require.NoError(t, err)
+	require.Zero(t, count)
+	_, err = db.Get(t.Context(), []byte{0xFF, 0xFF})
+	require.ErrorIs(t, err, zerokv.ErrKeyNotFound)
+
+	require.NoError(t, db.Put(t.Context(), []byte("after"), []byte("value")))
+	value, err := db.Get(t.Context(), []byte("after"))
+	require.NoError(t, err)
+	require.Equal(t, []byte("value"), value)
+}
+
+// testTruncatePrefix tests that TruncatePrefix removes only the prefix and
+// that the size estimate for it drops.
+func testTruncatePrefix(t *testing.T, name string) {
+	db := helpers.SetupDB(t, name)
+	defer db.Close()
+	for i := 0; i < 200; i++ {
+		require.NoError(t, db.Put(t.Context(), []byte(fmt.Sprintf("tenant_a/%03d", i)), helpers.RandomBytes(4096)))
+		require.NoError(t, db.Put(t.Context(), []byte(fmt.Sprintf("tenant_b/%03d", i)), helpers.RandomBytes(64)))
+	}
+	// flush memtables so that the estimates of Pebble and LevelDB see the data
+	if err := db.Compact(t.Context(), nil, nil); !errors.Is(err, zerokv.ErrNotSupported) {
+		require.NoError(t, err)
+	}
+	before, err := db.EstimateSize([]byte("tenant_a/"))
+	require.NoError(t, err)
+	require.Positive(t, before)
+
+	require.NoError(t, db.TruncatePrefix(t.Context(), []byte("tenant_a/")))
+	after, err := db.EstimateSize([]byte("tenant_a/"))
+	require.NoError(t, err)
+	require.Less(t, after, before, "The size estimate should drop")
+	n, err := db.Count(t.Context(), []byte("tenant_a/"))
+	require.NoError(t, err)
+	require.Zero(t, n)
+	n, err = db.Count(t.Context(), []byte("tenant_b/"))
+	require.NoError(t, err)
+	require.Equal(t, int64(200), n)
+
+	// an empty prefix range is not an error
+	require.NoError(t, db.TruncatePrefix(t.Context(), []byte("none/")))
+}
+
+// testStats tests that Stats succeeds and never reports negative values.
+func testStats(t *testing.T, name string) {
+	db := helpers.SetupDB(t, name)
+	defer db.Close()
+	_, _ = FillValues(t, db)
+	stats, err := db.Stats(t.Context())
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, stats.KeyCount, int64(0))
+	require.GreaterOrEqual(t, stats.OnDiskBytes, int64(0))
+	require.GreaterOrEqual(t, stats.MemtableBytes, int64(0))
+	require.GreaterOrEqual(t, stats.PendingCompactions, int64(0))
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	_, err = db.Stats(ctx)
+	require.ErrorIs(t, err, context.Canceled)
+}
+
+// testSync tests that Sync succeeds after writes and keeps them readable.
+func testSync(t *testing.T, name string) {
+	db := helpers.SetupDB(t, name)
+	defer db.Close()
+	batch := db.Batch()
+	require.NoError(t, batch.Put([]byte("key"), []byte("value")))
+	require.NoError(t, batch.Commit(t.Context()))
+	require.NoError(t, db.Sync(t.Context()))
+	value, err := db.Get(t.Context(), []byte("key"))
+	require.NoError(t, err)
+	require.Equal(t, []byte("value"), value)
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	require.ErrorIs(t, db.Sync(ctx), context.Canceled)
+}
+
+// testPing tests that Ping succeeds on an open store and fails after Close.
+func testPing(t *testing.T, name string) {
+	db := helpers.SetupDB(t, name)
+	require.NoError(t, db.Ping(t.Context()))
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	require.ErrorIs(t, db.Ping(ctx), context.Canceled)
+	require.NoError(t, db.Close())
+	require.Error(t, db.Ping(t.Context()), "Ping should fail once the store is closed")
+}
+
 // TestClose tests closing the PebbleDB instance.
 func testClose(t *testing.T, name string) {
 	db := helpers.SetupDB(t, name)
 	err := db.Close()
 	require.NoError(t, err, "Error closing PebbleDB")
 }
+
+// TestCompact tests that compacting after a large delete succeeds for full
+// and bounded ranges without touching live keys.
+func TestCompact(t *testing.T) {
+	for _, name := range []string{"badgerdb", "pebbledb", "memdb", "leveldb"} {
+		t.Run(name, func(t *testing.T) {
+			db := helpers.SetupDB(t, name)
+			defer db.Close()
+			for i := 0; i < 500; i++ {
+				require.NoError(t, db.Put(t.Context(), []byte(fmt.Sprintf("cmp_%03d", i)), helpers.RandomBytes(1024)))
+			}
+			require.NoError(t, db.Put(t.Context(), []byte("keep"), []byte("value")))
+			_, err := db.DeletePrefix(t.Context(), []byte("cmp_"))
+			require.NoError(t, err)
+			require.NoError(t, db.Compact(t.Context(), nil, nil))
+			require.NoError(t, db.Compact(t.Context(), []byte("a"), []byte("z")))
+			count, err := db.Count(t.Context(), []byte("cmp_"))
+			require.NoError(t, err)
+			require.Zero(t, count)
+			value, err := db.Get(t.Context(), []byte("keep"))
+			require.NoError(t, err)
+			require.Equal(t, []byte("value"), value)
+		})
+	}
+}
+
+// TestCompactNotSupported tests backends that cannot compact report ErrNotSupported.
+func TestCompactNotSupported(t *testing.T) {
+	db := helpers.SetupDB(t, "boltdb")
+	defer db.Close()
+	require.ErrorIs(t, db.Compact(t.Context(), nil, nil), zerokv.ErrNotSupported)
+}
+
+// TestRunValueLogGC tests every backend exposes value-log GC, as a no-op
+// where there is no value log.
+func TestRunValueLogGC(t *testing.T) {
+	for _, name := range []string{"badgerdb", "pebbledb", "memdb", "boltdb", "leveldb"} {
+		t.Run(name, func(t *testing.T) {
+			db := helpers.SetupDB(t, name)
+			defer db.Close()
+			gc, ok := db.(zerokv.ValueLogGC)
+			require.True(t, ok, "Backend should implement zerokv.ValueLogGC")
+			require.NoError(t, gc.RunValueLogGC(0.5))
+		})
+	}
+}
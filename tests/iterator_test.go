@@ -2,6 +2,7 @@ package tests
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"testing"
 
@@ -28,7 +29,7 @@ func FillValues(t *testing.T, db zerokv.Core) ([][]byte, [][]byte) {
 }
 
 func TestZeroKvIterator(t *testing.T) {
-	dbs := []string{"pebbledb", "badgerdb"}
+	dbs := []string{"pebbledb", "badgerdb", "memdb", "boltdb", "leveldb"}
 	list_test := []test{
 		{
 			name: "TestIterateValue",
@@ -69,6 +70,86 @@ func TestZeroKvIterator(t *testing.T) {
 			fn: func(t *testing.T, name string) {
 				testIterateKeysWithSpecialCharacters(t, name)
 			},
+		}, {
+			name: "testLimit",
+			fn: func(t *testing.T, name string) {
+				testLimit(t, name)
+			},
+		}, {
+			name: "testFilter",
+			fn: func(t *testing.T, name string) {
+				testFilter(t, name)
+			},
+		}, {
+			name: "testFold",
+			fn: func(t *testing.T, name string) {
+				testFold(t, name)
+			},
+		}, {
+			name: "testListPrefixes",
+			fn: func(t *testing.T, name string) {
+				testListPrefixes(t, name)
+			},
+		}, {
+			name: "testRetainedKeys",
+			fn: func(t *testing.T, name string) {
+				testRetainedKeys(t, name)
+			},
+		}, {
+			name: "testScanContext",
+			fn: func(t *testing.T, name string) {
+				testScanContext(t, name)
+			},
+		}, {
+			name: "testReverseScan",
+			fn: func(t *testing.T, name string) {
+				testReverseScan(t, name)
+			},
+		}, {
+			name: "testRangeScan",
+			fn: func(t *testing.T, name string) {
+				testRangeScan(t, name)
+			},
+		}, {
+			name: "testScanFrom",
+			fn: func(t *testing.T, name string) {
+				testScanFrom(t, name)
+			},
+		}, {
+			name: "testCollectAll",
+			fn: func(t *testing.T, name string) {
+				testCollectAll(t, name)
+			},
+		}, {
+			name: "testIteratorSeek",
+			fn: func(t *testing.T, name string) {
+				testIteratorSeek(t, name)
+			},
+		}, {
+			name: "testIteratorValid",
+			fn: func(t *testing.T, name string) {
+				testIteratorValid(t, name)
+			},
+		}, {
+			name: "testIteratorFirstLast",
+			fn: func(t *testing.T, name string) {
+				testIteratorFirstLast(t, name)
+			},
+		}, {
+			name: "testScanKeys",
+			fn: func(t *testing.T, name string) {
+				testScanKeys(t, name)
+			},
+		}, {
+			name: "testCount",
+			fn: func(t *testing.T, name string) {
+				testCount(t, name)
+			},
+		}, {
+			name: "testEstimateSize",
+			fn: func(t *testing.T, name string) {
+				testEstimateSize(t, name)
+			},
 		},
 	}
 	for i := range dbs {
@@ -269,3 +350,593 @@ func testIterateKeysWithSpecialCharacters(t *testing.T, name string) {
 	defer db.Close()
 	defer it.Release()
 }
+
+// testReverseScan tests descending iteration through the Core interface
+func testReverseScan(t *testing.T, name string) {
+	db := helpers.SetupDB(t, name)
+	keys := [][]byte{
+		[]byte("key_01"),
+		[]byte("key_02"),
+		[]byte("key_03"),
+	}
+	for _, key := range keys {
+		require.NoError(t, db.Put(t.Context(), key, key))
+	}
+	// keys on both sides of the prefix must not leak into the scan
+	require.NoError(t, db.Put(t.Context(), []byte("aaa"), []byte("value")))
+	require.NoError(t, db.Put(t.Context(), []byte("key`"), []byte("value")))
+	require.NoError(t, db.Put(t.Context(), []byte("zzz"), []byte("value")))
+
+	it := db.ReverseScan([]byte("key_"))
+	for i := len(keys) - 1; i >= 0; i-- {
+		require.True(t, it.Next(), "Expected key %s", keys[i])
+		require.Equal(t, keys[i], it.Key(), "Keys should be in descending order")
+		require.Equal(t, keys[i], it.Value())
+	}
+	require.False(t, it.Next(), "Reverse scan should stop at the prefix boundary")
+	require.NoError(t, it.Error())
+	it.Release()
+
+	// an empty prefix walks the whole keyspace backwards
+	it = db.ReverseScan(nil)
+	require.True(t, it.Next())
+	require.Equal(t, []byte("zzz"), it.Key())
+	count := 1
+	for it.Next() {
+		count++
+	}
+	require.Equal(t, 6, count)
+	it.Release()
+	defer db.Close()
+}
+
+// collectKeys drains an iterator and returns copies of its keys
+func collectKeys(t *testing.T, it zerokv.Iterator) [][]byte {
+	keys := make([][]byte, 0)
+	for it.Next() {
+		key := make([]byte, len(it.Key()))
+		copy(key, it.Key())
+		keys = append(keys, key)
+	}
+	require.NoError(t, it.Error())
+	it.Release()
+	return keys
+}
+
+// testCount tests counting keys under a prefix and honouring cancellation
+func testCount(t *testing.T, name string) {
+	db := helpers.SetupDB(t, name)
+	defer db.Close()
+	FillValues(t, db)
+	require.NoError(t, db.Put(t.Context(), []byte("other"), []byte("value")))
+
+	count, err := db.Count(t.Context(), []byte("pre_"))
+	require.NoError(t, err)
+	require.Equal(t, int64(10), count)
+	count, err = db.Count(t.Context(), nil)
+	require.NoError(t, err)
+	require.Equal(t, int64(11), count)
+	count, err = db.Count(t.Context(), []byte("missing"))
+	require.NoError(t, err)
+	require.Zero(t, count)
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	_, err = db.Count(ctx, []byte("pre_"))
+	require.ErrorIs(t, err, context.Canceled)
+}
+
+// testRangeScan tests half-open range iteration
+func testRangeScan(t *testing.T, name string) {
+	db := helpers.SetupDB(t, name)
+	keys := [][]byte{[]byte("a"), []byte("b"), []byte("c"), []byte("d"), []byte("e")}
+	for _, key := range keys {
+		require.NoError(t, db.Put(t.Context(), key, key))
+	}
+
+	// end bound is excluded, start bound is included
+	require.Equal(t, keys[1:3], collectKeys(t, db.RangeScan([]byte("b"), []byte("d"))))
+	// bounds need not be existing keys
+	require.Equal(t, keys[1:3], collectKeys(t, db.RangeScan([]byte("az"), []byte("cz"))))
+	// nil start and nil end are open-ended
+	require.Equal(t, keys[:2], collectKeys(t, db.RangeScan(nil, []byte("c"))))
+	require.Equal(t, keys[3:], collectKeys(t, db.RangeScan([]byte("d"), nil)))
+	require.Equal(t, keys, collectKeys(t, db.RangeScan(nil, nil)))
+	// empty ranges yield nothing
+	require.Empty(t, collectKeys(t, db.RangeScan([]byte("c"), []byte("c"))))
+	require.Empty(t, collectKeys(t, db.RangeScan([]byte("x"), []byte("z"))))
+
+	it := db.RangeScan([]byte("b"), []byte("c"))
+	require.True(t, it.Next())
+	require.Equal(t, []byte("b"), it.Value())
+	require.False(t, it.Next())
+	it.Release()
+	defer db.Close()
+}
+
+// testScanFrom tests resuming a prefix scan in pages of 3, each page
+// starting just after the last key of the previous one
+func testScanFrom(t *testing.T, name string) {
+	db := helpers.SetupDB(t, name)
+	defer db.Close()
+	var want [][]byte
+	for i := range 10 {
+		key := fmt.Appendf(nil, "page_%02d", i)
+		want = append(want, key)
+		require.NoError(t, db.Put(t.Context(), key, key))
+	}
+	// neighbours on either side of the prefix must never show up
+	require.NoError(t, db.Put(t.Context(), []byte("page"), []byte("x")))
+	require.NoError(t, db.Put(t.Context(), []byte("page`"), []byte("x")))
+
+	var got [][]byte
+	var cursor []byte
+	for pages := 0; ; pages++ {
+		require.Less(t, pages, 5, "pagination should finish in 4 pages")
+		page := collectKeys(t, zerokv.Limit(db.ScanFrom([]byte("page_"), cursor), 3))
+		if len(page) == 0 {
+			break
+		}
+		require.LessOrEqual(t, len(page), 3)
+		got = append(got, page...)
+		cursor = append(bytes.Clone(page[len(page)-1]), 0)
+	}
+	require.Equal(t, want, got, "pages should cover every key exactly once")
+
+	// a start below the prefix is clamped to it; one past it yields nothing
+	require.Equal(t, want, collectKeys(t, db.ScanFrom([]byte("page_"), []byte("a"))))
+	require.Empty(t, collectKeys(t, db.ScanFrom([]byte("page_"), []byte("page`"))))
+	require.Equal(t, want[7:], collectKeys(t, db.ScanFrom([]byte("page_"), []byte("page_07"))))
+}
+
+// testCollectAll tests draining an iterator into KeyValue pairs
+func testCollectAll(t *testing.T, name string) {
+	db := helpers.SetupDB(t, name)
+	defer db.Close()
+	for _, key := range []string{"a", "key_1", "key_2", "key_3", "z"} {
+		require.NoError(t, db.Put(t.Context(), []byte(key), []byte("v"+key)))
+	}
+
+	it := db.Scan([]byte("key_"))
+	kvs, err := zerokv.CollectAll(it)
+	require.NoError(t, err)
+	require.Equal(t, []zerokv.KeyValue{
+		{Key: []byte("key_1"), Value: []byte("vkey_1")},
+		{Key: []byte("key_2"), Value: []byte("vkey_2")},
+		{Key: []byte("key_3"), Value: []byte("vkey_3")},
+	}, kvs)
+	require.False(t, it.Valid(), "CollectAll should release the iterator")
+
+	kvs, err = zerokv.CollectAll(db.Scan([]byte("missing")))
+	require.NoError(t, err)
+	require.Empty(t, kvs)
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	kvs, err = zerokv.CollectAll(db.ScanContext(ctx, []byte("key_")))
+	require.ErrorIs(t, err, context.Canceled)
+	require.Nil(t, kvs)
+}
+
+// testIteratorSeek tests positioning iterators with Seek
+func testIteratorSeek(t *testing.T, name string) {
+	db := helpers.SetupDB(t, name)
+	for _, key := range []string{"a", "key_1", "key_3", "key_5", "z"} {
+		require.NoError(t, db.Put(t.Context(), []byte(key), []byte(key)))
+	}
+
+	it := db.Scan([]byte("key_"))
+	require.True(t, it.Seek([]byte("key_2")), "Seek should land on the next key")
+	require.Equal(t, []byte("key_3"), it.Key())
+	require.True(t, it.Next(), "Next should continue after the sought key")
+	require.Equal(t, []byte("key_5"), it.Key())
+	require.False(t, it.Next())
+	// seeking before the prefix is clamped to the prefix start
+	require.True(t, it.Seek([]byte("a")))
+	require.Equal(t, []byte("key_1"), it.Key())
+	// seeking past the prefix invalidates the iterator
+	require.False(t, it.Seek([]byte("key_6")))
+	require.Nil(t, it.Key())
+	it.Release()
+
+	rit := db.ReverseScan([]byte("key_"))
+	require.True(t, rit.Seek([]byte("key_4")), "Reverse seek should land on the previous key")
+	require.Equal(t, []byte("key_3"), rit.Key())
+	require.True(t, rit.Next())
+	require.Equal(t, []byte("key_1"), rit.Key())
+	require.True(t, rit.Seek([]byte("key_5")), "Reverse seek includes the sought key")
+	require.Equal(t, []byte("key_5"), rit.Key())
+	require.True(t, rit.Seek([]byte("zz")), "Reverse seek past the prefix is clamped")
+	require.Equal(t, []byte("key_5"), rit.Key())
+	rit.Release()
+
+	rng := db.RangeScan([]byte("key_1"), []byte("key_5"))
+	require.True(t, rng.Seek([]byte("key_2")))
+	require.Equal(t, []byte("key_3"), rng.Key())
+	require.False(t, rng.Seek([]byte("key_5")), "Seek to the exclusive end should be invalid")
+	rng.Release()
+	defer db.Close()
+}
+
+// testIteratorValid tests that Valid follows Next and Seek without moving
+// the iterator, including through the Limit wrapper.
+func testIteratorValid(t *testing.T, name string) {
+	db := helpers.SetupDB(t, name)
+	defer db.Close()
+	for _, key := range []string{"key_1", "key_3", "key_5"} {
+		require.NoError(t, db.Put(t.Context(), []byte(key), []byte(key)))
+	}
+
+	it := db.Scan([]byte("key_"))
+	require.False(t, it.Valid(), "Valid should be false before the first Next")
+	it.Seek([]byte("key_2"))
+	require.True(t, it.Valid())
+	require.True(t, it.Valid(), "Valid should not advance the iterator")
+	require.Equal(t, []byte("key_3"), it.Key())
+	it.Seek([]byte("key_6"))
+	require.False(t, it.Valid())
+	it.Release()
+	require.False(t, it.Valid(), "Valid should be false after Release")
+
+	rit := db.ReverseScan([]byte("key_"))
+	rit.Seek([]byte("key_4"))
+	require.True(t, rit.Valid())
+	require.Equal(t, []byte("key_3"), rit.Key())
+	rit.Release()
+
+	lit := zerokv.Limit(db.Scan([]byte("key_")), 1)
+	require.True(t, lit.Next())
+	require.True(t, lit.Valid())
+	require.False(t, lit.Next())
+	require.False(t, lit.Valid(), "Valid should be false once the limit is reached")
+	lit.Release()
+}
+
+// testIteratorFirstLast tests that First and Last land on the boundary keys
+// of prefix, reverse and range iterators.
+func testIteratorFirstLast(t *testing.T, name string) {
+	db := helpers.SetupDB(t, name)
+	defer db.Close()
+	for _, key := range []string{"a", "key_1", "key_3", "key_5", "z"} {
+		require.NoError(t, db.Put(t.Context(), []byte(key), []byte(key)))
+	}
+
+	it := db.Scan([]byte("key_"))
+	require.True(t, it.Last())
+	require.Equal(t, []byte("key_5"), it.Key())
+	require.Equal(t, []byte("key_5"), it.Value())
+	require.True(t, it.First())
+	require.Equal(t, []byte("key_1"), it.Key())
+	require.True(t, it.Next(), "Next should continue after First")
+	require.Equal(t, []byte("key_3"), it.Key())
+	it.Release()
+
+	rit := db.ReverseScan([]byte("key_"))
+	require.True(t, rit.First())
+	require.Equal(t, []byte("key_1"), rit.Key(), "First is the smallest key even when reversed")
+	require.True(t, rit.Last())
+	require.Equal(t, []byte("key_5"), rit.Key())
+	require.True(t, rit.Next(), "Next should continue downwards after Last")
+	require.Equal(t, []byte("key_3"), rit.Key())
+	rit.Release()
+
+	rng := db.RangeScan([]byte("key_2"), []byte("key_5"))
+	require.True(t, rng.Last(), "Last should stop before the exclusive end")
+	require.Equal(t, []byte("key_3"), rng.Key())
+	require.True(t, rng.First())
+	require.Equal(t, []byte("key_3"), rng.Key())
+	rng.Release()
+
+	all := db.Scan(nil)
+	require.True(t, all.Last())
+	require.Equal(t, []byte("z"), all.Key())
+	require.True(t, all.First())
+	require.Equal(t, []byte("a"), all.Key())
+	all.Release()
+
+	none := db.Scan([]byte("none"))
+	require.False(t, none.First())
+	require.False(t, none.Last())
+	none.Release()
+	rnone := db.ReverseScan([]byte("none"))
+	require.False(t, rnone.First())
+	require.False(t, rnone.Last())
+	rnone.Release()
+}
+
+// testScanKeys tests keys-only iteration
+func testScanKeys(t *testing.T, name string) {
+	db := helpers.SetupDB(t, name)
+	keys, _ := FillValues(t, db)
+	it := db.ScanKeys([]byte("pre_"))
+	count := 0
+	for it.Next() {
+		count++
+		require.True(t, bytes.HasPrefix(it.Key(), []byte("pre_")), "Key should have prefix")
+		require.Nil(t, it.Value(), "Keys-only iterator should not return values")
+	}
+	require.Equal(t, len(keys), count, "Should iterate every key")
+	require.NoError(t, it.Error())
+	it.Release()
+	defer db.Close()
+}
+
+// releaseSpy records whether the iterator it wraps was released.
+type releaseSpy struct {
+	zerokv.Iterator
+	released bool
+}
+
+func (r *releaseSpy) Release() {
+	r.released = true
+	r.Iterator.Release()
+}
+
+// testLimit tests that Limit stops after n entries and still releases the
+// underlying iterator.
+func testLimit(t *testing.T, name string) {
+	db := helpers.SetupDB(t, name)
+	defer db.Close()
+	for i := 0; i < 100; i++ {
+		require.NoError(t, db.Put(t.Context(), []byte(fmt.Sprintf("page_%03d", i)), []byte("value")))
+	}
+	spy := &releaseSpy{Iterator: db.Scan([]byte("page_"))}
+	it := zerokv.Limit(spy, 10)
+	keys := make([][]byte, 0)
+	for it.Next() {
+		keys = append(keys, bytes.Clone(it.Key()))
+		require.Equal(t, []byte("value"), it.Value())
+	}
+	require.False(t, it.Next(), "Next must stay false after the limit")
+	require.NoError(t, it.Error())
+	it.Release()
+	require.True(t, spy.released)
+	require.Len(t, keys, 10)
+	require.Equal(t, []byte("page_000"), keys[0])
+	require.Equal(t, []byte("page_009"), keys[9])
+
+	// a successful Seek counts as one entry
+	it = zerokv.Limit(db.Scan([]byte("page_")), 2)
+	require.True(t, it.Seek([]byte("page_050")))
+	require.Equal(t, []byte("page_050"), it.Key())
+	require.True(t, it.Next())
+	require.Equal(t, []byte("page_051"), it.Key())
+	require.False(t, it.Next())
+	it.Release()
+	require.Empty(t, collectKeys(t, zerokv.Limit(db.Scan([]byte("page_")), 0)))
+}
+
+// testFilter tests that Filter yields only the entries its predicate
+// accepts, including after a Seek and when First or Last lands on a
+// rejected edge entry, and still releases the underlying iterator.
+func testFilter(t *testing.T, name string) {
+	db := helpers.SetupDB(t, name)
+	defer db.Close()
+	for i := 0; i < 100; i++ {
+		value := []byte("live")
+		if i%2 == 1 {
+			value = []byte("dead")
+		}
+		require.NoError(t, db.Put(t.Context(), []byte(fmt.Sprintf("page_%03d", i)), value))
+	}
+	live := func(key, value []byte) bool { return bytes.Equal(value, []byte("live")) }
+
+	spy := &releaseSpy{Iterator: db.Scan([]byte("page_"))}
+	it := zerokv.Filter(spy, live)
+	count := 0
+	for it.Next() {
+		require.Equal(t, []byte(fmt.Sprintf("page_%03d", count*2)), it.Key())
+		require.Equal(t, []byte("live"), it.Value())
+		count++
+	}
+	require.False(t, it.Valid())
+	require.NoError(t, it.Error())
+	it.Release()
+	require.True(t, spy.released)
+	require.Equal(t, 50, count)
+
+	// Seek lands on the first accepted key at or after its target
+	it = zerokv.Filter(db.Scan([]byte("page_")), live)
+	require.True(t, it.Seek([]byte("page_051")))
+	require.Equal(t, []byte("page_052"), it.Key())
+	require.True(t, it.Valid())
+	it.Release()
+
+	// filters compose with reverse scans and Limit
+	keys := collectKeys(t, zerokv.Limit(zerokv.Filter(db.ReverseScan([]byte("page_")), live), 2))
+	require.Equal(t, [][]byte{[]byte("page_098"), []byte("page_096")}, keys)
+	none := func(key, value []byte) bool { return false }
+	require.Empty(t, collectKeys(t, zerokv.Filter(db.Scan([]byte("page_")), none)))
+
+	// the greatest key is rejected, so Last falls back to the last accepted
+	it = zerokv.Filter(db.Scan([]byte("page_")), live)
+	require.True(t, it.Last())
+	require.Equal(t, []byte("page_098"), it.Key())
+	require.True(t, it.First())
+	require.Equal(t, []byte("page_000"), it.Key())
+	it.Release()
+	it = zerokv.Filter(db.Scan([]byte("page_")), none)
+	require.False(t, it.Last())
+	it.Release()
+
+	// on a reverse scan the smallest key is the one Next cannot step back to
+	dead := func(key, value []byte) bool { return !live(key, value) }
+	it = zerokv.Filter(db.ReverseScan([]byte("page_")), dead)
+	require.True(t, it.First())
+	require.Equal(t, []byte("page_001"), it.Key())
+	require.False(t, it.Next(), "page_000 is rejected and nothing is below it")
+	require.True(t, it.Last())
+	require.Equal(t, []byte("page_099"), it.Key())
+	require.True(t, it.Next())
+	require.Equal(t, []byte("page_097"), it.Key())
+	it.Release()
+}
+
+// testFold tests that Fold sums counters under a prefix, and stops on an
+// error from fn or a cancelled context.
+func testFold(t *testing.T, name string) {
+	db := helpers.SetupDB(t, name)
+	defer db.Close()
+	for i := int64(1); i <= 10; i++ {
+		require.NoError(t, db.Put(t.Context(), []byte(fmt.Sprintf("sales_%02d", i)), zerokv.EncodeCounter(i*100)))
+	}
+	require.NoError(t, db.Put(t.Context(), []byte("other"), zerokv.EncodeCounter(1)))
+	sum := func(acc int64, key, value []byte) (int64, error) {
+		n, err := zerokv.DecodeCounter(value)
+		return acc + n, err
+	}
+
+	total, err := zerokv.Fold(t.Context(), db, []byte("sales_"), int64(0), sum)
+	require.NoError(t, err)
+	require.Equal(t, int64(5500), total)
+	total, err = zerokv.Fold(t.Context(), db, []byte("missing_"), int64(7), sum)
+	require.NoError(t, err)
+	require.Equal(t, int64(7), total, "An empty prefix should return init")
+	keys, err := zerokv.Fold(t.Context(), db, []byte("sales_0"), "", func(acc string, key, value []byte) (string, error) {
+		return acc + string(key[len(key)-1]), nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, "123456789", keys)
+
+	// an error from fn stops the scan
+	calls := 0
+	_, err = zerokv.Fold(t.Context(), db, []byte("sales_"), int64(0), func(acc int64, key, value []byte) (int64, error) {
+		calls++
+		if calls == 3 {
+			return acc, zerokv.ErrInvalidCounter
+		}
+		return acc + 1, nil
+	})
+	require.ErrorIs(t, err, zerokv.ErrInvalidCounter)
+	require.Equal(t, 3, calls)
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	_, err = zerokv.Fold(ctx, db, []byte("sales_"), int64(0), sum)
+	require.ErrorIs(t, err, context.Canceled)
+}
+
+// testScanContext tests that a ScanContext iterator stops once its context
+// is cancelled and reports the cancellation.
+func testScanContext(t *testing.T, name string) {
+	db := helpers.SetupDB(t, name)
+	defer db.Close()
+	for i := 0; i < 100; i++ {
+		require.NoError(t, db.Put(t.Context(), []byte(fmt.Sprintf("row_%03d", i)), []byte("value")))
+	}
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+	it := db.ScanContext(ctx, []byte("row_"))
+	seen := 0
+	for it.Next() {
+		seen++
+		if seen == 5 {
+			cancel()
+		}
+	}
+	require.Equal(t, 5, seen)
+	require.ErrorIs(t, it.Error(), context.Canceled)
+	it.Release()
+
+	it = db.ScanContext(ctx, []byte("row_"))
+	require.False(t, it.Next())
+	require.False(t, it.Seek([]byte("row_050")))
+	require.ErrorIs(t, it.Error(), context.Canceled)
+	it.Release()
+
+	require.Len(t, collectKeys(t, db.ScanContext(t.Context(), []byte("row_"))), 100)
+}
+
+// testEstimateSize tests that size estimates succeed for bounded and
+// unbounded prefixes and never report more for an empty prefix range.
+func testEstimateSize(t *testing.T, name string) {
+	db := helpers.SetupDB(t, name)
+	defer db.Close()
+	for i := 0; i < 100; i++ {
+		require.NoError(t, db.Put(t.Context(), []byte(fmt.Sprintf("est_%03d", i)), helpers.RandomBytes(1024)))
+	}
+	size, err := db.EstimateSize([]byte("est_"))
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, size, int64(0))
+	empty, err := db.EstimateSize([]byte("none_"))
+	require.NoError(t, err)
+	require.LessOrEqual(t, empty, size)
+	all, err := db.EstimateSize(nil)
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, all, size)
+}
+
+// testListPrefixes tests that ListPrefixes lists each top-level segment
+// once, including bare keys and segments that sort between others' keys.
+func testListPrefixes(t *testing.T, name string) {
+	db := helpers.SetupDB(t, name)
+	defer db.Close()
+	prefixes, err := zerokv.ListPrefixes(db, '/')
+	require.NoError(t, err)
+	require.Empty(t, prefixes)
+
+	for _, key := range []string{"a/1", "a/2", "b/1"} {
+		require.NoError(t, db.Put(t.Context(), []byte(key), []byte("v")))
+	}
+	prefixes, err = zerokv.ListPrefixes(db, '/')
+	require.NoError(t, err)
+	require.Equal(t, [][]byte{[]byte("a"), []byte("b")}, prefixes)
+
+	for i := 0; i < 100; i++ {
+		require.NoError(t, db.Put(t.Context(), []byte(fmt.Sprintf("a/x/%03d", i)), []byte("v")))
+	}
+	for _, key := range []string{"a", "a-b/1", "b/2/3", "c"} {
+		require.NoError(t, db.Put(t.Context(), []byte(key), []byte("v")))
+	}
+	prefixes, err = zerokv.ListPrefixes(db, '/')
+	require.NoError(t, err)
+	require.Equal(t, [][]byte{[]byte("a"), []byte("a-b"), []byte("b"), []byte("c")}, prefixes)
+}
+
+// testRetainedKeys tests that keys and values kept from Key and Value
+// without copying stay intact as the iterator moves on and is released.
+func testRetainedKeys(t *testing.T, name string) {
+	db := helpers.SetupDB(t, name)
+	defer db.Close()
+	const n = 500
+	for i := 0; i < n; i++ {
+		key := fmt.Sprintf("own_%03d", i)
+		require.NoError(t, db.Put(t.Context(), []byte(key), []byte("value_"+key)))
+	}
+	for _, reverse := range []bool{false, true} {
+		scan := db.Scan
+		if reverse {
+			scan = db.ReverseScan
+		}
+		it := scan([]byte("own_"))
+		var keys, values [][]byte
+		for it.Next() {
+			keys = append(keys, it.Key())
+			values = append(values, it.Value())
+		}
+		require.NoError(t, it.Error())
+		it.Release()
+		require.Len(t, keys, n)
+		for i := range keys {
+			want := fmt.Sprintf("own_%03d", i)
+			if reverse {
+				want = fmt.Sprintf("own_%03d", n-1-i)
+			}
+			require.Equal(t, want, string(keys[i]), "Retained key %d was overwritten", i)
+			require.Equal(t, "value_"+want, string(values[i]), "Retained value %d was overwritten", i)
+		}
+	}
+}
+
+func TestPrefixUpperBound(t *testing.T) {
+	for prefix, want := range map[string][]byte{
+		"":          nil,
+		"a":         []byte("b"),
+		"user:":     []byte("user;"),
+		"a\xff\xff": []byte("b"),
+		"\xff\xff":  nil,
+		"ab\xfe":    []byte("ab\xff"),
+	} {
+		require.Equal(t, want, zerokv.PrefixUpperBound([]byte(prefix)), "%q", prefix)
+	}
+}
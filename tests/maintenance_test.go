@@ -0,0 +1,125 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rawbytedev/zerokv"
+	"github.com/rawbytedev/zerokv/helpers"
+	"github.com/stretchr/testify/require"
+)
+
+func TestZeroKvMaintenance(t *testing.T) {
+	dbs := []string{"badgerdb", "pebbledb", "memdb", "boltdb", "leveldb"}
+	list_test := []test{
+		{
+			name: "TestStartMaintenance",
+			fn: func(t *testing.T, name string) {
+				testStartMaintenance(t, name)
+			}}, {
+			name: "TestOptimize",
+			fn: func(t *testing.T, name string) {
+				testOptimize(t, name)
+			}},
+	}
+	for i := range dbs {
+		for tt := range list_test {
+			testname := fmt.Sprintf("%s%s", list_test[tt].name, dbs[i])
+			t.Run(testname, func(t *testing.T) {
+				list_test[tt].fn(t, dbs[i])
+			})
+		}
+	}
+}
+
+// maintenanceCore counts the maintenance calls StartMaintenance makes on
+// the Core it wraps.
+type maintenanceCore struct {
+	zerokv.Core
+	gcs, compactions atomic.Int64
+}
+
+func (c *maintenanceCore) RunValueLogGC(discardRatio float64) error {
+	c.gcs.Add(1)
+	return c.Core.(zerokv.ValueLogGC).RunValueLogGC(discardRatio)
+}
+
+func (c *maintenanceCore) CompactAll(ctx context.Context) error {
+	compactor, ok := c.Core.(zerokv.Compactor)
+	if !ok {
+		return nil
+	}
+	c.compactions.Add(1)
+	return compactor.CompactAll(ctx)
+}
+
+// testStartMaintenance tests that maintenance rounds run on schedule, that
+// only backends implementing Compactor are compacted, that both are found
+// behind a wrapper, and that no round runs once stop has returned
+func testStartMaintenance(t *testing.T, name string) {
+	db := helpers.SetupDB(t, name)
+	defer db.Close()
+	for i := range 100 {
+		require.NoError(t, db.Put(t.Context(), fmt.Appendf(nil, "key_%03d", i), helpers.RandomBytes(64)))
+	}
+	core := &maintenanceCore{Core: db}
+
+	stop := zerokv.StartMaintenance(zerokv.Namespace(core, []byte("ns/")), 5*time.Millisecond)
+	require.Eventually(t, func() bool { return core.gcs.Load() >= 3 }, 5*time.Second, time.Millisecond)
+	stop()
+	stop() // stopping twice is harmless
+
+	gcs, compactions := core.gcs.Load(), core.compactions.Load()
+	_, ok := db.(zerokv.Compactor)
+	require.Equal(t, name == "badgerdb" || name == "pebbledb" || name == "leveldb", ok)
+	if !ok {
+		require.Zero(t, compactions)
+	} else {
+		require.GreaterOrEqual(t, compactions, gcs-1)
+	}
+	time.Sleep(25 * time.Millisecond)
+	require.Equal(t, gcs, core.gcs.Load(), "no round should run after stop")
+	require.Equal(t, compactions, core.compactions.Load())
+
+	// the data survives maintenance
+	n, err := db.Count(t.Context(), []byte("key_"))
+	require.NoError(t, err)
+	require.EqualValues(t, 100, n)
+
+	// a non-positive interval never starts
+	stop = zerokv.StartMaintenance(core, 0)
+	stop()
+}
+
+// testOptimize tests that Optimize keeps live data and drops nothing but
+// deleted keys, reaches Optimizer through wrappers, and is a no-op on
+// backends without it
+func testOptimize(t *testing.T, name string) {
+	db := helpers.SetupDB(t, name)
+	defer db.Close()
+	for i := range 200 {
+		require.NoError(t, db.Put(t.Context(), fmt.Appendf(nil, "key_%03d", i), helpers.RandomBytes(64)))
+	}
+	_, err := db.DeletePrefix(t.Context(), []byte("key_1"))
+	require.NoError(t, err)
+
+	_, ok := db.(zerokv.Optimizer)
+	require.Equal(t, name == "badgerdb" || name == "pebbledb", ok)
+	require.NoError(t, zerokv.Optimize(t.Context(), db))
+	n, err := db.Count(t.Context(), []byte("key_"))
+	require.NoError(t, err)
+	require.EqualValues(t, 100, n)
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	wrapped := zerokv.WithSlowLog(zerokv.Namespace(db, []byte("ns/")), time.Hour, nil)
+	if ok {
+		require.ErrorIs(t, zerokv.Optimize(ctx, db), context.Canceled)
+		require.ErrorIs(t, zerokv.Optimize(ctx, wrapped), context.Canceled)
+	} else {
+		require.NoError(t, zerokv.Optimize(ctx, wrapped))
+	}
+}
@@ -0,0 +1,115 @@
+package tests
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/rawbytedev/zerokv"
+	"github.com/rawbytedev/zerokv/helpers"
+	"github.com/stretchr/testify/require"
+)
+
+func TestZeroKvMetrics(t *testing.T) {
+	dbs := []string{"badgerdb", "pebbledb", "memdb", "boltdb", "leveldb"}
+	list_test := []test{
+		{
+			name: "TestMetricsOperations",
+			fn: func(t *testing.T, name string) {
+				testMetricsOperations(t, name)
+			}},
+		{
+			name: "TestMetricsSharedRegistry",
+			fn: func(t *testing.T, name string) {
+				testMetricsSharedRegistry(t, name)
+			}},
+	}
+	for i := range dbs {
+		for tt := range list_test {
+			testname := fmt.Sprintf("%s%s", list_test[tt].name, dbs[i])
+			t.Run(testname, func(t *testing.T) {
+				list_test[tt].fn(t, dbs[i])
+			})
+		}
+	}
+}
+
+// observations returns how many durations were recorded for op.
+func observations(t *testing.T, reg *prometheus.Registry, op string) uint64 {
+	t.Helper()
+	families, err := reg.Gather()
+	require.NoError(t, err)
+	for _, mf := range families {
+		if mf.GetName() != zerokv.MetricOperationDuration {
+			continue
+		}
+		for _, m := range mf.GetMetric() {
+			for _, l := range m.GetLabel() {
+				if l.GetName() == "op" && l.GetValue() == op {
+					return m.GetHistogram().GetSampleCount()
+				}
+			}
+		}
+	}
+	return 0
+}
+
+// testMetricsOperations tests that calls, iterator steps and commits are
+// timed, and that only real failures are counted as errors.
+func testMetricsOperations(t *testing.T, name string) {
+	reg := prometheus.NewRegistry()
+	db := zerokv.WithMetrics(helpers.SetupDB(t, name), reg)
+	defer db.Close()
+
+	require.NoError(t, db.Put(t.Context(), []byte("m/1"), []byte("one")))
+	require.NoError(t, db.Put(t.Context(), []byte("m/2"), []byte("two")))
+	_, err := db.Get(t.Context(), []byte("m/1"))
+	require.NoError(t, err)
+	_, err = db.Get(t.Context(), []byte("missing"))
+	require.ErrorIs(t, err, zerokv.ErrKeyNotFound)
+	_, err = db.Increment(t.Context(), []byte("m/1"), 1)
+	require.ErrorIs(t, err, zerokv.ErrInvalidCounter)
+
+	it := db.Scan([]byte("m/"))
+	n := 0
+	for it.Next() {
+		n++
+	}
+	require.NoError(t, it.Error())
+	it.Release()
+	require.Equal(t, 2, n)
+
+	batch := db.Batch()
+	require.NoError(t, batch.Put([]byte("m/3"), []byte("three")))
+	require.NoError(t, batch.Commit(t.Context()))
+
+	require.Equal(t, uint64(2), observations(t, reg, "put"))
+	require.Equal(t, uint64(2), observations(t, reg, "get"))
+	require.Equal(t, uint64(1), observations(t, reg, "scan"))
+	require.Equal(t, uint64(3), observations(t, reg, "iterator_next"))
+	require.Equal(t, uint64(1), observations(t, reg, "batch_commit"))
+
+	// The missing key is not an error; the bad counter is.
+	expected := `
+# HELP zerokv_operation_errors_total Number of zerokv operations that returned an error.
+# TYPE zerokv_operation_errors_total counter
+zerokv_operation_errors_total{op="increment"} 1
+`
+	require.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(expected), zerokv.MetricOperationErrors))
+}
+
+// testMetricsSharedRegistry tests that two stores can be wrapped with the
+// same registry and feed the same collectors.
+func testMetricsSharedRegistry(t *testing.T, name string) {
+	reg := prometheus.NewRegistry()
+	first := zerokv.WithMetrics(helpers.SetupDB(t, name), reg)
+	defer first.Close()
+	// second wraps first, so each of its puts is observed twice.
+	second := zerokv.WithMetrics(zerokv.Namespace(first, []byte("ns/")), reg)
+
+	require.NoError(t, first.Put(t.Context(), []byte("k"), []byte("v")))
+	require.NoError(t, second.Put(t.Context(), []byte("k"), []byte("v")))
+	require.Equal(t, uint64(3), observations(t, reg, "put"))
+}
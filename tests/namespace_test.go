@@ -0,0 +1,128 @@
+package tests
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/rawbytedev/zerokv"
+	"github.com/rawbytedev/zerokv/helpers"
+	"github.com/stretchr/testify/require"
+)
+
+func TestZeroKvNamespace(t *testing.T) {
+	dbs := []string{"badgerdb", "pebbledb", "memdb", "boltdb", "leveldb"}
+	list_test := []test{
+		{
+			name: "TestNamespaceIsolation",
+			fn: func(t *testing.T, name string) {
+				testNamespaceIsolation(t, name)
+			}},
+		{
+			name: "TestNamespaceNested",
+			fn: func(t *testing.T, name string) {
+				testNamespaceNested(t, name)
+			}},
+		{
+			name: "TestNamespaceWrites",
+			fn: func(t *testing.T, name string) {
+				testNamespaceWrites(t, name)
+			}},
+	}
+	for i := range dbs {
+		for tt := range list_test {
+			testname := fmt.Sprintf("%s%s", list_test[tt].name, dbs[i])
+			t.Run(testname, func(t *testing.T) {
+				list_test[tt].fn(t, dbs[i])
+			})
+		}
+	}
+}
+
+// testNamespaceIsolation tests that two namespaces over one store cannot see
+// each other's keys and that scans return keys without the prefix.
+func testNamespaceIsolation(t *testing.T, name string) {
+	db := helpers.SetupDB(t, name)
+	defer db.Close()
+	tenantA := zerokv.Namespace(db, []byte("a/"))
+	tenantB := zerokv.Namespace(db, []byte("b/"))
+	require.NoError(t, tenantA.Put(t.Context(), []byte("user:1"), []byte("alice")))
+	require.NoError(t, tenantA.Put(t.Context(), []byte("user:2"), []byte("bob")))
+	require.NoError(t, tenantB.Put(t.Context(), []byte("user:1"), []byte("carol")))
+
+	value, err := tenantA.Get(t.Context(), []byte("user:1"))
+	require.NoError(t, err)
+	require.Equal(t, []byte("alice"), value)
+	value, err = tenantB.Get(t.Context(), []byte("user:1"))
+	require.NoError(t, err)
+	require.Equal(t, []byte("carol"), value)
+	ok, err := tenantB.Has(t.Context(), []byte("user:2"))
+	require.NoError(t, err)
+	require.False(t, ok)
+	ok, err = db.Has(t.Context(), []byte("a/user:2"))
+	require.NoError(t, err)
+	require.True(t, ok, "keys are stored under the prefix")
+
+	require.Equal(t, [][]byte{[]byte("user:1"), []byte("user:2")}, collectKeys(t, tenantA.Scan(nil)))
+	require.Equal(t, [][]byte{[]byte("user:1")}, collectKeys(t, tenantB.Scan([]byte("user:"))))
+	require.Equal(t, [][]byte{[]byte("user:2"), []byte("user:1")}, collectKeys(t, tenantA.ReverseScan(nil)))
+	require.Equal(t, [][]byte{[]byte("user:1"), []byte("user:2")}, collectKeys(t, tenantA.RangeScan(nil, nil)))
+	require.Equal(t, [][]byte{[]byte("user:2")}, collectKeys(t, tenantA.RangeScan([]byte("user:2"), nil)))
+
+	count, err := tenantB.Count(t.Context(), nil)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), count)
+	n, err := tenantA.DeletePrefix(t.Context(), nil)
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+	value, err = tenantB.Get(t.Context(), []byte("user:1"))
+	require.NoError(t, err)
+	require.Equal(t, []byte("carol"), value, "deleting one namespace must not touch another")
+}
+
+// testNamespaceNested tests that nested namespaces compose their prefixes.
+func testNamespaceNested(t *testing.T, name string) {
+	db := helpers.SetupDB(t, name)
+	defer db.Close()
+	orders := zerokv.Namespace(zerokv.Namespace(db, []byte("tenant1/")), []byte("orders/"))
+	require.NoError(t, orders.Put(t.Context(), []byte("42"), []byte("paid")))
+	value, err := db.Get(t.Context(), []byte("tenant1/orders/42"))
+	require.NoError(t, err)
+	require.Equal(t, []byte("paid"), value)
+	require.Equal(t, [][]byte{[]byte("42")}, collectKeys(t, orders.Scan(nil)))
+	require.Equal(t, [][]byte{[]byte("orders/42")}, collectKeys(t, zerokv.Namespace(db, []byte("tenant1/")).Scan(nil)))
+	require.NoError(t, orders.Close())
+	_, err = db.Get(t.Context(), []byte("tenant1/orders/42"))
+	require.NoError(t, err, "closing a namespace must not close the underlying store")
+}
+
+// testNamespaceWrites tests batches, transactions and snapshots through a namespace.
+func testNamespaceWrites(t *testing.T, name string) {
+	db := helpers.SetupDB(t, name)
+	defer db.Close()
+	ns := zerokv.Namespace(db, []byte("ns/"))
+	batch := ns.Batch()
+	require.NoError(t, batch.Put([]byte("k1"), []byte("v1")))
+	require.NoError(t, batch.Commit(t.Context()))
+	txn, err := ns.NewTransaction(t.Context())
+	require.NoError(t, err)
+	value, err := txn.Get(t.Context(), []byte("k1"))
+	require.NoError(t, err)
+	require.Equal(t, []byte("v1"), value)
+	require.NoError(t, txn.Put(t.Context(), []byte("k2"), []byte("v2")))
+	require.NoError(t, txn.Commit(t.Context()))
+
+	snap, err := ns.Snapshot()
+	require.NoError(t, err)
+	defer snap.Release()
+	ok, err := snap.Has(t.Context(), []byte("k2"))
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, [][]byte{[]byte("k1"), []byte("k2")}, collectKeys(t, snap.Scan(nil)))
+
+	n, err := ns.Increment(t.Context(), []byte("hits"), 3)
+	require.NoError(t, err)
+	require.Equal(t, int64(3), n)
+	ok, err = db.Has(t.Context(), []byte("ns/hits"))
+	require.NoError(t, err)
+	require.True(t, ok)
+}
@@ -0,0 +1,105 @@
+package tests
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/rawbytedev/zerokv"
+	_ "github.com/rawbytedev/zerokv/badgerdb"
+	_ "github.com/rawbytedev/zerokv/boltdb"
+	_ "github.com/rawbytedev/zerokv/leveldb"
+	_ "github.com/rawbytedev/zerokv/memdb"
+	_ "github.com/rawbytedev/zerokv/pebbledb"
+	"github.com/stretchr/testify/require"
+)
+
+// TestOpenBackends tests selecting every registered backend by name.
+func TestOpenBackends(t *testing.T) {
+	names := zerokv.Backends()
+	require.Subset(t, names, []string{"badger", "bolt", "leveldb", "memory", "pebble"})
+	for _, name := range names {
+		t.Run(name, func(t *testing.T) {
+			db, err := zerokv.Open(name, zerokv.Config{Dir: t.TempDir()})
+			require.NoError(t, err)
+			require.NotNil(t, db)
+			require.NoError(t, db.Put(t.Context(), []byte("key"), []byte("value")))
+			value, err := db.Get(t.Context(), []byte("key"))
+			require.NoError(t, err)
+			require.Equal(t, []byte("value"), value)
+			require.NoError(t, db.Close())
+		})
+	}
+}
+
+// TestOpenUnknownBackend tests the error for an unregistered backend name.
+func TestOpenUnknownBackend(t *testing.T) {
+	db, err := zerokv.Open("rocks", zerokv.Config{Dir: t.TempDir()})
+	require.Nil(t, db)
+	require.EqualError(t, err, `unknown backend "rocks"`)
+}
+
+// TestOpenDSN tests opening every backend from a connection string with
+// tuning parameters.
+func TestOpenDSN(t *testing.T) {
+	dsns := map[string]string{
+		"badger":  "badger://%s?syncWrites=false&cache=16MB",
+		"bolt":    "bolt://%s?syncWrites=false&timeout=1s",
+		"leveldb": "leveldb://%s?cache=8MiB&writeBuffer=4mb",
+		"memory":  "memory:",
+		"pebble":  "pebble://%s?cache=256MB&ttl=true",
+	}
+	for name, format := range dsns {
+		t.Run(name, func(t *testing.T) {
+			dsn := format
+			if strings.Contains(format, "%s") {
+				dsn = fmt.Sprintf(format, t.TempDir())
+			}
+			db, err := zerokv.OpenDSN(dsn)
+			require.NoError(t, err)
+			require.NoError(t, db.Put(t.Context(), []byte("key"), []byte("value")))
+			value, err := db.Get(t.Context(), []byte("key"))
+			require.NoError(t, err)
+			require.Equal(t, []byte("value"), value)
+			require.NoError(t, db.Close())
+		})
+	}
+}
+
+// TestOpenDSNErrors tests that bad connection strings are rejected with
+// ErrInvalidDSN before anything is opened.
+func TestOpenDSNErrors(t *testing.T) {
+	dir := t.TempDir()
+	for _, dsn := range []string{
+		"rocks://" + dir,
+		dir,
+		"pebble://" + dir + "?cache=lots",
+		"pebble://" + dir + "?cahce=1MB",
+		"badger://" + dir + "?syncWrites=maybe",
+		"memory:?cache=1MB",
+	} {
+		db, err := zerokv.OpenDSN(dsn)
+		require.Nil(t, db, dsn)
+		require.ErrorIs(t, err, zerokv.ErrInvalidDSN, dsn)
+	}
+}
+
+// TestParseSize tests byte sizes with and without unit suffixes.
+func TestParseSize(t *testing.T) {
+	for s, want := range map[string]int64{
+		"4096":   4096,
+		"64KB":   64 << 10,
+		"256mb":  256 << 20,
+		"1.5GiB": 3 << 29,
+		"2G":     2 << 30,
+		"10 B":   10,
+	} {
+		n, err := zerokv.ParseSize(s)
+		require.NoError(t, err, s)
+		require.Equal(t, want, n, s)
+	}
+	for _, s := range []string{"", "MB", "-1KB", "1XB", "NaN", "1e30TB"} {
+		_, err := zerokv.ParseSize(s)
+		require.Error(t, err, s)
+	}
+}
@@ -0,0 +1,139 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/rawbytedev/zerokv"
+	"github.com/rawbytedev/zerokv/helpers"
+	"github.com/stretchr/testify/require"
+)
+
+func TestZeroKvParallelScan(t *testing.T) {
+	dbs := []string{"badgerdb", "pebbledb", "memdb", "boltdb", "leveldb"}
+	list_test := []test{
+		{
+			name: "TestParallelScanAll",
+			fn: func(t *testing.T, name string) {
+				testParallelScanAll(t, name)
+			}},
+		{
+			name: "TestParallelScanError",
+			fn: func(t *testing.T, name string) {
+				testParallelScanError(t, name)
+			}},
+		{
+			name: "TestPrefixHistogram",
+			fn: func(t *testing.T, name string) {
+				testPrefixHistogram(t, name)
+			}},
+	}
+	for i := range dbs {
+		for tt := range list_test {
+			testname := fmt.Sprintf("%s%s", list_test[tt].name, dbs[i])
+			t.Run(testname, func(t *testing.T) {
+				list_test[tt].fn(t, dbs[i])
+			})
+		}
+	}
+}
+
+// fillShards writes the prefix itself and one key per value of the byte
+// following it, plus a key outside the prefix.
+func fillShards(t *testing.T, db zerokv.Core, prefix []byte) map[string]string {
+	t.Helper()
+	want := map[string]string{string(prefix): "self"}
+	batch := db.Batch()
+	require.NoError(t, batch.Put(prefix, []byte("self")))
+	for b := range 256 {
+		key := append(append([]byte{}, prefix...), byte(b), 'x')
+		want[string(key)] = fmt.Sprint(b)
+		require.NoError(t, batch.Put(key, []byte(fmt.Sprint(b))))
+	}
+	require.NoError(t, batch.Put([]byte("q"), []byte("outside")))
+	require.NoError(t, batch.Commit(t.Context()))
+	return want
+}
+
+// testParallelScanAll tests that every key under the prefix is visited
+// exactly once, whatever the number of shards.
+func testParallelScanAll(t *testing.T, name string) {
+	db := helpers.SetupDB(t, name)
+	defer db.Close()
+	want := fillShards(t, db, []byte("p"))
+
+	for _, shards := range []int{0, 1, 3, 16, 1000} {
+		var mu sync.Mutex
+		got := make(map[string]string)
+		visits := 0
+		err := zerokv.ParallelScan(t.Context(), db, []byte("p"), shards, func(key, value []byte) error {
+			mu.Lock()
+			defer mu.Unlock()
+			got[string(key)] = string(value)
+			visits++
+			return nil
+		})
+		require.NoError(t, err)
+		require.Equal(t, want, got, "shards=%d", shards)
+		require.Equal(t, len(want), visits, "shards=%d", shards)
+	}
+}
+
+// testParallelScanError tests that an error from fn, or cancelling ctx,
+// stops every shard.
+func testParallelScanError(t *testing.T, name string) {
+	db := helpers.SetupDB(t, name)
+	defer db.Close()
+	fillShards(t, db, []byte("p"))
+
+	errStop := errors.New("stop")
+	var calls atomic.Int64
+	err := zerokv.ParallelScan(t.Context(), db, []byte("p"), 8, func(key, value []byte) error {
+		calls.Add(1)
+		return errStop
+	})
+	require.ErrorIs(t, err, errStop)
+	require.LessOrEqual(t, calls.Load(), int64(8))
+
+	ctx, cancel := context.WithCancel(t.Context())
+	calls.Store(0)
+	err = zerokv.ParallelScan(ctx, db, []byte("p"), 4, func(key, value []byte) error {
+		cancel()
+		calls.Add(1)
+		return nil
+	})
+	require.ErrorIs(t, err, context.Canceled)
+	require.LessOrEqual(t, calls.Load(), int64(8))
+}
+
+// testPrefixHistogram tests that keys are tallied by the byte after the
+// prefix on a skewed distribution, ignoring the prefix itself and keys
+// outside it.
+func testPrefixHistogram(t *testing.T, name string) {
+	db := helpers.SetupDB(t, name)
+	defer db.Close()
+
+	batch := db.Batch()
+	require.NoError(t, batch.Put([]byte("p"), []byte("self")))
+	for i := range 500 {
+		require.NoError(t, batch.Put([]byte(fmt.Sprintf("pa%04d", i)), []byte("v")))
+	}
+	for i := range 10 {
+		require.NoError(t, batch.Put([]byte(fmt.Sprintf("pm%04d", i)), []byte("v")))
+	}
+	require.NoError(t, batch.Put([]byte{'p', 0xff}, []byte("v")))
+	require.NoError(t, batch.Put([]byte("qa"), []byte("outside")))
+	require.NoError(t, batch.Commit(t.Context()))
+
+	hist, err := zerokv.PrefixHistogram(db, []byte("p"))
+	require.NoError(t, err)
+	require.Equal(t, map[byte]int64{'a': 500, 'm': 10, 0xff: 1}, hist)
+
+	hist, err = zerokv.PrefixHistogram(db, []byte("none"))
+	require.NoError(t, err)
+	require.Empty(t, hist)
+}
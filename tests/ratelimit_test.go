@@ -0,0 +1,74 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/rawbytedev/zerokv"
+	"github.com/rawbytedev/zerokv/helpers"
+	"github.com/stretchr/testify/require"
+)
+
+func TestZeroKvRateLimit(t *testing.T) {
+	dbs := []string{"badgerdb", "pebbledb", "memdb", "boltdb", "leveldb"}
+	list_test := []test{
+		{
+			name: "TestRateLimit",
+			fn: func(t *testing.T, name string) {
+				testRateLimit(t, name)
+			}},
+	}
+	for i := range dbs {
+		for tt := range list_test {
+			testname := fmt.Sprintf("%s%s", list_test[tt].name, dbs[i])
+			t.Run(testname, func(t *testing.T) {
+				list_test[tt].fn(t, dbs[i])
+			})
+		}
+	}
+}
+
+// testRateLimit tests that writes beyond the burst wait for a token and give
+// up with the context's error, while reads are never throttled
+func testRateLimit(t *testing.T, name string) {
+	db := zerokv.WithRateLimit(helpers.SetupDB(t, name), 10)
+	defer db.Close()
+	ctx := t.Context()
+
+	// the burst goes through at once
+	for i := range 10 {
+		require.NoError(t, db.Put(ctx, fmt.Appendf(nil, "key_%d", i), []byte("value")))
+	}
+
+	// the bucket is empty: a write that cannot wait gives up without writing
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	require.ErrorIs(t, db.Put(cancelled, []byte("late"), []byte("value")), context.Canceled)
+	short, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	require.ErrorIs(t, db.Delete(short, []byte("key_0")), context.DeadlineExceeded)
+	batch := db.Batch()
+	require.NoError(t, batch.Put([]byte("late"), []byte("value")))
+	require.ErrorIs(t, batch.Commit(short), context.DeadlineExceeded)
+
+	// reads are not limited
+	for range 100 {
+		_, err := db.Get(ctx, []byte("key_0"))
+		require.NoError(t, err)
+	}
+	has, err := db.Has(ctx, []byte("late"))
+	require.NoError(t, err)
+	require.False(t, has)
+
+	// a write that can wait gets a token after about 1/10 s
+	start := time.Now()
+	require.NoError(t, batch.Commit(ctx))
+	require.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+	has, err = db.Has(ctx, []byte("late"))
+	require.NoError(t, err)
+	require.True(t, has)
+
+	require.Same(t, db, zerokv.WithRateLimit(db, 0))
+}
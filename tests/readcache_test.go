@@ -0,0 +1,180 @@
+package tests
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rawbytedev/zerokv"
+	"github.com/rawbytedev/zerokv/helpers"
+	"github.com/stretchr/testify/require"
+)
+
+func TestZeroKvReadCache(t *testing.T) {
+	dbs := []string{"badgerdb", "pebbledb", "memdb", "boltdb", "leveldb"}
+	list_test := []test{
+		{
+			name: "TestReadCacheInvalidation",
+			fn: func(t *testing.T, name string) {
+				testReadCacheInvalidation(t, name)
+			}},
+		{
+			name: "TestReadCacheExpiry",
+			fn: func(t *testing.T, name string) {
+				testReadCacheExpiry(t, name)
+			}},
+		{
+			name: "TestReadCacheEviction",
+			fn: func(t *testing.T, name string) {
+				testReadCacheEviction(t, name)
+			}},
+		{
+			name: "TestReadCacheConcurrent",
+			fn: func(t *testing.T, name string) {
+				testReadCacheConcurrent(t, name)
+			}},
+	}
+	for i := range dbs {
+		for tt := range list_test {
+			testname := fmt.Sprintf("%s%s", list_test[tt].name, dbs[i])
+			t.Run(testname, func(t *testing.T) {
+				list_test[tt].fn(t, dbs[i])
+			})
+		}
+	}
+}
+
+// requireGet asserts that db holds want at key.
+func requireGet(t *testing.T, db zerokv.Core, key, want string) {
+	t.Helper()
+	value, err := db.Get(t.Context(), []byte(key))
+	require.NoError(t, err)
+	require.Equal(t, []byte(want), value)
+}
+
+// testReadCacheInvalidation tests that Get is served from the cache until a
+// write through the cache drops the key, and that scans bypass the cache.
+// Writes made to the backend directly reveal which reads hit the cache.
+func testReadCacheInvalidation(t *testing.T, name string) {
+	back := helpers.SetupDB(t, name)
+	db := zerokv.WithReadCache(back, 0, 0)
+	defer db.Close()
+	ctx := t.Context()
+
+	require.NoError(t, db.Put(ctx, []byte("key"), []byte("v1")))
+	requireGet(t, db, "key", "v1")
+	require.NoError(t, back.Put(ctx, []byte("key"), []byte("behind")))
+	requireGet(t, db, "key", "v1")
+
+	// scans read the backend
+	entries, err := zerokv.CollectAll(db.Scan([]byte("key")))
+	require.NoError(t, err)
+	require.Equal(t, []zerokv.KeyValue{{Key: []byte("key"), Value: []byte("behind")}}, entries)
+
+	require.NoError(t, db.Put(ctx, []byte("key"), []byte("v2")))
+	requireGet(t, db, "key", "v2")
+
+	require.NoError(t, db.Delete(ctx, []byte("key")))
+	_, err = db.Get(ctx, []byte("key"))
+	require.ErrorIs(t, err, zerokv.ErrKeyNotFound)
+
+	// missing keys are not cached
+	require.NoError(t, back.Put(ctx, []byte("key"), []byte("v3")))
+	requireGet(t, db, "key", "v3")
+
+	// a cached value cannot be changed through the returned slice
+	value, err := db.Get(ctx, []byte("key"))
+	require.NoError(t, err)
+	value[0] = 'x'
+	requireGet(t, db, "key", "v3")
+
+	batch := db.Batch()
+	require.NoError(t, batch.Put([]byte("key"), []byte("v4")))
+	require.NoError(t, batch.Commit(ctx))
+	requireGet(t, db, "key", "v4")
+
+	_, err = db.DeletePrefix(ctx, []byte("k"))
+	require.NoError(t, err)
+	_, err = db.Get(ctx, []byte("key"))
+	require.ErrorIs(t, err, zerokv.ErrKeyNotFound)
+}
+
+// testReadCacheExpiry tests that a cached value is read again from the
+// backend once ttl has passed.
+func testReadCacheExpiry(t *testing.T, name string) {
+	back := helpers.SetupDB(t, name)
+	db := zerokv.WithReadCache(back, 0, 50*time.Millisecond)
+	defer db.Close()
+
+	require.NoError(t, db.Put(t.Context(), []byte("key"), []byte("v1")))
+	requireGet(t, db, "key", "v1")
+	require.NoError(t, back.Put(t.Context(), []byte("key"), []byte("v2")))
+	requireGet(t, db, "key", "v1")
+
+	time.Sleep(60 * time.Millisecond)
+	requireGet(t, db, "key", "v2")
+}
+
+// testReadCacheEviction tests that the least recently used key is evicted
+// once maxEntries is exceeded.
+func testReadCacheEviction(t *testing.T, name string) {
+	back := helpers.SetupDB(t, name)
+	db := zerokv.WithReadCache(back, 2, 0)
+	defer db.Close()
+	ctx := t.Context()
+
+	for _, k := range []string{"a", "b", "c"} {
+		require.NoError(t, back.Put(ctx, []byte(k), []byte(k+"1")))
+	}
+	requireGet(t, db, "a", "a1")
+	requireGet(t, db, "b", "b1")
+	requireGet(t, db, "a", "a1") // b is now least recently used
+	requireGet(t, db, "c", "c1")
+
+	for _, k := range []string{"a", "b", "c"} {
+		require.NoError(t, back.Put(ctx, []byte(k), []byte(k+"2")))
+	}
+	requireGet(t, db, "a", "a1")
+	requireGet(t, db, "c", "c1")
+	requireGet(t, db, "b", "b2")
+}
+
+// testReadCacheConcurrent tests that concurrent reads and writes of the
+// same keys never leave a stale value cached.
+func testReadCacheConcurrent(t *testing.T, name string) {
+	back := helpers.SetupDB(t, name)
+	db := zerokv.WithReadCache(back, 8, 0)
+	defer db.Close()
+	ctx := t.Context()
+
+	keys := make([][]byte, 16)
+	for i := range keys {
+		keys[i] = fmt.Appendf(nil, "key_%02d", i)
+		require.NoError(t, db.Put(ctx, keys[i], []byte("0")))
+	}
+	var wg sync.WaitGroup
+	for w := range 4 {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for i := range 50 {
+				require.NoError(t, db.Put(ctx, keys[(w+i)%len(keys)], fmt.Appendf(nil, "%d", i)))
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for i := range 200 {
+				_, err := db.Get(ctx, keys[i%len(keys)])
+				require.NoError(t, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	for _, key := range keys {
+		want, err := back.Get(ctx, key)
+		require.NoError(t, err)
+		requireGet(t, db, string(key), string(want))
+	}
+}
@@ -0,0 +1,121 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/rawbytedev/zerokv"
+	"github.com/rawbytedev/zerokv/helpers"
+	"github.com/stretchr/testify/require"
+)
+
+func TestZeroKvSlowLog(t *testing.T) {
+	dbs := []string{"badgerdb", "pebbledb", "memdb", "boltdb", "leveldb"}
+	list_test := []test{
+		{
+			name: "TestSlowLog",
+			fn: func(t *testing.T, name string) {
+				testSlowLog(t, name)
+			}},
+		{
+			name: "TestSlowLogFullKeys",
+			fn: func(t *testing.T, name string) {
+				testSlowLogFullKeys(t, name)
+			}},
+	}
+	for i := range dbs {
+		for tt := range list_test {
+			testname := fmt.Sprintf("%s%s", list_test[tt].name, dbs[i])
+			t.Run(testname, func(t *testing.T) {
+				list_test[tt].fn(t, dbs[i])
+			})
+		}
+	}
+}
+
+// slowLogRecords decodes the JSON records written to buf
+func slowLogRecords(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	var records []map[string]any
+	dec := json.NewDecoder(buf)
+	for dec.More() {
+		var record map[string]any
+		require.NoError(t, dec.Decode(&record))
+		records = append(records, record)
+	}
+	return records
+}
+
+// testSlowLog tests that calls over the threshold are logged with the key's
+// length but not the key, and that faster calls are not logged
+func testSlowLog(t *testing.T, name string) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+	core := helpers.SetupDB(t, name)
+	defer core.Close()
+	ctx := t.Context()
+
+	// nothing is slower than an hour
+	db := zerokv.WithSlowLog(core, time.Hour, logger)
+	require.NoError(t, db.Put(ctx, []byte("secret"), []byte("value")))
+	_, err := db.Get(ctx, []byte("secret"))
+	require.NoError(t, err)
+	require.Zero(t, buf.Len())
+
+	// everything is slower than zero
+	db = zerokv.WithSlowLog(core, 0, logger)
+	require.NoError(t, db.Put(ctx, []byte("secret"), []byte("value")))
+	_, err = db.Get(ctx, []byte("missing"))
+	require.ErrorIs(t, err, zerokv.ErrKeyNotFound)
+	require.NoError(t, db.Delete(ctx, []byte("secret")))
+	batch := db.Batch()
+	require.NoError(t, batch.Put([]byte("a"), []byte("1")))
+	require.NoError(t, batch.Put([]byte("b"), []byte("2")))
+	require.NoError(t, batch.Commit(ctx))
+	txn, err := db.NewTransaction(ctx)
+	require.NoError(t, err)
+	require.NoError(t, txn.Put(ctx, []byte("c"), []byte("3")))
+	require.NoError(t, txn.Commit(ctx))
+	// untimed calls are not logged
+	_, err = db.Has(ctx, []byte("a"))
+	require.NoError(t, err)
+
+	require.NotContains(t, buf.String(), "secret")
+	records := slowLogRecords(t, &buf)
+	require.Len(t, records, 5)
+	ops := []string{"Put", "Get", "Delete", "Batch.Commit", "Txn.Commit"}
+	for i, record := range records {
+		require.Equal(t, "WARN", record["level"])
+		require.Equal(t, "zerokv: slow operation", record["msg"])
+		require.Equal(t, ops[i], record["op"])
+		require.Contains(t, record, "duration")
+		require.NotContains(t, record, "key")
+		require.NotContains(t, record, "error")
+	}
+	require.EqualValues(t, len("secret"), records[0]["key_size"])
+	require.EqualValues(t, len("missing"), records[1]["key_size"])
+	require.EqualValues(t, 2, records[3]["keys"])
+}
+
+// testSlowLogFullKeys tests that WithFullKeys adds the key to each record
+func testSlowLogFullKeys(t *testing.T, name string) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+	db := zerokv.WithSlowLog(helpers.SetupDB(t, name), 0, logger, zerokv.WithFullKeys())
+	defer db.Close()
+	ctx := t.Context()
+
+	require.NoError(t, db.Put(ctx, []byte("user:1"), []byte("value")))
+	_, err := db.Get(ctx, []byte("user:1"))
+	require.NoError(t, err)
+
+	records := slowLogRecords(t, &buf)
+	require.Len(t, records, 2)
+	for _, record := range records {
+		require.Equal(t, "user:1", record["key"])
+		require.EqualValues(t, len("user:1"), record["key_size"])
+	}
+}
@@ -0,0 +1,189 @@
+package tests
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/rawbytedev/zerokv"
+	"github.com/rawbytedev/zerokv/helpers"
+	"github.com/stretchr/testify/require"
+)
+
+func TestZeroKvSnapshot(t *testing.T) {
+	dbs := []string{"badgerdb", "pebbledb", "memdb", "boltdb", "leveldb"}
+	list_test := []test{
+		{
+			name: "TestSnapshotIsolation",
+			fn: func(t *testing.T, name string) {
+				testSnapshotIsolation(t, name)
+			}}, {
+			name: "TestSnapshotRelease",
+			fn: func(t *testing.T, name string) {
+				testSnapshotRelease(t, name)
+			}}, {
+			name: "TestSnapshotGetAndScan",
+			fn: func(t *testing.T, name string) {
+				testSnapshotGetAndScan(t, name)
+			}},
+	}
+	for i := range dbs {
+		for tt := range list_test {
+			testname := fmt.Sprintf("%s%s", list_test[tt].name, dbs[i])
+			t.Run(testname, func(t *testing.T) {
+				list_test[tt].fn(t, dbs[i])
+			})
+		}
+	}
+}
+
+// testSnapshotIsolation tests that writes after the snapshot are not visible through it.
+func testSnapshotIsolation(t *testing.T, name string) {
+	db := helpers.SetupDB(t, name)
+	defer db.Close()
+	require.NoError(t, db.Put(t.Context(), []byte("pre_1"), []byte("old")))
+	require.NoError(t, db.Put(t.Context(), []byte("pre_2"), []byte("old")))
+
+	snap, err := db.Snapshot()
+	require.NoError(t, err)
+	require.NoError(t, db.Put(t.Context(), []byte("pre_1"), []byte("new")))
+	require.NoError(t, db.Put(t.Context(), []byte("pre_3"), []byte("new")))
+	require.NoError(t, db.Delete(t.Context(), []byte("pre_2")))
+
+	value, err := snap.Get(t.Context(), []byte("pre_1"))
+	require.NoError(t, err)
+	require.Equal(t, []byte("old"), value)
+	ok, err := snap.Has(t.Context(), []byte("pre_2"))
+	require.NoError(t, err)
+	require.True(t, ok, "Deleted key should still be visible in the snapshot")
+	ok, err = snap.Has(t.Context(), []byte("pre_3"))
+	require.NoError(t, err)
+	require.False(t, ok, "Key written after the snapshot should not be visible")
+	_, err = snap.Get(t.Context(), []byte("pre_3"))
+	require.Error(t, err)
+
+	keys := collectKeys(t, snap.Scan([]byte("pre_")))
+	require.Equal(t, [][]byte{[]byte("pre_1"), []byte("pre_2")}, keys)
+	snap.Release()
+
+	value, err = db.Get(t.Context(), []byte("pre_1"))
+	require.NoError(t, err)
+	require.Equal(t, []byte("new"), value)
+}
+
+// testSnapshotRelease tests that reads after Release fail cleanly.
+func testSnapshotRelease(t *testing.T, name string) {
+	db := helpers.SetupDB(t, name)
+	defer db.Close()
+	require.NoError(t, db.Put(t.Context(), []byte("pre_1"), []byte("value")))
+	require.NoError(t, db.Put(t.Context(), []byte("pre_2"), []byte("value")))
+
+	snap, err := db.Snapshot()
+	require.NoError(t, err)
+	it := snap.Scan([]byte("pre_"))
+	require.True(t, it.Next())
+	snap.Release()
+	snap.Release() // releasing twice is a no-op
+
+	// an iterator opened before Release keeps working
+	require.True(t, it.Next())
+	require.Equal(t, []byte("pre_2"), it.Key())
+	require.Equal(t, []byte("value"), it.Value())
+	require.False(t, it.Next())
+	require.NoError(t, it.Error())
+	it.Release()
+
+	_, err = snap.Get(t.Context(), []byte("pre_1"))
+	require.ErrorIs(t, err, zerokv.ErrSnapshotReleased)
+	_, err = snap.Has(t.Context(), []byte("pre_1"))
+	require.ErrorIs(t, err, zerokv.ErrSnapshotReleased)
+	it = snap.Scan([]byte("pre_"))
+	require.False(t, it.Next())
+	require.ErrorIs(t, it.Error(), zerokv.ErrSnapshotReleased)
+	it.Release()
+}
+
+// testSnapshotGetAndScan tests that point gets and scans on one snapshot read
+// the same state, even with writes landing between them.
+func testSnapshotGetAndScan(t *testing.T, name string) {
+	db := helpers.SetupDB(t, name)
+	defer db.Close()
+	ctx := t.Context()
+	const accounts = 10
+	// writeRound sets every account to version v in one atomic batch
+	writeRound := func(v int) error {
+		batch := db.Batch()
+		for i := range accounts {
+			if err := batch.Put(fmt.Appendf(nil, "acct_%d", i), fmt.Appendf(nil, "v%d", v)); err != nil {
+				return err
+			}
+		}
+		return batch.Commit(ctx)
+	}
+	require.NoError(t, writeRound(0))
+
+	// a write between the get and the scan is invisible to both
+	snap, err := db.Snapshot()
+	require.NoError(t, err)
+	value, err := snap.Get(ctx, []byte("acct_0"))
+	require.NoError(t, err)
+	require.Equal(t, []byte("v0"), value)
+	require.NoError(t, db.Put(ctx, []byte("acct_0"), []byte("changed")))
+	require.NoError(t, db.Put(ctx, []byte("acct_x"), []byte("added")))
+	it := snap.Scan([]byte("acct_"))
+	n := 0
+	for it.Next() {
+		require.Equal(t, []byte("v0"), it.Value(), "key %s", it.Key())
+		n++
+	}
+	require.NoError(t, it.Error())
+	it.Release()
+	require.Equal(t, accounts, n)
+	snap.Release()
+	require.NoError(t, db.Delete(ctx, []byte("acct_x")))
+	require.NoError(t, writeRound(0))
+
+	// with a writer bumping every account concurrently, each snapshot's get
+	// and scan still agree on a single version
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	var writeErr error
+	// stopWriter also runs if an assertion fails, so Close never races it
+	stopWriter := sync.OnceFunc(func() {
+		close(stop)
+		wg.Wait()
+	})
+	defer stopWriter()
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for v := 1; ; v++ {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			if writeErr = writeRound(v); writeErr != nil {
+				return
+			}
+		}
+	}()
+	for range 50 {
+		snap, err := db.Snapshot()
+		require.NoError(t, err)
+		want, err := snap.Get(ctx, []byte("acct_0"))
+		require.NoError(t, err)
+		it := snap.Scan([]byte("acct_"))
+		n := 0
+		for it.Next() {
+			require.Equal(t, want, it.Value(), "key %s", it.Key())
+			n++
+		}
+		require.NoError(t, it.Error())
+		it.Release()
+		snap.Release()
+		require.Equal(t, accounts, n)
+	}
+	stopWriter()
+	require.NoError(t, writeErr)
+}
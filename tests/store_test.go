@@ -0,0 +1,72 @@
+package tests
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/rawbytedev/zerokv"
+	"github.com/rawbytedev/zerokv/helpers"
+	"github.com/stretchr/testify/require"
+)
+
+type user struct {
+	Name  string
+	Age   int
+	Email string
+}
+
+func TestZeroKvStore(t *testing.T) {
+	dbs := []string{"badgerdb", "pebbledb", "memdb", "boltdb", "leveldb"}
+	codecs := map[string]zerokv.Codec{"json": zerokv.JSONCodec{}, "gob": zerokv.GobCodec{}}
+	for i := range dbs {
+		for codecName, codec := range codecs {
+			testname := fmt.Sprintf("TestStore%s%s", codecName, dbs[i])
+			t.Run(testname, func(t *testing.T) {
+				testStore(t, dbs[i], codec)
+			})
+		}
+	}
+}
+
+// testStore tests typed Put, Get and Scan through a codec.
+func testStore(t *testing.T, name string, codec zerokv.Codec) {
+	db := helpers.SetupDB(t, name)
+	defer db.Close()
+	store := zerokv.NewStore[user](db, codec)
+	users := []user{
+		{Name: "alice", Age: 30, Email: "alice@example.com"},
+		{Name: "bob", Age: 25},
+		{Name: "carol", Age: 41, Email: "carol@example.com"},
+	}
+	for _, u := range users {
+		require.NoError(t, store.Put(t.Context(), []byte("user:"+u.Name), u))
+	}
+	got, err := store.Get(t.Context(), []byte("user:bob"))
+	require.NoError(t, err)
+	require.Equal(t, users[1], got)
+	_, err = store.Get(t.Context(), []byte("user:dave"))
+	require.Error(t, err)
+
+	it := store.Scan([]byte("user:"))
+	var scanned []user
+	for it.Next() {
+		require.Equal(t, "user:"+it.Value().Name, string(it.Key()))
+		scanned = append(scanned, it.Value())
+	}
+	require.NoError(t, it.Error())
+	it.Release()
+	require.Equal(t, users, scanned)
+
+	// a value the codec cannot decode stops iteration with an error
+	require.NoError(t, db.Put(t.Context(), []byte("user:zz"), []byte("\xff not encoded")))
+	_, err = store.Get(t.Context(), []byte("user:zz"))
+	require.ErrorContains(t, err, "decode")
+	it = store.Scan([]byte("user:"))
+	count := 0
+	for it.Next() {
+		count++
+	}
+	require.Equal(t, len(users), count)
+	require.ErrorContains(t, it.Error(), "decode")
+	it.Release()
+}
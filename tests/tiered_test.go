@@ -0,0 +1,204 @@
+package tests
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/rawbytedev/zerokv"
+	"github.com/rawbytedev/zerokv/helpers"
+	"github.com/stretchr/testify/require"
+)
+
+func TestZeroKvTiered(t *testing.T) {
+	dbs := []string{"badgerdb", "pebbledb", "memdb", "boltdb", "leveldb"}
+	list_test := []test{
+		{
+			name: "TestTieredReadThrough",
+			fn: func(t *testing.T, name string) {
+				testTieredReadThrough(t, name)
+			}},
+		{
+			name: "TestTieredWriteThrough",
+			fn: func(t *testing.T, name string) {
+				testTieredWriteThrough(t, name)
+			}},
+		{
+			name: "TestTieredEviction",
+			fn: func(t *testing.T, name string) {
+				testTieredEviction(t, name)
+			}},
+		{
+			name: "TestTieredInvalidation",
+			fn: func(t *testing.T, name string) {
+				testTieredInvalidation(t, name)
+			}},
+	}
+	for i := range dbs {
+		for tt := range list_test {
+			testname := fmt.Sprintf("%s%s", list_test[tt].name, dbs[i])
+			t.Run(testname, func(t *testing.T) {
+				list_test[tt].fn(t, dbs[i])
+			})
+		}
+	}
+}
+
+// TestTieredPutWithTTL runs the expiry test on every back tier with TTL
+// support, in parallel because each one has to wait for a key to expire.
+func TestTieredPutWithTTL(t *testing.T) {
+	for _, name := range []string{"badgerdb", "pebbledb-ttl", "memdb"} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			testTieredPutWithTTL(t, name)
+		})
+	}
+}
+
+// testTieredReadThrough tests that a miss in front is served from back and
+// cached in front.
+func testTieredReadThrough(t *testing.T, name string) {
+	front := helpers.SetupDB(t, "memdb")
+	back := helpers.SetupDB(t, name)
+	db := zerokv.Tiered(front, back, zerokv.TieredOptions{})
+	defer db.Close()
+
+	require.NoError(t, back.Put(t.Context(), []byte("key"), []byte("value")))
+	_, err := front.Get(t.Context(), []byte("key"))
+	require.ErrorIs(t, err, zerokv.ErrKeyNotFound)
+
+	value, err := db.Get(t.Context(), []byte("key"))
+	require.NoError(t, err)
+	require.Equal(t, []byte("value"), value)
+	value, err = front.Get(t.Context(), []byte("key"))
+	require.NoError(t, err)
+	require.Equal(t, []byte("value"), value)
+
+	_, err = db.Get(t.Context(), []byte("missing"))
+	require.ErrorIs(t, err, zerokv.ErrKeyNotFound)
+	ok, err := front.Has(t.Context(), []byte("missing"))
+	require.NoError(t, err)
+	require.False(t, ok)
+}
+
+// testTieredWriteThrough tests that writes reach both tiers.
+func testTieredWriteThrough(t *testing.T, name string) {
+	front := helpers.SetupDB(t, "memdb")
+	back := helpers.SetupDB(t, name)
+	db := zerokv.Tiered(front, back, zerokv.TieredOptions{})
+	defer db.Close()
+
+	require.NoError(t, db.Put(t.Context(), []byte("key"), []byte("value")))
+	for _, tier := range []zerokv.Core{front, back} {
+		value, err := tier.Get(t.Context(), []byte("key"))
+		require.NoError(t, err)
+		require.Equal(t, []byte("value"), value)
+	}
+
+	require.NoError(t, db.Delete(t.Context(), []byte("key")))
+	for _, tier := range []zerokv.Core{front, back} {
+		_, err := tier.Get(t.Context(), []byte("key"))
+		require.ErrorIs(t, err, zerokv.ErrKeyNotFound)
+	}
+}
+
+// testTieredEviction tests that front holds at most MaxEntries keys, drops
+// the least recently used one, and that evicted keys are still readable.
+func testTieredEviction(t *testing.T, name string) {
+	front := helpers.SetupDB(t, "memdb")
+	back := helpers.SetupDB(t, name)
+	db := zerokv.Tiered(front, back, zerokv.TieredOptions{MaxEntries: 2})
+	defer db.Close()
+
+	require.NoError(t, db.Put(t.Context(), []byte("a"), []byte("1")))
+	require.NoError(t, db.Put(t.Context(), []byte("b"), []byte("2")))
+	_, err := db.Get(t.Context(), []byte("a"))
+	require.NoError(t, err)
+	require.NoError(t, db.Put(t.Context(), []byte("c"), []byte("3")))
+
+	n, err := front.Count(t.Context(), nil)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), n)
+	ok, err := front.Has(t.Context(), []byte("b"))
+	require.NoError(t, err)
+	require.False(t, ok, "least recently used key was not evicted")
+
+	value, err := db.Get(t.Context(), []byte("b"))
+	require.NoError(t, err)
+	require.Equal(t, []byte("2"), value)
+	n, err = front.Count(t.Context(), nil)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), n)
+}
+
+// testTieredInvalidation tests that writes which do not go through front
+// leave no stale value behind.
+func testTieredInvalidation(t *testing.T, name string) {
+	front := helpers.SetupDB(t, "memdb")
+	back := helpers.SetupDB(t, name)
+	db := zerokv.Tiered(front, back, zerokv.TieredOptions{})
+	defer db.Close()
+
+	for _, k := range []string{"p/1", "p/2", "n"} {
+		require.NoError(t, db.Put(t.Context(), []byte(k), []byte("old")))
+	}
+	_, err := db.DeletePrefix(t.Context(), []byte("p/"))
+	require.NoError(t, err)
+	_, err = db.Get(t.Context(), []byte("p/1"))
+	require.ErrorIs(t, err, zerokv.ErrKeyNotFound)
+
+	batch := db.Batch()
+	require.NoError(t, batch.Put([]byte("n"), []byte("batch")))
+	require.NoError(t, batch.Commit(t.Context()))
+	value, err := db.Get(t.Context(), []byte("n"))
+	require.NoError(t, err)
+	require.Equal(t, []byte("batch"), value)
+
+	_, err = db.Increment(t.Context(), []byte("counter"), 1)
+	require.NoError(t, err)
+	_, err = db.Get(t.Context(), []byte("counter"))
+	require.NoError(t, err)
+	_, err = db.Increment(t.Context(), []byte("counter"), 1)
+	require.NoError(t, err)
+	value, err = db.Get(t.Context(), []byte("counter"))
+	require.NoError(t, err)
+	require.Equal(t, zerokv.EncodeCounter(2), value)
+
+	require.NoError(t, db.BulkLoad(t.Context(), func(b zerokv.Batch) error {
+		return b.Put([]byte("n"), []byte("bulk"))
+	}))
+	value, err = db.Get(t.Context(), []byte("n"))
+	require.NoError(t, err)
+	require.Equal(t, []byte("bulk"), value)
+}
+
+// testTieredPutWithTTL tests that a key written with a TTL and then cached
+// by a read is not served from front after it expires.
+func testTieredPutWithTTL(t *testing.T, name string) {
+	front := helpers.SetupDB(t, "memdb")
+	back := helpers.SetupDB(t, name)
+	db := zerokv.Tiered(front, back, zerokv.TieredOptions{})
+	defer db.Close()
+	// Badger stores expiry in whole seconds, so use a TTL well above that
+	ttl := 2 * time.Second
+	require.NoError(t, db.PutWithTTL(t.Context(), []byte("session"), []byte("value"), ttl))
+	require.NoError(t, db.PutWithTTL(t.Context(), []byte("kept"), []byte("old"), ttl))
+	require.NoError(t, db.Put(t.Context(), []byte("kept"), []byte("value")))
+
+	for _, key := range []string{"session", "kept"} {
+		value, err := db.Get(t.Context(), []byte(key))
+		require.NoError(t, err)
+		require.Equal(t, []byte("value"), value)
+		ok, err := front.Has(t.Context(), []byte(key))
+		require.NoError(t, err)
+		require.True(t, ok, "%s should be cached in front", key)
+	}
+
+	time.Sleep(ttl + 500*time.Millisecond)
+
+	_, err := db.Get(t.Context(), []byte("session"))
+	require.ErrorIs(t, err, zerokv.ErrKeyNotFound, "Expired key should not be served from front")
+	value, err := db.Get(t.Context(), []byte("kept"))
+	require.NoError(t, err, "A Put should drop the earlier TTL")
+	require.Equal(t, []byte("value"), value)
+}
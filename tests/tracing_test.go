@@ -0,0 +1,115 @@
+package tests
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/rawbytedev/zerokv"
+	"github.com/rawbytedev/zerokv/helpers"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestZeroKvTracing(t *testing.T) {
+	dbs := []string{"badgerdb", "pebbledb", "memdb", "boltdb", "leveldb"}
+	list_test := []test{
+		{
+			name: "TestTracingSpans",
+			fn: func(t *testing.T, name string) {
+				testTracingSpans(t, name)
+			}},
+		{
+			name: "TestTracingIteratorAndBatch",
+			fn: func(t *testing.T, name string) {
+				testTracingIteratorAndBatch(t, name)
+			}},
+	}
+	for i := range dbs {
+		for tt := range list_test {
+			testname := fmt.Sprintf("%s%s", list_test[tt].name, dbs[i])
+			t.Run(testname, func(t *testing.T) {
+				list_test[tt].fn(t, dbs[i])
+			})
+		}
+	}
+}
+
+// attr returns the value of the attribute key on span, or nil.
+func attr(span sdktrace.ReadOnlySpan, key string) any {
+	for _, kv := range span.Attributes() {
+		if string(kv.Key) == key {
+			return kv.Value.AsInterface()
+		}
+	}
+	return nil
+}
+
+// testTracingSpans tests that each call gets a span nested under the
+// caller's, with sizes and backend recorded and failures marked.
+func testTracingSpans(t *testing.T, name string) {
+	rec := tracetest.NewSpanRecorder()
+	tracer := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec)).Tracer("test")
+	db := zerokv.WithTracing(helpers.SetupDB(t, name), tracer)
+	defer db.Close()
+
+	ctx, parent := tracer.Start(t.Context(), "parent")
+	require.NoError(t, db.Put(ctx, []byte("key"), []byte("value")))
+	_, err := db.Get(ctx, []byte("missing"))
+	require.ErrorIs(t, err, zerokv.ErrKeyNotFound)
+	_, err = db.Increment(ctx, []byte("key"), 1)
+	require.ErrorIs(t, err, zerokv.ErrInvalidCounter)
+	parent.End()
+
+	spans := rec.Ended()
+	require.Len(t, spans, 4)
+	put, get, incr := spans[0], spans[1], spans[2]
+	require.Equal(t, "zerokv.Put", put.Name())
+	require.Equal(t, parent.SpanContext().SpanID(), put.Parent().SpanID())
+	require.Equal(t, name, attr(put, "zerokv.backend"))
+	require.Equal(t, int64(3), attr(put, "zerokv.key_size"))
+	require.Equal(t, int64(5), attr(put, "zerokv.value_size"))
+	require.Equal(t, codes.Unset, put.Status().Code)
+
+	require.Equal(t, "zerokv.Get", get.Name())
+	require.Equal(t, codes.Unset, get.Status().Code)
+
+	require.Equal(t, "zerokv.Increment", incr.Name())
+	require.Equal(t, codes.Error, incr.Status().Code)
+	require.Len(t, incr.Events(), 1)
+}
+
+// testTracingIteratorAndBatch tests that an iterator span lasts until
+// Release and a batch span covers the batch up to Commit.
+func testTracingIteratorAndBatch(t *testing.T, name string) {
+	rec := tracetest.NewSpanRecorder()
+	tracer := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec)).Tracer("test")
+	db := zerokv.WithTracing(helpers.SetupDB(t, name), tracer)
+	defer db.Close()
+	ctx, parent := tracer.Start(t.Context(), "parent")
+	defer parent.End()
+
+	batch := db.Batch()
+	require.NoError(t, batch.Put([]byte("t/1"), []byte("one")))
+	require.NoError(t, batch.Put([]byte("t/2"), []byte("two")))
+	require.Empty(t, rec.Ended())
+	require.NoError(t, batch.Commit(ctx))
+	spans := rec.Ended()
+	require.Len(t, spans, 1)
+	require.Equal(t, "zerokv.Batch", spans[0].Name())
+	require.Equal(t, parent.SpanContext().SpanID(), spans[0].Parent().SpanID())
+	require.Equal(t, int64(2), attr(spans[0], "zerokv.keys"))
+
+	it := db.ScanContext(ctx, []byte("t/"))
+	for it.Next() {
+	}
+	require.Len(t, rec.Ended(), 1, "iterator span ended before Release")
+	it.Release()
+	it.Release()
+	spans = rec.Ended()
+	require.Len(t, spans, 2)
+	require.Equal(t, "zerokv.ScanContext", spans[1].Name())
+	require.Equal(t, parent.SpanContext().SpanID(), spans[1].Parent().SpanID())
+	require.Equal(t, int64(2), attr(spans[1], "zerokv.entries"))
+}
@@ -0,0 +1,69 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rawbytedev/zerokv"
+	"github.com/rawbytedev/zerokv/helpers"
+	"github.com/stretchr/testify/require"
+)
+
+// TestZeroKvTTL runs the expiry test on every backend with TTL support.
+// Subtests run in parallel because each one has to wait for keys to expire.
+func TestZeroKvTTL(t *testing.T) {
+	dbs := []string{"badgerdb", "pebbledb-ttl", "memdb"}
+	for i := range dbs {
+		t.Run("TestPutWithTTL"+dbs[i], func(t *testing.T) {
+			t.Parallel()
+			testPutWithTTL(t, dbs[i])
+		})
+	}
+}
+
+// testPutWithTTL tests that keys written with a TTL disappear from every read
+// path once it elapses, while keys without a TTL remain.
+func testPutWithTTL(t *testing.T, name string) {
+	db := helpers.SetupDB(t, name)
+	defer db.Close()
+	// Badger stores expiry in whole seconds, so use a TTL well above that
+	ttl := 2 * time.Second
+	require.NoError(t, db.PutWithTTL(t.Context(), []byte("ttl_session"), []byte("value"), ttl))
+	require.NoError(t, db.Put(t.Context(), []byte("ttl_user"), []byte("value")))
+
+	value, err := db.Get(t.Context(), []byte("ttl_session"))
+	require.NoError(t, err)
+	require.Equal(t, []byte("value"), value)
+	count, err := db.Count(t.Context(), []byte("ttl_"))
+	require.NoError(t, err)
+	require.Equal(t, int64(2), count)
+
+	time.Sleep(ttl + 500*time.Millisecond)
+
+	_, err = db.Get(t.Context(), []byte("ttl_session"))
+	require.Error(t, err, "Expired key should not be returned")
+	ok, err := db.Has(t.Context(), []byte("ttl_session"))
+	require.NoError(t, err)
+	require.False(t, ok)
+	values, err := db.GetMany(t.Context(), [][]byte{[]byte("ttl_session"), []byte("ttl_user")})
+	require.NoError(t, err)
+	require.Nil(t, values[0])
+	require.Equal(t, []byte("value"), values[1])
+	require.Equal(t, [][]byte{[]byte("ttl_user")}, collectKeys(t, db.Scan([]byte("ttl_"))))
+	require.Equal(t, [][]byte{[]byte("ttl_user")}, collectKeys(t, db.ReverseScan([]byte("ttl_"))))
+	count, err = db.Count(t.Context(), []byte("ttl_"))
+	require.NoError(t, err)
+	require.Equal(t, int64(1), count)
+}
+
+// TestPutWithTTLNotSupported tests backends without expiry report ErrNotSupported.
+func TestPutWithTTLNotSupported(t *testing.T) {
+	for _, name := range []string{"boltdb", "leveldb", "pebbledb"} {
+		t.Run(name, func(t *testing.T) {
+			db := helpers.SetupDB(t, name)
+			defer db.Close()
+			err := db.PutWithTTL(t.Context(), []byte("key"), []byte("value"), time.Minute)
+			require.ErrorIs(t, err, zerokv.ErrNotSupported)
+		})
+	}
+}
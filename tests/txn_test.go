@@ -0,0 +1,88 @@
+package tests
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/rawbytedev/zerokv"
+	"github.com/rawbytedev/zerokv/helpers"
+	"github.com/stretchr/testify/require"
+)
+
+func TestZeroKvTxn(t *testing.T) {
+	dbs := []string{"badgerdb", "pebbledb", "memdb", "boltdb", "leveldb"}
+	list_test := []test{
+		{
+			name: "TestTxnReadYourWrites",
+			fn: func(t *testing.T, name string) {
+				testTxnReadYourWrites(t, name)
+			}}, {
+			name: "TestTxnDiscard",
+			fn: func(t *testing.T, name string) {
+				testTxnDiscard(t, name)
+			}},
+	}
+	for i := range dbs {
+		for tt := range list_test {
+			testname := fmt.Sprintf("%s%s", list_test[tt].name, dbs[i])
+			t.Run(testname, func(t *testing.T) {
+				list_test[tt].fn(t, dbs[i])
+			})
+		}
+	}
+}
+
+// testTxnReadYourWrites tests that pending writes are visible inside the
+// transaction only, and applied on Commit.
+func testTxnReadYourWrites(t *testing.T, name string) {
+	db := helpers.SetupDB(t, name)
+	defer db.Close()
+	require.NoError(t, db.Put(t.Context(), []byte("balance"), []byte("10")))
+	require.NoError(t, db.Put(t.Context(), []byte("stale"), []byte("value")))
+
+	txn, err := db.NewTransaction(t.Context())
+	require.NoError(t, err)
+	value, err := txn.Get(t.Context(), []byte("balance"))
+	require.NoError(t, err)
+	require.Equal(t, []byte("10"), value)
+	require.NoError(t, txn.Put(t.Context(), []byte("balance"), []byte("20")))
+	require.NoError(t, txn.Delete(t.Context(), []byte("stale")))
+
+	value, err = txn.Get(t.Context(), []byte("balance"))
+	require.NoError(t, err)
+	require.Equal(t, []byte("20"), value, "Transaction should read its own write")
+	_, err = txn.Get(t.Context(), []byte("stale"))
+	require.Error(t, err, "Transaction should read its own delete")
+	value, err = db.Get(t.Context(), []byte("balance"))
+	require.NoError(t, err)
+	require.Equal(t, []byte("10"), value, "Pending writes should not be visible outside the transaction")
+
+	require.NoError(t, txn.Commit(t.Context()))
+	value, err = db.Get(t.Context(), []byte("balance"))
+	require.NoError(t, err)
+	require.Equal(t, []byte("20"), value)
+	ok, err := db.Has(t.Context(), []byte("stale"))
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	require.ErrorIs(t, txn.Commit(t.Context()), zerokv.ErrTxnDone)
+	require.ErrorIs(t, txn.Put(t.Context(), []byte("balance"), []byte("30")), zerokv.ErrTxnDone)
+	txn.Discard() // no-op after Commit
+}
+
+// testTxnDiscard tests that discarded writes are never applied.
+func testTxnDiscard(t *testing.T, name string) {
+	db := helpers.SetupDB(t, name)
+	defer db.Close()
+	txn, err := db.NewTransaction(t.Context())
+	require.NoError(t, err)
+	require.NoError(t, txn.Put(t.Context(), []byte("key"), []byte("value")))
+	txn.Discard()
+
+	ok, err := db.Has(t.Context(), []byte("key"))
+	require.NoError(t, err)
+	require.False(t, ok)
+	_, err = txn.Get(t.Context(), []byte("key"))
+	require.ErrorIs(t, err, zerokv.ErrTxnDone)
+	require.ErrorIs(t, txn.Commit(t.Context()), zerokv.ErrTxnDone)
+}
@@ -0,0 +1,148 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/rawbytedev/zerokv"
+	"github.com/rawbytedev/zerokv/helpers"
+	"github.com/stretchr/testify/require"
+)
+
+func TestZeroKvWatch(t *testing.T) {
+	dbs := []string{"badgerdb", "pebbledb", "memdb", "boltdb", "leveldb"}
+	list_test := []test{
+		{
+			name: "TestWatchEvents",
+			fn: func(t *testing.T, name string) {
+				testWatchEvents(t, name)
+			}},
+		{
+			name: "TestWatchCancel",
+			fn: func(t *testing.T, name string) {
+				testWatchCancel(t, name)
+			}},
+		{
+			name: "TestWatchNamespace",
+			fn: func(t *testing.T, name string) {
+				testWatchNamespace(t, name)
+			}},
+	}
+	for i := range dbs {
+		for tt := range list_test {
+			testname := fmt.Sprintf("%s%s", list_test[tt].name, dbs[i])
+			t.Run(testname, func(t *testing.T) {
+				list_test[tt].fn(t, dbs[i])
+			})
+		}
+	}
+}
+
+// nextEvent waits for the next event on ch and fails the test if none
+// arrives in time or the channel is closed.
+func nextEvent(t *testing.T, ch <-chan zerokv.Event) zerokv.Event {
+	t.Helper()
+	select {
+	case ev, ok := <-ch:
+		require.True(t, ok, "Watch channel closed early")
+		return ev
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for a watch event")
+		return zerokv.Event{}
+	}
+}
+
+// testWatchEvents tests that puts, deletes, batches, transactions and prefix
+// deletes under the watched prefix are delivered in order and that writes
+// elsewhere are not.
+func testWatchEvents(t *testing.T, name string) {
+	db := helpers.SetupDB(t, name)
+	defer db.Close()
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+	ch, err := db.Watch(ctx, []byte("w/"))
+	require.NoError(t, err)
+
+	require.NoError(t, db.Put(t.Context(), []byte("other"), []byte("x")))
+	require.NoError(t, db.Put(t.Context(), []byte("w/1"), []byte("one")))
+	require.NoError(t, db.Delete(t.Context(), []byte("w/1")))
+	batch := db.Batch()
+	require.NoError(t, batch.Put([]byte("w/2"), []byte("two")))
+	require.NoError(t, batch.Put([]byte("skip"), []byte("x")))
+	require.NoError(t, batch.Commit(t.Context()))
+	txn, err := db.NewTransaction(t.Context())
+	require.NoError(t, err)
+	require.NoError(t, txn.Put(t.Context(), []byte("w/3"), []byte("three")))
+	require.NoError(t, txn.Commit(t.Context()))
+	_, err = db.Increment(t.Context(), []byte("w/n"), 1)
+	require.NoError(t, err)
+	_, err = db.DeletePrefix(t.Context(), []byte("w/"))
+	require.NoError(t, err)
+
+	ev := nextEvent(t, ch)
+	require.Equal(t, zerokv.OpPut, ev.Op)
+	require.Equal(t, []byte("w/1"), ev.Key)
+	require.Equal(t, []byte("one"), ev.Value)
+	ev = nextEvent(t, ch)
+	require.Equal(t, zerokv.OpDelete, ev.Op)
+	require.Equal(t, []byte("w/1"), ev.Key)
+	ev = nextEvent(t, ch)
+	require.Equal(t, zerokv.OpPut, ev.Op)
+	require.Equal(t, []byte("w/2"), ev.Key)
+	ev = nextEvent(t, ch)
+	require.Equal(t, []byte("w/3"), ev.Key)
+	ev = nextEvent(t, ch)
+	require.Equal(t, []byte("w/n"), ev.Key)
+	require.Equal(t, zerokv.EncodeCounter(1), ev.Value)
+	ev = nextEvent(t, ch)
+	require.Equal(t, zerokv.OpDeleteRange, ev.Op)
+	require.Equal(t, []byte("w/"), ev.Key)
+	require.Equal(t, []byte("w0"), ev.End)
+}
+
+// testWatchCancel tests that cancelling the context and closing the
+// database both close the Watch channel.
+func testWatchCancel(t *testing.T, name string) {
+	db := helpers.SetupDB(t, name)
+	ctx, cancel := context.WithCancel(t.Context())
+	ch, err := db.Watch(ctx, nil)
+	require.NoError(t, err)
+	cancel()
+	for range ch {
+	}
+	require.NoError(t, db.Put(t.Context(), []byte("after"), []byte("cancel")))
+
+	ch, err = db.Watch(t.Context(), nil)
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+	for range ch {
+	}
+}
+
+// testWatchNamespace tests that a namespaced Watch reports keys without the
+// namespace prefix and ignores other namespaces.
+func testWatchNamespace(t *testing.T, name string) {
+	db := helpers.SetupDB(t, name)
+	defer db.Close()
+	tenantA := zerokv.Namespace(db, []byte("a/"))
+	tenantB := zerokv.Namespace(db, []byte("b/"))
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+	ch, err := tenantA.Watch(ctx, nil)
+	require.NoError(t, err)
+
+	require.NoError(t, tenantB.Put(t.Context(), []byte("k"), []byte("b")))
+	require.NoError(t, tenantA.Put(t.Context(), []byte("k"), []byte("a")))
+	require.NoError(t, db.DeleteRange(t.Context(), nil, nil))
+
+	ev := nextEvent(t, ch)
+	require.Equal(t, zerokv.OpPut, ev.Op)
+	require.Equal(t, []byte("k"), ev.Key)
+	require.Equal(t, []byte("a"), ev.Value)
+	ev = nextEvent(t, ch)
+	require.Equal(t, zerokv.OpDeleteRange, ev.Op)
+	require.Empty(t, ev.Key)
+	require.Nil(t, ev.End)
+}
@@ -0,0 +1,693 @@
+package zerokv
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"time"
+)
+
+// TieredOptions configures Tiered.
+type TieredOptions struct {
+	// MaxEntries caps the number of keys cached in the front tier; the least
+	// recently used key is evicted when it is exceeded. Zero means no limit.
+	MaxEntries int
+}
+
+// tiered is a Core that caches the keys read from back in front.
+type tiered struct {
+	front, back Core
+	max         int
+
+	// Loading a key into front holds rangeMu for reading and the key's lock,
+	// so invalidations that take either one cannot interleave with a load
+	// that read back before the write and would cache a stale value.
+	rangeMu sync.RWMutex
+	locks   KeyedMutex
+
+	lruMu sync.Mutex
+	lru   *list.List               // cached keys, most recently used first
+	elems map[string]*list.Element // key -> element in lru
+
+	// expiries holds when the keys written by PutWithTTL expire, so that a
+	// load caches them in front only until then. Every other write through
+	// the Tiered Core forgets the key's entry, and entries more than
+	// expiryGrace past their deadline are pruned whenever the map reaches
+	// pruneAt entries.
+	ttlMu    sync.Mutex
+	expiries map[string]time.Time
+	pruneAt  int
+}
+
+const (
+	// expiryGrace is how long a key's deadline is kept once it has passed,
+	// so a load that read the key from back just before it expired still
+	// finds the deadline when it fills front.
+	expiryGrace = time.Second
+	// minExpiryPrune is the smallest map size that triggers a prune.
+	minExpiryPrune = 1024
+)
+
+// Tiered returns a Core that serves reads from front, usually a memdb, and
+// falls back to back on a miss, caching the value it finds in front.
+// Writes go to back first and then to front, so a successful write is
+// durable before it is visible in the cache. Close closes both tiers.
+//
+// The cache is consistent with back as long as every write goes through the
+// Tiered Core: Put and GetOrPut write through, other single-key writes
+// (PutWithTTL, CompareAndSwap, Increment, Merge, Delete) and PutMany
+// invalidate the keys they touch, and DeletePrefix, DeleteRange, Truncate,
+// TruncatePrefix and Restore invalidate the affected range. Batches and
+// transactions invalidate their keys once Commit returns, so until then a
+// reader may still see the previous value from front; with
+// BatchWithOptions this also applies to segments flushed before Commit.
+// Writes made to back directly are not seen until the key is evicted.
+//
+// A key written by PutWithTTL is cached in front only for what is left of
+// its TTL, using front's PutWithTTL; if front has no TTL support the key is
+// not cached. Any other write of the key through the Tiered Core replaces
+// its value and forgets the TTL. TTLs set on back directly, or before the
+// Tiered Core was created, are not known to it.
+//
+// Scans, Count, EstimateSize, snapshots, Watch and the maintenance methods
+// use back only. GetMany reads its misses from back in one call without
+// caching them.
+func Tiered(front, back Core, opts TieredOptions) Core {
+	return &tiered{
+		front:    front,
+		back:     back,
+		max:      opts.MaxEntries,
+		lru:      list.New(),
+		elems:    make(map[string]*list.Element),
+		expiries: make(map[string]time.Time),
+		pruneAt:  minExpiryPrune,
+	}
+}
+
+// touch marks key as most recently used and evicts the least recently used
+// keys beyond the limit from front.
+func (t *tiered) touch(ctx context.Context, key []byte) {
+	if t.max <= 0 {
+		return
+	}
+	var evicted [][]byte
+	t.lruMu.Lock()
+	if e, ok := t.elems[string(key)]; ok {
+		t.lru.MoveToFront(e)
+	} else {
+		t.elems[string(key)] = t.lru.PushFront(string(key))
+	}
+	for t.lru.Len() > t.max {
+		e := t.lru.Back()
+		k := t.lru.Remove(e).(string)
+		delete(t.elems, k)
+		evicted = append(evicted, []byte(k))
+	}
+	t.lruMu.Unlock()
+	for _, k := range evicted {
+		t.front.Delete(ctx, k)
+	}
+}
+
+// forget drops the keys matching match from the LRU list.
+func (t *tiered) forget(match func(key []byte) bool) {
+	if t.max <= 0 {
+		return
+	}
+	t.lruMu.Lock()
+	defer t.lruMu.Unlock()
+	for k, e := range t.elems {
+		if match([]byte(k)) {
+			t.lru.Remove(e)
+			delete(t.elems, k)
+		}
+	}
+}
+
+// invalidate removes keys from front after they were written to back, and
+// forgets any TTL they had.
+func (t *tiered) invalidate(ctx context.Context, keys ...[]byte) error {
+	var errs []error
+	for _, key := range keys {
+		errs = append(errs, t.expire(ctx, key, time.Time{}))
+	}
+	return errors.Join(errs...)
+}
+
+// expire records at as key's expiry, the zero time forgetting it, and
+// removes key from front. Both happen under the key's lock, so no load can
+// cache the key in between with the expiry it had before.
+func (t *tiered) expire(ctx context.Context, key []byte, at time.Time) error {
+	unlock := t.locks.Lock(key)
+	t.setExpiry(key, at)
+	err := t.front.Delete(ctx, key)
+	unlock()
+	t.forget(func(k []byte) bool { return bytes.Equal(k, key) })
+	return err
+}
+
+// invalidateRange removes every key in [start, end) from front.
+func (t *tiered) invalidateRange(ctx context.Context, start, end []byte) error {
+	inRange := func(k []byte) bool {
+		return bytes.Compare(k, start) >= 0 && (end == nil || bytes.Compare(k, end) < 0)
+	}
+	t.rangeMu.Lock()
+	err := t.front.DeleteRange(ctx, start, end)
+	t.rangeMu.Unlock()
+	t.forget(inRange)
+	t.forgetExpiries(inRange)
+	return err
+}
+
+// expiry returns when key, written by PutWithTTL, expires.
+func (t *tiered) expiry(key []byte) (time.Time, bool) {
+	t.ttlMu.Lock()
+	defer t.ttlMu.Unlock()
+	at, ok := t.expiries[string(key)]
+	return at, ok
+}
+
+// setExpiry records when key expires; the zero time forgets it. Once the
+// map reaches pruneAt entries the deadlines long past are dropped, and the
+// next prune waits until the map has doubled, so the map stays proportional
+// to the keys with a live TTL at an amortized constant cost per write.
+func (t *tiered) setExpiry(key []byte, at time.Time) {
+	t.ttlMu.Lock()
+	defer t.ttlMu.Unlock()
+	if at.IsZero() {
+		delete(t.expiries, string(key))
+		return
+	}
+	t.expiries[string(key)] = at
+	if len(t.expiries) < t.pruneAt {
+		return
+	}
+	cutoff := time.Now().Add(-expiryGrace)
+	for k, at := range t.expiries {
+		if at.Before(cutoff) {
+			delete(t.expiries, k)
+		}
+	}
+	t.pruneAt = max(2*len(t.expiries), minExpiryPrune)
+}
+
+// forgetExpiries drops the expiries of the keys matching match.
+func (t *tiered) forgetExpiries(match func(key []byte) bool) {
+	t.ttlMu.Lock()
+	defer t.ttlMu.Unlock()
+	for k := range t.expiries {
+		if match([]byte(k)) {
+			delete(t.expiries, k)
+		}
+	}
+}
+
+// fill caches value for key in front, with the time left on its TTL if it
+// was written by PutWithTTL. A key whose TTL has run out is not cached and
+// its expiry is forgotten: back only still holds it if it was rewritten
+// since, and the next load caches it. If front cannot be updated the key is
+// removed from it instead.
+func (t *tiered) fill(ctx context.Context, key, value []byte) error {
+	var err error
+	if at, ok := t.expiry(key); !ok {
+		err = t.front.Put(ctx, key, value)
+	} else if ttl := time.Until(at); ttl > 0 {
+		err = t.front.PutWithTTL(ctx, key, value, ttl)
+	} else {
+		t.setExpiry(key, time.Time{})
+		return t.front.Delete(ctx, key)
+	}
+	if err != nil {
+		return t.front.Delete(ctx, key)
+	}
+	return nil
+}
+
+// writeThrough stores key in back and then in front. If front cannot be
+// updated the key is invalidated instead, so front never keeps an old value.
+func (t *tiered) writeThrough(ctx context.Context, key []byte, write func() ([]byte, error)) ([]byte, error) {
+	t.rangeMu.RLock()
+	unlock := t.locks.Lock(key)
+	value, err := write()
+	if err == nil {
+		err = t.fill(ctx, key, value)
+	}
+	unlock()
+	t.rangeMu.RUnlock()
+	if err == nil {
+		t.touch(ctx, key)
+	}
+	return value, err
+}
+
+// Put also forgets any TTL key had, so it is cached without one.
+func (t *tiered) Put(ctx context.Context, key []byte, data []byte) error {
+	_, err := t.writeThrough(ctx, key, func() ([]byte, error) {
+		if err := t.back.Put(ctx, key, data); err != nil {
+			return nil, err
+		}
+		t.setExpiry(key, time.Time{})
+		return data, nil
+	})
+	return err
+}
+
+// PutWithTTL writes to back, then records when the key expires and
+// invalidates front. Later loads cache the key in front with the time left
+// on its TTL, so front stops serving it when back does. The deadline is
+// taken before the write, so it never falls after back's.
+func (t *tiered) PutWithTTL(ctx context.Context, key, value []byte, ttl time.Duration) error {
+	at := time.Now().Add(ttl)
+	if err := t.back.PutWithTTL(ctx, key, value, ttl); err != nil {
+		return err
+	}
+	return t.expire(ctx, key, at)
+}
+
+func (t *tiered) Get(ctx context.Context, key []byte) ([]byte, error) {
+	value, err := t.front.Get(ctx, key)
+	if err == nil {
+		t.touch(ctx, key)
+		return value, nil
+	}
+	if !errors.Is(err, ErrKeyNotFound) {
+		return nil, err
+	}
+	return t.writeThrough(ctx, key, func() ([]byte, error) {
+		return t.back.Get(ctx, key)
+	})
+}
+
+// GetOrDefault reads through Get, so a missing key is looked up in back
+// before def is returned.
+func (t *tiered) GetOrDefault(ctx context.Context, key, def []byte) ([]byte, error) {
+	value, err := t.Get(ctx, key)
+	if errors.Is(err, ErrKeyNotFound) {
+		return bytes.Clone(def), nil
+	}
+	return value, err
+}
+
+// GetInto reads a hit from front into dst; a miss is loaded as by Get and
+// then copied.
+func (t *tiered) GetInto(ctx context.Context, key, dst []byte) ([]byte, error) {
+	value, err := t.front.GetInto(ctx, key, dst)
+	if err == nil {
+		t.touch(ctx, key)
+		return value, nil
+	}
+	if !errors.Is(err, ErrKeyNotFound) {
+		return dst[:0], err
+	}
+	value, err = t.writeThrough(ctx, key, func() ([]byte, error) {
+		return t.back.Get(ctx, key)
+	})
+	if err != nil {
+		return dst[:0], err
+	}
+	return append(dst[:0], value...), nil
+}
+
+// View reads from front without copying on a hit; on a miss the value is
+// loaded as by Get and passed to fn.
+func (t *tiered) View(ctx context.Context, key []byte, fn func(value []byte) error) error {
+	called := false
+	err := t.front.View(ctx, key, func(value []byte) error {
+		called = true
+		return fn(value)
+	})
+	if called {
+		t.touch(ctx, key)
+		return err
+	}
+	if !errors.Is(err, ErrKeyNotFound) {
+		return err
+	}
+	value, err := t.Get(ctx, key)
+	if err != nil {
+		return err
+	}
+	return fn(value)
+}
+
+func (t *tiered) Has(ctx context.Context, key []byte) (bool, error) {
+	ok, err := t.front.Has(ctx, key)
+	if err != nil || ok {
+		return ok, err
+	}
+	return t.back.Has(ctx, key)
+}
+
+func (t *tiered) SizeOf(ctx context.Context, key []byte) (int, error) {
+	n, err := t.front.SizeOf(ctx, key)
+	if !errors.Is(err, ErrKeyNotFound) {
+		return n, err
+	}
+	return t.back.SizeOf(ctx, key)
+}
+
+func (t *tiered) GetMany(ctx context.Context, keys [][]byte) ([][]byte, error) {
+	values, err := t.front.GetMany(ctx, keys)
+	if err != nil {
+		return nil, err
+	}
+	var missing [][]byte
+	var at []int
+	for i, v := range values {
+		if v == nil {
+			missing = append(missing, keys[i])
+			at = append(at, i)
+		} else {
+			t.touch(ctx, keys[i])
+		}
+	}
+	if len(missing) == 0 {
+		return values, nil
+	}
+	found, err := t.back.GetMany(ctx, missing)
+	if err != nil {
+		return nil, err
+	}
+	for j, i := range at {
+		values[i] = found[j]
+	}
+	return values, nil
+}
+
+// HasMany answers from the front store where it can and asks the back
+// store only about the keys the front does not hold.
+func (t *tiered) HasMany(ctx context.Context, keys [][]byte) ([]bool, error) {
+	found, err := t.front.HasMany(ctx, keys)
+	if err != nil {
+		return nil, err
+	}
+	var missing [][]byte
+	var at []int
+	for i, ok := range found {
+		if !ok {
+			missing = append(missing, keys[i])
+			at = append(at, i)
+		}
+	}
+	if len(missing) == 0 {
+		return found, nil
+	}
+	back, err := t.back.HasMany(ctx, missing)
+	if err != nil {
+		return nil, err
+	}
+	for j, i := range at {
+		found[i] = back[j]
+	}
+	return found, nil
+}
+
+func (t *tiered) PutMany(ctx context.Context, keys, values [][]byte) error {
+	if err := t.back.PutMany(ctx, keys, values); err != nil {
+		return err
+	}
+	return t.invalidate(ctx, keys...)
+}
+
+func (t *tiered) Delete(ctx context.Context, key []byte) error {
+	if err := t.back.Delete(ctx, key); err != nil {
+		return err
+	}
+	return t.invalidate(ctx, key)
+}
+
+func (t *tiered) DeletePrefix(ctx context.Context, prefix []byte) (int, error) {
+	n, err := t.back.DeletePrefix(ctx, prefix)
+	if err != nil {
+		return n, err
+	}
+	return n, t.invalidateRange(ctx, prefix, PrefixUpperBound(prefix))
+}
+
+func (t *tiered) DeleteRange(ctx context.Context, start, end []byte) error {
+	if err := t.back.DeleteRange(ctx, start, end); err != nil {
+		return err
+	}
+	return t.invalidateRange(ctx, start, end)
+}
+
+func (t *tiered) Truncate(ctx context.Context) error {
+	if err := t.back.Truncate(ctx); err != nil {
+		return err
+	}
+	return t.invalidateRange(ctx, nil, nil)
+}
+
+func (t *tiered) TruncatePrefix(ctx context.Context, prefix []byte) error {
+	if err := t.back.TruncatePrefix(ctx, prefix); err != nil {
+		return err
+	}
+	return t.invalidateRange(ctx, prefix, PrefixUpperBound(prefix))
+}
+
+func (t *tiered) Count(ctx context.Context, prefix []byte) (int64, error) {
+	return t.back.Count(ctx, prefix)
+}
+
+func (t *tiered) EstimateSize(prefix []byte) (int64, error) {
+	return t.back.EstimateSize(prefix)
+}
+
+func (t *tiered) NewTransaction(ctx context.Context) (Txn, error) {
+	txn, err := t.back.NewTransaction(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &invalidatingTxn{txn: txn, invalidate: t.invalidate}, nil
+}
+
+func (t *tiered) CompareAndSwap(ctx context.Context, key, old, new []byte) (bool, error) {
+	swapped, err := t.back.CompareAndSwap(ctx, key, old, new)
+	if err != nil || !swapped {
+		return swapped, err
+	}
+	return true, t.invalidate(ctx, key)
+}
+
+func (t *tiered) Increment(ctx context.Context, key []byte, delta int64) (int64, error) {
+	n, err := t.back.Increment(ctx, key, delta)
+	if err != nil {
+		return n, err
+	}
+	return n, t.invalidate(ctx, key)
+}
+
+func (t *tiered) GetOrPut(ctx context.Context, key []byte, fill func() ([]byte, error)) ([]byte, error) {
+	if value, err := t.front.Get(ctx, key); err == nil {
+		t.touch(ctx, key)
+		return value, nil
+	}
+	return t.writeThrough(ctx, key, func() ([]byte, error) {
+		return t.back.GetOrPut(ctx, key, fill)
+	})
+}
+
+func (t *tiered) Merge(ctx context.Context, key, operand []byte, merge func(existing, operand []byte) []byte) error {
+	if err := t.back.Merge(ctx, key, operand, merge); err != nil {
+		return err
+	}
+	return t.invalidate(ctx, key)
+}
+
+func (t *tiered) Stats(ctx context.Context) (Stats, error) {
+	return t.back.Stats(ctx)
+}
+
+func (t *tiered) Sync(ctx context.Context) error {
+	return t.back.Sync(ctx)
+}
+
+func (t *tiered) Compact(ctx context.Context, start, end []byte) error {
+	return t.back.Compact(ctx, start, end)
+}
+
+func (t *tiered) Ping(ctx context.Context) error {
+	return t.back.Ping(ctx)
+}
+
+func (t *tiered) Backup(ctx context.Context, w io.Writer) error {
+	return t.back.Backup(ctx, w)
+}
+
+// Restore writes into back and then empties front.
+func (t *tiered) Restore(ctx context.Context, r io.Reader) error {
+	if err := t.back.Restore(ctx, r); err != nil {
+		return err
+	}
+	return t.invalidateRange(ctx, nil, nil)
+}
+
+// Checkpoint checkpoints back, which holds every entry; front is only a
+// cache.
+func (t *tiered) Checkpoint(ctx context.Context, destDir string) error {
+	return t.back.Checkpoint(ctx, destDir)
+}
+
+func (t *tiered) Snapshot() (Snapshot, error) {
+	return t.back.Snapshot()
+}
+
+func (t *tiered) Batch() Batch {
+	return &invalidatingBatch{batch: t.back.Batch(), invalidate: t.invalidate}
+}
+
+// BulkLoad loads into back and then invalidates every key fn wrote, even if
+// the load failed partway.
+func (t *tiered) BulkLoad(ctx context.Context, fn func(b Batch) error) error {
+	tb := &invalidatingBatch{invalidate: t.invalidate}
+	err := t.back.BulkLoad(ctx, func(b Batch) error {
+		tb.batch = b
+		return fn(tb)
+	})
+	if ierr := t.invalidate(context.WithoutCancel(ctx), tb.keys...); err == nil {
+		err = ierr
+	}
+	return err
+}
+
+func (t *tiered) BatchWithOptions(maxOps, maxBytes int) Batch {
+	return &invalidatingBatch{batch: t.back.BatchWithOptions(maxOps, maxBytes), invalidate: t.invalidate}
+}
+
+func (t *tiered) Scan(prefix []byte) Iterator {
+	return t.back.Scan(prefix)
+}
+
+func (t *tiered) ReverseScan(prefix []byte) Iterator {
+	return t.back.ReverseScan(prefix)
+}
+
+func (t *tiered) RangeScan(start, end []byte) Iterator {
+	return t.back.RangeScan(start, end)
+}
+
+func (t *tiered) ScanFrom(prefix, start []byte) Iterator {
+	return t.back.ScanFrom(prefix, start)
+}
+
+func (t *tiered) ScanKeys(prefix []byte) Iterator {
+	return t.back.ScanKeys(prefix)
+}
+
+func (t *tiered) ScanContext(ctx context.Context, prefix []byte) Iterator {
+	return t.back.ScanContext(ctx, prefix)
+}
+
+func (t *tiered) Watch(ctx context.Context, prefix []byte) (<-chan Event, error) {
+	return t.back.Watch(ctx, prefix)
+}
+
+func (t *tiered) Close() error {
+	return errors.Join(t.front.Close(), t.back.Close())
+}
+
+// Unwrap returns back, the tier that holds the data.
+func (t *tiered) Unwrap() Core {
+	return t.back
+}
+
+// invalidatingBatch remembers the keys it writes and passes them to
+// invalidate once Commit succeeds, so caching wrappers can drop the entries
+// the batch made stale.
+type invalidatingBatch struct {
+	batch      Batch
+	invalidate func(ctx context.Context, keys ...[]byte) error
+	keys       [][]byte
+}
+
+func (b *invalidatingBatch) Put(key []byte, data []byte) error {
+	b.keys = append(b.keys, bytes.Clone(key))
+	return b.batch.Put(key, data)
+}
+
+func (b *invalidatingBatch) Delete(key []byte) error {
+	b.keys = append(b.keys, bytes.Clone(key))
+	return b.batch.Delete(key)
+}
+
+func (b *invalidatingBatch) PutIfAbsent(key, data []byte) error {
+	b.keys = append(b.keys, bytes.Clone(key))
+	return b.batch.PutIfAbsent(key, data)
+}
+
+func (b *invalidatingBatch) PutCtx(ctx context.Context, key []byte, data []byte) error {
+	b.keys = append(b.keys, bytes.Clone(key))
+	if cb, ok := b.batch.(ContextBatch); ok {
+		return cb.PutCtx(ctx, key, data)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return b.batch.Put(key, data)
+}
+
+func (b *invalidatingBatch) DeleteCtx(ctx context.Context, key []byte) error {
+	b.keys = append(b.keys, bytes.Clone(key))
+	if cb, ok := b.batch.(ContextBatch); ok {
+		return cb.DeleteCtx(ctx, key)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return b.batch.Delete(key)
+}
+
+func (b *invalidatingBatch) Len() int  { return b.batch.Len() }
+func (b *invalidatingBatch) Size() int { return b.batch.Size() }
+
+func (b *invalidatingBatch) Reset() {
+	b.batch.Reset()
+	b.keys = nil
+}
+
+func (b *invalidatingBatch) Validate() error {
+	return b.batch.Validate()
+}
+
+func (b *invalidatingBatch) Commit(ctx context.Context) error {
+	if err := b.batch.Commit(ctx); err != nil {
+		return err
+	}
+	keys := b.keys
+	b.keys = nil
+	return b.invalidate(ctx, keys...)
+}
+
+// invalidatingTxn passes the keys the transaction wrote to invalidate once
+// Commit succeeds.
+type invalidatingTxn struct {
+	txn        Txn
+	invalidate func(ctx context.Context, keys ...[]byte) error
+	keys       [][]byte
+}
+
+func (x *invalidatingTxn) Get(ctx context.Context, key []byte) ([]byte, error) {
+	return x.txn.Get(ctx, key)
+}
+
+func (x *invalidatingTxn) Put(ctx context.Context, key []byte, data []byte) error {
+	x.keys = append(x.keys, bytes.Clone(key))
+	return x.txn.Put(ctx, key, data)
+}
+
+func (x *invalidatingTxn) Delete(ctx context.Context, key []byte) error {
+	x.keys = append(x.keys, bytes.Clone(key))
+	return x.txn.Delete(ctx, key)
+}
+
+func (x *invalidatingTxn) Commit(ctx context.Context) error {
+	if err := x.txn.Commit(ctx); err != nil {
+		return err
+	}
+	return x.invalidate(ctx, x.keys...)
+}
+
+func (x *invalidatingTxn) Discard() { x.txn.Discard() }
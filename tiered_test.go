@@ -0,0 +1,58 @@
+package zerokv_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/rawbytedev/zerokv"
+	"github.com/rawbytedev/zerokv/memdb"
+	"github.com/stretchr/testify/require"
+)
+
+// TestTieredExpiriesPruned tests that the deadlines of TTL keys that are
+// never read again do not pile up, and that other writes forget them.
+func TestTieredExpiriesPruned(t *testing.T) {
+	t.Parallel()
+	db := zerokv.Tiered(memdb.New(), memdb.New(), zerokv.TieredOptions{})
+	defer db.Close()
+
+	const perRound = 3000
+	for round := range 4 {
+		for i := range perRound {
+			key := fmt.Appendf(nil, "session_%d_%d", round, i)
+			require.NoError(t, db.PutWithTTL(t.Context(), key, []byte("token"), time.Millisecond))
+		}
+		time.Sleep(1100 * time.Millisecond)
+	}
+	require.Less(t, zerokv.TieredExpiries(db), 2*perRound, "expired deadlines should be pruned")
+
+	ctx := t.Context()
+	key := []byte("counter")
+	require.NoError(t, db.PutWithTTL(ctx, key, zerokv.EncodeCounter(1), time.Hour))
+	n := zerokv.TieredExpiries(db)
+	_, err := db.Increment(ctx, key, 1)
+	require.NoError(t, err)
+	require.Equal(t, n-1, zerokv.TieredExpiries(db), "Increment should forget the TTL")
+
+	require.NoError(t, db.PutWithTTL(ctx, key, []byte("a"), time.Hour))
+	require.NoError(t, db.Merge(ctx, key, []byte("b"), func(existing, operand []byte) []byte {
+		return append(existing, operand...)
+	}))
+	require.Equal(t, n-1, zerokv.TieredExpiries(db), "Merge should forget the TTL")
+
+	require.NoError(t, db.PutWithTTL(ctx, key, []byte("a"), time.Hour))
+	_, err = db.CompareAndSwap(ctx, key, []byte("a"), []byte("b"))
+	require.NoError(t, err)
+	require.Equal(t, n-1, zerokv.TieredExpiries(db), "CompareAndSwap should forget the TTL")
+
+	require.NoError(t, db.PutWithTTL(ctx, key, []byte("a"), time.Hour))
+	require.NoError(t, db.PutMany(ctx, [][]byte{key}, [][]byte{[]byte("b")}))
+	require.Equal(t, n-1, zerokv.TieredExpiries(db), "PutMany should forget the TTL")
+
+	require.NoError(t, db.PutWithTTL(ctx, key, []byte("a"), time.Hour))
+	batch := db.Batch()
+	require.NoError(t, batch.Put(key, []byte("b")))
+	require.NoError(t, batch.Commit(ctx))
+	require.Equal(t, n-1, zerokv.TieredExpiries(db), "a batch should forget the TTL")
+}
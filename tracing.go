@@ -0,0 +1,551 @@
+package zerokv
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Span attribute keys set by WithTracing.
+const (
+	AttrBackend   = attribute.Key("zerokv.backend")
+	AttrKeySize   = attribute.Key("zerokv.key_size")
+	AttrValueSize = attribute.Key("zerokv.value_size")
+	AttrKeys      = attribute.Key("zerokv.keys")    // keys read or written by multi-key calls and batches
+	AttrEntries   = attribute.Key("zerokv.entries") // entries returned by an iterator
+)
+
+// tracingCore starts a span for every call to the wrapped Core.
+type tracingCore struct {
+	core    Core
+	tracer  trace.Tracer
+	backend attribute.KeyValue
+}
+
+// WithTracing returns a Core that starts a span from tracer for every call to
+// core. Spans are named after the method ("zerokv.Get", "zerokv.Put", ...),
+// start from the context passed in so they nest under the caller's span,
+// and carry the backend name and the key and value sizes. A failed call
+// records the error and sets the span status to Error; a missing key is not
+// a failure.
+//
+// Iterators get a span from the call that opens them until Release, and
+// batches one from creation until Commit. Methods without a context (Scan,
+// EstimateSize, ...) start root spans; use ScanContext to nest a scan. Close,
+// Snapshot and Watch are not traced.
+func WithTracing(core Core, tracer trace.Tracer) Core {
+	return &tracingCore{core: core, tracer: tracer, backend: AttrBackend.String(backendName(core))}
+}
+
+// backendName returns the package name of core's concrete type, such as
+// "pebbledb" for *pebbledb.PebbleDB.
+func backendName(core Core) string {
+	name := strings.TrimPrefix(fmt.Sprintf("%T", core), "*")
+	if i := strings.IndexByte(name, '.'); i >= 0 {
+		name = name[:i]
+	}
+	return name
+}
+
+func (c *tracingCore) start(ctx context.Context, op string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return c.tracer.Start(ctx, "zerokv."+op, trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(append(attrs, c.backend)...))
+}
+
+// endSpan marks span as failed when err is a real failure, then ends it.
+func endSpan(span trace.Span, err error) {
+	if err != nil && !errors.Is(err, ErrKeyNotFound) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+func (c *tracingCore) Put(ctx context.Context, key []byte, data []byte) error {
+	ctx, span := c.start(ctx, "Put", AttrKeySize.Int(len(key)), AttrValueSize.Int(len(data)))
+	err := c.core.Put(ctx, key, data)
+	endSpan(span, err)
+	return err
+}
+
+func (c *tracingCore) PutWithTTL(ctx context.Context, key, value []byte, ttl time.Duration) error {
+	ctx, span := c.start(ctx, "PutWithTTL", AttrKeySize.Int(len(key)), AttrValueSize.Int(len(value)))
+	err := c.core.PutWithTTL(ctx, key, value, ttl)
+	endSpan(span, err)
+	return err
+}
+
+func (c *tracingCore) Get(ctx context.Context, key []byte) ([]byte, error) {
+	ctx, span := c.start(ctx, "Get", AttrKeySize.Int(len(key)))
+	value, err := c.core.Get(ctx, key)
+	if err == nil {
+		span.SetAttributes(AttrValueSize.Int(len(value)))
+	}
+	endSpan(span, err)
+	return value, err
+}
+
+func (c *tracingCore) GetOrDefault(ctx context.Context, key, def []byte) ([]byte, error) {
+	ctx, span := c.start(ctx, "GetOrDefault", AttrKeySize.Int(len(key)))
+	value, err := c.core.GetOrDefault(ctx, key, def)
+	if err == nil {
+		span.SetAttributes(AttrValueSize.Int(len(value)))
+	}
+	endSpan(span, err)
+	return value, err
+}
+
+func (c *tracingCore) GetInto(ctx context.Context, key, dst []byte) ([]byte, error) {
+	ctx, span := c.start(ctx, "GetInto", AttrKeySize.Int(len(key)))
+	value, err := c.core.GetInto(ctx, key, dst)
+	if err == nil {
+		span.SetAttributes(AttrValueSize.Int(len(value)))
+	}
+	endSpan(span, err)
+	return value, err
+}
+
+func (c *tracingCore) View(ctx context.Context, key []byte, fn func(value []byte) error) error {
+	ctx, span := c.start(ctx, "View", AttrKeySize.Int(len(key)))
+	err := c.core.View(ctx, key, func(value []byte) error {
+		span.SetAttributes(AttrValueSize.Int(len(value)))
+		return fn(value)
+	})
+	endSpan(span, err)
+	return err
+}
+
+func (c *tracingCore) Has(ctx context.Context, key []byte) (bool, error) {
+	ctx, span := c.start(ctx, "Has", AttrKeySize.Int(len(key)))
+	ok, err := c.core.Has(ctx, key)
+	endSpan(span, err)
+	return ok, err
+}
+
+func (c *tracingCore) SizeOf(ctx context.Context, key []byte) (int, error) {
+	ctx, span := c.start(ctx, "SizeOf", AttrKeySize.Int(len(key)))
+	n, err := c.core.SizeOf(ctx, key)
+	if err == nil {
+		span.SetAttributes(AttrValueSize.Int(n))
+	}
+	endSpan(span, err)
+	return n, err
+}
+
+func (c *tracingCore) GetMany(ctx context.Context, keys [][]byte) ([][]byte, error) {
+	ctx, span := c.start(ctx, "GetMany", AttrKeys.Int(len(keys)), AttrKeySize.Int(totalSize(keys)))
+	values, err := c.core.GetMany(ctx, keys)
+	if err == nil {
+		span.SetAttributes(AttrValueSize.Int(totalSize(values)))
+	}
+	endSpan(span, err)
+	return values, err
+}
+
+func (c *tracingCore) HasMany(ctx context.Context, keys [][]byte) ([]bool, error) {
+	ctx, span := c.start(ctx, "HasMany", AttrKeys.Int(len(keys)), AttrKeySize.Int(totalSize(keys)))
+	found, err := c.core.HasMany(ctx, keys)
+	endSpan(span, err)
+	return found, err
+}
+
+func (c *tracingCore) PutMany(ctx context.Context, keys, values [][]byte) error {
+	ctx, span := c.start(ctx, "PutMany", AttrKeys.Int(len(keys)),
+		AttrKeySize.Int(totalSize(keys)), AttrValueSize.Int(totalSize(values)))
+	err := c.core.PutMany(ctx, keys, values)
+	endSpan(span, err)
+	return err
+}
+
+func (c *tracingCore) Delete(ctx context.Context, key []byte) error {
+	ctx, span := c.start(ctx, "Delete", AttrKeySize.Int(len(key)))
+	err := c.core.Delete(ctx, key)
+	endSpan(span, err)
+	return err
+}
+
+func (c *tracingCore) DeletePrefix(ctx context.Context, prefix []byte) (int, error) {
+	ctx, span := c.start(ctx, "DeletePrefix", AttrKeySize.Int(len(prefix)))
+	n, err := c.core.DeletePrefix(ctx, prefix)
+	if err == nil {
+		span.SetAttributes(AttrKeys.Int(n))
+	}
+	endSpan(span, err)
+	return n, err
+}
+
+func (c *tracingCore) DeleteRange(ctx context.Context, start, end []byte) error {
+	ctx, span := c.start(ctx, "DeleteRange")
+	err := c.core.DeleteRange(ctx, start, end)
+	endSpan(span, err)
+	return err
+}
+
+func (c *tracingCore) Truncate(ctx context.Context) error {
+	ctx, span := c.start(ctx, "Truncate")
+	err := c.core.Truncate(ctx)
+	endSpan(span, err)
+	return err
+}
+
+func (c *tracingCore) TruncatePrefix(ctx context.Context, prefix []byte) error {
+	ctx, span := c.start(ctx, "TruncatePrefix", AttrKeySize.Int(len(prefix)))
+	err := c.core.TruncatePrefix(ctx, prefix)
+	endSpan(span, err)
+	return err
+}
+
+func (c *tracingCore) Count(ctx context.Context, prefix []byte) (int64, error) {
+	ctx, span := c.start(ctx, "Count", AttrKeySize.Int(len(prefix)))
+	n, err := c.core.Count(ctx, prefix)
+	endSpan(span, err)
+	return n, err
+}
+
+func (c *tracingCore) EstimateSize(prefix []byte) (int64, error) {
+	_, span := c.start(context.Background(), "EstimateSize", AttrKeySize.Int(len(prefix)))
+	n, err := c.core.EstimateSize(prefix)
+	endSpan(span, err)
+	return n, err
+}
+
+func (c *tracingCore) NewTransaction(ctx context.Context) (Txn, error) {
+	ctx, span := c.start(ctx, "NewTransaction")
+	txn, err := c.core.NewTransaction(ctx)
+	endSpan(span, err)
+	if err != nil {
+		return nil, err
+	}
+	return &tracingTxn{txn: txn, c: c}, nil
+}
+
+func (c *tracingCore) CompareAndSwap(ctx context.Context, key, old, new []byte) (bool, error) {
+	ctx, span := c.start(ctx, "CompareAndSwap", AttrKeySize.Int(len(key)), AttrValueSize.Int(len(new)))
+	swapped, err := c.core.CompareAndSwap(ctx, key, old, new)
+	endSpan(span, err)
+	return swapped, err
+}
+
+func (c *tracingCore) Increment(ctx context.Context, key []byte, delta int64) (int64, error) {
+	ctx, span := c.start(ctx, "Increment", AttrKeySize.Int(len(key)))
+	n, err := c.core.Increment(ctx, key, delta)
+	endSpan(span, err)
+	return n, err
+}
+
+func (c *tracingCore) GetOrPut(ctx context.Context, key []byte, fill func() ([]byte, error)) ([]byte, error) {
+	ctx, span := c.start(ctx, "GetOrPut", AttrKeySize.Int(len(key)))
+	value, err := c.core.GetOrPut(ctx, key, fill)
+	if err == nil {
+		span.SetAttributes(AttrValueSize.Int(len(value)))
+	}
+	endSpan(span, err)
+	return value, err
+}
+
+func (c *tracingCore) Merge(ctx context.Context, key, operand []byte, merge func(existing, operand []byte) []byte) error {
+	ctx, span := c.start(ctx, "Merge", AttrKeySize.Int(len(key)), AttrValueSize.Int(len(operand)))
+	err := c.core.Merge(ctx, key, operand, merge)
+	endSpan(span, err)
+	return err
+}
+
+func (c *tracingCore) Stats(ctx context.Context) (Stats, error) {
+	ctx, span := c.start(ctx, "Stats")
+	stats, err := c.core.Stats(ctx)
+	endSpan(span, err)
+	return stats, err
+}
+
+func (c *tracingCore) Sync(ctx context.Context) error {
+	ctx, span := c.start(ctx, "Sync")
+	err := c.core.Sync(ctx)
+	endSpan(span, err)
+	return err
+}
+
+func (c *tracingCore) Compact(ctx context.Context, start, end []byte) error {
+	ctx, span := c.start(ctx, "Compact")
+	err := c.core.Compact(ctx, start, end)
+	endSpan(span, err)
+	return err
+}
+
+func (c *tracingCore) Ping(ctx context.Context) error {
+	ctx, span := c.start(ctx, "Ping")
+	err := c.core.Ping(ctx)
+	endSpan(span, err)
+	return err
+}
+
+func (c *tracingCore) Backup(ctx context.Context, w io.Writer) error {
+	ctx, span := c.start(ctx, "Backup")
+	err := c.core.Backup(ctx, w)
+	endSpan(span, err)
+	return err
+}
+
+func (c *tracingCore) Restore(ctx context.Context, r io.Reader) error {
+	ctx, span := c.start(ctx, "Restore")
+	err := c.core.Restore(ctx, r)
+	endSpan(span, err)
+	return err
+}
+
+func (c *tracingCore) Checkpoint(ctx context.Context, destDir string) error {
+	ctx, span := c.start(ctx, "Checkpoint")
+	err := c.core.Checkpoint(ctx, destDir)
+	endSpan(span, err)
+	return err
+}
+
+func (c *tracingCore) Snapshot() (Snapshot, error) {
+	return c.core.Snapshot()
+}
+
+func (c *tracingCore) Batch() Batch {
+	return &tracingBatch{batch: c.core.Batch(), c: c, created: time.Now()}
+}
+
+func (c *tracingCore) BulkLoad(ctx context.Context, fn func(b Batch) error) error {
+	ctx, span := c.start(ctx, "BulkLoad")
+	// a tracingBatch tallies the load; it is never committed, so it records
+	// no span of its own
+	var tb *tracingBatch
+	err := c.core.BulkLoad(ctx, func(b Batch) error {
+		tb = &tracingBatch{batch: b, c: c}
+		return fn(tb)
+	})
+	if tb != nil {
+		span.SetAttributes(AttrKeys.Int(tb.keys), AttrKeySize.Int(tb.keyBytes), AttrValueSize.Int(tb.valueBytes))
+	}
+	endSpan(span, err)
+	return err
+}
+
+func (c *tracingCore) BatchWithOptions(maxOps, maxBytes int) Batch {
+	return &tracingBatch{batch: c.core.BatchWithOptions(maxOps, maxBytes), c: c, created: time.Now()}
+}
+
+func (c *tracingCore) Scan(prefix []byte) Iterator {
+	_, span := c.start(context.Background(), "Scan", AttrKeySize.Int(len(prefix)))
+	return &tracingIterator{it: c.core.Scan(prefix), span: span}
+}
+
+func (c *tracingCore) ReverseScan(prefix []byte) Iterator {
+	_, span := c.start(context.Background(), "ReverseScan", AttrKeySize.Int(len(prefix)))
+	return &tracingIterator{it: c.core.ReverseScan(prefix), span: span}
+}
+
+func (c *tracingCore) RangeScan(start, end []byte) Iterator {
+	_, span := c.start(context.Background(), "RangeScan")
+	return &tracingIterator{it: c.core.RangeScan(start, end), span: span}
+}
+
+func (c *tracingCore) ScanFrom(prefix, start []byte) Iterator {
+	_, span := c.start(context.Background(), "ScanFrom", AttrKeySize.Int(len(prefix)))
+	return &tracingIterator{it: c.core.ScanFrom(prefix, start), span: span}
+}
+
+func (c *tracingCore) ScanKeys(prefix []byte) Iterator {
+	_, span := c.start(context.Background(), "ScanKeys", AttrKeySize.Int(len(prefix)))
+	return &tracingIterator{it: c.core.ScanKeys(prefix), span: span}
+}
+
+func (c *tracingCore) ScanContext(ctx context.Context, prefix []byte) Iterator {
+	ctx, span := c.start(ctx, "ScanContext", AttrKeySize.Int(len(prefix)))
+	return &tracingIterator{it: c.core.ScanContext(ctx, prefix), span: span}
+}
+
+func (c *tracingCore) Watch(ctx context.Context, prefix []byte) (<-chan Event, error) {
+	return c.core.Watch(ctx, prefix)
+}
+
+func (c *tracingCore) Close() error {
+	return c.core.Close()
+}
+
+// Unwrap returns the wrapped Core.
+func (c *tracingCore) Unwrap() Core {
+	return c.core
+}
+
+func totalSize(bufs [][]byte) int {
+	n := 0
+	for _, b := range bufs {
+		n += len(b)
+	}
+	return n
+}
+
+// tracingIterator counts the entries it yields and ends its span on the
+// first Release.
+type tracingIterator struct {
+	it      Iterator
+	span    trace.Span
+	entries int
+	ended   bool
+}
+
+func (it *tracingIterator) Next() bool {
+	ok := it.it.Next()
+	if ok {
+		it.entries++
+	}
+	return ok
+}
+
+func (it *tracingIterator) Seek(key []byte) bool {
+	ok := it.it.Seek(key)
+	if ok {
+		it.entries++
+	}
+	return ok
+}
+
+func (it *tracingIterator) First() bool {
+	ok := it.it.First()
+	if ok {
+		it.entries++
+	}
+	return ok
+}
+
+func (it *tracingIterator) Last() bool {
+	ok := it.it.Last()
+	if ok {
+		it.entries++
+	}
+	return ok
+}
+
+func (it *tracingIterator) Valid() bool   { return it.it.Valid() }
+func (it *tracingIterator) Key() []byte   { return it.it.Key() }
+func (it *tracingIterator) Value() []byte { return it.it.Value() }
+func (it *tracingIterator) Error() error  { return it.it.Error() }
+
+func (it *tracingIterator) Release() {
+	if !it.ended {
+		it.ended = true
+		it.span.SetAttributes(AttrEntries.Int(it.entries))
+		endSpan(it.span, it.it.Error())
+	}
+	it.it.Release()
+}
+
+// tracingBatch tallies staged writes and, on Commit, records a span that
+// starts when the batch was created and nests under the Commit context.
+type tracingBatch struct {
+	batch      Batch
+	c          *tracingCore
+	created    time.Time
+	keys       int
+	keyBytes   int
+	valueBytes int
+}
+
+func (b *tracingBatch) Put(key []byte, data []byte) error {
+	b.keys++
+	b.keyBytes += len(key)
+	b.valueBytes += len(data)
+	return b.batch.Put(key, data)
+}
+
+func (b *tracingBatch) Delete(key []byte) error {
+	b.keys++
+	b.keyBytes += len(key)
+	return b.batch.Delete(key)
+}
+
+func (b *tracingBatch) PutIfAbsent(key, data []byte) error {
+	b.keys++
+	b.keyBytes += len(key)
+	b.valueBytes += len(data)
+	return b.batch.PutIfAbsent(key, data)
+}
+
+func (b *tracingBatch) PutCtx(ctx context.Context, key []byte, data []byte) error {
+	b.keys++
+	b.keyBytes += len(key)
+	b.valueBytes += len(data)
+	if cb, ok := b.batch.(ContextBatch); ok {
+		return cb.PutCtx(ctx, key, data)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return b.batch.Put(key, data)
+}
+
+func (b *tracingBatch) DeleteCtx(ctx context.Context, key []byte) error {
+	b.keys++
+	b.keyBytes += len(key)
+	if cb, ok := b.batch.(ContextBatch); ok {
+		return cb.DeleteCtx(ctx, key)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return b.batch.Delete(key)
+}
+
+func (b *tracingBatch) Len() int  { return b.batch.Len() }
+func (b *tracingBatch) Size() int { return b.batch.Size() }
+
+// Reset also restarts the batch span's clock and counts.
+func (b *tracingBatch) Reset() {
+	b.batch.Reset()
+	b.created = time.Now()
+	b.keys, b.keyBytes, b.valueBytes = 0, 0, 0
+}
+
+func (b *tracingBatch) Validate() error {
+	return b.batch.Validate()
+}
+
+func (b *tracingBatch) Commit(ctx context.Context) error {
+	ctx, span := b.c.tracer.Start(ctx, "zerokv.Batch", trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithTimestamp(b.created),
+		trace.WithAttributes(b.c.backend, AttrKeys.Int(b.keys),
+			AttrKeySize.Int(b.keyBytes), AttrValueSize.Int(b.valueBytes)))
+	err := b.batch.Commit(ctx)
+	endSpan(span, err)
+	return err
+}
+
+// tracingTxn traces Commit with the transaction's staged key count.
+type tracingTxn struct {
+	txn  Txn
+	c    *tracingCore
+	keys int
+}
+
+func (t *tracingTxn) Get(ctx context.Context, key []byte) ([]byte, error) {
+	return t.txn.Get(ctx, key)
+}
+
+func (t *tracingTxn) Put(ctx context.Context, key []byte, data []byte) error {
+	t.keys++
+	return t.txn.Put(ctx, key, data)
+}
+
+func (t *tracingTxn) Delete(ctx context.Context, key []byte) error {
+	t.keys++
+	return t.txn.Delete(ctx, key)
+}
+
+func (t *tracingTxn) Discard() { t.txn.Discard() }
+
+func (t *tracingTxn) Commit(ctx context.Context) error {
+	ctx, span := t.c.start(ctx, "Txn.Commit", AttrKeys.Int(t.keys))
+	err := t.txn.Commit(ctx)
+	endSpan(span, err)
+	return err
+}
@@ -0,0 +1,201 @@
+package zerokv
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// SizeLimits caps the keys and values a backend accepts, so an oversized
+// write fails with a typed error before it reaches the engine.
+type SizeLimits struct {
+	MaxKeySize   int // longest key in bytes; zero or less means unlimited
+	MaxValueSize int // longest value in bytes; zero or less means unlimited
+}
+
+// Check returns an error matching ErrKeyTooLarge or ErrValueTooLarge if key
+// or value breaks l, checking the key first.
+func (l SizeLimits) Check(key, value []byte) error {
+	if l.MaxKeySize > 0 && len(key) > l.MaxKeySize {
+		return fmt.Errorf("%w: %d bytes exceeds the %d byte limit", ErrKeyTooLarge, len(key), l.MaxKeySize)
+	}
+	if l.MaxValueSize > 0 && len(value) > l.MaxValueSize {
+		return fmt.Errorf("%w: %d bytes exceeds the %d byte limit", ErrValueTooLarge, len(value), l.MaxValueSize)
+	}
+	return nil
+}
+
+// CheckAll runs Check on each pair of the equal-length keys and values,
+// returning the first error.
+func (l SizeLimits) CheckAll(keys, values [][]byte) error {
+	for i := range keys {
+		if err := l.Check(keys[i], values[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var errEmptyKey = errors.New("empty key")
+
+// BatchLimits are the constraints Batch.Validate checks staged operations
+// against. The zero value only rejects empty keys.
+type BatchLimits struct {
+	MaxKeySize    int  // longest key in bytes; zero or less means unlimited
+	MaxValueSize  int  // longest value in bytes; zero or less means unlimited
+	AllowEmptyKey bool // accept zero-length keys
+}
+
+// check returns why an operation with key and value breaks l, or nil.
+func (l BatchLimits) check(key, value []byte) error {
+	if len(key) == 0 && !l.AllowEmptyKey {
+		return errEmptyKey
+	}
+	return SizeLimits{MaxKeySize: l.MaxKeySize, MaxValueSize: l.MaxValueSize}.Check(key, value)
+}
+
+// BatchOption sets one of the BatchLimits of a batch created by NewBatch.
+type BatchOption func(*BatchLimits)
+
+// WithMaxKeySize rejects keys longer than n bytes.
+func WithMaxKeySize(n int) BatchOption {
+	return func(l *BatchLimits) {
+		l.MaxKeySize = n
+	}
+}
+
+// WithMaxValueSize rejects values longer than n bytes.
+func WithMaxValueSize(n int) BatchOption {
+	return func(l *BatchLimits) {
+		l.MaxValueSize = n
+	}
+}
+
+// WithEmptyKeys accepts zero-length keys, which some backends can store.
+func WithEmptyKeys() BatchOption {
+	return func(l *BatchLimits) {
+		l.AllowEmptyKey = true
+	}
+}
+
+// BatchValidator checks the operations staged on a batch against its
+// Limits and remembers the first one that breaks them; a size violation
+// also matches ErrKeyTooLarge or ErrValueTooLarge. Batch
+// implementations call Put and Delete as they stage operations, return Err
+// from Validate, and call Reset once the batch is committed or reset. Every
+// bundled backend's batch keeps one with the limits of its engine, and
+// NewBatch adds one with the caller's. The zero value enforces the zero
+// BatchLimits.
+type BatchValidator struct {
+	Limits BatchLimits
+	ops    int
+	err    error
+}
+
+// Put checks a staged put.
+func (v *BatchValidator) Put(key, value []byte) {
+	v.check(key, value)
+}
+
+// Delete checks a staged delete; only the key is constrained.
+func (v *BatchValidator) Delete(key []byte) {
+	v.check(key, nil)
+}
+
+func (v *BatchValidator) check(key, value []byte) {
+	if v.err == nil {
+		if err := v.Limits.check(key, value); err != nil {
+			v.err = fmt.Errorf("%w: operation %d: %w", ErrInvalidBatch, v.ops, err)
+		}
+	}
+	v.ops++
+}
+
+// Err returns the first violation since the last Reset, or nil.
+func (v *BatchValidator) Err() error {
+	return v.err
+}
+
+// Reset forgets the operations checked so far.
+func (v *BatchValidator) Reset() {
+	v.ops, v.err = 0, nil
+}
+
+// limitedBatch adds caller-chosen BatchLimits to another Batch.
+type limitedBatch struct {
+	batch Batch
+	check BatchValidator
+}
+
+// NewBatch returns core.Batch() with Validate checking staged operations
+// against the limits set by opts, in addition to the batch's own checks.
+// Validate is a dry run: it writes nothing, and Commit does not call it, so
+// call it before an expensive commit to catch bad data early.
+func NewBatch(core Core, opts ...BatchOption) Batch {
+	b := &limitedBatch{batch: core.Batch()}
+	for _, opt := range opts {
+		opt(&b.check.Limits)
+	}
+	return b
+}
+
+func (b *limitedBatch) Put(key []byte, data []byte) error {
+	if err := b.batch.Put(key, data); err != nil {
+		return err
+	}
+	b.check.Put(key, data)
+	return nil
+}
+
+func (b *limitedBatch) Delete(key []byte) error {
+	if err := b.batch.Delete(key); err != nil {
+		return err
+	}
+	b.check.Delete(key)
+	return nil
+}
+
+func (b *limitedBatch) PutIfAbsent(key, data []byte) error {
+	if err := b.batch.PutIfAbsent(key, data); err != nil {
+		return err
+	}
+	b.check.Put(key, data)
+	return nil
+}
+
+func (b *limitedBatch) PutCtx(ctx context.Context, key []byte, data []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return b.Put(key, data)
+}
+
+func (b *limitedBatch) DeleteCtx(ctx context.Context, key []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return b.Delete(key)
+}
+
+func (b *limitedBatch) Len() int  { return b.batch.Len() }
+func (b *limitedBatch) Size() int { return b.batch.Size() }
+
+func (b *limitedBatch) Validate() error {
+	if err := b.check.Err(); err != nil {
+		return err
+	}
+	return b.batch.Validate()
+}
+
+func (b *limitedBatch) Commit(ctx context.Context) error {
+	if err := b.batch.Commit(ctx); err != nil {
+		return err
+	}
+	b.check.Reset()
+	return nil
+}
+
+func (b *limitedBatch) Reset() {
+	b.batch.Reset()
+	b.check.Reset()
+}
@@ -0,0 +1,225 @@
+package zerokv
+
+import (
+	"bytes"
+	"context"
+	"sync"
+)
+
+// Op is the kind of change an Event reports.
+type Op uint8
+
+const (
+	// OpPut reports a key set to Event.Value.
+	OpPut Op = iota + 1
+	// OpDelete reports a single key removed.
+	OpDelete
+	// OpDeleteRange reports every key in [Event.Key, Event.End) removed, as
+	// done by DeletePrefix and DeleteRange. A nil End means no upper bound.
+	OpDeleteRange
+)
+
+// String returns the name of the operation.
+func (o Op) String() string {
+	switch o {
+	case OpPut:
+		return "put"
+	case OpDelete:
+		return "delete"
+	case OpDeleteRange:
+		return "delete-range"
+	default:
+		return "unknown"
+	}
+}
+
+// Event describes one committed change delivered by Core.Watch. Key, Value
+// and End are shared between watchers and must not be modified.
+type Event struct {
+	Op    Op
+	Key   []byte
+	Value []byte // the new value for OpPut, nil otherwise
+	End   []byte // the exclusive end of an OpDeleteRange
+}
+
+// PutEvents returns one OpPut event per key and value pair, or nil when
+// the slices differ in length.
+func PutEvents(keys, values [][]byte) []Event {
+	if len(keys) != len(values) {
+		return nil
+	}
+	events := make([]Event, len(keys))
+	for i := range keys {
+		events[i] = Event{Op: OpPut, Key: keys[i], Value: values[i]}
+	}
+	return events
+}
+
+// DeletePrefixEvent returns the OpDeleteRange event for removing every key
+// with the given prefix.
+func DeletePrefixEvent(prefix []byte) Event {
+	return Event{Op: OpDeleteRange, Key: prefix, End: PrefixUpperBound(prefix)}
+}
+
+// Watchers fans committed changes out to the channels returned by Watch.
+// Backends embed one, Publish after every successful write and Close it when
+// the database closes. The zero value is ready to use.
+//
+// Publish never blocks: each watcher has its own unbounded queue, drained
+// into its channel by one goroutine, so a slow reader uses memory rather
+// than stalling writers.
+type Watchers struct {
+	mu     sync.Mutex
+	subs   map[*watcher]struct{}
+	closed bool
+}
+
+// watcher is one Watch registration.
+type watcher struct {
+	prefix []byte
+	end    []byte // upper bound of prefix, nil if unbounded
+	out    chan Event
+	wake   chan struct{}
+	done   chan struct{}
+
+	mu    sync.Mutex
+	queue []Event
+}
+
+// Watch registers a watcher for keys with the given prefix. The returned
+// channel is closed once ctx is done or Close is called; events still
+// queued at that point are dropped.
+func (w *Watchers) Watch(ctx context.Context, prefix []byte) <-chan Event {
+	s := &watcher{
+		prefix: bytes.Clone(prefix),
+		end:    PrefixUpperBound(prefix),
+		out:    make(chan Event),
+		wake:   make(chan struct{}, 1),
+		done:   make(chan struct{}),
+	}
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		close(s.out)
+		return s.out
+	}
+	if w.subs == nil {
+		w.subs = make(map[*watcher]struct{})
+	}
+	w.subs[s] = struct{}{}
+	w.mu.Unlock()
+	go w.run(ctx, s)
+	return s.out
+}
+
+// Active reports whether anyone is watching, so writers can skip building
+// events that nobody would receive.
+func (w *Watchers) Active() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return len(w.subs) > 0
+}
+
+// Publish queues events for every watcher whose prefix they touch. Events
+// are copied, so callers may reuse their buffers afterwards.
+func (w *Watchers) Publish(events ...Event) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(w.subs) == 0 {
+		return
+	}
+	for _, ev := range events {
+		copied := false
+		for s := range w.subs {
+			if !s.matches(ev) {
+				continue
+			}
+			if !copied {
+				ev = Event{Op: ev.Op, Key: bytes.Clone(ev.Key), Value: bytes.Clone(ev.Value), End: bytes.Clone(ev.End)}
+				copied = true
+			}
+			s.push(ev)
+		}
+	}
+}
+
+// Record appends a copy of ev to events if anyone is watching, and returns
+// events unchanged otherwise. Batches and transactions use it to collect the
+// events they Publish once they commit.
+func (w *Watchers) Record(events []Event, ev Event) []Event {
+	if !w.Active() {
+		return events
+	}
+	return append(events, Event{Op: ev.Op, Key: bytes.Clone(ev.Key), Value: bytes.Clone(ev.Value), End: bytes.Clone(ev.End)})
+}
+
+// Close closes every watcher channel. Later calls to Watch return a closed
+// channel.
+func (w *Watchers) Close() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return
+	}
+	w.closed = true
+	for s := range w.subs {
+		close(s.done)
+		delete(w.subs, s)
+	}
+}
+
+// run forwards queued events to s.out until ctx is done or the watcher is
+// closed, then unregisters s and closes its channel.
+func (w *Watchers) run(ctx context.Context, s *watcher) {
+	defer close(s.out)
+	defer func() {
+		w.mu.Lock()
+		delete(w.subs, s)
+		w.mu.Unlock()
+	}()
+	for {
+		s.mu.Lock()
+		if len(s.queue) == 0 {
+			s.mu.Unlock()
+			select {
+			case <-s.wake:
+				continue
+			case <-ctx.Done():
+				return
+			case <-s.done:
+				return
+			}
+		}
+		ev := s.queue[0]
+		s.queue[0] = Event{}
+		s.queue = s.queue[1:]
+		s.mu.Unlock()
+		select {
+		case s.out <- ev:
+		case <-ctx.Done():
+			return
+		case <-s.done:
+			return
+		}
+	}
+}
+
+func (s *watcher) push(ev Event) {
+	s.mu.Lock()
+	s.queue = append(s.queue, ev)
+	s.mu.Unlock()
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// matches reports whether ev touches a key under s.prefix.
+func (s *watcher) matches(ev Event) bool {
+	if ev.Op != OpDeleteRange {
+		return bytes.HasPrefix(ev.Key, s.prefix)
+	}
+	// [ev.Key, ev.End) overlaps [s.prefix, s.end)
+	return (s.end == nil || bytes.Compare(ev.Key, s.end) < 0) &&
+		(ev.End == nil || bytes.Compare(ev.End, s.prefix) > 0)
+}
@@ -0,0 +1,438 @@
+package zerokvgrpc
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/rawbytedev/zerokv"
+	"github.com/rawbytedev/zerokv/zerokvgrpc/zerokvpb"
+)
+
+// Client is a zerokv.Core backed by a remote KV service.
+//
+// Get, Put, Delete, Scan, ScanKeys and ScanContext are single RPCs. Has,
+// GetOrDefault and GetMany are built from Get; GetMany issues one RPC per
+// key, so it does not read from a single snapshot. The service has no RPCs
+// for the rest of Core, which return an error wrapping
+// zerokv.ErrNotSupported; batches return it from every method, and reverse
+// and range iterators report it from Error.
+type Client struct {
+	kv zerokvpb.KVClient
+}
+
+// NewClient returns a Core that sends its operations over cc. Close does
+// not close cc, which belongs to the caller.
+func NewClient(cc grpc.ClientConnInterface) *Client {
+	return &Client{kv: zerokvpb.NewKVClient(cc)}
+}
+
+var _ zerokv.Core = (*Client)(nil)
+
+func notSupported(op string) error {
+	return fmt.Errorf("zerokvgrpc: %s: %w", op, zerokv.ErrNotSupported)
+}
+
+// fromStatus maps a gRPC status back to the zerokv error it stands for.
+func fromStatus(err error) error {
+	if err == nil {
+		return nil
+	}
+	st, ok := status.FromError(err)
+	if !ok {
+		return err
+	}
+	switch st.Code() {
+	case codes.NotFound:
+		return zerokv.KeyNotFound(err)
+	case codes.FailedPrecondition:
+		return fmt.Errorf("%w: %s", zerokv.ErrReadOnly, st.Message())
+	case codes.Unimplemented:
+		return fmt.Errorf("%w: %s", zerokv.ErrNotSupported, st.Message())
+	case codes.Canceled:
+		return fmt.Errorf("%w: %s", context.Canceled, st.Message())
+	case codes.DeadlineExceeded:
+		return fmt.Errorf("%w: %s", context.DeadlineExceeded, st.Message())
+	default:
+		return err
+	}
+}
+
+// Put stores data under key on the server.
+func (c *Client) Put(ctx context.Context, key []byte, data []byte) error {
+	_, err := c.kv.Put(ctx, &zerokvpb.PutRequest{Key: key, Value: data})
+	return fromStatus(err)
+}
+
+// PutWithTTL is not supported by the service.
+func (c *Client) PutWithTTL(ctx context.Context, key, value []byte, ttl time.Duration) error {
+	return notSupported("PutWithTTL")
+}
+
+// Get returns the value of key from the server.
+func (c *Client) Get(ctx context.Context, key []byte) ([]byte, error) {
+	resp, err := c.kv.Get(ctx, &zerokvpb.GetRequest{Key: key})
+	if err != nil {
+		return nil, fromStatus(err)
+	}
+	if resp.Value == nil {
+		return []byte{}, nil
+	}
+	return resp.Value, nil
+}
+
+// GetOrDefault returns a copy of def if Get reports the key missing.
+func (c *Client) GetOrDefault(ctx context.Context, key, def []byte) ([]byte, error) {
+	value, err := c.Get(ctx, key)
+	if errors.Is(err, zerokv.ErrKeyNotFound) {
+		return bytes.Clone(def), nil
+	}
+	return value, err
+}
+
+// GetInto copies the value fetched by Get into dst. The RPC allocates the
+// value anyway, so this saves no allocation.
+func (c *Client) GetInto(ctx context.Context, key, dst []byte) ([]byte, error) {
+	value, err := c.Get(ctx, key)
+	if err != nil {
+		return dst[:0], err
+	}
+	return append(dst[:0], value...), nil
+}
+
+// View calls fn with the value fetched by Get.
+func (c *Client) View(ctx context.Context, key []byte, fn func(value []byte) error) error {
+	value, err := c.Get(ctx, key)
+	if err != nil {
+		return err
+	}
+	return fn(value)
+}
+
+// Has reports whether key exists, using Get.
+func (c *Client) Has(ctx context.Context, key []byte) (bool, error) {
+	_, err := c.Get(ctx, key)
+	if errors.Is(err, zerokv.ErrKeyNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// SizeOf fetches the value and returns its length; the service has no call
+// that reports a size alone.
+func (c *Client) SizeOf(ctx context.Context, key []byte) (int, error) {
+	val, err := c.Get(ctx, key)
+	if err != nil {
+		return 0, err
+	}
+	return len(val), nil
+}
+
+// GetMany calls Get once per key; missing keys yield nil.
+func (c *Client) GetMany(ctx context.Context, keys [][]byte) ([][]byte, error) {
+	values := make([][]byte, len(keys))
+	for i, key := range keys {
+		value, err := c.Get(ctx, key)
+		if errors.Is(err, zerokv.ErrKeyNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		values[i] = value
+	}
+	return values, nil
+}
+
+// HasMany calls Has once per key, so the answers do not come from one
+// consistent read.
+func (c *Client) HasMany(ctx context.Context, keys [][]byte) ([]bool, error) {
+	found := make([]bool, len(keys))
+	for i, key := range keys {
+		ok, err := c.Has(ctx, key)
+		if err != nil {
+			return nil, err
+		}
+		found[i] = ok
+	}
+	return found, nil
+}
+
+// PutMany is not supported: the service cannot write several keys atomically.
+func (c *Client) PutMany(ctx context.Context, keys, values [][]byte) error {
+	return notSupported("PutMany")
+}
+
+// Delete removes key on the server.
+func (c *Client) Delete(ctx context.Context, key []byte) error {
+	_, err := c.kv.Delete(ctx, &zerokvpb.DeleteRequest{Key: key})
+	return fromStatus(err)
+}
+
+// DeletePrefix is not supported by the service.
+func (c *Client) DeletePrefix(ctx context.Context, prefix []byte) (int, error) {
+	return 0, notSupported("DeletePrefix")
+}
+
+// DeleteRange is not supported by the service.
+func (c *Client) DeleteRange(ctx context.Context, start, end []byte) error {
+	return notSupported("DeleteRange")
+}
+
+// Truncate is not supported by the service.
+func (c *Client) Truncate(ctx context.Context) error {
+	return notSupported("Truncate")
+}
+
+// TruncatePrefix is not supported by the service.
+func (c *Client) TruncatePrefix(ctx context.Context, prefix []byte) error {
+	return notSupported("TruncatePrefix")
+}
+
+// Count is not supported by the service.
+func (c *Client) Count(ctx context.Context, prefix []byte) (int64, error) {
+	return 0, notSupported("Count")
+}
+
+// EstimateSize is not supported by the service.
+func (c *Client) EstimateSize(prefix []byte) (int64, error) {
+	return 0, notSupported("EstimateSize")
+}
+
+// NewTransaction is not supported by the service.
+func (c *Client) NewTransaction(ctx context.Context) (zerokv.Txn, error) {
+	return nil, notSupported("NewTransaction")
+}
+
+// CompareAndSwap is not supported by the service.
+func (c *Client) CompareAndSwap(ctx context.Context, key, old, new []byte) (bool, error) {
+	return false, notSupported("CompareAndSwap")
+}
+
+// Increment is not supported by the service.
+func (c *Client) Increment(ctx context.Context, key []byte, delta int64) (int64, error) {
+	return 0, notSupported("Increment")
+}
+
+// GetOrPut is not supported by the service.
+func (c *Client) GetOrPut(ctx context.Context, key []byte, fill func() ([]byte, error)) ([]byte, error) {
+	return nil, notSupported("GetOrPut")
+}
+
+// Merge is not supported by the service.
+func (c *Client) Merge(ctx context.Context, key, operand []byte, merge func(existing, operand []byte) []byte) error {
+	return notSupported("Merge")
+}
+
+// Stats is not supported by the service.
+func (c *Client) Stats(ctx context.Context) (zerokv.Stats, error) {
+	return zerokv.Stats{}, notSupported("Stats")
+}
+
+// Sync is not supported by the service.
+func (c *Client) Sync(ctx context.Context) error {
+	return notSupported("Sync")
+}
+
+// Compact is not supported by the service.
+func (c *Client) Compact(ctx context.Context, start, end []byte) error {
+	return notSupported("Compact")
+}
+
+// Ping is not supported by the service; use the gRPC health service to
+// check the server.
+func (c *Client) Ping(ctx context.Context) error {
+	return notSupported("Ping")
+}
+
+// Backup is not supported by the service.
+func (c *Client) Backup(ctx context.Context, w io.Writer) error {
+	return notSupported("Backup")
+}
+
+// Restore is not supported by the service.
+func (c *Client) Restore(ctx context.Context, r io.Reader) error {
+	return notSupported("Restore")
+}
+
+// Checkpoint is not supported by the service.
+func (c *Client) Checkpoint(ctx context.Context, destDir string) error {
+	return notSupported("Checkpoint")
+}
+
+// Snapshot is not supported by the service.
+func (c *Client) Snapshot() (zerokv.Snapshot, error) {
+	return nil, notSupported("Snapshot")
+}
+
+// Batch returns a batch whose every method fails, because the service
+// cannot apply several writes atomically.
+func (c *Client) Batch() zerokv.Batch {
+	return unsupportedBatch{}
+}
+
+// BulkLoad is not supported: the service has no batch RPC.
+func (c *Client) BulkLoad(ctx context.Context, fn func(zerokv.Batch) error) error {
+	return notSupported("BulkLoad")
+}
+
+// BatchWithOptions returns a batch whose every method fails, like Batch.
+func (c *Client) BatchWithOptions(maxOps, maxBytes int) zerokv.Batch {
+	return c.Batch()
+}
+
+// Scan streams every pair under prefix from the server.
+func (c *Client) Scan(prefix []byte) zerokv.Iterator {
+	return c.scan(context.Background(), &zerokvpb.ScanRequest{Prefix: prefix})
+}
+
+// ReverseScan is not supported by the service; the iterator is empty and
+// reports the error from Error.
+func (c *Client) ReverseScan(prefix []byte) zerokv.Iterator {
+	return &streamIterator{err: notSupported("ReverseScan")}
+}
+
+// RangeScan is not supported by the service; the iterator is empty and
+// reports the error from Error.
+func (c *Client) RangeScan(start, end []byte) zerokv.Iterator {
+	return &streamIterator{err: notSupported("RangeScan")}
+}
+
+// ScanFrom is not supported by the service; the iterator is empty and
+// reports the error from Error.
+func (c *Client) ScanFrom(prefix, start []byte) zerokv.Iterator {
+	return &streamIterator{err: notSupported("ScanFrom")}
+}
+
+// ScanKeys streams the keys under prefix; the server leaves values out.
+func (c *Client) ScanKeys(prefix []byte) zerokv.Iterator {
+	return c.scan(context.Background(), &zerokvpb.ScanRequest{Prefix: prefix, KeysOnly: true})
+}
+
+// ScanContext is Scan with the stream bound to ctx, so its deadline
+// reaches the server.
+func (c *Client) ScanContext(ctx context.Context, prefix []byte) zerokv.Iterator {
+	return zerokv.NewContextIterator(ctx, c.scan(ctx, &zerokvpb.ScanRequest{Prefix: prefix}))
+}
+
+// Watch is not supported by the service.
+func (c *Client) Watch(ctx context.Context, prefix []byte) (<-chan zerokv.Event, error) {
+	return nil, notSupported("Watch")
+}
+
+// Close does nothing; the connection is closed by its owner.
+func (c *Client) Close() error {
+	return nil
+}
+
+func (c *Client) scan(ctx context.Context, req *zerokvpb.ScanRequest) zerokv.Iterator {
+	ctx, cancel := context.WithCancel(ctx)
+	stream, err := c.kv.Scan(ctx, req)
+	if err != nil {
+		cancel()
+		return &streamIterator{err: fromStatus(err)}
+	}
+	return &streamIterator{stream: stream, cancel: cancel, keysOnly: req.KeysOnly}
+}
+
+// streamIterator reads a Scan stream. Releasing it cancels the stream.
+type streamIterator struct {
+	stream   grpc.ServerStreamingClient[zerokvpb.KeyValue]
+	cancel   context.CancelFunc
+	keysOnly bool
+	cur      *zerokvpb.KeyValue
+	err      error
+}
+
+func (it *streamIterator) Next() bool {
+	if it.stream == nil || it.err != nil {
+		return false
+	}
+	kv, err := it.stream.Recv()
+	if err != nil {
+		if !errors.Is(err, io.EOF) {
+			it.err = fromStatus(err)
+		}
+		it.Release()
+		return false
+	}
+	it.cur = kv
+	return true
+}
+
+// Seek is not supported on a stream; it ends the iteration with an error.
+func (it *streamIterator) Seek(key []byte) bool {
+	return it.unsupported("Iterator.Seek")
+}
+
+// First is not supported on a stream; it ends the iteration with an error.
+func (it *streamIterator) First() bool {
+	return it.unsupported("Iterator.First")
+}
+
+// Last is not supported on a stream; it ends the iteration with an error.
+func (it *streamIterator) Last() bool {
+	return it.unsupported("Iterator.Last")
+}
+
+func (it *streamIterator) unsupported(op string) bool {
+	if it.err == nil {
+		it.err = notSupported(op)
+	}
+	it.Release()
+	return false
+}
+
+// Valid reports whether an entry has been received and the stream is still
+// open.
+func (it *streamIterator) Valid() bool {
+	return it.cur != nil
+}
+
+func (it *streamIterator) Key() []byte {
+	if it.cur == nil {
+		return nil
+	}
+	return it.cur.Key
+}
+
+func (it *streamIterator) Value() []byte {
+	if it.cur == nil || it.keysOnly {
+		return nil
+	}
+	if it.cur.Value == nil {
+		return []byte{}
+	}
+	return it.cur.Value
+}
+
+func (it *streamIterator) Release() {
+	if it.cancel != nil {
+		it.cancel()
+	}
+	it.stream = nil
+	it.cur = nil
+}
+
+func (it *streamIterator) Error() error {
+	return it.err
+}
+
+// unsupportedBatch rejects every operation.
+type unsupportedBatch struct{}
+
+func (unsupportedBatch) Put(key []byte, data []byte) error  { return notSupported("Batch") }
+func (unsupportedBatch) Delete(key []byte) error            { return notSupported("Batch") }
+func (unsupportedBatch) PutIfAbsent(key, data []byte) error { return notSupported("Batch") }
+func (unsupportedBatch) Commit(ctx context.Context) error   { return notSupported("Batch") }
+func (unsupportedBatch) Len() int                           { return 0 }
+func (unsupportedBatch) Size() int                          { return 0 }
+func (unsupportedBatch) Reset()                             {}
+func (unsupportedBatch) Validate() error                    { return nil }
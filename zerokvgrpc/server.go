@@ -0,0 +1,102 @@
+// Package zerokvgrpc serves a zerokv.Core over gRPC and provides a client
+// that uses such a server as a zerokv.Core.
+//
+// The service, defined in zerokvpb/zerokv.proto, covers Get, Put, Delete and
+// a server-streaming Scan. Deadlines and cancellation of an RPC reach the
+// Core call on the server through the handler's context.
+package zerokvgrpc
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative zerokvpb/zerokv.proto
+
+import (
+	"context"
+	"errors"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/rawbytedev/zerokv"
+	"github.com/rawbytedev/zerokv/zerokvgrpc/zerokvpb"
+)
+
+// Server implements zerokvpb.KVServer on top of a Core.
+type Server struct {
+	zerokvpb.UnimplementedKVServer
+	core zerokv.Core
+}
+
+// NewServer returns a KV service backed by core. Register it with
+// zerokvpb.RegisterKVServer, or use Register.
+func NewServer(core zerokv.Core) *Server {
+	return &Server{core: core}
+}
+
+// Register registers a KV service backed by core on s.
+func Register(s grpc.ServiceRegistrar, core zerokv.Core) {
+	zerokvpb.RegisterKVServer(s, NewServer(core))
+}
+
+// Get returns the value of req.Key, or codes.NotFound.
+func (s *Server) Get(ctx context.Context, req *zerokvpb.GetRequest) (*zerokvpb.GetResponse, error) {
+	value, err := s.core.Get(ctx, req.GetKey())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &zerokvpb.GetResponse{Value: value}, nil
+}
+
+// Put stores req.Value under req.Key.
+func (s *Server) Put(ctx context.Context, req *zerokvpb.PutRequest) (*zerokvpb.PutResponse, error) {
+	if err := s.core.Put(ctx, req.GetKey(), req.GetValue()); err != nil {
+		return nil, toStatus(err)
+	}
+	return &zerokvpb.PutResponse{}, nil
+}
+
+// Delete removes req.Key.
+func (s *Server) Delete(ctx context.Context, req *zerokvpb.DeleteRequest) (*zerokvpb.DeleteResponse, error) {
+	if err := s.core.Delete(ctx, req.GetKey()); err != nil {
+		return nil, toStatus(err)
+	}
+	return &zerokvpb.DeleteResponse{}, nil
+}
+
+// Scan streams one KeyValue per key under req.Prefix. The iterator stops
+// as soon as the client cancels or its deadline passes.
+func (s *Server) Scan(req *zerokvpb.ScanRequest, stream grpc.ServerStreamingServer[zerokvpb.KeyValue]) error {
+	ctx := stream.Context()
+	var it zerokv.Iterator
+	if req.GetKeysOnly() {
+		it = zerokv.NewContextIterator(ctx, s.core.ScanKeys(req.GetPrefix()))
+	} else {
+		it = s.core.ScanContext(ctx, req.GetPrefix())
+	}
+	defer it.Release()
+	for it.Next() {
+		if err := stream.Send(&zerokvpb.KeyValue{Key: it.Key(), Value: it.Value()}); err != nil {
+			return err
+		}
+	}
+	if err := it.Error(); err != nil {
+		return toStatus(err)
+	}
+	return nil
+}
+
+// toStatus maps errors from the store to gRPC status codes that the client
+// maps back with fromStatus.
+func toStatus(err error) error {
+	switch {
+	case errors.Is(err, zerokv.ErrKeyNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, zerokv.ErrReadOnly):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, zerokv.ErrNotSupported):
+		return status.Error(codes.Unimplemented, err.Error())
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return status.FromContextError(err).Err()
+	default:
+		return status.Error(codes.Internal, err.Error())
+	}
+}
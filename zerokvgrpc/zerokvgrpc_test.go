@@ -0,0 +1,147 @@
+package zerokvgrpc_test
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/rawbytedev/zerokv"
+	"github.com/rawbytedev/zerokv/helpers"
+	"github.com/rawbytedev/zerokv/zerokvgrpc"
+	"github.com/rawbytedev/zerokv/zerokvgrpc/zerokvpb"
+	"github.com/stretchr/testify/require"
+)
+
+// serve starts a KV server for core on an in-memory listener and returns
+// a connection to it.
+func serve(t *testing.T, core zerokv.Core) *grpc.ClientConn {
+	t.Helper()
+	lis := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer()
+	zerokvgrpc.Register(srv, core)
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// TestClientCRUD tests that the client round-trips writes through the
+// server and maps missing keys back to zerokv.ErrKeyNotFound.
+func TestClientCRUD(t *testing.T) {
+	db := helpers.SetupDB(t, "memdb")
+	defer db.Close()
+	client := zerokvgrpc.NewClient(serve(t, db))
+
+	require.NoError(t, client.Put(t.Context(), []byte("key"), []byte("value")))
+	value, err := db.Get(t.Context(), []byte("key"))
+	require.NoError(t, err)
+	require.Equal(t, []byte("value"), value)
+	value, err = client.Get(t.Context(), []byte("key"))
+	require.NoError(t, err)
+	require.Equal(t, []byte("value"), value)
+
+	require.NoError(t, client.Put(t.Context(), []byte("empty"), nil))
+	value, err = client.Get(t.Context(), []byte("empty"))
+	require.NoError(t, err)
+	require.NotNil(t, value, "An empty value should not look missing")
+
+	require.NoError(t, client.Delete(t.Context(), []byte("key")))
+	_, err = client.Get(t.Context(), []byte("key"))
+	require.ErrorIs(t, err, zerokv.ErrKeyNotFound)
+	ok, err := client.Has(t.Context(), []byte("key"))
+	require.NoError(t, err)
+	require.False(t, ok)
+	values, err := client.GetMany(t.Context(), [][]byte{[]byte("key"), []byte("empty")})
+	require.NoError(t, err)
+	require.Nil(t, values[0])
+	require.Equal(t, []byte{}, values[1])
+}
+
+// TestClientScan tests that Scan and ScanKeys stream the pairs under a
+// prefix in order.
+func TestClientScan(t *testing.T) {
+	db := helpers.SetupDB(t, "memdb")
+	defer db.Close()
+	for _, k := range []string{"user:1", "user:2", "user:3", "post:1"} {
+		require.NoError(t, db.Put(t.Context(), []byte(k), []byte("v-"+k)))
+	}
+	client := zerokvgrpc.NewClient(serve(t, db))
+
+	it := client.Scan([]byte("user:"))
+	var keys []string
+	for it.Next() {
+		keys = append(keys, string(it.Key()))
+		require.Equal(t, "v-"+string(it.Key()), string(it.Value()))
+	}
+	require.NoError(t, it.Error())
+	it.Release()
+	require.Equal(t, []string{"user:1", "user:2", "user:3"}, keys)
+
+	it = client.ScanKeys([]byte("post:"))
+	require.True(t, it.Next())
+	require.Equal(t, []byte("post:1"), it.Key())
+	require.Nil(t, it.Value())
+	require.False(t, it.Next())
+	it.Release()
+
+	it = client.ReverseScan(nil)
+	require.False(t, it.Next())
+	require.ErrorIs(t, it.Error(), zerokv.ErrNotSupported)
+}
+
+// slowCore blocks Get until the request context is done, to show the RPC
+// deadline reaches the store.
+type slowCore struct {
+	zerokv.Core
+	deadline chan bool
+}
+
+func (s *slowCore) Get(ctx context.Context, key []byte) ([]byte, error) {
+	_, ok := ctx.Deadline()
+	<-ctx.Done()
+	s.deadline <- ok
+	return nil, ctx.Err()
+}
+
+// TestServerDeadline tests that a client deadline cancels the Core call on
+// the server.
+func TestServerDeadline(t *testing.T) {
+	core := &slowCore{deadline: make(chan bool, 1)}
+	kv := zerokvpb.NewKVClient(serve(t, core))
+	ctx, cancel := context.WithTimeout(t.Context(), 50*time.Millisecond)
+	defer cancel()
+	_, err := kv.Get(ctx, &zerokvpb.GetRequest{Key: []byte("key")})
+	require.Equal(t, codes.DeadlineExceeded, status.Code(err))
+	select {
+	case ok := <-core.deadline:
+		require.True(t, ok, "The server context should carry the client deadline")
+	case <-time.After(5 * time.Second):
+		t.Fatal("server Get never saw the deadline")
+	}
+}
+
+// TestClientReadOnly tests that a read-only server error maps back to
+// zerokv.ErrReadOnly and unsupported operations report ErrNotSupported.
+func TestClientReadOnly(t *testing.T) {
+	db := helpers.SetupDB(t, "memdb")
+	defer db.Close()
+	client := zerokvgrpc.NewClient(serve(t, readOnly{db}))
+	require.ErrorIs(t, client.Put(t.Context(), []byte("k"), []byte("v")), zerokv.ErrReadOnly)
+	require.ErrorIs(t, client.PutMany(t.Context(), nil, nil), zerokv.ErrNotSupported)
+	require.ErrorIs(t, client.Batch().Commit(t.Context()), zerokv.ErrNotSupported)
+}
+
+type readOnly struct{ zerokv.Core }
+
+func (readOnly) Put(ctx context.Context, key, data []byte) error { return zerokv.ErrReadOnly }
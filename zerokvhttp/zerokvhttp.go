@@ -0,0 +1,145 @@
+// Package zerokvhttp serves a zerokv.Core over HTTP so that services not
+// written in Go can use the store.
+//
+// Keys travel in the URL path base64url-encoded (RFC 4648 §5, padding
+// optional), so binary keys are safe. Values travel as raw request and
+// response bodies. The routes are:
+//
+//	GET    /kv/{key}         the value, or 404 if the key does not exist
+//	PUT    /kv/{key}         stores the request body as the value
+//	DELETE /kv/{key}         removes the key
+//	GET    /scan?prefix=...  every pair under prefix as JSON Lines
+//
+// Scan results use the zerokv.ExportJSONL format, one
+// {"key":...,"value":...} object per line with standard base64 fields, and
+// are streamed as they are read.
+package zerokvhttp
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/rawbytedev/zerokv"
+)
+
+// MaxValueSize is the largest request body PUT accepts; larger bodies are
+// rejected with 413 Request Entity Too Large.
+const MaxValueSize = 64 << 20
+
+// Handler returns an http.Handler serving core. Every request runs with the
+// request's context, so a client that goes away cancels its operation.
+func Handler(core zerokv.Core) http.Handler {
+	s := &server{core: core}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /kv/{key}", s.get)
+	mux.HandleFunc("PUT /kv/{key}", s.put)
+	mux.HandleFunc("DELETE /kv/{key}", s.delete)
+	mux.HandleFunc("GET /scan", s.scan)
+	return mux
+}
+
+type server struct {
+	core zerokv.Core
+}
+
+func (s *server) get(w http.ResponseWriter, r *http.Request) {
+	key, ok := pathKey(w, r)
+	if !ok {
+		return
+	}
+	value, err := s.core.Get(r.Context(), key)
+	if errors.Is(err, zerokv.ErrKeyNotFound) {
+		http.Error(w, "key not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Write(value)
+}
+
+func (s *server) put(w http.ResponseWriter, r *http.Request) {
+	key, ok := pathKey(w, r)
+	if !ok {
+		return
+	}
+	value, err := io.ReadAll(http.MaxBytesReader(w, r.Body, MaxValueSize))
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		http.Error(w, fmt.Sprintf("value larger than %d bytes", tooLarge.Limit), http.StatusRequestEntityTooLarge)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := s.core.Put(r.Context(), key, value); err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *server) delete(w http.ResponseWriter, r *http.Request) {
+	key, ok := pathKey(w, r)
+	if !ok {
+		return
+	}
+	if err := s.core.Delete(r.Context(), key); err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *server) scan(w http.ResponseWriter, r *http.Request) {
+	prefix, err := decodeKey(r.URL.Query().Get("prefix"))
+	if err != nil {
+		http.Error(w, "prefix is not base64url: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	// the status line is sent with the first line of output, so a failure
+	// part way through can only be reported by ending the stream early
+	zerokv.ExportJSONL(r.Context(), s.core, prefix, w)
+}
+
+// pathKey decodes the {key} path segment, writing 400 and returning false
+// if it is not valid base64url.
+func pathKey(w http.ResponseWriter, r *http.Request) ([]byte, bool) {
+	key, err := decodeKey(r.PathValue("key"))
+	if err != nil {
+		http.Error(w, "key is not base64url: "+err.Error(), http.StatusBadRequest)
+		return nil, false
+	}
+	return key, true
+}
+
+// decodeKey decodes base64url with or without padding.
+func decodeKey(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+}
+
+// EncodeKey returns key in the form used in request paths and the prefix
+// query parameter.
+func EncodeKey(key []byte) string {
+	return base64.RawURLEncoding.EncodeToString(key)
+}
+
+// writeError maps errors from the store to a status code.
+func writeError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, zerokv.ErrReadOnly):
+		status = http.StatusForbidden
+	case errors.Is(err, zerokv.ErrNotSupported):
+		status = http.StatusNotImplemented
+	}
+	http.Error(w, err.Error(), status)
+}
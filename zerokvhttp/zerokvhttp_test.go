@@ -0,0 +1,106 @@
+package zerokvhttp_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rawbytedev/zerokv/helpers"
+	"github.com/rawbytedev/zerokv/zerokvhttp"
+	"github.com/stretchr/testify/require"
+)
+
+func do(t *testing.T, h http.Handler, method, target, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(method, target, strings.NewReader(body)))
+	return rec
+}
+
+// TestHandlerCRUD tests put, get and delete through the gateway, including
+// a binary key.
+func TestHandlerCRUD(t *testing.T) {
+	db := helpers.SetupDB(t, "memdb")
+	defer db.Close()
+	h := zerokvhttp.Handler(db)
+	path := "/kv/" + zerokvhttp.EncodeKey([]byte{0x00, '/', 0xFF})
+
+	rec := do(t, h, http.MethodPut, path, "value")
+	require.Equal(t, http.StatusNoContent, rec.Code)
+	value, err := db.Get(t.Context(), []byte{0x00, '/', 0xFF})
+	require.NoError(t, err)
+	require.Equal(t, []byte("value"), value)
+
+	rec = do(t, h, http.MethodGet, path, "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "value", rec.Body.String())
+	require.Equal(t, "application/octet-stream", rec.Header().Get("Content-Type"))
+
+	rec = do(t, h, http.MethodDelete, path, "")
+	require.Equal(t, http.StatusNoContent, rec.Code)
+	rec = do(t, h, http.MethodGet, path, "")
+	require.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+// TestHandlerBadKey tests that keys that are not base64url are rejected.
+func TestHandlerBadKey(t *testing.T) {
+	db := helpers.SetupDB(t, "memdb")
+	defer db.Close()
+	h := zerokvhttp.Handler(db)
+	require.Equal(t, http.StatusBadRequest, do(t, h, http.MethodGet, "/kv/a*b", "").Code)
+	require.Equal(t, http.StatusBadRequest, do(t, h, http.MethodGet, "/scan?prefix=a*b", "").Code)
+	require.Equal(t, http.StatusMethodNotAllowed, do(t, h, http.MethodPost, "/kv/YQ", "").Code)
+}
+
+// TestHandlerScan tests that scan streams every pair under the prefix as
+// JSON Lines.
+func TestHandlerScan(t *testing.T) {
+	db := helpers.SetupDB(t, "memdb")
+	defer db.Close()
+	require.NoError(t, db.Put(t.Context(), []byte("user:1"), []byte("alice")))
+	require.NoError(t, db.Put(t.Context(), []byte("user:2"), []byte("bob")))
+	require.NoError(t, db.Put(t.Context(), []byte("post:1"), []byte("hello")))
+
+	srv := httptest.NewServer(zerokvhttp.Handler(db))
+	defer srv.Close()
+	resp, err := http.Get(srv.URL + "/scan?prefix=" + zerokvhttp.EncodeKey([]byte("user:")))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "application/x-ndjson", resp.Header.Get("Content-Type"))
+
+	var got []string
+	sc := bufio.NewScanner(resp.Body)
+	for sc.Scan() {
+		var rec struct{ Key, Value []byte }
+		require.NoError(t, json.Unmarshal(sc.Bytes(), &rec))
+		got = append(got, string(rec.Key)+"="+string(rec.Value))
+	}
+	require.NoError(t, sc.Err())
+	require.Equal(t, []string{"user:1=alice", "user:2=bob"}, got)
+}
+
+// TestHandlerPutTooLarge tests that bodies over MaxValueSize are refused.
+func TestHandlerPutTooLarge(t *testing.T) {
+	db := helpers.SetupDB(t, "memdb")
+	defer db.Close()
+	h := zerokvhttp.Handler(db)
+	rec := httptest.NewRecorder()
+	body := io.LimitReader(zeros{}, zerokvhttp.MaxValueSize+1)
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/kv/YQ", body))
+	require.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+	ok, err := db.Has(t.Context(), []byte("a"))
+	require.NoError(t, err)
+	require.False(t, ok)
+}
+
+type zeros struct{}
+
+func (zeros) Read(p []byte) (int, error) {
+	clear(p)
+	return len(p), nil
+}